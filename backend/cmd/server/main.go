@@ -16,14 +16,19 @@ import (
 	"github.com/leondli/workspace/internal/infrastructure/database"
 	"github.com/leondli/workspace/internal/infrastructure/logger"
 	"github.com/leondli/workspace/internal/infrastructure/server"
+	"github.com/leondli/workspace/internal/usecase/account"
 	"github.com/leondli/workspace/internal/usecase/auth"
+	"github.com/leondli/workspace/internal/usecase/event"
 	"github.com/leondli/workspace/internal/usecase/kernel"
+	"github.com/leondli/workspace/internal/usecase/maintenance"
+	"github.com/leondli/workspace/internal/usecase/notification"
 	"github.com/leondli/workspace/internal/usecase/object"
 	"github.com/leondli/workspace/internal/usecase/permission"
 	"github.com/leondli/workspace/internal/usecase/search"
 	"github.com/leondli/workspace/internal/usecase/tag"
 	"github.com/leondli/workspace/internal/usecase/user"
 	"github.com/leondli/workspace/internal/usecase/version"
+	"github.com/leondli/workspace/internal/usecase/webhook"
 	"github.com/leondli/workspace/pkg/jwt"
 )
 
@@ -51,7 +56,11 @@ func main() {
 	defer database.Close()
 
 	// Initialize file storage
-	fileStorage := storage.NewLocalFileStorage(cfg.Storage.BasePath, cfg.Storage.VersionPath)
+	fileStorage := storage.NewLocalFileStorage(cfg.Storage.BasePath, cfg.Storage.VersionPath, cfg.Storage.GetTrashPath(), cfg.Storage.GetUploadPath(), cfg.Storage.GetCompressTypes())
+	uploadScanner, err := storage.NewScanner(cfg.Storage.GetScanner(), cfg.Storage.MaxUploadSize, cfg.Storage.ScannerCommand, cfg.Storage.GetScannerTimeout())
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create upload scanner")
+	}
 
 	// Initialize JWT manager
 	jwtManager := jwt.NewJWTManager(
@@ -68,14 +77,43 @@ func main() {
 	permissionRepo := repository.NewPermissionRepository(db)
 	versionRepo := repository.NewVersionRepository(db)
 	tagRepo := repository.NewTagRepository(db)
+	kernelSessionRepo := repository.NewKernelSessionRepository(db)
+	userSettingRepo := repository.NewUserSettingRepository(db)
+	nameHistoryRepo := repository.NewObjectNameHistoryRepository(db)
+	deleteBatchRepo := repository.NewDeleteBatchRepository(db)
+	notebookRunRepo := repository.NewNotebookRunRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	uploadSessionRepo := repository.NewUploadSessionRepository(db)
+	objectPinRepo := repository.NewObjectPinRepository(db)
+	objectAccessRepo := repository.NewObjectAccessRepository(db)
+	objectTemplateRepo := repository.NewObjectTemplateRepository(db)
+	if cfg.Cache.ObjectEnabled {
+		objectCache := repository.NewObjectCache(cfg.Cache.GetObjectSize(), cfg.Cache.GetObjectTTL())
+		objectRepo = repository.NewCachedObjectRepository(objectRepo, objectCache)
+		tagRepo = repository.NewCachedTagRepository(tagRepo, objectCache)
+	}
 
 	// Initialize use cases
 	authUseCase := auth.NewUseCase(userRepo, refreshTokenRepo, jwtManager, &cfg.Storage)
-	userUseCase := user.NewUseCase(userRepo)
-	objectUseCase := object.NewUseCase(objectRepo, versionRepo, permissionRepo, fileStorage)
-	permissionUseCase := permission.NewUseCase(permissionRepo, objectRepo, userRepo)
-	versionUseCase := version.NewUseCase(versionRepo, objectRepo, fileStorage)
-	searchUseCase := search.NewUseCase(objectRepo, tagRepo, fileStorage)
+	userUseCase := user.NewUseCase(userRepo, userSettingRepo)
+	webhookDispatcher := webhook.NewDispatcher(&cfg.Webhook)
+	webhookUseCase := webhook.NewUseCase(webhookRepo, objectRepo, permissionRepo, webhookDispatcher)
+	notificationUseCase := notification.NewUseCase(notificationRepo)
+
+	// Change events from the use cases below go to webhooks and notifications.
+	// Webhooks queue their own deliveries; notifications are written off the
+	// request path.
+	events := event.NewBus()
+	events.Subscribe(webhookUseCase, event.Types...)
+	events.SubscribeAsync("notifications", notificationUseCase, 1000, event.PermissionChanged, event.ObjectCreated)
+
+	accessRecorder := object.NewAccessRecorder(objectAccessRepo, &cfg.AccessStats)
+	exportJobs := object.NewExportJobs(&cfg.Export)
+	objectUseCase := object.NewUseCase(objectRepo, versionRepo, permissionRepo, nameHistoryRepo, deleteBatchRepo, notebookRunRepo, uploadSessionRepo, tagRepo, objectPinRepo, objectTemplateRepo, fileStorage, uploadScanner, accessRecorder, exportJobs, &cfg.Storage, &cfg.Permission, events)
+	permissionUseCase := permission.NewUseCase(permissionRepo, objectRepo, userRepo, events)
+	versionUseCase := version.NewUseCase(versionRepo, objectRepo, permissionRepo, fileStorage, &cfg.Storage, events)
+	searchUseCase := search.NewUseCase(objectRepo, tagRepo, permissionRepo, fileStorage)
 	tagUseCase := tag.NewUseCase(tagRepo, objectRepo)
 
 	// Initialize kernel use case with gateway support
@@ -83,30 +121,78 @@ func main() {
 	if cfg.Kernel.Gateway.Enabled {
 		log.Info().Str("gateway_url", cfg.Kernel.Gateway.URL).Msg("Initializing kernel with gateway support")
 		var err error
-		kernelUseCase, err = kernel.NewUseCaseWithGateway(cfg.Kernel.PythonPath, cfg.Storage.BasePath, &cfg.Kernel.Gateway)
+		kernelUseCase, err = kernel.NewUseCaseWithGateway(&cfg.Kernel, cfg.Storage.BasePath, kernelSessionRepo, &cfg.Server, events)
 		if err != nil {
 			log.Warn().Err(err).Msg("Failed to initialize gateway, falling back to local kernel mode")
-			kernelUseCase = kernel.NewUseCase(cfg.Kernel.PythonPath, cfg.Storage.BasePath)
+			kernelUseCase = kernel.NewUseCase(&cfg.Kernel, cfg.Storage.BasePath, kernelSessionRepo, &cfg.Server, events)
 		}
 	} else {
-		kernelUseCase = kernel.NewUseCase(cfg.Kernel.PythonPath, cfg.Storage.BasePath)
+		kernelUseCase = kernel.NewUseCase(&cfg.Kernel, cfg.Storage.BasePath, kernelSessionRepo, &cfg.Server, events)
+	}
+
+	accountUseCase := account.NewUseCase(userRepo, refreshTokenRepo, objectUseCase, kernelUseCase, jwtManager, &cfg.Account)
+	maintenanceUseCase := maintenance.NewUseCase(refreshTokenRepo, uploadSessionRepo, fileStorage, accountUseCase)
+
+	// Validate local kernel interpreters so misconfiguration surfaces at startup
+	if policy := cfg.Kernel.GetPythonCheck(); policy != "off" {
+		checkCtx, checkCancel := context.WithTimeout(context.Background(), 60*time.Second)
+		if err := kernelUseCase.ValidateInterpreters(checkCtx); err != nil {
+			if policy == "fatal" {
+				log.Fatal().Err(err).Msg("Kernel interpreter check failed")
+			}
+			log.Warn().Err(err).Msg("Kernel interpreter check failed, kernels may not start")
+		}
+		checkCancel()
+	}
+
+	// Remove connection directories left by local kernels of a crashed run
+	sweepCtx, sweepCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := kernelUseCase.SweepConnectionDirs(sweepCtx); err != nil {
+		log.Warn().Err(err).Msg("Failed to sweep kernel connection directories")
 	}
+	sweepCancel()
+
+	// Restore persisted kernel sessions, reattaching gateway kernels that survived a restart
+	restoreCtx, restoreCancel := context.WithTimeout(context.Background(), 60*time.Second)
+	if err := kernelUseCase.RestoreKernels(restoreCtx); err != nil {
+		log.Warn().Err(err).Msg("Failed to restore kernel sessions")
+	}
+	restoreCancel()
+
+	// Probe kernels periodically so hung kernels are reported as unresponsive
+	healthCtx, healthCancel := context.WithCancel(context.Background())
+	go kernelUseCase.RunHealthChecks(healthCtx)
+
+	// Start background cleanup of expired tokens and erasure of deleted accounts
+	maintenanceScheduler := maintenance.NewScheduler(maintenanceUseCase, &cfg.Maintenance)
+	maintenanceScheduler.Start()
+
+	// Start webhook delivery workers
+	webhookDispatcher.Start()
+
+	// Start writing buffered object access counts
+	accessRecorder.Start()
 
 	// Initialize handlers
 	handlers := &handler.Handlers{
-		Auth:       handler.NewAuthHandler(authUseCase),
-		User:       handler.NewUserHandler(userUseCase),
-		Object:     handler.NewObjectHandler(objectUseCase),
-		Permission: handler.NewPermissionHandler(permissionUseCase),
-		Version:    handler.NewVersionHandler(versionUseCase),
-		Search:     handler.NewSearchHandler(searchUseCase),
-		Tag:        handler.NewTagHandler(tagUseCase),
-		Kernel:     handler.NewKernelHandler(kernelUseCase),
+		Auth:         handler.NewAuthHandler(authUseCase, &cfg.JWT),
+		User:         handler.NewUserHandler(userUseCase),
+		Account:      handler.NewAccountHandler(accountUseCase, &cfg.JWT),
+		Object:       handler.NewObjectHandler(objectUseCase, &cfg.Pagination),
+		Permission:   handler.NewPermissionHandler(permissionUseCase),
+		Version:      handler.NewVersionHandler(versionUseCase, &cfg.Pagination),
+		Search:       handler.NewSearchHandler(searchUseCase, &cfg.Pagination),
+		Tag:          handler.NewTagHandler(tagUseCase, &cfg.Pagination),
+		Kernel:       handler.NewKernelHandler(kernelUseCase, objectUseCase, &cfg.Kernel.WebSocket),
+		Maintenance:  handler.NewMaintenanceHandler(maintenanceUseCase, &cfg.Maintenance),
+		Webhook:      handler.NewWebhookHandler(webhookUseCase, &cfg.Pagination),
+		Notification: handler.NewNotificationHandler(notificationUseCase, &cfg.Pagination),
+		Config:       handler.NewConfigHandler(&cfg.Maintenance),
 	}
 
 	// Initialize HTTP server
 	srv := server.New(&cfg.Server)
-	handler.RegisterRoutes(srv.Router(), handlers, jwtManager)
+	handler.RegisterRoutes(srv.Router(), handlers, jwtManager, cfg)
 
 	// Start server in goroutine
 	go func() {
@@ -130,5 +216,13 @@ func main() {
 		log.Error().Err(err).Msg("Server forced to shutdown")
 	}
 
+	maintenanceScheduler.Stop()
+	accountUseCase.Wait()
+	events.Close()
+	webhookDispatcher.Stop()
+	accessRecorder.Stop()
+	exportJobs.Stop()
+	healthCancel()
+
 	log.Info().Msg("Server exited")
 }
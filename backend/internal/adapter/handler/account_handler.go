@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	"github.com/leondli/workspace/internal/infrastructure/middleware"
+	"github.com/leondli/workspace/internal/usecase/account"
+	"github.com/leondli/workspace/pkg/response"
+)
+
+// AccountHandler handles account deletion requests
+type AccountHandler struct {
+	accountUseCase account.UseCase
+	jwtConfig      *config.JWTConfig
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(accountUseCase account.UseCase, jwtConfig *config.JWTConfig) *AccountHandler {
+	return &AccountHandler{accountUseCase: accountUseCase, jwtConfig: jwtConfig}
+}
+
+type deleteAccountRequest struct {
+	Password string `json:"password" binding:"required"` // Current password, to confirm the deletion
+}
+
+// DeleteMe godoc
+// @Summary Delete the current user's account
+// @Description Signs the user out everywhere. With a deletion grace period configured the account is disabled and erased when it ends; otherwise it is erased at once. Objects shared with other users are transferred or deleted as configured.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body deleteAccountRequest true "Password confirmation"
+// @Success 200 {object} response.Response{data=account.Deletion}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/v1/users/me [delete]
+func (h *AccountHandler) DeleteMe(c *gin.Context) {
+	userID, err := uuid.Parse(middleware.GetUserID(c))
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	var req deleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	deletion, err := h.accountUseCase.DeleteAccount(c.Request.Context(), userID, req.Password)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	middleware.ClearAuthCookies(c, h.jwtConfig)
+	response.Success(c, deletion)
+}
+
+// DeleteUser godoc
+// @Summary Delete a user's account
+// @Description Disables the account until the deletion grace period ends, or erases it at once. Without a mode the account is deleted as self-service deletion does. An account already disabled for deletion can be erased early.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Param mode query string false "disable or erase"
+// @Success 200 {object} response.Response{data=account.Deletion}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/users/{id} [delete]
+func (h *AccountHandler) DeleteUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid user ID")
+		return
+	}
+
+	deletion, err := h.accountUseCase.AdminDeleteAccount(c.Request.Context(), userID, account.DeletionMode(c.Query("mode")))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, deletion)
+}
+
+// RestoreUser godoc
+// @Summary Restore an account disabled for deletion
+// @Description Reactivates an account before its deletion grace period ends. The user signs in again.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} response.Response{data=entity.UserResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/users/{id}/restore [post]
+func (h *AccountHandler) RestoreUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid user ID")
+		return
+	}
+
+	user, err := h.accountUseCase.RestoreAccount(c.Request.Context(), userID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, user)
+}
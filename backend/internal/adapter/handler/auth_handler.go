@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/leondli/workspace/internal/infrastructure/config"
 	"github.com/leondli/workspace/internal/infrastructure/middleware"
 	"github.com/leondli/workspace/internal/usecase/auth"
 	apperrors "github.com/leondli/workspace/pkg/errors"
@@ -13,11 +14,12 @@ import (
 // AuthHandler handles authentication requests
 type AuthHandler struct {
 	authUseCase auth.UseCase
+	jwtConfig   *config.JWTConfig
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(authUseCase auth.UseCase) *AuthHandler {
-	return &AuthHandler{authUseCase: authUseCase}
+func NewAuthHandler(authUseCase auth.UseCase, jwtConfig *config.JWTConfig) *AuthHandler {
+	return &AuthHandler{authUseCase: authUseCase, jwtConfig: jwtConfig}
 }
 
 // Register godoc
@@ -43,6 +45,10 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	if !h.setAuthCookies(c, output) {
+		return
+	}
+
 	response.Created(c, output)
 }
 
@@ -69,6 +75,10 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if !h.setAuthCookies(c, output) {
+		return
+	}
+
 	response.Success(c, output)
 }
 
@@ -94,6 +104,10 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	if !h.setAuthCookies(c, output) {
+		return
+	}
+
 	response.Success(c, output)
 }
 
@@ -117,9 +131,38 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
+	middleware.ClearAuthCookies(c, h.jwtConfig)
+
 	response.Success(c, gin.H{"message": "logged out successfully"})
 }
 
+// ForceLogout godoc
+// @Summary Force logout all of a user's sessions
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/users/{id}/logout [post]
+func (h *AuthHandler) ForceLogout(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid user ID")
+		return
+	}
+
+	if err := h.authUseCase.ForceLogout(c.Request.Context(), userID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "user logged out from all sessions"})
+}
+
 // ChangePassword godoc
 // @Summary Change user password
 // @Tags auth
@@ -153,6 +196,16 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	response.Success(c, gin.H{"message": "password changed successfully"})
 }
 
+// setAuthCookies sets the access token and CSRF cookies when cookie auth is
+// enabled, writing an error response and returning false on failure
+func (h *AuthHandler) setAuthCookies(c *gin.Context, output *auth.AuthOutput) bool {
+	if err := middleware.SetAuthCookies(c, h.jwtConfig, output.AccessToken, int(output.ExpiresIn)); err != nil {
+		handleError(c, apperrors.InternalError("failed to issue CSRF token", err))
+		return false
+	}
+	return true
+}
+
 type refreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
@@ -170,10 +223,16 @@ func handleError(c *gin.Context, err error) {
 			response.NotFound(c, appErr.Message)
 		case apperrors.IsAlreadyExists(appErr.Err):
 			response.Conflict(c, appErr.Message)
+		case apperrors.IsConflict(appErr.Err):
+			response.Error(c, appErr.HTTPCode, appErr.Code, appErr.Message)
 		case apperrors.IsUnauthorized(appErr.Err):
 			response.Unauthorized(c, appErr.Message)
 		case apperrors.IsForbidden(appErr.Err):
 			response.Forbidden(c, appErr.Message)
+		case apperrors.IsInvalidInput(appErr.Err):
+			response.Error(c, appErr.HTTPCode, appErr.Code, appErr.Message)
+		case apperrors.IsUnavailable(appErr.Err):
+			response.Error(c, appErr.HTTPCode, appErr.Code, appErr.Message)
 		default:
 			response.InternalError(c, appErr.Message)
 		}
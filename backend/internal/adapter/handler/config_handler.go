@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	"github.com/leondli/workspace/pkg/response"
+)
+
+// ClientConfig is the server configuration clients adapt to
+type ClientConfig struct {
+	Maintenance config.MaintenanceMode `json:"maintenance"`
+}
+
+// ConfigHandler serves client configuration
+type ConfigHandler struct {
+	maintenanceConfig *config.MaintenanceConfig
+}
+
+// NewConfigHandler creates a new config handler
+func NewConfigHandler(maintenanceConfig *config.MaintenanceConfig) *ConfigHandler {
+	return &ConfigHandler{maintenanceConfig: maintenanceConfig}
+}
+
+// GetConfig godoc
+// @Summary Get client configuration
+// @Description Returns the announced maintenance state, for showing a banner and disabling edits in read-only mode.
+// @Tags config
+// @Produce json
+// @Success 200 {object} response.Response{data=ClientConfig}
+// @Router /api/v1/config [get]
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	response.Success(c, ClientConfig{Maintenance: h.maintenanceConfig.GetMode()})
+}
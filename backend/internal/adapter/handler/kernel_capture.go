@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/usecase/kernel"
+	"github.com/leondli/workspace/internal/usecase/object"
+)
+
+// capturedOutputs lists the files an execution with capture_outputs wrote, in
+// the metadata of its execute_reply
+type capturedOutputs struct {
+	Objects []object.ImportedObject `json:"objects"`
+	Skipped []object.ImportIssue    `json:"skipped,omitempty"`
+}
+
+// startCapture makes req run in a new capture directory if it asks for
+// capture_outputs. The caller collects the directory with finishCapture when
+// the execution replies, or discards it.
+func (h *KernelHandler) startCapture(req *kernel.ExecuteRequest, ws executeWorkspace) error {
+	req.CaptureDir = ""
+	if !req.CaptureOutputs {
+		return nil
+	}
+	if _, err := uuid.Parse(ws.userID); err != nil || ws.appID == "" || ws.email == "" {
+		return fmt.Errorf("capture_outputs requires an authenticated user")
+	}
+	if req.MsgID == "" {
+		return fmt.Errorf("capture_outputs requires a msg_id")
+	}
+
+	dir, err := h.kernelUseCase.StartCapture()
+	if err != nil {
+		return err
+	}
+	req.CaptureDir = dir
+	return nil
+}
+
+// finishCapture imports the files written to a capture directory into the
+// user's results folder and returns a copy of reply listing them in its
+// metadata under captured_outputs
+func (h *KernelHandler) finishCapture(ctx context.Context, dir string, ws executeWorkspace, reply *kernel.KernelMessage) *kernel.KernelMessage {
+	resultsDir := h.kernelUseCase.CaptureResultsDir()
+	files, issues := h.kernelUseCase.CollectCapture(dir)
+
+	outputs := capturedOutputs{Objects: []object.ImportedObject{}}
+	for _, issue := range issues {
+		outputs.Skipped = append(outputs.Skipped, object.ImportIssue{Path: path.Join(resultsDir, issue.Path), Reason: issue.Reason})
+	}
+	if len(files) > 0 {
+		imported := make([]object.ImportedFile, len(files))
+		for i, file := range files {
+			imported[i] = object.ImportedFile{Path: file.Path, Content: file.Content}
+		}
+
+		userID, _ := uuid.Parse(ws.userID)
+		objects, skipped, err := h.objectUseCase.ImportFiles(ctx, userID, ws.appID, ws.email, resultsDir, imported)
+		outputs.Objects = append(outputs.Objects, objects...)
+		outputs.Skipped = append(outputs.Skipped, skipped...)
+		if err != nil {
+			log.Warn().Err(err).Str("msg_id", reply.ParentID).Msg("Failed to import captured files")
+			// Files are imported in order, each either created or skipped
+			for _, file := range files[len(objects)+len(skipped):] {
+				outputs.Skipped = append(outputs.Skipped, object.ImportIssue{Path: path.Join(resultsDir, file.Path), Reason: "import failed"})
+			}
+		}
+	}
+
+	// The reply is shared with the kernel's other sessions
+	captured := *reply
+	captured.Metadata = make(map[string]interface{}, len(reply.Metadata)+1)
+	for k, v := range reply.Metadata {
+		captured.Metadata[k] = v
+	}
+	captured.Metadata["captured_outputs"] = outputs
+	return &captured
+}
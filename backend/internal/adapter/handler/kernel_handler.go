@@ -3,9 +3,14 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
 	"net/http"
+	"path"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,21 +18,58 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog/log"
 
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	"github.com/leondli/workspace/internal/infrastructure/database"
+	"github.com/leondli/workspace/internal/infrastructure/gateway"
+	"github.com/leondli/workspace/internal/infrastructure/middleware"
 	"github.com/leondli/workspace/internal/usecase/kernel"
+	"github.com/leondli/workspace/internal/usecase/object"
 	"github.com/leondli/workspace/pkg/response"
 )
 
+// closeTooManyConnections is the WebSocket close code sent when a connection
+// limit is exceeded (4000-4999 is reserved for application use)
+const closeTooManyConnections = 4429
+
+// closeIdleTimeout is the WebSocket close code sent when a connection has been
+// idle for longer than the idle timeout. Only the socket is closed; the kernel
+// keeps running and the client may reconnect.
+const closeIdleTimeout = 4408
+
+// wsConnectionsGauge exposes the current number of kernel WebSocket connections
+var wsConnectionsGauge = expvar.NewInt("kernel_ws_connections")
+
+// wsConnection tracks a single kernel WebSocket connection
+type wsConnection struct {
+	conn   *websocket.Conn
+	userID string
+}
+
 // KernelHandler handles kernel-related HTTP and WebSocket requests
 type KernelHandler struct {
 	kernelUseCase *kernel.UseCase
+	objectUseCase object.UseCase
 	upgrader      websocket.Upgrader
-	connections   sync.Map // map[string]*websocket.Conn - sessionID -> connection
+	wsConfig      *config.WebSocketConfig
+
+	connMu      sync.Mutex
+	connections map[string]*wsConnection // sessionID -> connection
+	userConns   map[string]int           // userID -> number of open connections
+
+	runMu sync.Mutex
+	runs  map[int64]string // objectID -> kernel ID of notebooks being run
 }
 
 // NewKernelHandler creates a new KernelHandler
-func NewKernelHandler(kernelUseCase *kernel.UseCase) *KernelHandler {
+func NewKernelHandler(kernelUseCase *kernel.UseCase, objectUseCase object.UseCase, wsConfig *config.WebSocketConfig) *KernelHandler {
 	return &KernelHandler{
 		kernelUseCase: kernelUseCase,
+		objectUseCase: objectUseCase,
+		wsConfig:      wsConfig,
+		connections:   make(map[string]*wsConnection),
+		userConns:     make(map[string]int),
+		runs:          make(map[int64]string),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
@@ -38,6 +80,30 @@ func NewKernelHandler(kernelUseCase *kernel.UseCase) *KernelHandler {
 	}
 }
 
+// Readiness reports whether requests can be served. It fails while the
+// database is unreachable or the gateway circuit breaker is open, so load
+// balancers route to healthier instances.
+func (h *KernelHandler) Readiness(c *gin.Context) {
+	body := gin.H{"status": "ready", "db_pool": database.Stats()}
+	if err := database.Ping(c.Request.Context()); err != nil {
+		log.Warn().Err(err).Msg("Readiness check: database unreachable")
+		body["status"] = "unavailable"
+		body["database"] = "unreachable"
+		c.JSON(http.StatusServiceUnavailable, body)
+		return
+	}
+	state := h.kernelUseCase.GatewayCircuitState()
+	if state != "" {
+		body["gateway_circuit"] = state
+	}
+	if state == gateway.CircuitOpen {
+		body["status"] = "unavailable"
+		c.JSON(http.StatusServiceUnavailable, body)
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
 // ListKernelSpecs returns available kernel specifications
 func (h *KernelHandler) ListKernelSpecs(c *gin.Context) {
 	specs, err := h.kernelUseCase.ListKernelSpecs(c.Request.Context())
@@ -45,12 +111,26 @@ func (h *KernelHandler) ListKernelSpecs(c *gin.Context) {
 		response.InternalError(c, err.Error())
 		return
 	}
-	response.Success(c, specs)
+	response.Success(c, h.kernelUseCase.AllowedSpecs(middleware.GetAppID(c), specs))
+}
+
+// RefreshKernelSpecs forces kernel spec rediscovery and returns the refreshed list
+func (h *KernelHandler) RefreshKernelSpecs(c *gin.Context) {
+	specs, err := h.kernelUseCase.RefreshKernelSpecs(c.Request.Context())
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+	response.Success(c, h.kernelUseCase.AllowedSpecs(middleware.GetAppID(c), specs))
 }
 
 // StartKernelRequest represents the request to start a kernel
 type StartKernelRequest struct {
-	Name string `json:"name" binding:"required"` // kernel spec name, e.g., "python3"
+	Name     string `json:"name" binding:"required"` // kernel spec name, e.g., "python3"
+	ObjectID *int64 `json:"object_id"`               // Notebook to associate; its kernelspec metadata is updated to match
+	// Run executions concurrently instead of one at a time in submission
+	// order (default: false). Gateway kernels always run them in order.
+	AllowConcurrentExecution bool `json:"allow_concurrent_execution"`
 }
 
 // StartKernel starts a new kernel instance
@@ -67,15 +147,71 @@ func (h *KernelHandler) StartKernel(c *gin.Context) {
 		return
 	}
 
-	kernelInfo, err := h.kernelUseCase.StartKernel(c.Request.Context(), req.Name, userID.(string))
+	homeDir := workspaceRoot(middleware.GetAppID(c), middleware.GetEmail(c))
+	kernelInfo, err := h.kernelUseCase.StartKernel(c.Request.Context(), req.Name, userID.(string), middleware.GetAppID(c), homeDir, req.AllowConcurrentExecution)
 	if err != nil {
-		response.InternalError(c, "Failed to start kernel: "+err.Error())
+		handleKernelError(c, "Failed to start kernel", err)
 		return
 	}
 
+	if req.ObjectID != nil {
+		h.kernelUseCase.AssociateObject(c.Request.Context(), kernelInfo.ID, *req.ObjectID)
+		h.syncNotebookKernelspec(c.Request.Context(), *req.ObjectID, req.Name, userID.(string))
+	}
+
 	response.Success(c, kernelInfo)
 }
 
+// syncNotebookKernelspec updates a notebook's kernelspec metadata to the spec
+// it was started with. The kernel is already running, so failures are logged
+// rather than returned.
+func (h *KernelHandler) syncNotebookKernelspec(ctx context.Context, objectID int64, specName, userIDStr string) {
+	logger := log.With().Int64("object_id", objectID).Str("spec", specName).Logger()
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Invalid user ID, skipping notebook kernelspec update")
+		return
+	}
+
+	specs, err := h.kernelUseCase.ListKernelSpecs(ctx)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to list kernel specs, skipping notebook kernelspec update")
+		return
+	}
+	spec, ok := specs[specName]
+	if !ok {
+		logger.Warn().Msg("Kernel spec not found, skipping notebook kernelspec update")
+		return
+	}
+
+	if _, err := h.objectUseCase.SetNotebookKernelspec(ctx, objectID, userID, &object.NotebookKernelspec{
+		Name:        spec.Name,
+		DisplayName: spec.DisplayName,
+		Language:    spec.Language,
+	}); err != nil {
+		logger.Warn().Err(err).Msg("Failed to update notebook kernelspec")
+	}
+}
+
+// ListObjectKernels returns the kernels started for a notebook by any user,
+// with their owners and status, so collaborators can see who has a live
+// session. Requires view permission on the notebook.
+func (h *KernelHandler) ListObjectKernels(c *gin.Context) {
+	objectID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	kernels, err := h.kernelUseCase.ListKernelsForObject(c.Request.Context(), objectID)
+	if err != nil {
+		response.InternalError(c, "Failed to list kernels: "+err.Error())
+		return
+	}
+	response.Success(c, kernels)
+}
+
 // StopKernel stops a running kernel
 func (h *KernelHandler) StopKernel(c *gin.Context) {
 	kernelID := c.Param("kernel_id")
@@ -85,7 +221,7 @@ func (h *KernelHandler) StopKernel(c *gin.Context) {
 	}
 
 	if err := h.kernelUseCase.StopKernel(c.Request.Context(), kernelID); err != nil {
-		response.InternalError(c, "Failed to stop kernel: "+err.Error())
+		handleKernelError(c, "Failed to stop kernel", err)
 		return
 	}
 
@@ -101,7 +237,7 @@ func (h *KernelHandler) RestartKernel(c *gin.Context) {
 	}
 
 	if err := h.kernelUseCase.RestartKernel(c.Request.Context(), kernelID); err != nil {
-		response.InternalError(c, "Failed to restart kernel: "+err.Error())
+		handleKernelError(c, "Failed to restart kernel", err)
 		return
 	}
 
@@ -117,13 +253,29 @@ func (h *KernelHandler) InterruptKernel(c *gin.Context) {
 	}
 
 	if err := h.kernelUseCase.InterruptKernel(c.Request.Context(), kernelID); err != nil {
-		response.InternalError(c, "Failed to interrupt kernel: "+err.Error())
+		handleKernelError(c, "Failed to interrupt kernel", err)
 		return
 	}
 
 	response.Success(c, gin.H{"message": "Kernel interrupted"})
 }
 
+// ResetKernel clears a kernel's variables without restarting it
+func (h *KernelHandler) ResetKernel(c *gin.Context) {
+	kernelID := c.Param("kernel_id")
+	if kernelID == "" {
+		response.BadRequest(c, "Kernel ID is required")
+		return
+	}
+
+	if err := h.kernelUseCase.ResetKernel(c.Request.Context(), kernelID); err != nil {
+		handleKernelError(c, "Failed to reset kernel", err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Kernel namespace reset"})
+}
+
 // GetKernelStatus returns the status of a kernel
 func (h *KernelHandler) GetKernelStatus(c *gin.Context) {
 	kernelID := c.Param("kernel_id")
@@ -134,13 +286,30 @@ func (h *KernelHandler) GetKernelStatus(c *gin.Context) {
 
 	status, err := h.kernelUseCase.GetKernelStatus(c.Request.Context(), kernelID)
 	if err != nil {
-		response.InternalError(c, "Failed to get kernel status: "+err.Error())
+		handleKernelError(c, "Failed to get kernel status", err)
 		return
 	}
 
 	response.Success(c, status)
 }
 
+// GetKernelLogs returns the most recent stderr lines of a kernel
+func (h *KernelHandler) GetKernelLogs(c *gin.Context) {
+	kernelID := c.Param("kernel_id")
+	if kernelID == "" {
+		response.BadRequest(c, "Kernel ID is required")
+		return
+	}
+
+	logs, err := h.kernelUseCase.GetKernelLogs(c.Request.Context(), kernelID)
+	if err != nil {
+		handleKernelError(c, "Failed to get kernel logs", err)
+		return
+	}
+
+	response.Success(c, logs)
+}
+
 // ListKernels returns all running kernels for the current user
 func (h *KernelHandler) ListKernels(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -158,6 +327,34 @@ func (h *KernelHandler) ListKernels(c *gin.Context) {
 	response.Success(c, kernels)
 }
 
+// ListAllKernels returns the running kernels of all users, for administrators
+func (h *KernelHandler) ListAllKernels(c *gin.Context) {
+	kernels, err := h.kernelUseCase.ListAllKernels(c.Request.Context())
+	if err != nil {
+		response.InternalError(c, "Failed to list kernels: "+err.Error())
+		return
+	}
+
+	response.Success(c, kernels)
+}
+
+// ForceStopKernel stops any kernel regardless of its owner, for administrators
+func (h *KernelHandler) ForceStopKernel(c *gin.Context) {
+	kernelID := c.Param("kernel_id")
+	if kernelID == "" {
+		response.BadRequest(c, "Kernel ID is required")
+		return
+	}
+
+	log.Info().Str("kernel_id", kernelID).Str("admin", middleware.GetEmail(c)).Msg("Force stopping kernel")
+	if err := h.kernelUseCase.StopKernel(c.Request.Context(), kernelID); err != nil {
+		handleKernelError(c, "Failed to stop kernel", err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Kernel stopped"})
+}
+
 // WebSocketConnect handles WebSocket connections for kernel communication
 func (h *KernelHandler) WebSocketConnect(c *gin.Context) {
 	kernelID := c.Param("kernel_id")
@@ -166,6 +363,16 @@ func (h *KernelHandler) WebSocketConnect(c *gin.Context) {
 		return
 	}
 
+	// Clients may limit forwarded messages, e.g. ?msg_types=stream,execute_result,error
+	filter := kernel.ParseMessageFilter(c.Query("msg_types"))
+
+	// Kernels owned by another instance must be reached through that instance
+	var remote *kernel.RemoteKernelError
+	if err := h.kernelUseCase.LocateKernel(c.Request.Context(), kernelID); errors.As(err, &remote) {
+		handleKernelError(c, "Failed to connect to kernel", err)
+		return
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -173,42 +380,165 @@ func (h *KernelHandler) WebSocketConnect(c *gin.Context) {
 		return
 	}
 
+	userID := middleware.GetUserID(c)
+	// Needed to resolve execute request working directories
+	workspace := requestWorkspace(c)
+
 	sessionID := uuid.New().String()
-	h.connections.Store(sessionID, conn)
+	if !h.addConnection(sessionID, userID, conn) {
+		log.Warn().Str("user_id", userID).Str("kernel_id", kernelID).Msg("WebSocket connection limit exceeded")
+		closeMsg := websocket.FormatCloseMessage(closeTooManyConnections, "too many connections")
+		conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		conn.Close()
+		return
+	}
 
 	// Create a context for this WebSocket connection that won't be cancelled
 	// when the HTTP request ends
 	ctx, cancel := context.WithCancel(context.Background())
 
-	defer func() {
-		cancel()
-		h.connections.Delete(sessionID)
-		conn.Close()
-	}()
+	// Both the read loop and the send goroutine may end the connection, so
+	// cleanup must only run once
+	var cleanupOnce sync.Once
+	cleanup := func() {
+		cleanupOnce.Do(func() {
+			cancel()
+			h.removeConnection(sessionID)
+			conn.Close()
+		})
+	}
+	defer cleanup()
+
+	// Messages in either direction count as activity for the idle timeout
+	var lastActivity atomic.Int64
+	touch := func() { lastActivity.Store(time.Now().UnixNano()) }
+	touch()
+	if idleTimeout := h.wsConfig.GetIdleTimeout(); idleTimeout > 0 {
+		go h.closeWhenIdle(ctx, conn, idleTimeout, &lastActivity, cleanup, kernelID, userID)
+	}
 
 	// Create a channel to receive messages from kernel
-	outputChan := make(chan *kernel.KernelMessage, 100)
+	outputChan := make(chan *kernel.KernelMessage, h.kernelUseCase.OutputBufferSize())
 	doneChan := make(chan struct{})
 
 	// Register this connection to receive kernel output
-	h.kernelUseCase.RegisterOutputChannel(kernelID, sessionID, outputChan)
+	h.kernelUseCase.RegisterOutputChannel(kernelID, sessionID, outputChan, filter)
 	defer h.kernelUseCase.UnregisterOutputChannel(kernelID, sessionID)
 
+	// Throttle output so tight print loops do not flood the client
+	outputRate := 0
+	if h.wsConfig != nil {
+		outputRate = h.wsConfig.OutputRateLimit
+	}
+	limitedChan := kernel.RateLimitOutput(outputChan, outputRate, doneChan)
+
+	// Server-generated replies to the client (acks, errors). They bypass the
+	// message filter and rate limit, and go through the writer goroutine
+	// because a WebSocket connection allows only one concurrent writer.
+	controlChan := make(chan *kernel.KernelMessage, controlBufferSize)
+	sendControl := func(msg *kernel.KernelMessage) {
+		select {
+		case controlChan <- msg:
+		case <-ctx.Done():
+		}
+	}
+
+	// Capture directories of executions with capture_outputs, by msg_id. The
+	// writer holds back their execute_reply until the files are imported.
+	var captures sync.Map
+	defer captures.Range(func(msgID, dir any) bool {
+		if _, ok := captures.LoadAndDelete(msgID); ok {
+			h.kernelUseCase.DiscardCapture(dir.(string))
+		}
+		return true
+	})
+
+	// Executions whose ack or nack has not been written yet, by msg_id. The
+	// kernel may answer before the ack is queued, so the writer holds back
+	// their output until it has written the ack.
+	var unacked sync.Map
+
 	// Goroutine to send kernel output to WebSocket client
 	go func() {
+		write := func(msg *kernel.KernelMessage) bool {
+			data, err := json.Marshal(msg)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to marshal kernel message")
+				return true
+			}
+			frameType := websocket.TextMessage
+			if len(msg.Buffers) > 0 {
+				// Binary buffers (e.g. widget data) use Jupyter's binary framing
+				frameType = websocket.BinaryMessage
+				data = gateway.SerializeBinaryMessage(data, msg.Buffers)
+			}
+			if err := conn.WriteMessage(frameType, data); err != nil {
+				log.Error().Err(err).Msg("Failed to write WebSocket message")
+				cleanup()
+				return false
+			}
+			touch()
+			return true
+		}
+
+		// forward writes kernel output, except execute_replies of executions
+		// that capture outputs, which are sent once the files are imported
+		forward := func(msg *kernel.KernelMessage) bool {
+			if msg.MsgType == "execute_reply" {
+				if dir, ok := captures.LoadAndDelete(msg.ParentID); ok {
+					go func(reply *kernel.KernelMessage) {
+						sendControl(h.finishCapture(ctx, dir.(string), workspace, reply))
+					}(msg)
+					return true
+				}
+			}
+			return write(msg)
+		}
+
+		// Output held back until the ack of its execution is written
+		held := make(map[string][]*kernel.KernelMessage)
+		writeControl := func(msg *kernel.KernelMessage) bool {
+			if !write(msg) {
+				return false
+			}
+			if msg.MsgType != "execute_ack" && msg.MsgType != "execute_nack" {
+				return true
+			}
+			unacked.Delete(msg.ParentID)
+			for _, out := range held[msg.ParentID] {
+				if !forward(out) {
+					return false
+				}
+			}
+			delete(held, msg.ParentID)
+			return true
+		}
+
 		for {
+			// Pending control messages go first
+			select {
+			case msg := <-controlChan:
+				if !writeControl(msg) {
+					return
+				}
+				continue
+			default:
+			}
+
 			select {
-			case msg := <-outputChan:
+			case msg := <-controlChan:
+				if !writeControl(msg) {
+					return
+				}
+			case msg := <-limitedChan:
 				if msg == nil {
 					return
 				}
-				data, err := json.Marshal(msg)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to marshal kernel message")
+				if _, ok := unacked.Load(msg.ParentID); ok && msg.ParentID != "" {
+					held[msg.ParentID] = append(held[msg.ParentID], msg)
 					continue
 				}
-				if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-					log.Error().Err(err).Msg("Failed to write WebSocket message")
+				if !forward(msg) {
 					return
 				}
 			case <-doneChan:
@@ -229,43 +559,331 @@ func (h *KernelHandler) WebSocketConnect(c *gin.Context) {
 			close(doneChan)
 			break
 		}
+		touch()
 
-		if messageType != websocket.TextMessage {
+		switch messageType {
+		case websocket.TextMessage:
+		case websocket.BinaryMessage:
+			// Binary frames carry the JSON request as their first segment
+			jsonData, _, err := gateway.DeserializeBinaryMessage(message)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to decode binary WebSocket message")
+				continue
+			}
+			message = jsonData
+		default:
 			continue
 		}
 
 		var execReq kernel.ExecuteRequest
 		if err := json.Unmarshal(message, &execReq); err != nil {
 			log.Error().Err(err).Msg("Failed to unmarshal execute request")
+			sendControl(executeNack("", "INVALID_REQUEST", err))
 			continue
 		}
 
 		// Execute code on kernel using the WebSocket context
 		go func(req kernel.ExecuteRequest) {
+			// A kernel that is already busy queues the request, unless it
+			// runs executions concurrently
+			state := "running"
+			if status, err := h.kernelUseCase.GetKernelStatus(ctx, kernelID); err == nil && status.ExecutionState == "busy" && !status.AllowConcurrentExecution {
+				state = "queued"
+			}
+
+			if err := h.resolveExecuteCwd(ctx, &req, workspace); err != nil {
+				sendControl(executeNack(req.MsgID, "INVALID_CWD", err))
+				return
+			}
+			if err := h.startCapture(&req, workspace); err != nil {
+				sendControl(executeNack(req.MsgID, "INVALID_CAPTURE", err))
+				return
+			}
+			if req.CaptureDir != "" {
+				if _, pending := captures.LoadOrStore(req.MsgID, req.CaptureDir); pending {
+					h.kernelUseCase.DiscardCapture(req.CaptureDir)
+					sendControl(executeNack(req.MsgID, "INVALID_CAPTURE", fmt.Errorf("an execution with msg_id %q is already capturing outputs", req.MsgID)))
+					return
+				}
+			}
+
+			if req.MsgID != "" {
+				unacked.Store(req.MsgID, struct{}{})
+			}
 			if err := h.kernelUseCase.ExecuteCode(ctx, kernelID, sessionID, &req); err != nil {
-				// Send error message to client
-				errMsg := &kernel.KernelMessage{
+				if req.CaptureDir != "" {
+					captures.Delete(req.MsgID)
+					h.kernelUseCase.DiscardCapture(req.CaptureDir)
+				}
+				sendControl(executeNack(req.MsgID, executeNackReason(err), err))
+
+				// Send error message to client; KernelDead lets it offer a restart
+				ename := "ExecutionError"
+				if errors.Is(err, kernel.ErrKernelDead) {
+					ename = "KernelDead"
+				}
+				sendControl(&kernel.KernelMessage{
 					MsgType:  "error",
 					ParentID: req.MsgID,
 					Content: map[string]interface{}{
-						"ename":     "ExecutionError",
+						"ename":     ename,
 						"evalue":    err.Error(),
 						"traceback": []string{},
 					},
-				}
-				if data, err := json.Marshal(errMsg); err == nil {
-					conn.WriteMessage(websocket.TextMessage, data)
-				}
+				})
+				return
 			}
+
+			sendControl(&kernel.KernelMessage{
+				MsgID:    uuid.New().String(),
+				MsgType:  "execute_ack",
+				ParentID: req.MsgID,
+				Content: map[string]interface{}{
+					"msg_id":          req.MsgID,
+					"status":          "accepted",
+					"execution_state": state,
+				},
+			})
 		}(execReq)
 	}
 }
 
+// closeWhenIdle sends a close frame and tears down a WebSocket connection once
+// lastActivity is older than idleTimeout, until ctx is cancelled. Closing the
+// connection ends its read loop, which unregisters its output channel.
+func (h *KernelHandler) closeWhenIdle(ctx context.Context, conn *websocket.Conn, idleTimeout time.Duration, lastActivity *atomic.Int64, cleanup func(), kernelID, userID string) {
+	timer := time.NewTimer(idleTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		idle := time.Since(time.Unix(0, lastActivity.Load()))
+		if idle < idleTimeout {
+			timer.Reset(idleTimeout - idle)
+			continue
+		}
+
+		log.Info().Str("kernel_id", kernelID).Str("user_id", userID).Dur("idle", idle).Msg("Closing idle WebSocket connection")
+		// WriteControl may be called concurrently with the writer goroutine
+		closeMsg := websocket.FormatCloseMessage(closeIdleTimeout, "idle timeout")
+		conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		cleanup()
+		return
+	}
+}
+
+// executeWorkspace identifies the workspace an execute request's cwd is relative to
+type executeWorkspace struct {
+	userID string
+	appID  string
+	email  string
+}
+
+// requestWorkspace returns the workspace of the user a request was
+// authenticated as
+func requestWorkspace(c *gin.Context) executeWorkspace {
+	return executeWorkspace{userID: middleware.GetUserID(c), appID: middleware.GetAppID(c), email: middleware.GetEmail(c)}
+}
+
+// resolveExecuteCwd checks that the cwd of req names a directory in the
+// user's workspace the user can view, and sets req.WorkDir to its storage
+// path. Cleaning the path first keeps ".." from leaving the workspace.
+func (h *KernelHandler) resolveExecuteCwd(ctx context.Context, req *kernel.ExecuteRequest, ws executeWorkspace) error {
+	req.WorkDir = ""
+	if req.Cwd == "" {
+		return nil
+	}
+	userID, err := uuid.Parse(ws.userID)
+	if err != nil || ws.appID == "" || ws.email == "" {
+		return fmt.Errorf("cwd requires an authenticated user")
+	}
+
+	root := workspaceRoot(ws.appID, ws.email)
+	if path.Clean("/"+req.Cwd) == "/" {
+		req.WorkDir = root
+		return nil
+	}
+
+	dir, err := h.objectUseCase.ResolveByDisplayPath(ctx, userID, ws.appID, ws.email, req.Cwd)
+	if err != nil {
+		return fmt.Errorf("cwd %q not found in workspace", req.Cwd)
+	}
+	if dir.Type != entity.ObjectTypeDirectory {
+		return fmt.Errorf("cwd %q is not a directory", req.Cwd)
+	}
+	req.WorkDir = dir.Path
+	return nil
+}
+
+// workspaceRoot returns the storage path of a user's workspace root, or ""
+// when the user's app or email is unknown
+func workspaceRoot(appID, email string) string {
+	if appID == "" || email == "" {
+		return ""
+	}
+	return "/" + appID + "/" + email
+}
+
+// controlBufferSize is the per-connection buffer for server-generated replies
+const controlBufferSize = 16
+
+// executeNack builds the reply to an execute request the kernel did not accept
+func executeNack(msgID, reason string, err error) *kernel.KernelMessage {
+	return &kernel.KernelMessage{
+		MsgID:    uuid.New().String(),
+		MsgType:  "execute_nack",
+		ParentID: msgID,
+		Content: map[string]interface{}{
+			"msg_id": msgID,
+			"reason": reason,
+			"error":  err.Error(),
+		},
+	}
+}
+
+// executeNackReason maps an ExecuteCode error to an execute_nack reason,
+// using the same reason codes as the REST API
+func executeNackReason(err error) string {
+	var remote *kernel.RemoteKernelError
+	switch {
+	case errors.Is(err, kernel.ErrKernelDead):
+		return "KERNEL_DEAD"
+	case errors.Is(err, kernel.ErrKernelNotFound):
+		return "KERNEL_NOT_FOUND"
+	case errors.Is(err, kernel.ErrGatewayUnavailable):
+		return "GATEWAY_UNAVAILABLE"
+	case errors.Is(err, kernel.ErrCaptureUnsupported):
+		return "CAPTURE_UNSUPPORTED"
+	case errors.As(err, &remote):
+		return "KERNEL_ON_ANOTHER_INSTANCE"
+	default:
+		return "EXECUTE_FAILED"
+	}
+}
+
+// handleKernelError maps kernel use case errors to HTTP responses. Kernels
+// owned by another instance get a 421 with routing info for sticky load
+// balancing; startup failures carry the kernel's output; unknown kernels get
+// a 404 and dead kernels a 409 so clients can offer a restart; requests
+// failed fast by the open gateway circuit breaker get a 503; other errors are
+// reported as internal errors.
+func handleKernelError(c *gin.Context, message string, err error) {
+	if errors.Is(err, kernel.ErrKernelNotFound) {
+		response.NotFoundWithReason(c, err.Error(), "KERNEL_NOT_FOUND", map[string]string{
+			"kernel_id": c.Param("kernel_id"),
+		})
+		return
+	}
+
+	if errors.Is(err, kernel.ErrKernelDead) {
+		response.ErrorWithReason(c, http.StatusConflict, response.CodeFailedPrecondition, message+": "+err.Error(),
+			"KERNEL_DEAD", map[string]string{
+				"kernel_id": c.Param("kernel_id"),
+			})
+		return
+	}
+
+	if errors.Is(err, kernel.ErrKernelSpecNotAllowed) {
+		response.ErrorWithReason(c, http.StatusForbidden, response.CodeForbidden, message+": "+err.Error(),
+			"KERNEL_SPEC_NOT_ALLOWED", nil)
+		return
+	}
+
+	if errors.Is(err, kernel.ErrCaptureUnsupported) {
+		response.ErrorWithReason(c, http.StatusBadRequest, response.CodeInvalidArgument, message+": "+err.Error(),
+			"CAPTURE_UNSUPPORTED", nil)
+		return
+	}
+
+	if errors.Is(err, kernel.ErrGatewayUnavailable) {
+		response.ErrorWithReason(c, http.StatusServiceUnavailable, response.CodeUnavailable, message+": "+err.Error(),
+			"GATEWAY_UNAVAILABLE", nil)
+		return
+	}
+
+	var startErr *kernel.KernelStartError
+	if errors.As(err, &startErr) {
+		response.InternalErrorWithReason(c, message+": "+startErr.Reason, "KERNEL_START_FAILED", map[string]string{
+			"kernel_id": startErr.KernelID,
+			"output":    startErr.Output,
+		})
+		return
+	}
+
+	var remote *kernel.RemoteKernelError
+	if errors.As(err, &remote) {
+		c.Header("X-Kernel-Instance", remote.InstanceID)
+		response.ErrorWithReason(c, http.StatusMisdirectedRequest, response.CodeMisdirected, err.Error(),
+			"KERNEL_ON_ANOTHER_INSTANCE", map[string]string{
+				"kernel_id":     remote.KernelID,
+				"instance_id":   remote.InstanceID,
+				"instance_addr": remote.InstanceAddr,
+			})
+		return
+	}
+	response.InternalError(c, message+": "+err.Error())
+}
+
+// addConnection registers a WebSocket connection if the global and per-user
+// limits allow it, returning false when a limit is exceeded
+func (h *KernelHandler) addConnection(sessionID, userID string, conn *websocket.Conn) bool {
+	h.connMu.Lock()
+	defer h.connMu.Unlock()
+
+	if h.wsConfig != nil {
+		if h.wsConfig.MaxConnections > 0 && len(h.connections) >= h.wsConfig.MaxConnections {
+			return false
+		}
+		if h.wsConfig.MaxConnectionsPerUser > 0 && h.userConns[userID] >= h.wsConfig.MaxConnectionsPerUser {
+			return false
+		}
+	}
+
+	h.connections[sessionID] = &wsConnection{conn: conn, userID: userID}
+	h.userConns[userID]++
+	wsConnectionsGauge.Set(int64(len(h.connections)))
+	return true
+}
+
+// removeConnection unregisters a WebSocket connection and releases its slot
+func (h *KernelHandler) removeConnection(sessionID string) {
+	h.connMu.Lock()
+	defer h.connMu.Unlock()
+
+	wc, ok := h.connections[sessionID]
+	if !ok {
+		return
+	}
+	delete(h.connections, sessionID)
+	if h.userConns[wc.userID] <= 1 {
+		delete(h.userConns, wc.userID)
+	} else {
+		h.userConns[wc.userID]--
+	}
+	wsConnectionsGauge.Set(int64(len(h.connections)))
+}
+
+// ConnectionCount returns the number of open kernel WebSocket connections
+func (h *KernelHandler) ConnectionCount() int {
+	h.connMu.Lock()
+	defer h.connMu.Unlock()
+	return len(h.connections)
+}
+
 // ExecuteCodeRequest represents a code execution request
 type ExecuteCodeRequest struct {
-	Code    string `json:"code" binding:"required"`
-	Silent  bool   `json:"silent"`
-	StoreHistory bool `json:"store_history"`
+	Code            string            `json:"code" binding:"required"`
+	Silent          bool              `json:"silent"`
+	StoreHistory    bool              `json:"store_history"`
+	UserExpressions map[string]string `json:"user_expressions"`                       // Named expressions to evaluate after the code
+	StopOnError     *bool             `json:"stop_on_error"`                          // Abort queued executions if this one errors (default: true)
+	LineOffset      int               `json:"line_offset" binding:"min=0,max=100000"` // Lines preceding code in its cell, for traceback line numbers
+	Cwd             string            `json:"cwd"`                                    // Directory to run in, relative to the workspace root (local kernels only)
+	CaptureOutputs  bool              `json:"capture_outputs"`                        // Run in an empty directory and import files written there into the results folder (local kernels only)
 }
 
 // ExecuteCode executes code and returns result (non-streaming)
@@ -283,22 +901,40 @@ func (h *KernelHandler) ExecuteCode(c *gin.Context) {
 	}
 
 	execReq := &kernel.ExecuteRequest{
-		MsgID:        uuid.New().String(),
-		Code:         req.Code,
-		Silent:       req.Silent,
-		StoreHistory: req.StoreHistory,
+		MsgID:           uuid.New().String(),
+		Code:            req.Code,
+		Silent:          req.Silent,
+		StoreHistory:    req.StoreHistory,
+		UserExpressions: req.UserExpressions,
+		StopOnError:     req.StopOnError,
+		LineOffset:      req.LineOffset,
+		Cwd:             req.Cwd,
+		CaptureOutputs:  req.CaptureOutputs,
+	}
+	workspace := requestWorkspace(c)
+	if err := h.resolveExecuteCwd(c.Request.Context(), execReq, workspace); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if err := h.startCapture(execReq, workspace); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if execReq.CaptureDir != "" {
+		// Collecting removes the directory too; this covers failed executions
+		defer h.kernelUseCase.DiscardCapture(execReq.CaptureDir)
 	}
 
 	// Create temporary channel for this execution
-	outputChan := make(chan *kernel.KernelMessage, 100)
+	outputChan := make(chan *kernel.KernelMessage, h.kernelUseCase.OutputBufferSize())
 	sessionID := fmt.Sprintf("http-%s", uuid.New().String())
 
-	h.kernelUseCase.RegisterOutputChannel(kernelID, sessionID, outputChan)
+	h.kernelUseCase.RegisterOutputChannel(kernelID, sessionID, outputChan, nil)
 	defer h.kernelUseCase.UnregisterOutputChannel(kernelID, sessionID)
 
 	// Execute code
 	if err := h.kernelUseCase.ExecuteCode(c.Request.Context(), kernelID, sessionID, execReq); err != nil {
-		response.InternalError(c, "Failed to execute code: "+err.Error())
+		handleKernelError(c, "Failed to execute code", err)
 		return
 	}
 
@@ -316,13 +952,20 @@ func (h *KernelHandler) ExecuteCode(c *gin.Context) {
 				})
 				return
 			}
+			if msg.MsgType == "execute_reply" && msg.ParentID == execReq.MsgID && execReq.CaptureDir != "" {
+				msg = h.finishCapture(c.Request.Context(), execReq.CaptureDir, workspace, msg)
+			}
 			outputs = append(outputs, msg)
 			// Check if execution is complete
 			if msg.MsgType == "execute_reply" || msg.MsgType == "error" {
-				response.Success(c, gin.H{
+				result := gin.H{
 					"msg_id":  execReq.MsgID,
 					"outputs": outputs,
-				})
+				}
+				if exprs, ok := msg.Content["user_expressions"]; ok && msg.MsgType == "execute_reply" {
+					result["user_expressions"] = exprs
+				}
+				response.Success(c, result)
 				return
 			}
 		case <-timeout:
@@ -333,3 +976,246 @@ func (h *KernelHandler) ExecuteCode(c *gin.Context) {
 		}
 	}
 }
+
+// RunNotebookRequest represents a request to run all code cells of a notebook
+type RunNotebookRequest struct {
+	ObjectID int64 `json:"object_id" binding:"required"`
+}
+
+// RunNotebook runs the code cells of a notebook on a kernel in order, in the
+// background. Outputs are written into the stored notebook as cells complete,
+// at most once per save interval, so a client reconnecting mid-run sees the
+// results so far. The finished notebook is saved as a single version with the
+// run recorded against it. A failing cell ends the run.
+func (h *KernelHandler) RunNotebook(c *gin.Context) {
+	kernelID := c.Param("kernel_id")
+	if kernelID == "" {
+		response.BadRequest(c, "Kernel ID is required")
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(c))
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	var req RunNotebookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	cells, err := h.objectUseCase.GetCodeCells(c.Request.Context(), req.ObjectID, userID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	if _, err := h.kernelUseCase.GetKernelStatus(c.Request.Context(), kernelID); err != nil {
+		handleKernelError(c, "Failed to run notebook", err)
+		return
+	}
+
+	h.runMu.Lock()
+	if running, ok := h.runs[req.ObjectID]; ok {
+		h.runMu.Unlock()
+		response.ConflictWithReason(c, "Notebook is already running", "NOTEBOOK_RUNNING", map[string]string{
+			"kernel_id": running,
+		})
+		return
+	}
+	h.runs[req.ObjectID] = kernelID
+	h.runMu.Unlock()
+
+	go func() {
+		if err := h.runNotebook(kernelID, req.ObjectID, userID, cells); err != nil {
+			log.Warn().Err(err).Str("kernel_id", kernelID).Int64("object_id", req.ObjectID).Msg("Notebook run failed")
+		}
+	}()
+
+	response.Success(c, gin.H{
+		"object_id": req.ObjectID,
+		"kernel_id": kernelID,
+		"cells":     len(cells),
+	})
+}
+
+// errRunOutputClosed ends a notebook run whose output channel stopped
+// delivering messages before the running cell replied
+var errRunOutputClosed = errors.New("kernel output channel closed during notebook run")
+
+// runNotebook executes cells one after another, streaming their outputs into
+// the stored notebook. It outlives the request that started it. Outputs
+// received so far are saved even when the run fails.
+func (h *KernelHandler) runNotebook(kernelID string, objectID int64, userID uuid.UUID, cells []object.NotebookCell) error {
+	defer func() {
+		h.runMu.Lock()
+		delete(h.runs, objectID)
+		h.runMu.Unlock()
+	}()
+
+	logger := log.With().Str("kernel_id", kernelID).Int64("object_id", objectID).Logger()
+	ctx := context.Background()
+	started := time.Now()
+
+	outputChan := make(chan *kernel.KernelMessage, h.kernelUseCase.OutputBufferSize())
+	sessionID := fmt.Sprintf("run-%s", uuid.New().String())
+	h.kernelUseCase.RegisterOutputChannel(kernelID, sessionID, outputChan, nil)
+	defer h.kernelUseCase.UnregisterOutputChannel(kernelID, sessionID)
+
+	saveInterval := h.kernelUseCase.RunSaveInterval()
+	ticker := time.NewTicker(saveInterval)
+	defer ticker.Stop()
+
+	// results[written:] holds outputs not yet stored; the last entry is the
+	// running cell, whose outputs are stored again once it completes
+	results := make([]object.CellOutputs, 0, len(cells))
+	written, completed := 0, 0
+	dirty := false
+	lastSave := time.Now()
+	var runErr error
+	save := func() {
+		if !dirty {
+			return
+		}
+		if err := h.objectUseCase.WriteCellOutputs(ctx, objectID, results[written:]); err != nil {
+			logger.Warn().Err(err).Msg("Failed to write notebook run outputs")
+		}
+		written = completed
+		dirty = false
+		lastSave = time.Now()
+	}
+
+run:
+	for _, cell := range cells {
+		results = append(results, object.CellOutputs{Index: cell.Index, CellID: cell.CellID})
+		current := &results[len(results)-1]
+
+		msgID := uuid.New().String()
+		if err := h.kernelUseCase.ExecuteCode(ctx, kernelID, sessionID, &kernel.ExecuteRequest{
+			MsgID:        msgID,
+			Code:         cell.Source,
+			StoreHistory: true,
+			CellID:       cell.CellID,
+		}); err != nil {
+			logger.Warn().Err(err).Int("cell", cell.Index).Msg("Failed to execute notebook cell")
+			results = results[:len(results)-1]
+			break
+		}
+
+		status := ""
+		for status == "" {
+			select {
+			case msg, ok := <-outputChan:
+				if !ok || msg == nil {
+					runErr = errRunOutputClosed
+					completed++
+					break run
+				}
+				if msg.ParentID != msgID {
+					continue
+				}
+				dirty = true
+				switch msg.MsgType {
+				case "execute_input":
+					current.ExecutionCount = executionCount(msg)
+				case "execute_reply":
+					if count := executionCount(msg); count != nil {
+						current.ExecutionCount = count
+					}
+					status, _ = msg.Content["status"].(string)
+					if status == "" {
+						status = "ok"
+					}
+				default:
+					appendNotebookOutput(current, msg)
+				}
+			case <-ticker.C:
+				// No reply comes from a kernel that died or was stopped
+				if kernelStatus, err := h.kernelUseCase.GetKernelStatus(ctx, kernelID); err != nil || kernelStatus.Status == "dead" {
+					logger.Warn().Err(err).Int("cell", cell.Index).Msg("Kernel lost during notebook run")
+					completed++
+					break run
+				}
+				save()
+			}
+		}
+
+		completed++
+		if status != "ok" {
+			break
+		}
+		if time.Since(lastSave) >= saveInterval {
+			save()
+		}
+	}
+	save()
+
+	if _, err := h.objectUseCase.FinishNotebookRun(ctx, objectID, userID, kernelID, time.Since(started)); err != nil {
+		return fmt.Errorf("failed to save notebook run: %w", err)
+	}
+	if runErr != nil {
+		return runErr
+	}
+	logger.Info().Int("cells", completed).Dur("duration", time.Since(started)).Msg("Notebook run finished")
+	return nil
+}
+
+// executionCount returns the execution count carried by an execute_input or
+// execute_reply message
+func executionCount(msg *kernel.KernelMessage) *int {
+	n, ok := msg.Content["execution_count"].(float64)
+	if !ok {
+		return nil
+	}
+	count := int(n)
+	return &count
+}
+
+// appendNotebookOutput adds a kernel output message to a cell's outputs in
+// nbformat form. Consecutive stream text of one stream is merged, as
+// Jupyter stores it.
+func appendNotebookOutput(cell *object.CellOutputs, msg *kernel.KernelMessage) {
+	switch msg.MsgType {
+	case "stream":
+		name, _ := msg.Content["name"].(string)
+		text, _ := msg.Content["text"].(string)
+		if n := len(cell.Outputs); n > 0 {
+			if last := cell.Outputs[n-1]; last["output_type"] == "stream" && last["name"] == name {
+				last["text"] = last["text"].(string) + text
+				return
+			}
+		}
+		cell.Outputs = append(cell.Outputs, map[string]any{"output_type": "stream", "name": name, "text": text})
+	case "execute_result":
+		cell.Outputs = append(cell.Outputs, map[string]any{
+			"output_type":     "execute_result",
+			"data":            msg.Content["data"],
+			"metadata":        outputMetadata(msg),
+			"execution_count": msg.Content["execution_count"],
+		})
+	case "display_data":
+		cell.Outputs = append(cell.Outputs, map[string]any{
+			"output_type": "display_data",
+			"data":        msg.Content["data"],
+			"metadata":    outputMetadata(msg),
+		})
+	case "error":
+		cell.Outputs = append(cell.Outputs, map[string]any{
+			"output_type": "error",
+			"ename":       msg.Content["ename"],
+			"evalue":      msg.Content["evalue"],
+			"traceback":   msg.Content["traceback"],
+		})
+	case "clear_output":
+		cell.Outputs = nil
+	}
+}
+
+// outputMetadata returns the metadata of an output message, which nbformat requires
+func outputMetadata(msg *kernel.KernelMessage) any {
+	if metadata, ok := msg.Content["metadata"]; ok && metadata != nil {
+		return metadata
+	}
+	return map[string]any{}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/usecase/kernel"
+	"github.com/leondli/workspace/internal/usecase/object"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// displayPathObjects resolves display paths from a fixed set of objects
+type displayPathObjects struct {
+	object.UseCase
+	objects map[string]*entity.ObjectResponse // Display path -> object
+}
+
+func (o *displayPathObjects) ResolveByDisplayPath(ctx context.Context, userID uuid.UUID, appID, email, displayPath string) (*entity.ObjectResponse, error) {
+	if obj, ok := o.objects[displayPath]; ok {
+		return obj, nil
+	}
+	return nil, apperrors.NotFoundError("object")
+}
+
+func TestResolveExecuteCwd(t *testing.T) {
+	h := &KernelHandler{
+		objectUseCase: &displayPathObjects{objects: map[string]*entity.ObjectResponse{
+			"/projects":      {Path: "/app/ada@example.com/projects", Type: entity.ObjectTypeDirectory},
+			"/projects/a.py": {Path: "/app/ada@example.com/projects/a.py", Type: entity.ObjectTypeFile},
+		}},
+	}
+	ada := executeWorkspace{userID: uuid.New().String(), appID: "app", email: "ada@example.com"}
+
+	tests := []struct {
+		name      string
+		workspace executeWorkspace
+		cwd       string
+		workDir   string
+		err       string
+	}{
+		{name: "no cwd", workspace: ada, cwd: "", workDir: ""},
+		{name: "no cwd without a user", workspace: executeWorkspace{}, cwd: "", workDir: ""},
+		{name: "workspace root", workspace: ada, cwd: "/", workDir: "/app/ada@example.com"},
+		{name: "directory", workspace: ada, cwd: "/projects", workDir: "/app/ada@example.com/projects"},
+		{name: "escape is cleaned to the root", workspace: ada, cwd: "/../..", workDir: "/app/ada@example.com"},
+		{name: "file", workspace: ada, cwd: "/projects/a.py", err: "is not a directory"},
+		{name: "missing directory", workspace: ada, cwd: "/nope", err: "not found in workspace"},
+		{name: "unauthenticated", workspace: executeWorkspace{}, cwd: "/projects", err: "requires an authenticated user"},
+		{name: "missing email", workspace: executeWorkspace{userID: ada.userID, appID: "app"}, cwd: "/projects", err: "requires an authenticated user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &kernel.ExecuteRequest{Code: "pass", Cwd: tt.cwd, WorkDir: "/stale"}
+			err := h.resolveExecuteCwd(context.Background(), req, tt.workspace)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if req.WorkDir != tt.workDir {
+				t.Fatalf("WorkDir = %q, want %q", req.WorkDir, tt.workDir)
+			}
+		})
+	}
+}
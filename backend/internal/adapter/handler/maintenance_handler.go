@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	"github.com/leondli/workspace/internal/infrastructure/middleware"
+	"github.com/leondli/workspace/internal/usecase/maintenance"
+	"github.com/leondli/workspace/pkg/response"
+)
+
+// MaintenanceHandler handles admin maintenance requests
+type MaintenanceHandler struct {
+	maintenanceUseCase maintenance.UseCase
+	maintenanceConfig  *config.MaintenanceConfig
+}
+
+// NewMaintenanceHandler creates a new maintenance handler
+func NewMaintenanceHandler(maintenanceUseCase maintenance.UseCase, maintenanceConfig *config.MaintenanceConfig) *MaintenanceHandler {
+	return &MaintenanceHandler{maintenanceUseCase: maintenanceUseCase, maintenanceConfig: maintenanceConfig}
+}
+
+// CleanupTokens godoc
+// @Summary Delete expired tokens
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} response.Response{data=maintenance.CleanupResult}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/maintenance/cleanup-tokens [post]
+func (h *MaintenanceHandler) CleanupTokens(c *gin.Context) {
+	result, err := h.maintenanceUseCase.CleanupTokens(c.Request.Context())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// SetMode godoc
+// @Summary Set maintenance mode
+// @Description Announces maintenance with a banner message and optionally rejects writes. The change lasts
+// @Description until the config file is next reloaded.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body config.MaintenanceMode true "Maintenance mode"
+// @Success 200 {object} response.Response{data=config.MaintenanceMode}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /api/v1/admin/maintenance/mode [put]
+func (h *MaintenanceHandler) SetMode(c *gin.Context) {
+	var mode config.MaintenanceMode
+	if err := c.ShouldBindJSON(&mode); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	h.maintenanceConfig.SetMode(mode)
+	log.Info().Str("admin", middleware.GetEmail(c)).Bool("enabled", mode.Enabled).Bool("read_only", mode.ReadOnly).Msg("Maintenance mode changed")
+
+	response.Success(c, h.maintenanceConfig.GetMode())
+}
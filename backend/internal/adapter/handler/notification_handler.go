@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	"github.com/leondli/workspace/internal/infrastructure/middleware"
+	"github.com/leondli/workspace/internal/usecase/notification"
+	"github.com/leondli/workspace/pkg/response"
+)
+
+// NotificationHandler handles notification requests
+type NotificationHandler struct {
+	notificationUseCase notification.UseCase
+	pagination          *config.PaginationConfig
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(notificationUseCase notification.UseCase, paginationConfig *config.PaginationConfig) *NotificationHandler {
+	return &NotificationHandler{notificationUseCase: notificationUseCase, pagination: paginationConfig}
+}
+
+// List godoc
+// @Summary List the current user's notifications
+// @Description Newest first. Notifications are created when an object is shared
+// @Description with the user or copied into one of their directories.
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Param unread query bool false "Only unread notifications"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size, at most the configured maximum" default(20)
+// @Success 200 {object} response.Response{data=response.PaginatedData}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/v1/notifications [get]
+func (h *NotificationHandler) List(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	unreadOnly := false
+	if v := c.Query("unread"); v != "" {
+		if unreadOnly, err = strconv.ParseBool(v); err != nil {
+			response.BadRequest(c, "invalid unread")
+			return
+		}
+	}
+
+	page, pageSize, ok := parsePagination(c, h.pagination)
+	if !ok {
+		return
+	}
+
+	notifications, total, err := h.notificationUseCase.ListNotifications(c.Request.Context(), userID, unreadOnly, page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.SuccessWithPagination(c, notifications, page, pageSize, total)
+}
+
+// MarkRead godoc
+// @Summary Mark notifications as read
+// @Description Marks the listed notifications read, or all of them when ids is empty
+// @Tags notifications
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body notification.MarkReadInput false "Notification IDs"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/v1/notifications/read [post]
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	// The body is optional; without one every notification is marked read
+	var input notification.MarkReadInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	count, err := h.notificationUseCase.MarkRead(c.Request.Context(), userID, input.IDs)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"marked": count})
+}
@@ -1,26 +1,36 @@
 package handler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/infrastructure/config"
 	"github.com/leondli/workspace/internal/infrastructure/middleware"
 	"github.com/leondli/workspace/internal/usecase/object"
+	"github.com/leondli/workspace/pkg/charset"
 	"github.com/leondli/workspace/pkg/response"
 )
 
 // ObjectHandler handles object requests
 type ObjectHandler struct {
 	objectUseCase object.UseCase
+	pagination    *config.PaginationConfig
 }
 
 // NewObjectHandler creates a new object handler
-func NewObjectHandler(objectUseCase object.UseCase) *ObjectHandler {
-	return &ObjectHandler{objectUseCase: objectUseCase}
+func NewObjectHandler(objectUseCase object.UseCase, paginationConfig *config.PaginationConfig) *ObjectHandler {
+	return &ObjectHandler{objectUseCase: objectUseCase, pagination: paginationConfig}
 }
 
 // CreateDirectory godoc
@@ -175,6 +185,342 @@ func (h *ObjectHandler) GetByID(c *gin.Context) {
 	response.Success(c, obj)
 }
 
+// ResolveByDisplayPath godoc
+// @Summary Resolve an object by its display path
+// @Tags objects
+// @Security BearerAuth
+// @Produce json
+// @Param path query string true "Path relative to the workspace root, e.g. /projects/demo.ipynb"
+// @Success 200 {object} response.Response{data=entity.ObjectResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/resolve [get]
+func (h *ObjectHandler) ResolveByDisplayPath(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	appID := middleware.GetAppID(c)
+	email := middleware.GetEmail(c)
+	if appID == "" || email == "" {
+		response.Unauthorized(c, "missing app ID or email")
+		return
+	}
+
+	displayPath := c.Query("path")
+	if displayPath == "" {
+		response.BadRequest(c, "path is required")
+		return
+	}
+
+	obj, err := h.objectUseCase.ResolveByDisplayPath(c.Request.Context(), userID, appID, email, displayPath)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, obj)
+}
+
+// GetByContentHash godoc
+// @Summary Find the current user's files by content hash
+// @Description Returns the user's files whose content has the given SHA-256
+// @Description digest, newest first, so clients can skip uploading content
+// @Description that is already stored. Returns an empty list when nothing matches.
+// @Tags objects
+// @Security BearerAuth
+// @Produce json
+// @Param hash query string true "Hex-encoded SHA-256 digest of the content"
+// @Success 200 {object} response.Response{data=[]entity.ObjectResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/v1/objects/by-hash [get]
+func (h *ObjectHandler) GetByContentHash(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	hash := c.Query("hash")
+	if hash == "" {
+		response.BadRequest(c, "hash is required")
+		return
+	}
+
+	objects, err := h.objectUseCase.GetByContentHash(c.Request.Context(), userID, hash)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, objects)
+}
+
+// GetAncestors godoc
+// @Summary Get object ancestors for breadcrumbs
+// @Tags objects
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Object ID"
+// @Success 200 {object} response.Response{data=[]entity.ObjectResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/ancestors [get]
+func (h *ObjectHandler) GetAncestors(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	ancestors, err := h.objectUseCase.GetAncestors(c.Request.Context(), id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, ancestors)
+}
+
+// GetNameHistory godoc
+// @Summary Get the former names of an object
+// @Tags objects
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Object ID"
+// @Success 200 {object} response.Response{data=[]entity.ObjectNameHistory}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/name-history [get]
+func (h *ObjectHandler) GetNameHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	history, err := h.objectUseCase.GetNameHistory(c.Request.Context(), id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, history)
+}
+
+// SetContentType godoc
+// @Summary Override the type of a file
+// @Description Corrects the app type and MIME type inferred from the file name
+// @Description without re-uploading it. An empty type keeps the current one; an
+// @Description empty mime_type clears the override. Requires editor access.
+// @Tags objects
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Object ID"
+// @Param request body object.SetContentTypeInput true "Content type"
+// @Success 200 {object} response.Response{data=entity.ObjectResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/content-type [put]
+func (h *ObjectHandler) SetContentType(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	var input object.SetContentTypeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	obj, err := h.objectUseCase.SetContentType(c.Request.Context(), id, userID, input.Type, input.MimeType)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, obj)
+}
+
+// RenderMarkdown godoc
+// @Summary Render a markdown file to HTML
+// @Description Returns the file rendered to sanitized HTML, safe to insert into a page.
+// @Description Fenced code is highlighted, and relative links and images naming other
+// @Description objects in the workspace are rewritten to open them.
+// @Tags objects
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Object ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/render [get]
+func (h *ObjectHandler) RenderMarkdown(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	html, err := h.objectUseCase.RenderMarkdown(c.Request.Context(), id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"html": html})
+}
+
+// SetPinned godoc
+// @Summary Pin an object to the top of its directory
+// @Description Pinned objects are listed first in their directory, before the usual
+// @Description directories-then-name order. Send {"pinned": false} to unpin. Depending
+// @Description on configuration pins are shared, needing editor access, or per user.
+// @Tags objects
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Object ID"
+// @Param request body object.SetPinnedInput false "Pin state, pinned when omitted"
+// @Success 200 {object} response.Response{data=entity.ObjectResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/pin [post]
+func (h *ObjectHandler) SetPinned(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	// The body is optional; without one the object is pinned
+	var input object.SetPinnedInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	pinned := input.Pinned == nil || *input.Pinned
+
+	obj, err := h.objectUseCase.SetPinned(c.Request.Context(), id, userID, pinned)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, obj)
+}
+
+// RecordRun godoc
+// @Summary Record a notebook run
+// @Description Links an execution of the notebook to the version that saved its results,
+// @Description by default the current version.
+// @Tags objects
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Object ID"
+// @Param request body object.RecordRunInput true "Run details"
+// @Success 201 {object} response.Response{data=entity.NotebookRun}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/runs [post]
+func (h *ObjectHandler) RecordRun(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	var input object.RecordRunInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	run, err := h.objectUseCase.RecordRun(c.Request.Context(), id, userID, &input)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, run)
+}
+
+// ListRuns godoc
+// @Summary List the recorded runs of a notebook
+// @Description Each run carries the version number holding its results, for comparison
+// @Description with the current content through the version endpoints.
+// @Tags objects
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Object ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size, at most the configured maximum" default(20)
+// @Success 200 {object} response.Response{data=response.PaginatedData}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/runs [get]
+func (h *ObjectHandler) ListRuns(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	page, pageSize, ok := parsePagination(c, h.pagination)
+	if !ok {
+		return
+	}
+
+	runs, total, err := h.objectUseCase.ListRuns(c.Request.Context(), id, page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.SuccessWithPagination(c, runs, page, pageSize, total)
+}
+
 // List godoc
 // @Summary List objects
 // @Tags objects
@@ -184,14 +530,19 @@ func (h *ObjectHandler) GetByID(c *gin.Context) {
 // @Param type query []string false "Object types"
 // @Param search query string false "Search query"
 // @Param page query int false "Page number" default(1)
-// @Param page_size query int false "Page size" default(20)
+// @Param page_size query int false "Page size, at most the configured maximum" default(20)
 // @Success 200 {object} response.Response{data=response.PaginatedData}
+// @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Router /api/v1/objects [get]
 func (h *ObjectHandler) List(c *gin.Context) {
+	page, pageSize, ok := parsePagination(c, h.pagination)
+	if !ok {
+		return
+	}
 	filter := &entity.ObjectFilter{
-		Page:     1,
-		PageSize: 20,
+		Page:     page,
+		PageSize: pageSize,
 	}
 
 	// Parse query parameters
@@ -212,38 +563,667 @@ func (h *ObjectHandler) List(c *gin.Context) {
 		filter.Search = search
 	}
 
-	if page := c.Query("page"); page != "" {
-		if p, err := strconv.Atoi(page); err == nil && p > 0 {
-			filter.Page = p
-		}
+	objects, total, err := h.objectUseCase.List(c.Request.Context(), filter)
+	if err != nil {
+		handleError(c, err)
+		return
 	}
 
-	if pageSize := c.Query("page_size"); pageSize != "" {
-		if ps, err := strconv.Atoi(pageSize); err == nil && ps > 0 && ps <= 100 {
-			filter.PageSize = ps
-		}
+	response.SuccessWithPagination(c, objects, filter.Page, filter.PageSize, total)
+}
+
+// GetTree godoc
+// @Summary Get directory tree
+// @Tags objects
+// @Security BearerAuth
+// @Produce json
+// @Description With lazy=true only one level is returned, the children of parent_id or
+// @Description the user's top-level objects, and directories carry has_children and
+// @Description child_count hints so deeper levels can be fetched on demand.
+// @Param parent_id query int false "Parent ID (root if not specified), used with lazy=true"
+// @Param depth query int false "Tree depth, at most the configured maximum" default(3)
+// @Param lazy query bool false "Return a single level with child count hints"
+// @Success 200 {object} response.Response{data=[]entity.ObjectResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/tree [get]
+func (h *ObjectHandler) GetTree(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	appID := middleware.GetAppID(c)
+	email := middleware.GetEmail(c)
+	if appID == "" || email == "" {
+		response.Unauthorized(c, "missing app ID or email")
+		return
+	}
+
+	if lazy, _ := strconv.ParseBool(c.Query("lazy")); lazy {
+		var parentID *int64
+		if parentIDStr := c.Query("parent_id"); parentIDStr != "" {
+			pid, err := strconv.ParseInt(parentIDStr, 10, 64)
+			if err != nil {
+				response.BadRequest(c, "invalid parent_id")
+				return
+			}
+			parentID = &pid
+		}
+
+		level, err := h.objectUseCase.GetTreeLevel(c.Request.Context(), userID, parentID)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		response.Success(c, level)
+		return
+	}
+
+	depth := 3
+	if depthStr := c.Query("depth"); depthStr != "" {
+		if d, err := strconv.Atoi(depthStr); err == nil && d > 0 {
+			depth = d
+		}
+	}
+
+	tree, err := h.objectUseCase.GetTree(c.Request.Context(), userID, appID, email, depth)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, tree)
+}
+
+// GetContent godoc
+// @Summary Get file content
+// @Description Text content is converted to UTF-8 with any byte order mark removed;
+// @Description the detected charset is returned in X-Original-Charset. Binary content
+// @Description and raw=true responses are the stored bytes as application/octet-stream.
+// @Description include_outputs=false strips cell outputs and execution counts from
+// @Description notebooks without modifying the stored file. The ETag is the SHA-256 of
+// @Description the returned content; a matching If-None-Match gets 304 without a body.
+// @Tags objects
+// @Security BearerAuth
+// @Produce octet-stream,plain
+// @Param id path int true "Object ID"
+// @Param raw query bool false "Return the stored bytes untouched"
+// @Param include_outputs query bool false "Include notebook cell outputs" default(true)
+// @Param If-None-Match header string false "ETag of a copy the client holds"
+// @Success 200 {file} binary
+// @Success 304
+// @Header 200 {string} ETag "Quoted SHA-256 of the content"
+// @Header 200 {string} Last-Modified "When the object was last updated"
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/content [get]
+func (h *ObjectHandler) GetContent(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	obj, err := h.objectUseCase.GetByID(c.Request.Context(), id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	includeOutputs := true
+	if v := c.Query("include_outputs"); v != "" {
+		if includeOutputs, err = strconv.ParseBool(v); err != nil {
+			response.BadRequest(c, "invalid include_outputs")
+			return
+		}
+	}
+
+	var content []byte
+	if includeOutputs {
+		content, err = h.objectUseCase.GetContent(c.Request.Context(), id)
+	} else {
+		content, err = h.objectUseCase.GetContentWithoutOutputs(c.Request.Context(), id)
+	}
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	if c.Query("raw") == "true" {
+		h.writeContent(c, obj, "application/octet-stream", content)
+		return
+	}
+
+	text, originalCharset, ok := charset.ToUTF8(content)
+	if !ok {
+		h.writeContent(c, obj, "application/octet-stream", content)
+		return
+	}
+	c.Header("X-Original-Charset", originalCharset)
+	h.writeContent(c, obj, "text/plain; charset=utf-8", text)
+}
+
+// HeadContent godoc
+// @Summary Get file content metadata
+// @Description Returns the headers GET would, including Content-Length, Content-Type, ETag
+// @Description and Last-Modified, without the body, so clients can check a file before fetching it.
+// @Tags objects
+// @Security BearerAuth
+// @Param id path int true "Object ID"
+// @Param raw query bool false "Describe the stored bytes untouched"
+// @Param include_outputs query bool false "Include notebook cell outputs" default(true)
+// @Param If-None-Match header string false "ETag of a copy the client holds"
+// @Success 200
+// @Success 304
+// @Header 200 {string} ETag "Quoted SHA-256 of the content"
+// @Header 200 {string} Last-Modified "When the object was last updated"
+// @Failure 400
+// @Failure 401
+// @Failure 404
+// @Router /api/v1/objects/{id}/content [head]
+func (h *ObjectHandler) HeadContent(c *gin.Context) {
+	// The server drops the body of HEAD responses
+	h.GetContent(c)
+}
+
+// writeContent sends file content with the validators clients use to skip
+// fetching it again. Reads are counted unless the client's copy is current
+// or the request is HEAD.
+func (h *ObjectHandler) writeContent(c *gin.Context, obj *entity.ObjectResponse, contentType string, content []byte) {
+	sum := sha256.Sum256(content)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", obj.UpdatedAt.UTC().Format(http.TimeFormat))
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	if c.Request.Method != http.MethodHead {
+		h.recordAccess(c, obj.ID)
+	}
+	c.Header("Content-Length", strconv.Itoa(len(content)))
+	c.Data(http.StatusOK, contentType, content)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing
+// weakly as RFC 9110 requires for it
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// GetContents godoc
+// @Summary Get the content of several files
+// @Description Contents are base64-encoded and keyed by object ID. Directories and
+// @Description objects that do not exist or cannot be read are omitted.
+// @Tags objects
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body object.BatchContentInput true "Object IDs"
+// @Success 200 {object} response.Response{data=map[string]string}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/v1/objects/batch-content [post]
+func (h *ObjectHandler) GetContents(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	var input object.BatchContentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	// []byte values are encoded as base64 strings in the JSON response
+	contents, err := h.objectUseCase.GetContents(c.Request.Context(), userID, input.IDs)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, contents)
+}
+
+// Download godoc
+// @Summary Download file
+// @Tags objects
+// @Security BearerAuth
+// @Produce octet-stream
+// @Param id path int true "Object ID"
+// @Success 200 {file} binary
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/download [get]
+func (h *ObjectHandler) Download(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	obj, err := h.objectUseCase.GetByID(c.Request.Context(), id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	content, err := h.objectUseCase.GetContent(c.Request.Context(), id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	h.recordAccess(c, id)
+
+	// Set Content-Disposition header for file download
+	c.Header("Content-Disposition", "attachment; filename=\""+obj.Name+"\"")
+	contentType := "application/octet-stream"
+	if obj.MimeType != "" {
+		contentType = obj.MimeType
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.Itoa(len(content)))
+	c.Data(200, contentType, content)
+}
+
+// GetExportFormats godoc
+// @Summary List export formats
+// @Description Returns the formats the object can be exported in, for building export menus.
+// @Tags objects
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Object ID"
+// @Success 200 {object} response.Response{data=[]object.ExportFormat}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/export-formats [get]
+func (h *ObjectHandler) GetExportFormats(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	formats, err := h.objectUseCase.GetExportFormats(c.Request.Context(), id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, formats)
+}
+
+// Export godoc
+// @Summary Export file
+// @Description Downloads the file converted to one of its export formats. The stored file is not modified.
+// @Tags objects
+// @Security BearerAuth
+// @Produce octet-stream
+// @Param id path int true "Object ID"
+// @Param format query string true "Export format ID from export-formats"
+// @Success 200 {file} binary
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/export [get]
+func (h *ObjectHandler) Export(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	exported, err := h.objectUseCase.ExportObject(c.Request.Context(), id, c.Query("format"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	h.recordAccess(c, id)
+
+	c.Header("Content-Disposition", "attachment; filename=\""+exported.Name+"\"")
+	c.Header("Content-Length", strconv.Itoa(len(exported.Content)))
+	c.Data(200, exported.MimeType, exported.Content)
+}
+
+// StartExportJob godoc
+// @Summary Start an export job
+// @Description Starts exporting the file in the background. Formats marked async in export-formats, such as
+// @Description PDF, can only be exported this way. Poll the job and download the file once it is done.
+// @Tags objects
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Object ID"
+// @Param request body object.StartExportJobInput true "Export format"
+// @Success 201 {object} response.Response{data=object.ExportJob}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /api/v1/objects/{id}/export-jobs [post]
+func (h *ObjectHandler) StartExportJob(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	var input object.StartExportJobInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	job, err := h.objectUseCase.StartExportJob(c.Request.Context(), id, userID, input.Format)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, job)
+}
+
+// GetExportBackends godoc
+// @Summary List export storage backends
+// @Description Returns the names of the storage backends files and directories can be pushed to
+// @Tags objects
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} response.Response{data=[]string}
+// @Failure 401 {object} response.Response
+// @Router /api/v1/export-backends [get]
+func (h *ObjectHandler) GetExportBackends(c *gin.Context) {
+	response.Success(c, h.objectUseCase.GetExportBackends(c.Request.Context()))
+}
+
+// ExportToBackend godoc
+// @Summary Push a copy to a storage backend
+// @Description Starts an export job copying the file, or the directory with everything in it, to a storage
+// @Description backend. Files already at the destination are replaced. The job reports each file copied.
+// @Tags objects
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Object ID"
+// @Param request body object.ExportToBackendInput true "Backend and destination"
+// @Success 201 {object} response.Response{data=object.ExportJob}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/export-to-backend [post]
+func (h *ObjectHandler) ExportToBackend(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	var input object.ExportToBackendInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	job, err := h.objectUseCase.ExportToBackend(c.Request.Context(), id, userID, input.Backend, input.DestPrefix)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, job)
+}
+
+// GetExportJob godoc
+// @Summary Get an export job
+// @Description Returns the state of an export job started by the current user. Finished jobs expire after a while.
+// @Tags objects
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Export job ID"
+// @Success 200 {object} response.Response{data=object.ExportJob}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/export-jobs/{id} [get]
+func (h *ObjectHandler) GetExportJob(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	job, err := h.objectUseCase.GetExportJob(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, job)
+}
+
+// DownloadExportJob godoc
+// @Summary Download an export job's file
+// @Description Downloads the exported file of a finished export job
+// @Tags objects
+// @Security BearerAuth
+// @Produce octet-stream
+// @Param id path string true "Export job ID"
+// @Success 200 {file} binary
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/export-jobs/{id}/download [get]
+func (h *ObjectHandler) DownloadExportJob(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	exported, err := h.objectUseCase.DownloadExportJob(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\""+exported.Name+"\"")
+	c.Header("Content-Length", strconv.Itoa(len(exported.Content)))
+	c.Data(200, exported.MimeType, exported.Content)
+}
+
+// ListTemplates godoc
+// @Summary List templates
+// @Description Returns the templates new files can be created from: shared templates and the user's own
+// @Tags templates
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} response.Response{data=[]entity.ObjectTemplate}
+// @Failure 401 {object} response.Response
+// @Router /api/v1/templates [get]
+func (h *ObjectHandler) ListTemplates(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	templates, err := h.objectUseCase.ListTemplates(c.Request.Context(), userID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, templates)
+}
+
+// RegisterTemplate godoc
+// @Summary Register a template
+// @Description Offers a file the user can view as a template to the user only
+// @Tags templates
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body object.RegisterTemplateInput true "Template file and name"
+// @Success 201 {object} response.Response{data=entity.ObjectTemplate}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/templates [post]
+func (h *ObjectHandler) RegisterTemplate(c *gin.Context) {
+	h.registerTemplate(c, false)
+}
+
+// RegisterSharedTemplate godoc
+// @Summary Register a shared template
+// @Description Offers a file as a template to every user
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body object.RegisterTemplateInput true "Template file and name"
+// @Success 201 {object} response.Response{data=entity.ObjectTemplate}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/templates [post]
+func (h *ObjectHandler) RegisterSharedTemplate(c *gin.Context) {
+	h.registerTemplate(c, true)
+}
+
+func (h *ObjectHandler) registerTemplate(c *gin.Context, shared bool) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	var input object.RegisterTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	template, err := h.objectUseCase.RegisterTemplate(c.Request.Context(), userID, shared, &input)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, template)
+}
+
+// DeleteTemplate godoc
+// @Summary Delete a template
+// @Description Stops offering one of the user's templates. The template file is kept.
+// @Tags templates
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Template ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/templates/{id} [delete]
+func (h *ObjectHandler) DeleteTemplate(c *gin.Context) {
+	h.deleteTemplate(c, false)
+}
+
+// DeleteSharedTemplate godoc
+// @Summary Delete any template
+// @Description Stops offering a template, including shared ones. The template file is kept.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Template ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/admin/templates/{id} [delete]
+func (h *ObjectHandler) DeleteSharedTemplate(c *gin.Context) {
+	h.deleteTemplate(c, true)
+}
+
+func (h *ObjectHandler) deleteTemplate(c *gin.Context, asAdmin bool) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid template ID")
+		return
 	}
 
-	objects, total, err := h.objectUseCase.List(c.Request.Context(), filter)
-	if err != nil {
+	if err := h.objectUseCase.DeleteTemplate(c.Request.Context(), id, userID, asAdmin); err != nil {
 		handleError(c, err)
 		return
 	}
 
-	response.SuccessWithPagination(c, objects, filter.Page, filter.PageSize, total)
+	response.Success(c, gin.H{"message": "template deleted"})
 }
 
-// GetTree godoc
-// @Summary Get directory tree
+// CreateFromTemplate godoc
+// @Summary Create a file from a template
+// @Description Creates a file with a copy of a template's content. In text and notebook templates,
+// @Description {{name}}, {{date}} and {{user}} are replaced with the new file's name, today's date and
+// @Description the user's email. A name without an extension gets the template file's.
 // @Tags objects
 // @Security BearerAuth
+// @Accept json
 // @Produce json
-// @Param parent_id query int false "Parent ID (root if not specified)"
-// @Param depth query int false "Tree depth" default(3)
-// @Success 200 {object} response.Response{data=[]entity.ObjectResponse}
+// @Param request body object.CreateFromTemplateInput true "Template and new file"
+// @Success 201 {object} response.Response{data=entity.ObjectResponse}
+// @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
-// @Router /api/v1/objects/tree [get]
-func (h *ObjectHandler) GetTree(c *gin.Context) {
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/objects/from-template [post]
+func (h *ObjectHandler) CreateFromTemplate(c *gin.Context) {
 	userIDStr := middleware.GetUserID(c)
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
@@ -258,86 +1238,134 @@ func (h *ObjectHandler) GetTree(c *gin.Context) {
 		return
 	}
 
-	depth := 3
-	if depthStr := c.Query("depth"); depthStr != "" {
-		if d, err := strconv.Atoi(depthStr); err == nil && d > 0 && d <= 10 {
-			depth = d
-		}
+	var input object.CreateFromTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.BadRequest(c, err.Error())
+		return
 	}
 
-	tree, err := h.objectUseCase.GetTree(c.Request.Context(), userID, appID, email, depth)
+	obj, err := h.objectUseCase.CreateFromTemplate(c.Request.Context(), userID, appID, email, &input)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	response.Success(c, tree)
+	response.Created(c, obj)
 }
 
-// GetContent godoc
-// @Summary Get file content
+// recordAccess counts a read of an object's content for its access statistics
+func (h *ObjectHandler) recordAccess(c *gin.Context, objectID int64) {
+	// Reads without a valid user ID are counted but not attributed
+	userID, _ := uuid.Parse(middleware.GetUserID(c))
+	h.objectUseCase.RecordAccess(objectID, userID)
+}
+
+// GetAccessStats godoc
+// @Summary Get access statistics of a file
+// @Description Returns how many times the file's content was read or downloaded and when it was last
+// @Description read. Distinct readers are reported only while the access log is enabled.
 // @Tags objects
 // @Security BearerAuth
-// @Produce octet-stream
+// @Produce json
 // @Param id path int true "Object ID"
-// @Success 200 {file} binary
+// @Success 200 {object} response.Response{data=entity.ObjectAccessStats}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 404 {object} response.Response
-// @Router /api/v1/objects/{id}/content [get]
-func (h *ObjectHandler) GetContent(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+// @Router /api/v1/objects/{id}/access-stats [get]
+func (h *ObjectHandler) GetAccessStats(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		response.BadRequest(c, "invalid object ID")
 		return
 	}
 
-	content, err := h.objectUseCase.GetContent(c.Request.Context(), id)
+	stats, err := h.objectUseCase.GetAccessStats(c.Request.Context(), id)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	c.Data(200, "application/octet-stream", content)
+	response.Success(c, stats)
 }
 
-// Download godoc
-// @Summary Download file
-// @Tags objects
+// ExportWorkspace godoc
+// @Summary Export the user's workspace
+// @Description Streams a .tar.gz of all the user's files under files/ plus a manifest.json of object metadata
+// @Tags users
 // @Security BearerAuth
-// @Produce octet-stream
-// @Param id path int true "Object ID"
-// @Success 200 {file} binary
-// @Failure 400 {object} response.Response
+// @Produce application/gzip
+// @Success 200 {file} file
 // @Failure 401 {object} response.Response
-// @Failure 404 {object} response.Response
-// @Router /api/v1/objects/{id}/download [get]
-func (h *ObjectHandler) Download(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+// @Router /api/v1/users/me/export [get]
+func (h *ObjectHandler) ExportWorkspace(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		response.BadRequest(c, "invalid object ID")
+		response.Unauthorized(c, "invalid user ID")
 		return
 	}
 
-	obj, err := h.objectUseCase.GetByID(c.Request.Context(), id)
+	archive, err := h.objectUseCase.ExportWorkspace(c.Request.Context(), userID)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
+	defer archive.Close()
 
-	content, err := h.objectUseCase.GetContent(c.Request.Context(), id)
+	user := strings.SplitN(middleware.GetEmail(c), "@", 2)[0]
+	filename := fmt.Sprintf("workspace-%s-%s.tar.gz", user, time.Now().UTC().Format("20060102-150405"))
+
+	// The archive is written while streaming, so errors after this point can
+	// only abort the connection and leave the client with a truncated file
+	c.Header("Content-Disposition", "attachment; filename=\""+filename+"\"")
+	c.DataFromReader(http.StatusOK, -1, "application/gzip", archive, nil)
+}
+
+// ImportWorkspace godoc
+// @Summary Import a workspace export
+// @Description Extracts a tarball produced by the export endpoint into the user's workspace
+// @Tags users
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Workspace export (.tar.gz)"
+// @Param conflict formData string false "What to do with files that already exist: skip, overwrite or rename" default(skip)
+// @Success 200 {object} response.Response{data=object.ImportResult}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/v1/users/me/import [post]
+func (h *ObjectHandler) ImportWorkspace(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	appID := middleware.GetAppID(c)
+	email := middleware.GetEmail(c)
+	if appID == "" || email == "" {
+		response.Unauthorized(c, "missing app ID or email")
+		return
+	}
+
+	strategy := object.ImportConflictStrategy(c.DefaultPostForm("conflict", string(object.ImportConflictSkip)))
+
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		response.BadRequest(c, "file is required")
+		return
+	}
+	defer file.Close()
+
+	result, err := h.objectUseCase.ImportWorkspace(c.Request.Context(), userID, appID, email, file, strategy)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	// Set Content-Disposition header for file download
-	c.Header("Content-Disposition", "attachment; filename=\""+obj.Name+"\"")
-	c.Header("Content-Type", "application/octet-stream")
-	c.Header("Content-Length", strconv.Itoa(len(content)))
-	c.Data(200, "application/octet-stream", content)
+	response.Success(c, result)
 }
 
 // SaveContent godoc
@@ -383,6 +1411,74 @@ func (h *ObjectHandler) SaveContent(c *gin.Context) {
 	response.Success(c, obj)
 }
 
+// DiffAgainst godoc
+// @Summary Diff stored content against new content
+// @Description Compares the object's current content with the given content without saving. Notebooks are compared cell by cell.
+// @Tags objects
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Object ID"
+// @Param request body diffRequest true "Content to compare"
+// @Success 200 {object} response.Response{data=object.VersionDiff}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/diff [post]
+func (h *ObjectHandler) DiffAgainst(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	var req diffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	diff, err := h.objectUseCase.DiffAgainst(c.Request.Context(), id, []byte(req.Content))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, diff)
+}
+
+// ValidateContent godoc
+// @Summary Validate content before saving
+// @Description Checks that content is well-formed for its type without saving it. Notebooks are checked
+// @Description against nbformat 4 and JSON and YAML config files are parsed; issues carry a line and
+// @Description column or a path where known. The type is inferred from name when omitted, and content
+// @Description of types without a validator is reported valid.
+// @Tags objects
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body validateContentRequest true "Content to validate"
+// @Success 200 {object} response.Response{data=object.ValidationResult}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/v1/objects/validate [post]
+func (h *ObjectHandler) ValidateContent(c *gin.Context) {
+	var req validateContentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.objectUseCase.ValidateContent(c.Request.Context(), req.Type, req.Name, []byte(req.Content))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
 // PatchNotebook godoc
 // @Summary Patch notebook content incrementally
 // @Description Incrementally update notebook cells without sending the entire file
@@ -456,6 +1552,7 @@ func (h *ObjectHandler) PatchNotebook(c *gin.Context) {
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
 // @Router /api/v1/objects/{id} [put]
 func (h *ObjectHandler) Update(c *gin.Context) {
 	idStr := c.Param("id")
@@ -470,6 +1567,9 @@ func (h *ObjectHandler) Update(c *gin.Context) {
 		response.BadRequest(c, err.Error())
 		return
 	}
+	if userID, err := uuid.Parse(middleware.GetUserID(c)); err == nil {
+		input.UserID = userID
+	}
 
 	obj, err := h.objectUseCase.Update(c.Request.Context(), id, &input)
 	if err != nil {
@@ -482,14 +1582,26 @@ func (h *ObjectHandler) Update(c *gin.Context) {
 
 // Delete godoc
 // @Summary Delete object
+// @Description Deletes an object and its descendants, keeping their content in the
+// @Description trash so they can be restored. With dry_run=true nothing is deleted;
+// @Description the objects that would be removed are returned instead.
 // @Tags objects
 // @Security BearerAuth
 // @Param id path int true "Object ID"
-// @Success 200 {object} response.Response
+// @Param dry_run query bool false "Only list the affected objects"
+// @Success 200 {object} response.Response{data=object.OperationPlan}
+// @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 404 {object} response.Response
 // @Router /api/v1/objects/{id} [delete]
 func (h *ObjectHandler) Delete(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -497,7 +1609,21 @@ func (h *ObjectHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.objectUseCase.Delete(c.Request.Context(), id); err != nil {
+	dryRun, ok := parseDryRun(c)
+	if !ok {
+		return
+	}
+	if dryRun {
+		plan, err := h.objectUseCase.PlanDelete(c.Request.Context(), id)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		response.Success(c, plan)
+		return
+	}
+
+	if err := h.objectUseCase.Delete(c.Request.Context(), id, userID); err != nil {
 		handleError(c, err)
 		return
 	}
@@ -505,13 +1631,84 @@ func (h *ObjectHandler) Delete(c *gin.Context) {
 	response.Success(c, gin.H{"message": "deleted successfully"})
 }
 
+// ListTrash godoc
+// @Summary List the user's deleted objects
+// @Description Each entry is one delete operation: a deleted object and the number of
+// @Description objects, including descendants, removed with it. Restore an entry with
+// @Description POST /objects/{root_object_id}/restore.
+// @Tags objects
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size, at most the configured maximum" default(20)
+// @Success 200 {object} response.Response{data=response.PaginatedData}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/v1/objects/trash [get]
+func (h *ObjectHandler) ListTrash(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	page, pageSize, ok := parsePagination(c, h.pagination)
+	if !ok {
+		return
+	}
+
+	batches, total, err := h.objectUseCase.ListTrash(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.SuccessWithPagination(c, batches, page, pageSize, total)
+}
+
+// Restore godoc
+// @Summary Restore a deleted object
+// @Description Restores a deleted object and the descendants deleted with it. If the
+// @Description original parent directory no longer exists the object is restored to
+// @Description the workspace root and a note is returned.
+// @Tags objects
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Object ID"
+// @Success 200 {object} response.Response{data=object.RestoreResult}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/objects/{id}/restore [post]
+func (h *ObjectHandler) Restore(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	result, err := h.objectUseCase.RestoreTree(c.Request.Context(), id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
 // Move godoc
 // @Summary Move object
+// @Description With dry_run=true the move is validated but not performed; the
+// @Description objects that would be relocated, with their new paths, are returned.
 // @Tags objects
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "Object ID"
+// @Param dry_run query bool false "Only list the affected objects"
 // @Param request body object.MoveInput true "Move input"
 // @Success 200 {object} response.Response{data=entity.ObjectResponse}
 // @Failure 400 {object} response.Response
@@ -551,6 +1748,20 @@ func (h *ObjectHandler) Move(c *gin.Context) {
 	input.AppID = appID
 	input.Email = email
 
+	dryRun, ok := parseDryRun(c)
+	if !ok {
+		return
+	}
+	if dryRun {
+		plan, err := h.objectUseCase.PlanMove(c.Request.Context(), id, &input)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		response.Success(c, plan)
+		return
+	}
+
 	obj, err := h.objectUseCase.Move(c.Request.Context(), id, &input)
 	if err != nil {
 		handleError(c, err)
@@ -562,11 +1773,15 @@ func (h *ObjectHandler) Move(c *gin.Context) {
 
 // Copy godoc
 // @Summary Copy object
+// @Description With dry_run=true the copy is validated but not performed; the
+// @Description objects that would be copied, with the paths of the copies, are
+// @Description returned with status 200.
 // @Tags objects
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "Object ID"
+// @Param dry_run query bool false "Only list the affected objects"
 // @Param request body object.CopyInput true "Copy input"
 // @Success 201 {object} response.Response{data=entity.ObjectResponse}
 // @Failure 400 {object} response.Response
@@ -601,6 +1816,20 @@ func (h *ObjectHandler) Copy(c *gin.Context) {
 		return
 	}
 
+	dryRun, ok := parseDryRun(c)
+	if !ok {
+		return
+	}
+	if dryRun {
+		plan, err := h.objectUseCase.PlanCopy(c.Request.Context(), id, appID, email, &input)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		response.Success(c, plan)
+		return
+	}
+
 	obj, err := h.objectUseCase.Copy(c.Request.Context(), id, userID, appID, email, &input)
 	if err != nil {
 		handleError(c, err)
@@ -610,11 +1839,63 @@ func (h *ObjectHandler) Copy(c *gin.Context) {
 	response.Created(c, obj)
 }
 
+// Duplicate godoc
+// @Summary Duplicate object into the same folder
+// @Tags objects
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Object ID"
+// @Success 201 {object} response.Response{data=entity.ObjectResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/duplicate [post]
+func (h *ObjectHandler) Duplicate(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	appID := middleware.GetAppID(c)
+	email := middleware.GetEmail(c)
+	if appID == "" || email == "" {
+		response.Unauthorized(c, "missing app ID or email")
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	obj, err := h.objectUseCase.Duplicate(c.Request.Context(), id, userID, appID, email)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, obj)
+}
+
 type saveContentRequest struct {
 	Content string `json:"content" binding:"required"`
 	Message string `json:"message"`
 }
 
+type diffRequest struct {
+	Content string `json:"content"`
+}
+
+type validateContentRequest struct {
+	Type    entity.ObjectType `json:"type"` // Inferred from name when empty
+	Name    string            `json:"name"` // File name; tells config formats apart
+	Content string            `json:"content"`
+}
+
 // NotebookCellOperation represents a single cell operation for incremental update
 type NotebookCellOperation struct {
 	Op       string `json:"op" binding:"required,oneof=add update delete move"`       // Operation type: add, update, delete, move
@@ -628,3 +1909,18 @@ type patchNotebookRequest struct {
 	Operations []NotebookCellOperation `json:"operations" binding:"required,min=1"`
 	Message    string                  `json:"message"`
 }
+
+// parseDryRun reads the dry_run query parameter of tree operations. ok is
+// false once an error response has been written.
+func parseDryRun(c *gin.Context) (dryRun bool, ok bool) {
+	v := c.Query("dry_run")
+	if v == "" {
+		return false, true
+	}
+	dryRun, err := strconv.ParseBool(v)
+	if err != nil {
+		response.BadRequest(c, "invalid dry_run")
+		return false, false
+	}
+	return dryRun, true
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// parsePagination reads the page and page_size query parameters, applying the
+// default page size of cfg. Invalid values and page sizes above the
+// maximum of cfg are rejected with a 400; ok is false once the error
+// response has been written.
+func parsePagination(c *gin.Context, cfg *config.PaginationConfig) (page, pageSize int, ok bool) {
+	page = 1
+	pageSize = cfg.GetDefaultPageSize()
+
+	if p := c.Query("page"); p != "" {
+		pInt, err := strconv.Atoi(p)
+		if err != nil || pInt < 1 {
+			handleError(c, apperrors.InvalidArgumentError("page must be a positive integer", "page"))
+			return 0, 0, false
+		}
+		page = pInt
+	}
+
+	if ps := c.Query("page_size"); ps != "" {
+		psInt, err := strconv.Atoi(ps)
+		if err != nil || psInt < 1 {
+			handleError(c, apperrors.InvalidArgumentError("page_size must be a positive integer", "page_size"))
+			return 0, 0, false
+		}
+		if maxPageSize := cfg.GetMaxPageSize(); psInt > maxPageSize {
+			handleError(c, apperrors.InvalidArgumentError(fmt.Sprintf("page_size must not exceed %d", maxPageSize), "page_size"))
+			return 0, 0, false
+		}
+		pageSize = psInt
+	}
+
+	return page, pageSize, true
+}
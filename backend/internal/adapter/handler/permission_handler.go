@@ -78,6 +78,7 @@ func (h *PermissionHandler) Grant(c *gin.Context) {
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
 // @Router /api/v1/permissions/objects/{id}/{user_id} [put]
 func (h *PermissionHandler) Update(c *gin.Context) {
 	idStr := c.Param("id")
@@ -1,36 +1,57 @@
 package handler
 
 import (
+	"expvar"
+
 	"github.com/gin-gonic/gin"
 
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/infrastructure/config"
 	"github.com/leondli/workspace/internal/infrastructure/middleware"
 	"github.com/leondli/workspace/pkg/jwt"
 )
 
 // Handlers contains all HTTP handlers
 type Handlers struct {
-	Auth       *AuthHandler
-	User       *UserHandler
-	Object     *ObjectHandler
-	Permission *PermissionHandler
-	Version    *VersionHandler
-	Search     *SearchHandler
-	Tag        *TagHandler
-	Kernel     *KernelHandler
+	Auth         *AuthHandler
+	User         *UserHandler
+	Account      *AccountHandler
+	Object       *ObjectHandler
+	Permission   *PermissionHandler
+	Version      *VersionHandler
+	Search       *SearchHandler
+	Tag          *TagHandler
+	Kernel       *KernelHandler
+	Maintenance  *MaintenanceHandler
+	Webhook      *WebhookHandler
+	Notification *NotificationHandler
+	Config       *ConfigHandler
 }
 
 // RegisterRoutes registers all API routes
-func RegisterRoutes(router *gin.Engine, handlers *Handlers, jwtManager *jwt.JWTManager) {
+func RegisterRoutes(router *gin.Engine, handlers *Handlers, jwtManager *jwt.JWTManager, cfg *config.Config) {
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
-	// API v1
-	v1 := router.Group("/api/v1")
+	// Readiness check, failing while the database or kernel gateway is unavailable
+	router.GET("/ready", handlers.Kernel.Readiness)
+
+	// Request body limits: small for auth/metadata JSON, larger for content
+	// saves, and largest for the multipart upload routes
+	bodyLimit := middleware.BodySizeLimit(cfg.Server.GetMaxBodySize)
+	contentBodyLimit := middleware.BodySizeLimit(cfg.Server.GetMaxContentBodySize)
+	uploadBodyLimit := middleware.UploadSizeLimit(cfg.Server.GetMaxUploadBodySize)
+
+	// API v1. Writes are rejected while maintenance is in read-only mode.
+	v1 := router.Group("/api/v1", middleware.ReadOnly(&cfg.Maintenance))
+
+	// Client configuration (public), including the maintenance banner
+	v1.GET("/config", handlers.Config.GetConfig)
 
 	// Auth routes (public)
-	auth := v1.Group("/auth")
+	auth := v1.Group("/auth", bodyLimit)
 	{
 		auth.POST("/register", handlers.Auth.Register)
 		auth.POST("/login", handlers.Auth.Login)
@@ -39,40 +60,105 @@ func RegisterRoutes(router *gin.Engine, handlers *Handlers, jwtManager *jwt.JWTM
 
 	// Protected routes
 	protected := v1.Group("")
-	protected.Use(middleware.AuthMiddleware(jwtManager))
+	protected.Use(middleware.AuthMiddleware(jwtManager, &cfg.JWT))
 	{
 		// Auth routes (protected)
-		protected.POST("/auth/logout", handlers.Auth.Logout)
+		protected.POST("/auth/logout", bodyLimit, handlers.Auth.Logout)
 
 		// User routes
-		users := protected.Group("/users")
+		users := protected.Group("/users", bodyLimit)
 		{
 			users.GET("/me", handlers.User.GetMe)
 			users.PUT("/me", handlers.User.UpdateMe)
+			users.DELETE("/me", handlers.Account.DeleteMe)
 			users.PUT("/me/password", handlers.Auth.ChangePassword)
+			users.GET("/me/settings", handlers.User.GetSettings)
+			users.GET("/me/settings/:key", handlers.User.GetSetting)
+			users.PUT("/me/settings/:key", handlers.User.SetSetting)
+			users.GET("/me/export", middleware.LongLived(), handlers.Object.ExportWorkspace)
 			users.GET("/app", handlers.User.ListByAppID)
 		}
 
 		// Object routes
-		objects := protected.Group("/objects")
+		objects := protected.Group("/objects", bodyLimit)
 		{
 			objects.GET("", handlers.Object.List)
 			objects.GET("/tree", handlers.Object.GetTree)
+			objects.GET("/resolve", handlers.Object.ResolveByDisplayPath)
+			objects.GET("/by-hash", handlers.Object.GetByContentHash)
+			objects.GET("/trash", handlers.Object.ListTrash)
 			objects.POST("/directories", handlers.Object.CreateDirectory)
-			objects.POST("/files", handlers.Object.CreateFile)
+			objects.POST("/from-template", handlers.Object.CreateFromTemplate)
+			objects.POST("/batch-content", handlers.Object.GetContents)
 			objects.GET("/:id", handlers.Object.GetByID)
+			objects.GET("/:id/ancestors", handlers.Object.GetAncestors)
+			objects.GET("/:id/name-history", handlers.Object.GetNameHistory)
+			objects.GET("/:id/runs", handlers.Object.ListRuns)
+			objects.POST("/:id/runs", handlers.Object.RecordRun)
 			objects.PUT("/:id", handlers.Object.Update)
+			objects.PUT("/:id/content-type", handlers.Object.SetContentType)
+			objects.POST("/:id/pin", handlers.Object.SetPinned)
 			objects.DELETE("/:id", handlers.Object.Delete)
+			objects.POST("/:id/restore", handlers.Object.Restore)
 			objects.GET("/:id/content", handlers.Object.GetContent)
-			objects.PUT("/:id/content", handlers.Object.SaveContent)
-			objects.PATCH("/:id/notebook", handlers.Object.PatchNotebook)
+			objects.HEAD("/:id/content", handlers.Object.HeadContent)
+			objects.GET("/:id/render", handlers.Object.RenderMarkdown)
+			objects.GET("/:id/access-stats", handlers.Object.GetAccessStats)
+			objects.GET("/:id/kernels", PermissionMiddleware(handlers.Permission.permissionUseCase, entity.RoleViewer), handlers.Kernel.ListObjectKernels)
 			objects.POST("/:id/move", handlers.Object.Move)
 			objects.POST("/:id/copy", handlers.Object.Copy)
-			objects.GET("/:id/download", handlers.Object.Download)
+			objects.POST("/:id/duplicate", handlers.Object.Duplicate)
+			objects.GET("/:id/download", middleware.LongLived(), handlers.Object.Download)
+			objects.GET("/:id/export-formats", handlers.Object.GetExportFormats)
+			objects.GET("/:id/export", middleware.LongLived(), handlers.Object.Export)
+			objects.POST("/:id/export-jobs", handlers.Object.StartExportJob)
+			objects.POST("/:id/export-to-backend", handlers.Object.ExportToBackend)
+			objects.GET("/:id/search", handlers.Search.SearchInDirectory)
+		}
+
+		// Template routes
+		templates := protected.Group("/templates", bodyLimit)
+		{
+			templates.GET("", handlers.Object.ListTemplates)
+			templates.POST("", handlers.Object.RegisterTemplate)
+			templates.DELETE("/:id", handlers.Object.DeleteTemplate)
+		}
+
+		// Export jobs convert slow formats and push copies to storage backends
+		// in the background
+		protected.GET("/export-backends", handlers.Object.GetExportBackends)
+		exportJobs := protected.Group("/export-jobs", bodyLimit)
+		{
+			exportJobs.GET("/:id", handlers.Object.GetExportJob)
+			exportJobs.GET("/:id/download", middleware.LongLived(), handlers.Object.DownloadExportJob)
+		}
+
+		// Object content routes accept full file bodies as JSON
+		objectContent := protected.Group("/objects", contentBodyLimit)
+		{
+			objectContent.PUT("/:id/content", handlers.Object.SaveContent)
+			objectContent.POST("/:id/diff", handlers.Object.DiffAgainst)
+			objectContent.PATCH("/:id/notebook", handlers.Object.PatchNotebook)
+			objectContent.POST("/validate", handlers.Object.ValidateContent)
+		}
+
+		// Multipart upload routes
+		protected.POST("/objects/files", uploadBodyLimit, handlers.Object.CreateFile)
+		protected.POST("/users/me/import", middleware.LongLived(), uploadBodyLimit, handlers.Object.ImportWorkspace)
+
+		// Chunked upload routes. Chunks are raw bodies bounded by the session's
+		// size, and may take longer than the server timeouts on slow links.
+		uploads := protected.Group("/uploads")
+		{
+			uploads.POST("", bodyLimit, handlers.Object.StartUpload)
+			uploads.GET("/:id", handlers.Object.GetUpload)
+			uploads.PUT("/:id", middleware.LongLived(), handlers.Object.AppendUpload)
+			uploads.POST("/:id/complete", middleware.LongLived(), handlers.Object.CompleteUpload)
+			uploads.DELETE("/:id", handlers.Object.AbortUpload)
 		}
 
 		// Permission routes
-		permissions := protected.Group("/permissions")
+		permissions := protected.Group("/permissions", bodyLimit)
 		{
 			permissions.GET("/objects/:id", handlers.Permission.ListByObject)
 			permissions.POST("/objects/:id", handlers.Permission.Grant)
@@ -81,16 +167,17 @@ func RegisterRoutes(router *gin.Engine, handlers *Handlers, jwtManager *jwt.JWTM
 		}
 
 		// Version routes
-		versions := protected.Group("/versions")
+		versions := protected.Group("/versions", bodyLimit)
 		{
 			versions.GET("/objects/:id", handlers.Version.ListByObject)
+			versions.POST("/objects/:id/delete", handlers.Version.DeleteVersions)
 			versions.GET("/:version_id", handlers.Version.GetByID)
 			versions.GET("/:version_id/content", handlers.Version.GetContent)
 			versions.POST("/:version_id/restore", handlers.Version.Restore)
 		}
 
 		// Search routes
-		search := protected.Group("/search")
+		search := protected.Group("/search", bodyLimit)
 		{
 			search.GET("", handlers.Search.SearchByName)
 			search.GET("/content", handlers.Search.SearchByContent)
@@ -98,7 +185,7 @@ func RegisterRoutes(router *gin.Engine, handlers *Handlers, jwtManager *jwt.JWTM
 		}
 
 		// Tag routes
-		tags := protected.Group("/tags")
+		tags := protected.Group("/tags", bodyLimit)
 		{
 			tags.GET("", handlers.Tag.List)
 			tags.POST("", handlers.Tag.Create)
@@ -108,21 +195,60 @@ func RegisterRoutes(router *gin.Engine, handlers *Handlers, jwtManager *jwt.JWTM
 			tags.DELETE("/objects/:obj_id/:tag_id", handlers.Tag.RemoveFromObject)
 		}
 
+		// Webhook routes
+		webhooks := protected.Group("/webhooks", bodyLimit)
+		{
+			webhooks.GET("", handlers.Webhook.List)
+			webhooks.POST("", handlers.Webhook.Register)
+			webhooks.DELETE("/:id", handlers.Webhook.Delete)
+		}
+
+		// Notification routes
+		notifications := protected.Group("/notifications", bodyLimit)
+		{
+			notifications.GET("", handlers.Notification.List)
+			notifications.POST("/read", handlers.Notification.MarkRead)
+		}
+
 		// Kernel routes
-		kernels := protected.Group("/kernels")
+		kernels := protected.Group("/kernels", bodyLimit)
 		{
 			kernels.GET("/specs", handlers.Kernel.ListKernelSpecs)
+			kernels.POST("/specs/refresh", handlers.Kernel.RefreshKernelSpecs)
 			kernels.GET("", handlers.Kernel.ListKernels)
 			kernels.POST("", handlers.Kernel.StartKernel)
 			kernels.GET("/:kernel_id", handlers.Kernel.GetKernelStatus)
+			kernels.GET("/:kernel_id/logs", handlers.Kernel.GetKernelLogs)
 			kernels.DELETE("/:kernel_id", handlers.Kernel.StopKernel)
 			kernels.POST("/:kernel_id/restart", handlers.Kernel.RestartKernel)
 			kernels.POST("/:kernel_id/interrupt", handlers.Kernel.InterruptKernel)
+			kernels.POST("/:kernel_id/reset", handlers.Kernel.ResetKernel)
 			kernels.POST("/:kernel_id/execute", handlers.Kernel.ExecuteCode)
+			kernels.POST("/:kernel_id/run-notebook", handlers.Kernel.RunNotebook)
+		}
+
+		// Admin routes
+		admin := protected.Group("/admin", bodyLimit)
+		admin.Use(middleware.AdminMiddleware(&cfg.Maintenance))
+		{
+			admin.POST("/maintenance/cleanup-tokens", handlers.Maintenance.CleanupTokens)
+			admin.POST("/templates", handlers.Object.RegisterSharedTemplate)
+			admin.DELETE("/templates/:id", handlers.Object.DeleteSharedTemplate)
+			admin.PUT("/maintenance/mode", handlers.Maintenance.SetMode)
+			admin.POST("/users/:id/logout", handlers.Auth.ForceLogout)
+			admin.DELETE("/users/:id", handlers.Account.DeleteUser)
+			admin.POST("/users/:id/restore", handlers.Account.RestoreUser)
+			admin.GET("/kernels", handlers.Kernel.ListAllKernels)
+			admin.DELETE("/kernels/:kernel_id", handlers.Kernel.ForceStopKernel)
+
+			// Runtime metrics (expvar gauges such as kernel_ws_connections, gateway_circuit_state and db_pool)
+			admin.GET("/debug/vars", gin.WrapH(expvar.Handler()))
 		}
 	}
 
-	// WebSocket route for kernel communication (needs special handling)
-	// Note: Authentication is handled within the handler
-	router.GET("/api/v1/kernels/:kernel_id/ws", handlers.Kernel.WebSocketConnect)
+	// WebSocket route for kernel communication (needs special handling).
+	// Browsers cannot set headers on the handshake, so the access token may
+	// also come from the token query parameter. The connection is long-lived,
+	// so it is exempt from the server read/write timeouts.
+	router.GET("/api/v1/kernels/:kernel_id/ws", middleware.WebSocketAuth(jwtManager, &cfg.JWT), middleware.LongLived(), handlers.Kernel.WebSocketConnect)
 }
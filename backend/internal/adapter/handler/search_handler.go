@@ -4,8 +4,11 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	"github.com/leondli/workspace/internal/infrastructure/middleware"
 	"github.com/leondli/workspace/internal/usecase/search"
 	"github.com/leondli/workspace/pkg/response"
 )
@@ -13,11 +16,12 @@ import (
 // SearchHandler handles search requests
 type SearchHandler struct {
 	searchUseCase search.UseCase
+	pagination    *config.PaginationConfig
 }
 
 // NewSearchHandler creates a new search handler
-func NewSearchHandler(searchUseCase search.UseCase) *SearchHandler {
-	return &SearchHandler{searchUseCase: searchUseCase}
+func NewSearchHandler(searchUseCase search.UseCase, paginationConfig *config.PaginationConfig) *SearchHandler {
+	return &SearchHandler{searchUseCase: searchUseCase, pagination: paginationConfig}
 }
 
 // SearchByName godoc
@@ -28,7 +32,7 @@ func NewSearchHandler(searchUseCase search.UseCase) *SearchHandler {
 // @Param q query string true "Search query"
 // @Param type query []string false "Object types"
 // @Param page query int false "Page number" default(1)
-// @Param page_size query int false "Page size" default(20)
+// @Param page_size query int false "Page size, at most the configured maximum" default(20)
 // @Success 200 {object} response.Response{data=response.PaginatedData}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
@@ -47,22 +51,12 @@ func (h *SearchHandler) SearchByName(c *gin.Context) {
 		}
 	}
 
-	page := 1
-	pageSize := 20
-
-	if p := c.Query("page"); p != "" {
-		if pInt, err := strconv.Atoi(p); err == nil && pInt > 0 {
-			page = pInt
-		}
-	}
-
-	if ps := c.Query("page_size"); ps != "" {
-		if psInt, err := strconv.Atoi(ps); err == nil && psInt > 0 && psInt <= 100 {
-			pageSize = psInt
-		}
+	page, pageSize, ok := parsePagination(c, h.pagination)
+	if !ok {
+		return
 	}
 
-	results, total, err := h.searchUseCase.SearchByName(c.Request.Context(), query, types, page, pageSize)
+	results, total, err := h.searchUseCase.SearchByName(c.Request.Context(), query, types, "", page, pageSize)
 	if err != nil {
 		handleError(c, err)
 		return
@@ -79,7 +73,7 @@ func (h *SearchHandler) SearchByName(c *gin.Context) {
 // @Param q query string true "Search query"
 // @Param type query []string false "Object types"
 // @Param page query int false "Page number" default(1)
-// @Param page_size query int false "Page size" default(20)
+// @Param page_size query int false "Page size, at most the configured maximum" default(20)
 // @Success 200 {object} response.Response{data=response.PaginatedData}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
@@ -98,22 +92,12 @@ func (h *SearchHandler) SearchByContent(c *gin.Context) {
 		}
 	}
 
-	page := 1
-	pageSize := 20
-
-	if p := c.Query("page"); p != "" {
-		if pInt, err := strconv.Atoi(p); err == nil && pInt > 0 {
-			page = pInt
-		}
-	}
-
-	if ps := c.Query("page_size"); ps != "" {
-		if psInt, err := strconv.Atoi(ps); err == nil && psInt > 0 && psInt <= 100 {
-			pageSize = psInt
-		}
+	page, pageSize, ok := parsePagination(c, h.pagination)
+	if !ok {
+		return
 	}
 
-	results, total, err := h.searchUseCase.SearchByContent(c.Request.Context(), query, types, page, pageSize)
+	results, total, err := h.searchUseCase.SearchByContent(c.Request.Context(), query, types, "", page, pageSize)
 	if err != nil {
 		handleError(c, err)
 		return
@@ -129,7 +113,7 @@ func (h *SearchHandler) SearchByContent(c *gin.Context) {
 // @Produce json
 // @Param tag query string true "Tag name"
 // @Param page query int false "Page number" default(1)
-// @Param page_size query int false "Page size" default(20)
+// @Param page_size query int false "Page size, at most the configured maximum" default(20)
 // @Success 200 {object} response.Response{data=response.PaginatedData}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
@@ -141,22 +125,87 @@ func (h *SearchHandler) SearchByTag(c *gin.Context) {
 		return
 	}
 
-	page := 1
-	pageSize := 20
+	page, pageSize, ok := parsePagination(c, h.pagination)
+	if !ok {
+		return
+	}
 
-	if p := c.Query("page"); p != "" {
-		if pInt, err := strconv.Atoi(p); err == nil && pInt > 0 {
-			page = pInt
-		}
+	results, total, err := h.searchUseCase.SearchByTag(c.Request.Context(), tagName, page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
 	}
 
-	if ps := c.Query("page_size"); ps != "" {
-		if psInt, err := strconv.Atoi(ps); err == nil && psInt > 0 && psInt <= 100 {
-			pageSize = psInt
-		}
+	response.SuccessWithPagination(c, results, page, pageSize, total)
+}
+
+// SearchInDirectory godoc
+// @Summary Search within a directory subtree
+// @Description Searches the names, or with mode=content the contents, of the
+// @Description objects below a directory
+// @Tags search
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Directory ID"
+// @Param q query string true "Search query"
+// @Param mode query string false "What to match: name or content" default(name)
+// @Param type query []string false "Object types"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size, at most the configured maximum" default(20)
+// @Success 200 {object} response.Response{data=response.PaginatedData}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/objects/{id}/search [get]
+func (h *SearchHandler) SearchInDirectory(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
 	}
 
-	results, total, err := h.searchUseCase.SearchByTag(c.Request.Context(), tagName, page, pageSize)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	query := c.Query("q")
+	if query == "" {
+		response.BadRequest(c, "search query is required")
+		return
+	}
+
+	mode := c.DefaultQuery("mode", "name")
+	if mode != "name" && mode != "content" {
+		response.BadRequest(c, "mode must be name or content")
+		return
+	}
+
+	var types []entity.ObjectType
+	for _, t := range c.QueryArray("type") {
+		types = append(types, entity.ObjectType(t))
+	}
+
+	page, pageSize, ok := parsePagination(c, h.pagination)
+	if !ok {
+		return
+	}
+
+	pathPrefix, err := h.searchUseCase.DirectoryScope(c.Request.Context(), userID, id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	var results interface{}
+	var total int64
+	if mode == "content" {
+		results, total, err = h.searchUseCase.SearchByContent(c.Request.Context(), query, types, pathPrefix, page, pageSize)
+	} else {
+		results, total, err = h.searchUseCase.SearchByName(c.Request.Context(), query, types, pathPrefix, page, pageSize)
+	}
 	if err != nil {
 		handleError(c, err)
 		return
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/leondli/workspace/internal/infrastructure/config"
 	"github.com/leondli/workspace/internal/usecase/tag"
 	"github.com/leondli/workspace/pkg/response"
 )
@@ -13,11 +14,12 @@ import (
 // TagHandler handles tag requests
 type TagHandler struct {
 	tagUseCase tag.UseCase
+	pagination *config.PaginationConfig
 }
 
 // NewTagHandler creates a new tag handler
-func NewTagHandler(tagUseCase tag.UseCase) *TagHandler {
-	return &TagHandler{tagUseCase: tagUseCase}
+func NewTagHandler(tagUseCase tag.UseCase, paginationConfig *config.PaginationConfig) *TagHandler {
+	return &TagHandler{tagUseCase: tagUseCase, pagination: paginationConfig}
 }
 
 // Create godoc
@@ -54,24 +56,15 @@ func (h *TagHandler) Create(c *gin.Context) {
 // @Security BearerAuth
 // @Produce json
 // @Param page query int false "Page number" default(1)
-// @Param page_size query int false "Page size" default(50)
+// @Param page_size query int false "Page size, at most the configured maximum" default(20)
 // @Success 200 {object} response.Response{data=response.PaginatedData}
+// @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Router /api/v1/tags [get]
 func (h *TagHandler) List(c *gin.Context) {
-	page := 1
-	pageSize := 50
-
-	if p := c.Query("page"); p != "" {
-		if pInt, err := strconv.Atoi(p); err == nil && pInt > 0 {
-			page = pInt
-		}
-	}
-
-	if ps := c.Query("page_size"); ps != "" {
-		if psInt, err := strconv.Atoi(ps); err == nil && psInt > 0 && psInt <= 100 {
-			pageSize = psInt
-		}
+	page, pageSize, ok := parsePagination(c, h.pagination)
+	if !ok {
+		return
 	}
 
 	tags, total, err := h.tagUseCase.List(c.Request.Context(), page, pageSize)
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/infrastructure/middleware"
+	"github.com/leondli/workspace/internal/usecase/object"
+	"github.com/leondli/workspace/pkg/response"
+)
+
+// StartUpload godoc
+// @Summary Start a resumable chunked upload
+// @Description Opens an upload session for a new file of the given size. Send
+// @Description the content with PUT /uploads/{id}?offset=, then complete it.
+// @Description Sessions idle for longer than the configured TTL expire.
+// @Tags uploads
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body object.StartUploadInput true "Upload input"
+// @Success 201 {object} response.Response{data=entity.UploadSession}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/uploads [post]
+func (h *ObjectHandler) StartUpload(c *gin.Context) {
+	userID, appID, email, ok := uploadCaller(c)
+	if !ok {
+		return
+	}
+
+	var input object.StartUploadInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	session, err := h.objectUseCase.StartUpload(c.Request.Context(), userID, appID, email, &input)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, session)
+}
+
+// GetUpload godoc
+// @Summary Get a chunked upload's progress
+// @Description The received size is the offset to resume the upload from
+// @Tags uploads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Upload session ID"
+// @Success 200 {object} response.Response{data=entity.UploadSession}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/uploads/{id} [get]
+func (h *ObjectHandler) GetUpload(c *gin.Context) {
+	userID, _, _, ok := uploadCaller(c)
+	if !ok {
+		return
+	}
+	id, ok := parseUploadID(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.objectUseCase.GetUpload(c.Request.Context(), id, userID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, session)
+}
+
+// AppendUpload godoc
+// @Summary Upload a chunk
+// @Description Appends the raw request body at offset, which must equal the
+// @Description bytes received so far; a mismatch returns 409 with the received size.
+// @Tags uploads
+// @Security BearerAuth
+// @Accept application/octet-stream
+// @Produce json
+// @Param id path string true "Upload session ID"
+// @Param offset query int true "Byte offset of the chunk"
+// @Success 200 {object} response.Response{data=entity.UploadSession}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/uploads/{id} [put]
+func (h *ObjectHandler) AppendUpload(c *gin.Context) {
+	userID, _, _, ok := uploadCaller(c)
+	if !ok {
+		return
+	}
+	id, ok := parseUploadID(c)
+	if !ok {
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.Query("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		response.BadRequest(c, "invalid offset")
+		return
+	}
+
+	session, err := h.objectUseCase.AppendUpload(c.Request.Context(), id, userID, offset, c.Request.Body)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, session)
+}
+
+// CompleteUpload godoc
+// @Summary Complete a chunked upload
+// @Description Verifies the size and optional hash of the received content and
+// @Description creates the file object
+// @Tags uploads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Upload session ID"
+// @Success 201 {object} response.Response{data=entity.ObjectResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /api/v1/uploads/{id}/complete [post]
+func (h *ObjectHandler) CompleteUpload(c *gin.Context) {
+	userID, appID, email, ok := uploadCaller(c)
+	if !ok {
+		return
+	}
+	id, ok := parseUploadID(c)
+	if !ok {
+		return
+	}
+
+	obj, err := h.objectUseCase.CompleteUpload(c.Request.Context(), id, userID, appID, email)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, obj)
+}
+
+// AbortUpload godoc
+// @Summary Abort a chunked upload
+// @Tags uploads
+// @Security BearerAuth
+// @Param id path string true "Upload session ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/uploads/{id} [delete]
+func (h *ObjectHandler) AbortUpload(c *gin.Context) {
+	userID, _, _, ok := uploadCaller(c)
+	if !ok {
+		return
+	}
+	id, ok := parseUploadID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.objectUseCase.AbortUpload(c.Request.Context(), id, userID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "upload aborted"})
+}
+
+// uploadCaller returns the authenticated user, writing an error response if
+// the token lacks the user, app or email claims
+func uploadCaller(c *gin.Context) (uuid.UUID, string, string, bool) {
+	userID, err := uuid.Parse(middleware.GetUserID(c))
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return uuid.Nil, "", "", false
+	}
+
+	appID := middleware.GetAppID(c)
+	email := middleware.GetEmail(c)
+	if appID == "" || email == "" {
+		response.Unauthorized(c, "missing app ID or email")
+		return uuid.Nil, "", "", false
+	}
+	return userID, appID, email, true
+}
+
+func parseUploadID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid upload ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"encoding/json"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
@@ -101,3 +103,92 @@ func (h *UserHandler) ListByAppID(c *gin.Context) {
 
 	response.Success(c, users)
 }
+
+// GetSettings godoc
+// @Summary Get all settings of the current user
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} response.Response{data=map[string]interface{}}
+// @Failure 401 {object} response.Response
+// @Router /api/v1/users/me/settings [get]
+func (h *UserHandler) GetSettings(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	settings, err := h.userUseCase.GetAllSettings(c.Request.Context(), userID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, settings)
+}
+
+// GetSetting godoc
+// @Summary Get a setting of the current user
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Param key path string true "Setting key"
+// @Success 200 {object} response.Response{data=entity.UserSetting}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/users/me/settings/{key} [get]
+func (h *UserHandler) GetSetting(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	setting, err := h.userUseCase.GetSetting(c.Request.Context(), userID, c.Param("key"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, setting)
+}
+
+// SetSetting godoc
+// @Summary Create or replace a setting of the current user
+// @Description The request body is stored as-is and must be valid JSON
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key path string true "Setting key"
+// @Param request body object true "Setting value"
+// @Success 200 {object} response.Response{data=entity.UserSetting}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/v1/users/me/settings/{key} [put]
+func (h *UserHandler) SetSetting(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	value, err := c.GetRawData()
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	setting, err := h.userUseCase.SetSetting(c.Request.Context(), userID, c.Param("key"), json.RawMessage(value))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, setting)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/leondli/workspace/internal/infrastructure/config"
 	"github.com/leondli/workspace/internal/infrastructure/middleware"
 	"github.com/leondli/workspace/internal/usecase/version"
 	"github.com/leondli/workspace/pkg/response"
@@ -14,11 +15,12 @@ import (
 // VersionHandler handles version requests
 type VersionHandler struct {
 	versionUseCase version.UseCase
+	pagination     *config.PaginationConfig
 }
 
 // NewVersionHandler creates a new version handler
-func NewVersionHandler(versionUseCase version.UseCase) *VersionHandler {
-	return &VersionHandler{versionUseCase: versionUseCase}
+func NewVersionHandler(versionUseCase version.UseCase, paginationConfig *config.PaginationConfig) *VersionHandler {
+	return &VersionHandler{versionUseCase: versionUseCase, pagination: paginationConfig}
 }
 
 // ListByObject godoc
@@ -28,7 +30,7 @@ func NewVersionHandler(versionUseCase version.UseCase) *VersionHandler {
 // @Produce json
 // @Param id path int true "Object ID"
 // @Param page query int false "Page number" default(1)
-// @Param page_size query int false "Page size" default(20)
+// @Param page_size query int false "Page size, at most the configured maximum" default(20)
 // @Success 200 {object} response.Response{data=response.PaginatedData}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
@@ -41,19 +43,9 @@ func (h *VersionHandler) ListByObject(c *gin.Context) {
 		return
 	}
 
-	page := 1
-	pageSize := 20
-
-	if p := c.Query("page"); p != "" {
-		if pInt, err := strconv.Atoi(p); err == nil && pInt > 0 {
-			page = pInt
-		}
-	}
-
-	if ps := c.Query("page_size"); ps != "" {
-		if psInt, err := strconv.Atoi(ps); err == nil && psInt > 0 && psInt <= 100 {
-			pageSize = psInt
-		}
+	page, pageSize, ok := parsePagination(c, h.pagination)
+	if !ok {
+		return
 	}
 
 	versions, total, err := h.versionUseCase.ListByObject(c.Request.Context(), objectID, page, pageSize)
@@ -155,3 +147,54 @@ func (h *VersionHandler) Restore(c *gin.Context) {
 
 	response.Success(c, obj)
 }
+
+// DeleteVersionsRequest lists the versions to delete
+type DeleteVersionsRequest struct {
+	VersionNumbers []int `json:"version_numbers" binding:"required,min=1,max=1000"`
+}
+
+// DeleteVersions godoc
+// @Summary Delete versions of an object
+// @Description Removes the listed versions and their snapshots. The current version and the
+// @Description most recent versions (storage.version_keep_min) are kept and reported as protected.
+// @Description Owner only.
+// @Tags versions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Object ID"
+// @Param request body DeleteVersionsRequest true "Version numbers"
+// @Success 200 {object} response.Response{data=version.DeleteVersionsResult}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/versions/objects/{id}/delete [post]
+func (h *VersionHandler) DeleteVersions(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	objectID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "invalid object ID")
+		return
+	}
+
+	var req DeleteVersionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.versionUseCase.DeleteVersions(c.Request.Context(), objectID, req.VersionNumbers, userID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
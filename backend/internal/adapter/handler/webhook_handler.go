@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	"github.com/leondli/workspace/internal/infrastructure/middleware"
+	"github.com/leondli/workspace/internal/usecase/webhook"
+	"github.com/leondli/workspace/pkg/response"
+)
+
+// WebhookHandler handles webhook requests
+type WebhookHandler struct {
+	webhookUseCase webhook.UseCase
+	pagination     *config.PaginationConfig
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(webhookUseCase webhook.UseCase, paginationConfig *config.PaginationConfig) *WebhookHandler {
+	return &WebhookHandler{webhookUseCase: webhookUseCase, pagination: paginationConfig}
+}
+
+// Register godoc
+// @Summary Register a webhook
+// @Description Subscribes a URL to change events on an object. For directories,
+// @Description events on any descendant are delivered too. The secret, generated
+// @Description when omitted, is returned only here and signs every delivery.
+// @Description URLs on loopback, link-local or private addresses are rejected
+// @Description unless the server allows them. Deliveries stop while the creator
+// @Description cannot view the object.
+// @Tags webhooks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body webhook.RegisterInput true "Webhook input"
+// @Success 201 {object} response.Response{data=entity.Webhook}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/webhooks [post]
+func (h *WebhookHandler) Register(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	var input webhook.RegisterInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	hook, err := h.webhookUseCase.RegisterWebhook(c.Request.Context(), userID, &input)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, hook)
+}
+
+// List godoc
+// @Summary List the current user's webhooks
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size, at most the configured maximum" default(20)
+// @Success 200 {object} response.Response{data=response.PaginatedData}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/v1/webhooks [get]
+func (h *WebhookHandler) List(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	page, pageSize, ok := parsePagination(c, h.pagination)
+	if !ok {
+		return
+	}
+
+	hooks, total, err := h.webhookUseCase.ListWebhooks(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.SuccessWithPagination(c, hooks, page, pageSize, total)
+}
+
+// Delete godoc
+// @Summary Delete a webhook
+// @Tags webhooks
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/v1/webhooks/{id} [delete]
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	userIDStr := middleware.GetUserID(c)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Unauthorized(c, "invalid user ID")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "invalid webhook ID")
+		return
+	}
+
+	if err := h.webhookUseCase.DeleteWebhook(c.Request.Context(), id, userID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "webhook deleted"})
+}
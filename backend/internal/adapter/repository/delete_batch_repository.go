@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+)
+
+// DeleteBatchModel is the Gorm model for delete_batches table
+type DeleteBatchModel struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	RootObjectID int64      `gorm:"not null"`
+	RootName     string     `gorm:"size:255;not null"`
+	RootPath     string     `gorm:"size:1000;not null"`
+	ItemCount    int        `gorm:"not null;default:1"`
+	DeletedBy    *uuid.UUID `gorm:"type:uuid;index"`
+	DeletedAt    time.Time
+}
+
+// TableName returns the table name
+func (DeleteBatchModel) TableName() string {
+	return "delete_batches"
+}
+
+// ToEntity converts DeleteBatchModel to entity.DeleteBatch
+func (m *DeleteBatchModel) ToEntity() *entity.DeleteBatch {
+	return &entity.DeleteBatch{
+		ID:           m.ID,
+		RootObjectID: m.RootObjectID,
+		RootName:     m.RootName,
+		RootPath:     m.RootPath,
+		ItemCount:    m.ItemCount,
+		DeletedBy:    m.DeletedBy,
+		DeletedAt:    m.DeletedAt,
+	}
+}
+
+// deleteBatchRepository implements repository.DeleteBatchRepository
+type deleteBatchRepository struct {
+	db *gorm.DB
+}
+
+// NewDeleteBatchRepository creates a new delete batch repository
+func NewDeleteBatchRepository(db *gorm.DB) repository.DeleteBatchRepository {
+	return &deleteBatchRepository{db: db}
+}
+
+func (r *deleteBatchRepository) Create(ctx context.Context, batch *entity.DeleteBatch) error {
+	if batch.ID == uuid.Nil {
+		batch.ID = uuid.New()
+	}
+	if batch.DeletedAt.IsZero() {
+		batch.DeletedAt = time.Now()
+	}
+
+	model := &DeleteBatchModel{
+		ID:           batch.ID,
+		RootObjectID: batch.RootObjectID,
+		RootName:     batch.RootName,
+		RootPath:     batch.RootPath,
+		ItemCount:    batch.ItemCount,
+		DeletedBy:    batch.DeletedBy,
+		DeletedAt:    batch.DeletedAt,
+	}
+
+	return r.db.WithContext(ctx).Create(model).Error
+}
+
+func (r *deleteBatchRepository) ListByUser(ctx context.Context, userID uuid.UUID, page, pageSize int) ([]entity.DeleteBatch, int64, error) {
+	query := r.db.WithContext(ctx).Model(&DeleteBatchModel{}).Where("deleted_by = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var models []DeleteBatchModel
+	if err := query.
+		Order("deleted_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&models).Error; err != nil {
+		return nil, 0, err
+	}
+
+	batches := make([]entity.DeleteBatch, len(models))
+	for i, m := range models {
+		batches[i] = *m.ToEntity()
+	}
+	return batches, total, nil
+}
+
+func (r *deleteBatchRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&DeleteBatchModel{}, "id = ?", id).Error
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// isUniqueViolation reports whether err is a unique constraint violation
+// (Postgres SQLSTATE 23505, translated by GORM into gorm.ErrDuplicatedKey)
+func isUniqueViolation(err error) bool {
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// KernelSessionModel is the Gorm model for kernel_sessions table
+type KernelSessionModel struct {
+	KernelID     string    `gorm:"primaryKey;size:255"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null;index"`
+	SpecName     string    `gorm:"size:255;not null"`
+	IsGateway    bool      `gorm:"default:false"`
+	InstanceID   string    `gorm:"size:255;not null;default:'';index"`
+	InstanceAddr string    `gorm:"size:255;not null;default:''"`
+	ObjectID     *int64    `gorm:"index"`
+	LastActivity time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	Owner *UserModel `gorm:"foreignKey:UserID"`
+}
+
+// TableName returns the table name
+func (KernelSessionModel) TableName() string {
+	return "kernel_sessions"
+}
+
+// ToEntity converts KernelSessionModel to entity.KernelSession
+func (m *KernelSessionModel) ToEntity() *entity.KernelSession {
+	session := &entity.KernelSession{
+		KernelID:     m.KernelID,
+		UserID:       m.UserID,
+		SpecName:     m.SpecName,
+		IsGateway:    m.IsGateway,
+		InstanceID:   m.InstanceID,
+		InstanceAddr: m.InstanceAddr,
+		ObjectID:     m.ObjectID,
+		LastActivity: m.LastActivity,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    m.UpdatedAt,
+	}
+	if m.Owner != nil {
+		session.Owner = m.Owner.ToEntity()
+	}
+	return session
+}
+
+// kernelSessionRepository implements repository.KernelSessionRepository
+type kernelSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewKernelSessionRepository creates a new kernel session repository
+func NewKernelSessionRepository(db *gorm.DB) repository.KernelSessionRepository {
+	return &kernelSessionRepository{db: db}
+}
+
+func (r *kernelSessionRepository) Create(ctx context.Context, session *entity.KernelSession) error {
+	now := time.Now()
+	session.LastActivity = now
+	session.CreatedAt = now
+	session.UpdatedAt = now
+
+	model := &KernelSessionModel{
+		KernelID:     session.KernelID,
+		UserID:       session.UserID,
+		SpecName:     session.SpecName,
+		IsGateway:    session.IsGateway,
+		InstanceID:   session.InstanceID,
+		InstanceAddr: session.InstanceAddr,
+		ObjectID:     session.ObjectID,
+		LastActivity: session.LastActivity,
+		CreatedAt:    session.CreatedAt,
+		UpdatedAt:    session.UpdatedAt,
+	}
+
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(model).Error
+}
+
+func (r *kernelSessionRepository) GetByKernelID(ctx context.Context, kernelID string) (*entity.KernelSession, error) {
+	var model KernelSessionModel
+	if err := r.db.WithContext(ctx).Where("kernel_id = ?", kernelID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrNotFound
+		}
+		return nil, err
+	}
+	return model.ToEntity(), nil
+}
+
+func (r *kernelSessionRepository) List(ctx context.Context) ([]entity.KernelSession, error) {
+	var models []KernelSessionModel
+	if err := r.db.WithContext(ctx).Order("created_at ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	sessions := make([]entity.KernelSession, len(models))
+	for i, m := range models {
+		sessions[i] = *m.ToEntity()
+	}
+	return sessions, nil
+}
+
+func (r *kernelSessionRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.KernelSession, error) {
+	var models []KernelSessionModel
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	sessions := make([]entity.KernelSession, len(models))
+	for i, m := range models {
+		sessions[i] = *m.ToEntity()
+	}
+	return sessions, nil
+}
+
+func (r *kernelSessionRepository) ListByObject(ctx context.Context, objectID int64) ([]entity.KernelSession, error) {
+	var models []KernelSessionModel
+	if err := r.db.WithContext(ctx).
+		Preload("Owner").
+		Where("object_id = ?", objectID).
+		Order("created_at ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	sessions := make([]entity.KernelSession, len(models))
+	for i, m := range models {
+		sessions[i] = *m.ToEntity()
+	}
+	return sessions, nil
+}
+
+func (r *kernelSessionRepository) SetObject(ctx context.Context, kernelID string, objectID int64) error {
+	return r.db.WithContext(ctx).Model(&KernelSessionModel{}).
+		Where("kernel_id = ?", kernelID).
+		Update("object_id", objectID).Error
+}
+
+func (r *kernelSessionRepository) UpdateLastActivity(ctx context.Context, kernelID string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&KernelSessionModel{}).
+		Where("kernel_id = ?", kernelID).
+		Update("last_activity", at).Error
+}
+
+func (r *kernelSessionRepository) Delete(ctx context.Context, kernelID string) error {
+	return r.db.WithContext(ctx).Where("kernel_id = ?", kernelID).Delete(&KernelSessionModel{}).Error
+}
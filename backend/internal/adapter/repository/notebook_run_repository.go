@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+)
+
+// NotebookRunModel is the Gorm model for notebook_runs table
+type NotebookRunModel struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	ObjectID      int64      `gorm:"not null;index"`
+	VersionID     *uuid.UUID `gorm:"type:uuid;index"`
+	VersionNumber int        `gorm:"not null"`
+	KernelID      string     `gorm:"size:255;not null"`
+	DurationMs    int64      `gorm:"not null;default:0"`
+	RunBy         *uuid.UUID `gorm:"type:uuid"`
+	RanAt         time.Time
+}
+
+// TableName returns the table name
+func (NotebookRunModel) TableName() string {
+	return "notebook_runs"
+}
+
+// ToEntity converts NotebookRunModel to entity.NotebookRun
+func (m *NotebookRunModel) ToEntity() *entity.NotebookRun {
+	return &entity.NotebookRun{
+		ID:            m.ID,
+		ObjectID:      m.ObjectID,
+		VersionID:     m.VersionID,
+		VersionNumber: m.VersionNumber,
+		KernelID:      m.KernelID,
+		DurationMs:    m.DurationMs,
+		RunBy:         m.RunBy,
+		RanAt:         m.RanAt,
+	}
+}
+
+// notebookRunRepository implements repository.NotebookRunRepository
+type notebookRunRepository struct {
+	db *gorm.DB
+}
+
+// NewNotebookRunRepository creates a new notebook run repository
+func NewNotebookRunRepository(db *gorm.DB) repository.NotebookRunRepository {
+	return &notebookRunRepository{db: db}
+}
+
+func (r *notebookRunRepository) Create(ctx context.Context, run *entity.NotebookRun) error {
+	if run.ID == uuid.Nil {
+		run.ID = uuid.New()
+	}
+	if run.RanAt.IsZero() {
+		run.RanAt = time.Now()
+	}
+
+	model := &NotebookRunModel{
+		ID:            run.ID,
+		ObjectID:      run.ObjectID,
+		VersionID:     run.VersionID,
+		VersionNumber: run.VersionNumber,
+		KernelID:      run.KernelID,
+		DurationMs:    run.DurationMs,
+		RunBy:         run.RunBy,
+		RanAt:         run.RanAt,
+	}
+
+	return r.db.WithContext(ctx).Create(model).Error
+}
+
+func (r *notebookRunRepository) ListByObject(ctx context.Context, objectID int64, page, pageSize int) ([]entity.NotebookRun, int64, error) {
+	query := r.db.WithContext(ctx).Model(&NotebookRunModel{}).Where("object_id = ?", objectID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var models []NotebookRunModel
+	if err := query.
+		Order("ran_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&models).Error; err != nil {
+		return nil, 0, err
+	}
+
+	runs := make([]entity.NotebookRun, len(models))
+	for i, m := range models {
+		runs[i] = *m.ToEntity()
+	}
+	return runs, total, nil
+}
+
+func (r *notebookRunRepository) ExistsForVersion(ctx context.Context, versionID uuid.UUID) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&NotebookRunModel{}).
+		Where("version_id = ?", versionID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
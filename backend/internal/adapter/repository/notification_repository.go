@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+)
+
+// NotificationModel is the Gorm model for notifications table
+type NotificationModel struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Type       string     `gorm:"size:50;not null"`
+	ObjectID   *int64     `gorm:"index"`
+	ObjectName string     `gorm:"size:255;not null;default:''"`
+	ActorID    *uuid.UUID `gorm:"type:uuid"`
+	Role       string     `gorm:"size:20;not null;default:''"`
+	ReadAt     *time.Time
+	CreatedAt  time.Time
+}
+
+// TableName returns the table name
+func (NotificationModel) TableName() string {
+	return "notifications"
+}
+
+// ToEntity converts NotificationModel to entity.Notification
+func (m *NotificationModel) ToEntity() *entity.Notification {
+	return &entity.Notification{
+		ID:         m.ID,
+		UserID:     m.UserID,
+		Type:       m.Type,
+		ObjectID:   m.ObjectID,
+		ObjectName: m.ObjectName,
+		ActorID:    m.ActorID,
+		Role:       entity.Role(m.Role),
+		ReadAt:     m.ReadAt,
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+// notificationRepository implements repository.NotificationRepository
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository creates a new notification repository
+func NewNotificationRepository(db *gorm.DB) repository.NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) Create(ctx context.Context, notification *entity.Notification) error {
+	if notification.ID == uuid.Nil {
+		notification.ID = uuid.New()
+	}
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+
+	model := &NotificationModel{
+		ID:         notification.ID,
+		UserID:     notification.UserID,
+		Type:       notification.Type,
+		ObjectID:   notification.ObjectID,
+		ObjectName: notification.ObjectName,
+		ActorID:    notification.ActorID,
+		Role:       string(notification.Role),
+		ReadAt:     notification.ReadAt,
+		CreatedAt:  notification.CreatedAt,
+	}
+
+	return r.db.WithContext(ctx).Create(model).Error
+}
+
+func (r *notificationRepository) ListByUser(ctx context.Context, userID uuid.UUID, unreadOnly bool, page, pageSize int) ([]entity.Notification, int64, error) {
+	query := r.db.WithContext(ctx).Model(&NotificationModel{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var models []NotificationModel
+	if err := query.
+		Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&models).Error; err != nil {
+		return nil, 0, err
+	}
+
+	notifications := make([]entity.Notification, len(models))
+	for i, m := range models {
+		notifications[i] = *m.ToEntity()
+	}
+	return notifications, total, nil
+}
+
+func (r *notificationRepository) MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&NotificationModel{}).
+		Where("user_id = ? AND read_at IS NULL", userID)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+
+	result := query.Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
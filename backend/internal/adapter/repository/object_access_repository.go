@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// objectAccessRepository implements repository.ObjectAccessRepository. The
+// counters are left out of ObjectModel so that saving an object read before
+// a flush never overwrites them.
+type objectAccessRepository struct {
+	db *gorm.DB
+}
+
+// NewObjectAccessRepository creates a new object access repository
+func NewObjectAccessRepository(db *gorm.DB) repository.ObjectAccessRepository {
+	return &objectAccessRepository{db: db}
+}
+
+func (r *objectAccessRepository) Record(ctx context.Context, accesses []*entity.ObjectAccess) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, access := range accesses {
+			if err := tx.Exec(`UPDATE objects
+				SET download_count = download_count + ?,
+					last_accessed_at = GREATEST(COALESCE(last_accessed_at, ?), ?)
+				WHERE id = ?`,
+				access.Count, access.LastAccessedAt, access.LastAccessedAt, access.ObjectID).Error; err != nil {
+				return err
+			}
+
+			// Objects deleted since the read are skipped rather than failing the batch
+			for userID, user := range access.Users {
+				if err := tx.Exec(`INSERT INTO object_access_log (object_id, user_id, access_count, last_accessed_at)
+					SELECT ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM objects WHERE id = ?)
+					ON CONFLICT (object_id, user_id) DO UPDATE SET
+						access_count = object_access_log.access_count + EXCLUDED.access_count,
+						last_accessed_at = GREATEST(object_access_log.last_accessed_at, EXCLUDED.last_accessed_at)`,
+					access.ObjectID, userID, user.Count, user.LastAccessedAt, access.ObjectID).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (r *objectAccessRepository) GetStats(ctx context.Context, objectID int64, withUsers bool) (*entity.ObjectAccessStats, error) {
+	var row struct {
+		DownloadCount  int64
+		LastAccessedAt *time.Time
+	}
+	result := r.db.WithContext(ctx).Table("objects").
+		Select("download_count, last_accessed_at").
+		Where("id = ? AND is_deleted = false", objectID).
+		Scan(&row)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperrors.ErrNotFound
+	}
+
+	stats := &entity.ObjectAccessStats{
+		ObjectID:       objectID,
+		DownloadCount:  row.DownloadCount,
+		LastAccessedAt: row.LastAccessedAt,
+	}
+	if withUsers {
+		var users int64
+		if err := r.db.WithContext(ctx).Table("object_access_log").
+			Where("object_id = ?", objectID).
+			Count(&users).Error; err != nil {
+			return nil, err
+		}
+		stats.DistinctUsers = &users
+	}
+	return stats, nil
+}
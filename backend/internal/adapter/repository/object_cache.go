@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"expvar"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+)
+
+// Runtime metrics for the object metadata cache
+var (
+	objectCacheHits          = new(expvar.Int)
+	objectCacheMisses        = new(expvar.Int)
+	objectCacheEvictions     = new(expvar.Int)
+	objectCacheInvalidations = new(expvar.Int)
+)
+
+func init() {
+	m := expvar.NewMap("object_cache")
+	m.Set("hits", objectCacheHits)
+	m.Set("misses", objectCacheMisses)
+	m.Set("evictions", objectCacheEvictions)
+	m.Set("invalidations", objectCacheInvalidations)
+	m.Set("hit_rate", expvar.Func(func() any {
+		hits, misses := objectCacheHits.Value(), objectCacheMisses.Value()
+		if hits+misses == 0 {
+			return 0.0
+		}
+		return float64(hits) / float64(hits+misses)
+	}))
+}
+
+// ObjectCache is a size-bounded LRU cache of object metadata keyed by ID.
+// Entries expire after a TTL, which bounds how stale an object changed by
+// another instance can be. Each invalidation bumps a generation so that a
+// read racing with a write never stores what it read before the write.
+type ObjectCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[int64]*list.Element
+	lru     *list.List // Front is most recently used
+	gen     uint64
+}
+
+type objectCacheEntry struct {
+	obj       *entity.Object
+	expiresAt time.Time
+}
+
+// NewObjectCache creates an object cache holding at most size objects for ttl each
+func NewObjectCache(size int, ttl time.Duration) *ObjectCache {
+	return &ObjectCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[int64]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get returns a copy of the cached object. On a miss it returns the current
+// generation, to be passed to put with the object read from the database.
+func (c *ObjectCache) get(id int64) (*entity.Object, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[id]; ok {
+		entry := elem.Value.(*objectCacheEntry)
+		if time.Now().Before(entry.expiresAt) {
+			c.lru.MoveToFront(elem)
+			objectCacheHits.Add(1)
+			return cloneObject(entry.obj), c.gen, true
+		}
+		c.remove(elem)
+	}
+	objectCacheMisses.Add(1)
+	return nil, c.gen, false
+}
+
+// put stores a copy of obj unless the cache was invalidated since gen
+func (c *ObjectCache) put(obj *entity.Object, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+	entry := &objectCacheEntry{obj: cloneObject(obj), expiresAt: time.Now().Add(c.ttl)}
+	if elem, ok := c.entries[obj.ID]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[obj.ID] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+		objectCacheEvictions.Add(1)
+	}
+}
+
+// invalidate drops the given objects
+func (c *ObjectCache) invalidate(ids ...int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for _, id := range ids {
+		if elem, ok := c.entries[id]; ok {
+			c.remove(elem)
+			objectCacheInvalidations.Add(1)
+		}
+	}
+}
+
+// invalidateTree drops the object at path and its descendants
+func (c *ObjectCache) invalidateTree(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	prefix := path + "/"
+	for _, elem := range c.entries {
+		if p := elem.Value.(*objectCacheEntry).obj.Path; p == path || strings.HasPrefix(p, prefix) {
+			c.remove(elem)
+			objectCacheInvalidations.Add(1)
+		}
+	}
+}
+
+// purge drops all objects
+func (c *ObjectCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	objectCacheInvalidations.Add(int64(len(c.entries)))
+	c.entries = make(map[int64]*list.Element)
+	c.lru.Init()
+}
+
+// remove must be called with mu held
+func (c *ObjectCache) remove(elem *list.Element) {
+	delete(c.entries, elem.Value.(*objectCacheEntry).obj.ID)
+	c.lru.Remove(elem)
+}
+
+// cloneObject copies obj so that callers modifying the object they got, as
+// the use cases do before Update, never modify a cached entry
+func cloneObject(obj *entity.Object) *entity.Object {
+	clone := *obj
+	clone.ParentID = clonePtr(obj.ParentID)
+	clone.LastModifiedBy = clonePtr(obj.LastModifiedBy)
+	clone.DeletedAt = clonePtr(obj.DeletedAt)
+	clone.DeleteBatchID = clonePtr(obj.DeleteBatchID)
+	clone.Creator = clonePtr(obj.Creator)
+	clone.LastModifier = clonePtr(obj.LastModifier)
+	clone.Tags = slices.Clone(obj.Tags)
+	clone.Children = slices.Clone(obj.Children)
+	return &clone
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// cachedObjectRepository serves GetByID from an ObjectCache and invalidates
+// it on every write. Permissions are stored apart from objects, so
+// permission changes leave the cache alone.
+type cachedObjectRepository struct {
+	repository.ObjectRepository
+	cache *ObjectCache
+}
+
+// NewCachedObjectRepository wraps repo with a read-through cache for GetByID
+func NewCachedObjectRepository(repo repository.ObjectRepository, cache *ObjectCache) repository.ObjectRepository {
+	return &cachedObjectRepository{ObjectRepository: repo, cache: cache}
+}
+
+func (r *cachedObjectRepository) GetByID(ctx context.Context, id int64) (*entity.Object, error) {
+	obj, gen, ok := r.cache.get(id)
+	if ok {
+		return obj, nil
+	}
+	// Misses, including deleted objects, are not cached
+	obj, err := r.ObjectRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.put(obj, gen)
+	return obj, nil
+}
+
+func (r *cachedObjectRepository) Update(ctx context.Context, obj *entity.Object) error {
+	defer r.cache.invalidate(obj.ID)
+	return r.ObjectRepository.Update(ctx, obj)
+}
+
+func (r *cachedObjectRepository) UpdateIfUnmodified(ctx context.Context, obj *entity.Object, expectedUpdatedAt time.Time) error {
+	defer r.cache.invalidate(obj.ID)
+	return r.ObjectRepository.UpdateIfUnmodified(ctx, obj, expectedUpdatedAt)
+}
+
+func (r *cachedObjectRepository) Delete(ctx context.Context, id int64) error {
+	defer r.cache.invalidate(id)
+	return r.ObjectRepository.Delete(ctx, id)
+}
+
+func (r *cachedObjectRepository) DeleteTree(ctx context.Context, obj *entity.Object, batchID uuid.UUID) error {
+	defer func() {
+		r.cache.invalidate(obj.ID)
+		r.cache.invalidateTree(obj.Path)
+	}()
+	return r.ObjectRepository.DeleteTree(ctx, obj, batchID)
+}
+
+func (r *cachedObjectRepository) RestoreBatch(ctx context.Context, batchID uuid.UUID) error {
+	// Deleted objects are never cached, yet a concurrent read may have
+	// started before the restore
+	defer r.cache.invalidate()
+	return r.ObjectRepository.RestoreBatch(ctx, batchID)
+}
+
+func (r *cachedObjectRepository) HardDelete(ctx context.Context, id int64) error {
+	defer r.cache.invalidate(id)
+	return r.ObjectRepository.HardDelete(ctx, id)
+}
+
+func (r *cachedObjectRepository) UpdatePath(ctx context.Context, id int64, newPath string) error {
+	defer r.cache.invalidate(id)
+	return r.ObjectRepository.UpdatePath(ctx, id, newPath)
+}
+
+func (r *cachedObjectRepository) TransferTree(ctx context.Context, rootPath string, from, to uuid.UUID) error {
+	defer r.cache.invalidateTree(rootPath)
+	return r.ObjectRepository.TransferTree(ctx, rootPath, from, to)
+}
+
+func (r *cachedObjectRepository) EraseByCreator(ctx context.Context, creatorID uuid.UUID, workspacePath string) (*entity.ObjectErasure, error) {
+	// The erased objects may be anywhere, in other users' directories too
+	defer r.cache.purge()
+	return r.ObjectRepository.EraseByCreator(ctx, creatorID, workspacePath)
+}
+
+// cachedTagRepository invalidates an ObjectCache on tag changes, since cached
+// objects include their tags
+type cachedTagRepository struct {
+	repository.TagRepository
+	cache *ObjectCache
+}
+
+// NewCachedTagRepository wraps repo so tag writes invalidate the objects cached in cache
+func NewCachedTagRepository(repo repository.TagRepository, cache *ObjectCache) repository.TagRepository {
+	return &cachedTagRepository{TagRepository: repo, cache: cache}
+}
+
+// Update renames a tag, which changes every object carrying it
+func (r *cachedTagRepository) Update(ctx context.Context, tag *entity.Tag) error {
+	defer r.cache.purge()
+	return r.TagRepository.Update(ctx, tag)
+}
+
+// Delete removes a tag from every object carrying it
+func (r *cachedTagRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	defer r.cache.purge()
+	return r.TagRepository.Delete(ctx, id)
+}
+
+func (r *cachedTagRepository) AddToObject(ctx context.Context, objectID int64, tagID uuid.UUID) error {
+	defer r.cache.invalidate(objectID)
+	return r.TagRepository.AddToObject(ctx, objectID, tagID)
+}
+
+func (r *cachedTagRepository) RemoveFromObject(ctx context.Context, objectID int64, tagID uuid.UUID) error {
+	defer r.cache.invalidate(objectID)
+	return r.TagRepository.RemoveFromObject(ctx, objectID, tagID)
+}
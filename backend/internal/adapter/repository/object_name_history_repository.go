@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+)
+
+// ObjectNameHistoryModel is the Gorm model for object_name_history table
+type ObjectNameHistoryModel struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	ObjectID  int64      `gorm:"not null;index"`
+	OldName   string     `gorm:"size:255;not null"`
+	NewName   string     `gorm:"size:255;not null"`
+	RenamedBy *uuid.UUID `gorm:"type:uuid"`
+	RenamedAt time.Time
+}
+
+// TableName returns the table name
+func (ObjectNameHistoryModel) TableName() string {
+	return "object_name_history"
+}
+
+// ToEntity converts ObjectNameHistoryModel to entity.ObjectNameHistory
+func (m *ObjectNameHistoryModel) ToEntity() *entity.ObjectNameHistory {
+	return &entity.ObjectNameHistory{
+		ID:        m.ID,
+		ObjectID:  m.ObjectID,
+		OldName:   m.OldName,
+		NewName:   m.NewName,
+		RenamedBy: m.RenamedBy,
+		RenamedAt: m.RenamedAt,
+	}
+}
+
+// objectNameHistoryRepository implements repository.ObjectNameHistoryRepository
+type objectNameHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewObjectNameHistoryRepository creates a new object name history repository
+func NewObjectNameHistoryRepository(db *gorm.DB) repository.ObjectNameHistoryRepository {
+	return &objectNameHistoryRepository{db: db}
+}
+
+func (r *objectNameHistoryRepository) Create(ctx context.Context, history *entity.ObjectNameHistory) error {
+	if history.ID == uuid.Nil {
+		history.ID = uuid.New()
+	}
+	if history.RenamedAt.IsZero() {
+		history.RenamedAt = time.Now()
+	}
+
+	model := &ObjectNameHistoryModel{
+		ID:        history.ID,
+		ObjectID:  history.ObjectID,
+		OldName:   history.OldName,
+		NewName:   history.NewName,
+		RenamedBy: history.RenamedBy,
+		RenamedAt: history.RenamedAt,
+	}
+
+	return r.db.WithContext(ctx).Create(model).Error
+}
+
+func (r *objectNameHistoryRepository) ListByObject(ctx context.Context, objectID int64) ([]entity.ObjectNameHistory, error) {
+	var models []ObjectNameHistoryModel
+	if err := r.db.WithContext(ctx).
+		Where("object_id = ?", objectID).
+		Order("renamed_at DESC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	history := make([]entity.ObjectNameHistory, len(models))
+	for i, m := range models {
+		history[i] = *m.ToEntity()
+	}
+	return history, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/leondli/workspace/internal/domain/repository"
+)
+
+// ObjectPinModel is the Gorm model for object_pins table
+type ObjectPinModel struct {
+	ObjectID  int64     `gorm:"primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	CreatedAt time.Time
+}
+
+// TableName returns the table name
+func (ObjectPinModel) TableName() string {
+	return "object_pins"
+}
+
+// objectPinRepository implements repository.ObjectPinRepository
+type objectPinRepository struct {
+	db *gorm.DB
+}
+
+// NewObjectPinRepository creates a new object pin repository
+func NewObjectPinRepository(db *gorm.DB) repository.ObjectPinRepository {
+	return &objectPinRepository{db: db}
+}
+
+func (r *objectPinRepository) Pin(ctx context.Context, objectID int64, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&ObjectPinModel{ObjectID: objectID, UserID: userID, CreatedAt: time.Now()}).Error
+}
+
+func (r *objectPinRepository) Unpin(ctx context.Context, objectID int64, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Delete(&ObjectPinModel{}, "object_id = ? AND user_id = ?", objectID, userID).Error
+}
+
+func (r *objectPinRepository) ListPinned(ctx context.Context, userID uuid.UUID, objectIDs []int64) (map[int64]bool, error) {
+	pinned := make(map[int64]bool)
+	if len(objectIDs) == 0 {
+		return pinned, nil
+	}
+
+	var ids []int64
+	if err := r.db.WithContext(ctx).Model(&ObjectPinModel{}).
+		Where("user_id = ? AND object_id IN ?", userID, objectIDs).
+		Pluck("object_id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		pinned[id] = true
+	}
+	return pinned, nil
+}
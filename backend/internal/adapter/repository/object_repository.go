@@ -3,10 +3,13 @@ package repository
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/leondli/workspace/internal/domain/entity"
 	"github.com/leondli/workspace/internal/domain/repository"
@@ -15,25 +18,30 @@ import (
 
 // ObjectModel is the Gorm model for objects table
 type ObjectModel struct {
-	ID             int64     `gorm:"primaryKey"`
-	Name           string    `gorm:"size:255;not null"`
-	Type           string    `gorm:"size:50;not null;index"`
-	Path           string    `gorm:"size:1000;uniqueIndex;not null"`
-	ParentID       *int64    `gorm:"index"`
-	CreatorID      uuid.UUID `gorm:"type:uuid;not null;index"`
-	Size           int64     `gorm:"default:0"`
-	ContentHash    string    `gorm:"size:64"`
-	Description    string    `gorm:"type:text"`
-	CurrentVersion int       `gorm:"default:1"`
-	IsDeleted      bool      `gorm:"default:false;index"`
+	ID             int64      `gorm:"primaryKey"`
+	Name           string     `gorm:"size:255;not null"`
+	Type           string     `gorm:"size:50;not null;index"`
+	Path           string     `gorm:"size:1000;uniqueIndex;not null"`
+	ParentID       *int64     `gorm:"index"`
+	CreatorID      uuid.UUID  `gorm:"type:uuid;not null;index"`
+	LastModifiedBy *uuid.UUID `gorm:"type:uuid;index"`
+	Size           int64      `gorm:"default:0"`
+	ContentHash    string     `gorm:"size:64"`
+	MimeType       string     `gorm:"size:255"`
+	Language       string     `gorm:"size:50"`
+	Description    string     `gorm:"type:text"`
+	CurrentVersion int        `gorm:"default:1"`
+	IsDeleted      bool       `gorm:"default:false;index"`
 	DeletedAt      *time.Time
+	DeleteBatchID  *uuid.UUID `gorm:"type:uuid;index"`
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 
 	// Relations
-	Creator *UserModel   `gorm:"foreignKey:CreatorID"`
-	Tags    []TagModel   `gorm:"many2many:object_tags;foreignKey:ID;joinForeignKey:object_id;References:ID;joinReferences:tag_id"`
-	Parent  *ObjectModel `gorm:"foreignKey:ParentID"`
+	Creator      *UserModel   `gorm:"foreignKey:CreatorID"`
+	LastModifier *UserModel   `gorm:"foreignKey:LastModifiedBy"`
+	Tags         []TagModel   `gorm:"many2many:object_tags;foreignKey:ID;joinForeignKey:object_id;References:ID;joinReferences:tag_id"`
+	Parent       *ObjectModel `gorm:"foreignKey:ParentID"`
 }
 
 // TableName returns the table name
@@ -50,12 +58,16 @@ func (m *ObjectModel) ToEntity() *entity.Object {
 		Path:           m.Path,
 		ParentID:       m.ParentID,
 		CreatorID:      m.CreatorID,
+		LastModifiedBy: m.LastModifiedBy,
 		Size:           m.Size,
 		ContentHash:    m.ContentHash,
+		MimeType:       m.MimeType,
+		Language:       m.Language,
 		Description:    m.Description,
 		CurrentVersion: m.CurrentVersion,
 		IsDeleted:      m.IsDeleted,
 		DeletedAt:      m.DeletedAt,
+		DeleteBatchID:  m.DeleteBatchID,
 		CreatedAt:      m.CreatedAt,
 		UpdatedAt:      m.UpdatedAt,
 	}
@@ -64,6 +76,10 @@ func (m *ObjectModel) ToEntity() *entity.Object {
 		obj.Creator = m.Creator.ToEntity()
 	}
 
+	if m.LastModifier != nil {
+		obj.LastModifier = m.LastModifier.ToEntity()
+	}
+
 	if len(m.Tags) > 0 {
 		obj.Tags = make([]entity.Tag, len(m.Tags))
 		for i, tag := range m.Tags {
@@ -83,12 +99,16 @@ func ObjectModelFromEntity(o *entity.Object) *ObjectModel {
 		Path:           o.Path,
 		ParentID:       o.ParentID,
 		CreatorID:      o.CreatorID,
+		LastModifiedBy: o.LastModifiedBy,
 		Size:           o.Size,
 		ContentHash:    o.ContentHash,
+		MimeType:       o.MimeType,
+		Language:       o.Language,
 		Description:    o.Description,
 		CurrentVersion: o.CurrentVersion,
 		IsDeleted:      o.IsDeleted,
 		DeletedAt:      o.DeletedAt,
+		DeleteBatchID:  o.DeleteBatchID,
 		CreatedAt:      o.CreatedAt,
 		UpdatedAt:      o.UpdatedAt,
 	}
@@ -107,14 +127,24 @@ func NewObjectRepository(db *gorm.DB) repository.ObjectRepository {
 func (r *objectRepository) Create(ctx context.Context, obj *entity.Object) error {
 	obj.CreatedAt = time.Now()
 	obj.UpdatedAt = time.Now()
+	if obj.LastModifiedBy == nil {
+		creatorID := obj.CreatorID
+		obj.LastModifiedBy = &creatorID
+	}
 	model := ObjectModelFromEntity(obj)
-	return r.db.WithContext(ctx).Create(model).Error
+	if err := r.db.WithContext(ctx).Create(model).Error; err != nil {
+		if isUniqueViolation(err) {
+			return apperrors.AlreadyExistsError("object at this path")
+		}
+		return err
+	}
+	return nil
 }
 
 func (r *objectRepository) GetByID(ctx context.Context, id int64) (*entity.Object, error) {
 	var model ObjectModel
 	if err := r.db.WithContext(ctx).
-		Preload("Creator").
+		Preload("Creator").Preload("LastModifier").
 		Preload("Tags").
 		Where("id = ? AND is_deleted = false", id).
 		First(&model).Error; err != nil {
@@ -129,7 +159,7 @@ func (r *objectRepository) GetByID(ctx context.Context, id int64) (*entity.Objec
 func (r *objectRepository) GetByPath(ctx context.Context, path string) (*entity.Object, error) {
 	var model ObjectModel
 	if err := r.db.WithContext(ctx).
-		Preload("Creator").
+		Preload("Creator").Preload("LastModifier").
 		Preload("Tags").
 		Where("path = ? AND is_deleted = false", path).
 		First(&model).Error; err != nil {
@@ -146,6 +176,30 @@ func (r *objectRepository) Update(ctx context.Context, obj *entity.Object) error
 	return r.db.WithContext(ctx).Save(model).Error
 }
 
+// UpdateIfUnmodified updates obj only if its updated_at still equals
+// expectedUpdatedAt, returning ErrConflict otherwise, and sets obj.UpdatedAt
+// to the value the trigger stored. A path taken by another object is an
+// already-exists error.
+func (r *objectRepository) UpdateIfUnmodified(ctx context.Context, obj *entity.Object, expectedUpdatedAt time.Time) error {
+	model := ObjectModelFromEntity(obj)
+	result := r.db.WithContext(ctx).Model(model).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "updated_at"}}}).
+		Where("updated_at = ?", expectedUpdatedAt).
+		Select("*").Omit("id", "created_at", "updated_at").
+		Updates(model)
+	if result.Error != nil {
+		if isUniqueViolation(result.Error) {
+			return apperrors.AlreadyExistsError("object at this path")
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.ErrConflict
+	}
+	obj.UpdatedAt = model.UpdatedAt
+	return nil
+}
+
 func (r *objectRepository) Delete(ctx context.Context, id int64) error {
 	now := time.Now()
 	return r.db.WithContext(ctx).Model(&ObjectModel{}).
@@ -156,6 +210,59 @@ func (r *objectRepository) Delete(ctx context.Context, id int64) error {
 		}).Error
 }
 
+func (r *objectRepository) DeleteTree(ctx context.Context, obj *entity.Object, batchID uuid.UUID) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Model(&ObjectModel{}).
+		Where("(id = ? OR path LIKE ?) AND is_deleted = false", obj.ID, escapeLike(obj.Path)+"/%").
+		Updates(map[string]interface{}{
+			"is_deleted":      true,
+			"deleted_at":      &now,
+			"delete_batch_id": batchID,
+		}).Error
+}
+
+func (r *objectRepository) GetDeletedByID(ctx context.Context, id int64) (*entity.Object, error) {
+	var model ObjectModel
+	if err := r.db.WithContext(ctx).
+		Preload("Creator").Preload("LastModifier").
+		Preload("Tags").
+		Where("id = ? AND is_deleted = true", id).
+		First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrNotFound
+		}
+		return nil, err
+	}
+	return model.ToEntity(), nil
+}
+
+func (r *objectRepository) ListDeleteBatch(ctx context.Context, batchID uuid.UUID) ([]entity.Object, error) {
+	var models []ObjectModel
+	if err := r.db.WithContext(ctx).
+		Where("delete_batch_id = ? AND is_deleted = true", batchID).
+		Order("path ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	objects := make([]entity.Object, len(models))
+	for i, m := range models {
+		objects[i] = *m.ToEntity()
+	}
+
+	return objects, nil
+}
+
+func (r *objectRepository) RestoreBatch(ctx context.Context, batchID uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&ObjectModel{}).
+		Where("delete_batch_id = ?", batchID).
+		Updates(map[string]interface{}{
+			"is_deleted":      false,
+			"deleted_at":      nil,
+			"delete_batch_id": nil,
+		}).Error
+}
+
 func (r *objectRepository) HardDelete(ctx context.Context, id int64) error {
 	return r.db.WithContext(ctx).Delete(&ObjectModel{}, id).Error
 }
@@ -190,6 +297,10 @@ func (r *objectRepository) List(ctx context.Context, filter *entity.ObjectFilter
 		query = query.Where("name ILIKE ?", "%"+filter.Search+"%")
 	}
 
+	if filter.PathPrefix != "" {
+		query = query.Where("path LIKE ?", escapeLike(filter.PathPrefix)+"/%")
+	}
+
 	// Count total
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -201,7 +312,7 @@ func (r *objectRepository) List(ctx context.Context, filter *entity.ObjectFilter
 	query = query.Offset(offset).Limit(filter.PageSize)
 
 	// Load with relations
-	query = query.Preload("Creator").Preload("Tags").Order("type ASC, name ASC")
+	query = query.Preload("Creator").Preload("LastModifier").Preload("Tags").Order("type ASC, name ASC")
 
 	var models []ObjectModel
 	if err := query.Find(&models).Error; err != nil {
@@ -216,7 +327,7 @@ func (r *objectRepository) List(ctx context.Context, filter *entity.ObjectFilter
 	return objects, total, nil
 }
 
-func (r *objectRepository) ListChildren(ctx context.Context, parentID *int64, page, pageSize int) ([]entity.Object, int64, error) {
+func (r *objectRepository) ListChildren(ctx context.Context, parentID *int64, pinnedBy *uuid.UUID, page, pageSize int) ([]entity.Object, int64, error) {
 	query := r.db.WithContext(ctx).Model(&ObjectModel{}).Where("is_deleted = false")
 
 	if parentID != nil {
@@ -230,9 +341,17 @@ func (r *objectRepository) ListChildren(ctx context.Context, parentID *int64, pa
 		return nil, 0, err
 	}
 
+	if pinnedBy != nil {
+		query = query.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "EXISTS (SELECT 1 FROM object_pins WHERE object_pins.object_id = objects.id AND object_pins.user_id = ?) DESC",
+			Vars:               []interface{}{*pinnedBy},
+			WithoutParentheses: true,
+		}})
+	}
+
 	offset := (page - 1) * pageSize
 	query = query.Offset(offset).Limit(pageSize).
-		Preload("Creator").
+		Preload("Creator").Preload("LastModifier").
 		Preload("Tags").
 		Order("type ASC, name ASC")
 
@@ -249,13 +368,37 @@ func (r *objectRepository) ListChildren(ctx context.Context, parentID *int64, pa
 	return objects, total, nil
 }
 
+func (r *objectRepository) CountChildren(ctx context.Context, parentIDs []int64) (map[int64]int64, error) {
+	counts := make(map[int64]int64, len(parentIDs))
+	if len(parentIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ParentID int64
+		Count    int64
+	}
+	if err := r.db.WithContext(ctx).Model(&ObjectModel{}).
+		Select("parent_id, COUNT(*) AS count").
+		Where("parent_id IN ? AND is_deleted = false", parentIDs).
+		Group("parent_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.ParentID] = row.Count
+	}
+	return counts, nil
+}
+
 func (r *objectRepository) GetTree(ctx context.Context, parentID *int64, depth int) ([]entity.Object, error) {
 	// Recursive CTE for getting tree
 	var models []ObjectModel
 
 	query := r.db.WithContext(ctx).
 		Where("is_deleted = false").
-		Preload("Creator")
+		Preload("Creator").Preload("LastModifier")
 
 	if parentID != nil {
 		query = query.Where("parent_id = ?", *parentID)
@@ -294,6 +437,16 @@ func (r *objectRepository) ExistsByPath(ctx context.Context, path string) (bool,
 	return count > 0, nil
 }
 
+func (r *objectRepository) ExistsByPathFold(ctx context.Context, path string, excludeID int64) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&ObjectModel{}).
+		Where("LOWER(path) = LOWER(?) AND id <> ? AND is_deleted = false", path, excludeID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 func (r *objectRepository) ExistsInParent(ctx context.Context, parentID *int64, name string) (bool, error) {
 	query := r.db.WithContext(ctx).Model(&ObjectModel{}).Where("name = ? AND is_deleted = false", name)
 
@@ -322,7 +475,7 @@ func (r *objectRepository) GetByCreator(ctx context.Context, creatorID uuid.UUID
 	offset := (page - 1) * pageSize
 	var models []ObjectModel
 	if err := query.Offset(offset).Limit(pageSize).
-		Preload("Creator").
+		Preload("Creator").Preload("LastModifier").
 		Preload("Tags").
 		Order("updated_at DESC").
 		Find(&models).Error; err != nil {
@@ -341,7 +494,8 @@ func (r *objectRepository) ListByCreator(ctx context.Context, creatorID uuid.UUI
 	var models []ObjectModel
 	if err := r.db.WithContext(ctx).
 		Where("creator_id = ? AND is_deleted = false", creatorID).
-		Preload("Creator").
+		Preload("Creator").Preload("LastModifier").
+		Preload("Tags").
 		Order("type ASC, name ASC").
 		Find(&models).Error; err != nil {
 		return nil, err
@@ -355,7 +509,46 @@ func (r *objectRepository) ListByCreator(ctx context.Context, creatorID uuid.UUI
 	return objects, nil
 }
 
-func (r *objectRepository) Search(ctx context.Context, query string, types []entity.ObjectType, page, pageSize int) ([]entity.Object, int64, error) {
+func (r *objectRepository) GetByContentHash(ctx context.Context, creatorID uuid.UUID, hash string, limit int) ([]entity.Object, error) {
+	var models []ObjectModel
+	if err := r.db.WithContext(ctx).
+		Where("creator_id = ? AND content_hash = ? AND type != ? AND is_deleted = false", creatorID, hash, entity.ObjectTypeDirectory).
+		Preload("Creator").Preload("LastModifier").
+		Preload("Tags").
+		Order("updated_at DESC").
+		Limit(limit).
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	objects := make([]entity.Object, len(models))
+	for i, m := range models {
+		objects[i] = *m.ToEntity()
+	}
+
+	return objects, nil
+}
+
+func (r *objectRepository) ListRootsByCreator(ctx context.Context, creatorID uuid.UUID) ([]entity.Object, error) {
+	var models []ObjectModel
+	if err := r.db.WithContext(ctx).
+		Where("creator_id = ? AND parent_id IS NULL AND is_deleted = false", creatorID).
+		Preload("Creator").Preload("LastModifier").
+		Preload("Tags").
+		Order("type ASC, name ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	objects := make([]entity.Object, len(models))
+	for i, m := range models {
+		objects[i] = *m.ToEntity()
+	}
+
+	return objects, nil
+}
+
+func (r *objectRepository) Search(ctx context.Context, query string, types []entity.ObjectType, pathPrefix string, page, pageSize int) ([]entity.Object, int64, error) {
 	dbQuery := r.db.WithContext(ctx).Model(&ObjectModel{}).
 		Where("is_deleted = false").
 		Where("name ILIKE ?", "%"+query+"%")
@@ -368,6 +561,10 @@ func (r *objectRepository) Search(ctx context.Context, query string, types []ent
 		dbQuery = dbQuery.Where("type IN ?", typeStrs)
 	}
 
+	if pathPrefix != "" {
+		dbQuery = dbQuery.Where("path LIKE ?", escapeLike(pathPrefix)+"/%")
+	}
+
 	var total int64
 	if err := dbQuery.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -376,7 +573,7 @@ func (r *objectRepository) Search(ctx context.Context, query string, types []ent
 	offset := (page - 1) * pageSize
 	var models []ObjectModel
 	if err := dbQuery.Offset(offset).Limit(pageSize).
-		Preload("Creator").
+		Preload("Creator").Preload("LastModifier").
 		Preload("Tags").
 		Order("name ASC").
 		Find(&models).Error; err != nil {
@@ -400,7 +597,7 @@ func (r *objectRepository) UpdatePath(ctx context.Context, id int64, newPath str
 func (r *objectRepository) GetDescendants(ctx context.Context, parentPath string) ([]entity.Object, error) {
 	var models []ObjectModel
 	if err := r.db.WithContext(ctx).
-		Where("path LIKE ? AND is_deleted = false", parentPath+"/%").
+		Where("path LIKE ? AND is_deleted = false", escapeLike(parentPath)+"/%").
 		Order("path ASC").
 		Find(&models).Error; err != nil {
 		return nil, err
@@ -413,3 +610,78 @@ func (r *objectRepository) GetDescendants(ctx context.Context, parentPath string
 
 	return objects, nil
 }
+
+func (r *objectRepository) TransferTree(ctx context.Context, rootPath string, from, to uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&ObjectModel{}).
+		Where("(path = ? OR path LIKE ?) AND creator_id = ?", rootPath, escapeLike(rootPath)+"/%", from).
+		Update("creator_id", to).Error
+}
+
+func (r *objectRepository) EraseByCreator(ctx context.Context, creatorID uuid.UUID, workspacePath string) (*entity.ObjectErasure, error) {
+	erasure := &entity.ObjectErasure{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Deleted objects are included, so trashed content goes too
+		erased := func() *gorm.DB {
+			return tx.Model(&ObjectModel{}).Where("creator_id = ? OR path LIKE ?", creatorID, escapeLike(workspacePath)+"/%")
+		}
+
+		if err := tx.Model(&VersionModel{}).
+			Where("object_id IN (?)", erased().Select("id")).
+			Pluck("storage_path", &erasure.VersionPaths).Error; err != nil {
+			return err
+		}
+		var batches []uuid.UUID
+		if err := erased().Where("delete_batch_id IS NOT NULL").
+			Distinct().Pluck("delete_batch_id", &batches).Error; err != nil {
+			return err
+		}
+
+		// A batch belongs to whoever trashed it; one that also holds objects
+		// of other users stays, losing only the erased files' content
+		var shared []uuid.UUID
+		if len(batches) > 0 {
+			if err := tx.Model(&ObjectModel{}).
+				Where("delete_batch_id IN ?", batches).
+				Where("NOT (creator_id = ? OR path LIKE ?)", creatorID, escapeLike(workspacePath)+"/%").
+				Distinct().Pluck("delete_batch_id", &shared).Error; err != nil {
+				return err
+			}
+		}
+		if len(shared) > 0 {
+			var trashed []ObjectModel
+			if err := erased().Select("delete_batch_id", "path").
+				Where("delete_batch_id IN ? AND type <> ?", shared, string(entity.ObjectTypeDirectory)).
+				Find(&trashed).Error; err != nil {
+				return err
+			}
+			for _, m := range trashed {
+				erasure.TrashPaths = append(erasure.TrashPaths, entity.TrashedPath{BatchID: *m.DeleteBatchID, Path: m.Path})
+			}
+		}
+		for _, batchID := range batches {
+			if !slices.Contains(shared, batchID) {
+				erasure.TrashBatches = append(erasure.TrashBatches, batchID)
+			}
+		}
+
+		// Versions, permissions and tags of the objects go with them
+		if err := erased().Delete(&ObjectModel{}).Error; err != nil {
+			return err
+		}
+		if len(erasure.TrashBatches) > 0 {
+			if err := tx.Where("id IN ?", erasure.TrashBatches).Delete(&DeleteBatchModel{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return erasure, nil
+}
+
+// escapeLike escapes LIKE wildcards so a path is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
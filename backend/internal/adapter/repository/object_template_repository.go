@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// ObjectTemplateModel is the Gorm model for object_templates table
+type ObjectTemplateModel struct {
+	ID          int64      `gorm:"primaryKey;autoIncrement"`
+	ObjectID    int64      `gorm:"not null;index"`
+	Name        string     `gorm:"size:255;not null"`
+	Description string     `gorm:"not null;default:''"`
+	OwnerID     *uuid.UUID `gorm:"type:uuid;index"`
+	CreatedBy   *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt   time.Time
+
+	// Joined from the template's object
+	ObjectName string `gorm:"->"`
+	ObjectType string `gorm:"->"`
+}
+
+// TableName returns the table name
+func (ObjectTemplateModel) TableName() string {
+	return "object_templates"
+}
+
+// ToEntity converts ObjectTemplateModel to entity.ObjectTemplate
+func (m *ObjectTemplateModel) ToEntity() *entity.ObjectTemplate {
+	return &entity.ObjectTemplate{
+		ID:          m.ID,
+		ObjectID:    m.ObjectID,
+		ObjectName:  m.ObjectName,
+		ObjectType:  entity.ObjectType(m.ObjectType),
+		Name:        m.Name,
+		Description: m.Description,
+		Shared:      m.OwnerID == nil,
+		OwnerID:     m.OwnerID,
+		CreatedBy:   m.CreatedBy,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+// objectTemplateRepository implements repository.ObjectTemplateRepository
+type objectTemplateRepository struct {
+	db *gorm.DB
+}
+
+// NewObjectTemplateRepository creates a new object template repository
+func NewObjectTemplateRepository(db *gorm.DB) repository.ObjectTemplateRepository {
+	return &objectTemplateRepository{db: db}
+}
+
+// withObject selects templates joined with their object, skipping deleted ones
+func (r *objectTemplateRepository) withObject(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&ObjectTemplateModel{}).
+		Select("object_templates.*, objects.name AS object_name, objects.type AS object_type").
+		Joins("JOIN objects ON objects.id = object_templates.object_id AND objects.is_deleted = false")
+}
+
+func (r *objectTemplateRepository) Create(ctx context.Context, template *entity.ObjectTemplate) error {
+	if template.CreatedAt.IsZero() {
+		template.CreatedAt = time.Now()
+	}
+
+	model := &ObjectTemplateModel{
+		ObjectID:    template.ObjectID,
+		Name:        template.Name,
+		Description: template.Description,
+		OwnerID:     template.OwnerID,
+		CreatedBy:   template.CreatedBy,
+		CreatedAt:   template.CreatedAt,
+	}
+	if err := r.db.WithContext(ctx).Create(model).Error; err != nil {
+		return err
+	}
+	template.ID = model.ID
+	template.Shared = template.OwnerID == nil
+	return nil
+}
+
+func (r *objectTemplateRepository) GetByID(ctx context.Context, id int64) (*entity.ObjectTemplate, error) {
+	var model ObjectTemplateModel
+	if err := r.withObject(ctx).Where("object_templates.id = ?", id).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrNotFound
+		}
+		return nil, err
+	}
+	return model.ToEntity(), nil
+}
+
+func (r *objectTemplateRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]entity.ObjectTemplate, error) {
+	var models []ObjectTemplateModel
+	if err := r.withObject(ctx).
+		Where("object_templates.owner_id IS NULL OR object_templates.owner_id = ?", userID).
+		Order("object_templates.name ASC, object_templates.id ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	templates := make([]entity.ObjectTemplate, len(models))
+	for i, m := range models {
+		templates[i] = *m.ToEntity()
+	}
+	return templates, nil
+}
+
+func (r *objectTemplateRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&ObjectTemplateModel{}, "id = ?", id).Error
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/leondli/workspace/internal/domain/entity"
 	"github.com/leondli/workspace/internal/domain/repository"
@@ -133,6 +134,27 @@ func (r *permissionRepository) Update(ctx context.Context, perm *entity.Permissi
 		}).Error
 }
 
+// UpdateIfUnmodified changes the role of perm only if the grant's updated_at
+// still equals expectedUpdatedAt, returning ErrConflict otherwise, and sets
+// perm.UpdatedAt to the value the trigger stored
+func (r *permissionRepository) UpdateIfUnmodified(ctx context.Context, perm *entity.Permission, expectedUpdatedAt time.Time) error {
+	var model PermissionModel
+	result := r.db.WithContext(ctx).Model(&model).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "updated_at"}}}).
+		Where("id = ? AND updated_at = ?", perm.ID, expectedUpdatedAt).
+		Updates(map[string]interface{}{
+			"role": string(perm.Role),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.ErrConflict
+	}
+	perm.UpdatedAt = model.UpdatedAt
+	return nil
+}
+
 func (r *permissionRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&PermissionModel{}, "id = ?", id).Error
 }
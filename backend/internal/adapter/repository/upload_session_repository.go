@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// UploadSessionModel is the Gorm model for upload_sessions table
+type UploadSessionModel struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null"`
+	Name        string    `gorm:"size:255;not null"`
+	Type        string    `gorm:"size:50;not null;default:''"`
+	ParentID    *int64
+	Description string `gorm:"type:text;not null;default:''"`
+	Size        int64  `gorm:"not null"`
+	Hash        string `gorm:"size:64;not null;default:''"`
+	Received    int64  `gorm:"not null;default:0"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
+
+// TableName returns the table name
+func (UploadSessionModel) TableName() string {
+	return "upload_sessions"
+}
+
+// ToEntity converts UploadSessionModel to entity.UploadSession
+func (m *UploadSessionModel) ToEntity() *entity.UploadSession {
+	return &entity.UploadSession{
+		ID:          m.ID,
+		UserID:      m.UserID,
+		Name:        m.Name,
+		Type:        entity.ObjectType(m.Type),
+		ParentID:    m.ParentID,
+		Description: m.Description,
+		Size:        m.Size,
+		Hash:        m.Hash,
+		Received:    m.Received,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+		ExpiresAt:   m.ExpiresAt,
+	}
+}
+
+func uploadSessionModelFromEntity(session *entity.UploadSession) *UploadSessionModel {
+	return &UploadSessionModel{
+		ID:          session.ID,
+		UserID:      session.UserID,
+		Name:        session.Name,
+		Type:        string(session.Type),
+		ParentID:    session.ParentID,
+		Description: session.Description,
+		Size:        session.Size,
+		Hash:        session.Hash,
+		Received:    session.Received,
+		CreatedAt:   session.CreatedAt,
+		UpdatedAt:   session.UpdatedAt,
+		ExpiresAt:   session.ExpiresAt,
+	}
+}
+
+// uploadSessionRepository implements repository.UploadSessionRepository
+type uploadSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewUploadSessionRepository creates a new upload session repository
+func NewUploadSessionRepository(db *gorm.DB) repository.UploadSessionRepository {
+	return &uploadSessionRepository{db: db}
+}
+
+func (r *uploadSessionRepository) Create(ctx context.Context, session *entity.UploadSession) error {
+	if session.ID == uuid.Nil {
+		session.ID = uuid.New()
+	}
+	now := time.Now()
+	session.CreatedAt = now
+	session.UpdatedAt = now
+
+	return r.db.WithContext(ctx).Create(uploadSessionModelFromEntity(session)).Error
+}
+
+func (r *uploadSessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.UploadSession, error) {
+	var model UploadSessionModel
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrNotFound
+		}
+		return nil, err
+	}
+	return model.ToEntity(), nil
+}
+
+func (r *uploadSessionRepository) Update(ctx context.Context, session *entity.UploadSession) error {
+	session.UpdatedAt = time.Now()
+	return r.db.WithContext(ctx).Save(uploadSessionModelFromEntity(session)).Error
+}
+
+func (r *uploadSessionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&UploadSessionModel{}).Error
+}
+
+func (r *uploadSessionRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]entity.UploadSession, error) {
+	var models []UploadSessionModel
+	if err := r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	sessions := make([]entity.UploadSession, len(models))
+	for i, m := range models {
+		sessions[i] = *m.ToEntity()
+	}
+	return sessions, nil
+}
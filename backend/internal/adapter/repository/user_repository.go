@@ -23,6 +23,7 @@ type UserModel struct {
 	DisplayName  string    `gorm:"size:100"`
 	AvatarURL    string    `gorm:"size:500"`
 	Status       string    `gorm:"size:20;default:'active'"`
+	DeleteAfter  *time.Time
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
@@ -43,6 +44,7 @@ func (m *UserModel) ToEntity() *entity.User {
 		DisplayName:  m.DisplayName,
 		AvatarURL:    m.AvatarURL,
 		Status:       entity.UserStatus(m.Status),
+		DeleteAfter:  m.DeleteAfter,
 		CreatedAt:    m.CreatedAt,
 		UpdatedAt:    m.UpdatedAt,
 	}
@@ -59,6 +61,7 @@ func UserModelFromEntity(u *entity.User) *UserModel {
 		DisplayName:  u.DisplayName,
 		AvatarURL:    u.AvatarURL,
 		Status:       string(u.Status),
+		DeleteAfter:  u.DeleteAfter,
 		CreatedAt:    u.CreatedAt,
 		UpdatedAt:    u.UpdatedAt,
 	}
@@ -110,6 +113,9 @@ func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
 
 	model := UserModelFromEntity(user)
 	if err := r.db.WithContext(ctx).Create(model).Error; err != nil {
+		if isUniqueViolation(err) {
+			return apperrors.AlreadyExistsError("user with this email or username")
+		}
 		return err
 	}
 	return nil
@@ -187,6 +193,52 @@ func (r *userRepository) GetByAppID(ctx context.Context, appID string) ([]*entit
 	return users, nil
 }
 
+func (r *userRepository) ListDueForErase(ctx context.Context, before time.Time, limit int) ([]*entity.User, error) {
+	var models []UserModel
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND delete_after < ?", entity.UserStatusPendingDeletion, before).
+		Order("delete_after ASC").
+		Limit(limit).
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+	users := make([]*entity.User, len(models))
+	for i := range models {
+		users[i] = models[i].ToEntity()
+	}
+	return users, nil
+}
+
+func (r *userRepository) Erase(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`UPDATE versions SET creator_id = objects.creator_id
+			FROM objects WHERE versions.object_id = objects.id AND versions.creator_id = ?`, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&PermissionModel{}).Where("granted_by = ?", id).Update("granted_by", nil).Error; err != nil {
+			return err
+		}
+
+		// Pins and the access log have no foreign key to users
+		if err := tx.Where("user_id = ?", id).Delete(&ObjectPinModel{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM object_access_log WHERE user_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		// Tokens, grants, settings, sessions, webhooks and notifications go with the user
+		result := tx.Delete(&UserModel{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperrors.ErrNotFound
+		}
+		return nil
+	})
+}
+
 // refreshTokenRepository implements repository.RefreshTokenRepository
 type refreshTokenRepository struct {
 	db *gorm.DB
@@ -238,8 +290,9 @@ func (r *refreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uu
 		Update("revoked_at", &now).Error
 }
 
-func (r *refreshTokenRepository) DeleteExpired(ctx context.Context) error {
-	return r.db.WithContext(ctx).
+func (r *refreshTokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
 		Where("expires_at < ?", time.Now()).
-		Delete(&RefreshTokenModel{}).Error
+		Delete(&RefreshTokenModel{})
+	return result.RowsAffected, result.Error
 }
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// UserSettingModel is the Gorm model for user_settings table
+type UserSettingModel struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Key       string    `gorm:"primaryKey;size:100"`
+	Value     string    `gorm:"type:jsonb;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName returns the table name
+func (UserSettingModel) TableName() string {
+	return "user_settings"
+}
+
+// ToEntity converts UserSettingModel to entity.UserSetting
+func (m *UserSettingModel) ToEntity() *entity.UserSetting {
+	return &entity.UserSetting{
+		UserID:    m.UserID,
+		Key:       m.Key,
+		Value:     json.RawMessage(m.Value),
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+// userSettingRepository implements repository.UserSettingRepository
+type userSettingRepository struct {
+	db *gorm.DB
+}
+
+// NewUserSettingRepository creates a new user setting repository
+func NewUserSettingRepository(db *gorm.DB) repository.UserSettingRepository {
+	return &userSettingRepository{db: db}
+}
+
+func (r *userSettingRepository) Get(ctx context.Context, userID uuid.UUID, key string) (*entity.UserSetting, error) {
+	var model UserSettingModel
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND key = ?", userID, key).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrNotFound
+		}
+		return nil, err
+	}
+	return model.ToEntity(), nil
+}
+
+func (r *userSettingRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.UserSetting, error) {
+	var models []UserSettingModel
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("key").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	settings := make([]entity.UserSetting, len(models))
+	for i, m := range models {
+		settings[i] = *m.ToEntity()
+	}
+	return settings, nil
+}
+
+func (r *userSettingRepository) CountByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&UserSettingModel{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *userSettingRepository) Upsert(ctx context.Context, setting *entity.UserSetting) error {
+	now := time.Now()
+	setting.UpdatedAt = now
+
+	model := &UserSettingModel{
+		UserID:    setting.UserID,
+		Key:       setting.Key,
+		Value:     string(setting.Value),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+		}).
+		Create(model).Error
+}
@@ -155,6 +155,15 @@ func (r *versionRepository) ListByObject(ctx context.Context, objectID int64, pa
 	return versions, total, nil
 }
 
+func (r *versionRepository) UpdateSnapshot(ctx context.Context, id uuid.UUID, contentHash string, size int64) error {
+	return r.db.WithContext(ctx).Model(&VersionModel{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"content_hash": contentHash,
+			"size":         size,
+		}).Error
+}
+
 func (r *versionRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&VersionModel{}, "id = ?", id).Error
 }
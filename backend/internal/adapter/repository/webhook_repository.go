@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// WebhookModel is the Gorm model for webhooks table
+type WebhookModel struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	ObjectID  int64     `gorm:"not null;index"`
+	CreatorID uuid.UUID `gorm:"type:uuid;not null;index"`
+	URL       string    `gorm:"size:2000;not null"`
+	Secret    string    `gorm:"size:255;not null"`
+	Events    string    `gorm:"size:255;not null;default:''"` // Comma-separated event types
+	CreatedAt time.Time
+}
+
+// TableName returns the table name
+func (WebhookModel) TableName() string {
+	return "webhooks"
+}
+
+// ToEntity converts WebhookModel to entity.Webhook
+func (m *WebhookModel) ToEntity() *entity.Webhook {
+	events := []string{}
+	if m.Events != "" {
+		events = strings.Split(m.Events, ",")
+	}
+	return &entity.Webhook{
+		ID:        m.ID,
+		ObjectID:  m.ObjectID,
+		CreatorID: m.CreatorID,
+		URL:       m.URL,
+		Secret:    m.Secret,
+		Events:    events,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// webhookRepository implements repository.WebhookRepository
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *gorm.DB) repository.WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook *entity.Webhook) error {
+	if webhook.ID == uuid.Nil {
+		webhook.ID = uuid.New()
+	}
+	if webhook.CreatedAt.IsZero() {
+		webhook.CreatedAt = time.Now()
+	}
+
+	model := &WebhookModel{
+		ID:        webhook.ID,
+		ObjectID:  webhook.ObjectID,
+		CreatorID: webhook.CreatorID,
+		URL:       webhook.URL,
+		Secret:    webhook.Secret,
+		Events:    strings.Join(webhook.Events, ","),
+		CreatedAt: webhook.CreatedAt,
+	}
+
+	return r.db.WithContext(ctx).Create(model).Error
+}
+
+func (r *webhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.Webhook, error) {
+	var model WebhookModel
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrNotFound
+		}
+		return nil, err
+	}
+	return model.ToEntity(), nil
+}
+
+func (r *webhookRepository) ListByCreator(ctx context.Context, creatorID uuid.UUID, page, pageSize int) ([]entity.Webhook, int64, error) {
+	query := r.db.WithContext(ctx).Model(&WebhookModel{}).Where("creator_id = ?", creatorID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var models []WebhookModel
+	if err := query.
+		Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&models).Error; err != nil {
+		return nil, 0, err
+	}
+
+	webhooks := make([]entity.Webhook, len(models))
+	for i, m := range models {
+		webhooks[i] = *m.ToEntity()
+	}
+	return webhooks, total, nil
+}
+
+func (r *webhookRepository) ListMatching(ctx context.Context, objectID int64, paths []string) ([]entity.Webhook, error) {
+	query := r.db.WithContext(ctx).Model(&WebhookModel{}).
+		Joins("JOIN objects ON objects.id = webhooks.object_id")
+	if len(paths) > 0 {
+		query = query.Where("webhooks.object_id = ? OR (objects.path IN ? AND objects.is_deleted = false)", objectID, paths)
+	} else {
+		query = query.Where("webhooks.object_id = ?", objectID)
+	}
+
+	var models []WebhookModel
+	if err := query.Select("webhooks.*").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	webhooks := make([]entity.Webhook, len(models))
+	for i, m := range models {
+		webhooks[i] = *m.ToEntity()
+	}
+	return webhooks, nil
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&WebhookModel{}, "id = ?", id).Error
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// compressedSuffix marks a gzipped version snapshot. Version file names end in
+// ".v<N>" otherwise, so the suffix never comes from the object's own name.
+const compressedSuffix = ".gz"
+
+// compressible reports whether version snapshots of the file at path are
+// stored gzipped
+func (s *LocalFileStorage) compressible(path string) bool {
+	return s.compressTypes[strings.ToLower(filepath.Ext(path))]
+}
+
+func gzipBytes(content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(content); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipBytes(content []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
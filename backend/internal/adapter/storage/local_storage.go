@@ -8,6 +8,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/rs/zerolog/log"
@@ -21,15 +23,28 @@ type FileStorage interface {
 	// WriteFile writes content to a file
 	WriteFile(ctx context.Context, path string, content []byte) error
 
+	// WriteStream writes a file from r, returning the bytes written. The file
+	// appears at path only once fully written.
+	WriteStream(ctx context.Context, path string, r io.Reader) (int64, error)
+
 	// ReadFile reads content from a file
 	ReadFile(ctx context.Context, path string) ([]byte, error)
 
+	// OpenFile opens a file for streaming reads
+	OpenFile(ctx context.Context, path string) (io.ReadCloser, error)
+
 	// Delete deletes a file or directory
 	Delete(ctx context.Context, path string) error
 
 	// Move moves a file or directory
 	Move(ctx context.Context, srcPath, dstPath string) error
 
+	// MoveToTrash moves a file or directory into the trash area of a delete batch
+	MoveToTrash(ctx context.Context, path, batchID string) error
+
+	// RestoreFromTrash moves a trashed file or directory back to dstPath
+	RestoreFromTrash(ctx context.Context, batchID, path, dstPath string) error
+
 	// Copy copies a file
 	Copy(ctx context.Context, srcPath, dstPath string) error
 
@@ -56,13 +71,30 @@ type FileStorage interface {
 type LocalFileStorage struct {
 	basePath    string
 	versionPath string
+	trashPath   string
+	uploadPath  string
+
+	compressTypes map[string]bool // Lower-case extensions whose version snapshots are gzipped
+
+	caseOnce        sync.Once
+	caseInsensitive bool
 }
 
-// NewLocalFileStorage creates a new local file storage
-func NewLocalFileStorage(basePath, versionPath string) *LocalFileStorage {
+// NewLocalFileStorage creates a new local file storage. Chunked uploads are
+// assembled under uploadPath. Version snapshots of files with one of
+// compressTypes' extensions are stored gzipped; workspace files themselves are
+// always stored raw, since kernels read them directly.
+func NewLocalFileStorage(basePath, versionPath, trashPath, uploadPath string, compressTypes []string) *LocalFileStorage {
+	types := make(map[string]bool, len(compressTypes))
+	for _, ext := range compressTypes {
+		types[strings.ToLower(ext)] = true
+	}
 	return &LocalFileStorage{
-		basePath:    basePath,
-		versionPath: versionPath,
+		basePath:      basePath,
+		versionPath:   versionPath,
+		trashPath:     trashPath,
+		uploadPath:    uploadPath,
+		compressTypes: types,
 	}
 }
 
@@ -93,12 +125,46 @@ func (s *LocalFileStorage) WriteFile(ctx context.Context, path string, content [
 	return os.WriteFile(fullPath, content, 0644)
 }
 
+func (s *LocalFileStorage) WriteStream(ctx context.Context, path string, r io.Reader) (int64, error) {
+	fullPath := s.GetFullPath(path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	log.Debug().Str("path", fullPath).Msg("Writing file stream")
+	f, err := os.CreateTemp(filepath.Dir(fullPath), "."+filepath.Base(fullPath)+".*.part")
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), fullPath)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return 0, err
+	}
+	return n, nil
+}
+
 func (s *LocalFileStorage) ReadFile(ctx context.Context, path string) ([]byte, error) {
 	fullPath := s.GetFullPath(path)
 	log.Debug().Str("path", fullPath).Msg("Reading file")
 	return os.ReadFile(fullPath)
 }
 
+func (s *LocalFileStorage) OpenFile(ctx context.Context, path string) (io.ReadCloser, error) {
+	fullPath := s.GetFullPath(path)
+	log.Debug().Str("path", fullPath).Msg("Opening file")
+	return os.Open(fullPath)
+}
+
 func (s *LocalFileStorage) Delete(ctx context.Context, path string) error {
 	fullPath := s.GetFullPath(path)
 	log.Debug().Str("path", fullPath).Msg("Deleting file/directory")
@@ -119,6 +185,53 @@ func (s *LocalFileStorage) Move(ctx context.Context, srcPath, dstPath string) er
 	return os.Rename(fullSrcPath, fullDstPath)
 }
 
+// MoveToTrash keeps deleted content under <trash>/<batchID>/<path> so it can
+// be restored. The trash must be on the same filesystem as the base path.
+func (s *LocalFileStorage) MoveToTrash(ctx context.Context, path, batchID string) error {
+	fullSrcPath := s.GetFullPath(path)
+	fullDstPath := filepath.Join(s.trashPath, batchID, path)
+
+	if err := os.MkdirAll(filepath.Dir(fullDstPath), 0755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	log.Debug().Str("src", fullSrcPath).Str("dst", fullDstPath).Msg("Moving file/directory to trash")
+	return os.Rename(fullSrcPath, fullDstPath)
+}
+
+// RestoreFromTrash moves content trashed from path back to dstPath and removes
+// the emptied batch directory
+func (s *LocalFileStorage) RestoreFromTrash(ctx context.Context, batchID, path, dstPath string) error {
+	batchDir := filepath.Join(s.trashPath, batchID)
+	fullSrcPath := filepath.Join(batchDir, path)
+	fullDstPath := s.GetFullPath(dstPath)
+
+	if err := os.MkdirAll(filepath.Dir(fullDstPath), 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	log.Debug().Str("src", fullSrcPath).Str("dst", fullDstPath).Msg("Restoring file/directory from trash")
+	if err := os.Rename(fullSrcPath, fullDstPath); err != nil {
+		return err
+	}
+	return os.RemoveAll(batchDir)
+}
+
+// DeleteTrash removes the content of a trash batch for good
+func (s *LocalFileStorage) DeleteTrash(ctx context.Context, batchID string) error {
+	batchDir := filepath.Join(s.trashPath, batchID)
+	log.Debug().Str("path", batchDir).Msg("Deleting trash batch")
+	return os.RemoveAll(batchDir)
+}
+
+// DeleteTrashPath removes the content trashed from path in a trash batch,
+// leaving the rest of the batch
+func (s *LocalFileStorage) DeleteTrashPath(ctx context.Context, batchID, path string) error {
+	fullPath := filepath.Join(s.trashPath, batchID, path)
+	log.Debug().Str("path", fullPath).Msg("Deleting trashed content")
+	return os.RemoveAll(fullPath)
+}
+
 func (s *LocalFileStorage) Copy(ctx context.Context, srcPath, dstPath string) error {
 	fullSrcPath := s.GetFullPath(srcPath)
 	fullDstPath := s.GetFullPath(dstPath)
@@ -225,7 +338,11 @@ func (s *LocalFileStorage) GetSize(ctx context.Context, path string) (int64, err
 }
 
 func (s *LocalFileStorage) GetInode(ctx context.Context, path string) (int64, error) {
-	fullPath := s.GetFullPath(path)
+	return inode(s.GetFullPath(path))
+}
+
+// inode returns the inode of the file at fullPath
+func inode(fullPath string) (int64, error) {
 	info, err := os.Stat(fullPath)
 	if err != nil {
 		return 0, err
@@ -244,7 +361,9 @@ func (s *LocalFileStorage) CalculateHash(content []byte) string {
 	return hex.EncodeToString(hash[:])
 }
 
-// SaveVersion saves a version snapshot of a file
+// SaveVersion saves a version snapshot of a file. Snapshots of compressible
+// files are gzipped when that makes them smaller, and their path gets a ".gz"
+// suffix so reads know to decompress them.
 func (s *LocalFileStorage) SaveVersion(ctx context.Context, objectPath string, versionNumber int, content []byte) (string, error) {
 	versionFileName := fmt.Sprintf("%s.v%d", filepath.Base(objectPath), versionNumber)
 	versionDir := filepath.Join(s.versionPath, filepath.Dir(objectPath))
@@ -254,16 +373,49 @@ func (s *LocalFileStorage) SaveVersion(ctx context.Context, objectPath string, v
 	}
 
 	versionPath := filepath.Join(versionDir, versionFileName)
-	if err := os.WriteFile(versionPath, content, 0644); err != nil {
+	data := content
+	if s.compressible(objectPath) {
+		compressed, err := gzipBytes(content)
+		if err != nil {
+			return "", fmt.Errorf("failed to compress version: %w", err)
+		}
+		if len(compressed) < len(content) {
+			versionPath += compressedSuffix
+			data = compressed
+		}
+	}
+
+	if err := os.WriteFile(versionPath, data, 0644); err != nil {
 		return "", err
 	}
 
 	return versionPath, nil
 }
 
-// ReadVersion reads a version snapshot
+// OverwriteVersion replaces the content of an existing version snapshot,
+// keeping the snapshot's compression
+func (s *LocalFileStorage) OverwriteVersion(ctx context.Context, storagePath string, content []byte) error {
+	if strings.HasSuffix(storagePath, compressedSuffix) {
+		compressed, err := gzipBytes(content)
+		if err != nil {
+			return fmt.Errorf("failed to compress version: %w", err)
+		}
+		content = compressed
+	}
+	return os.WriteFile(storagePath, content, 0644)
+}
+
+// ReadVersion reads a version snapshot, decompressing it if it was stored gzipped
 func (s *LocalFileStorage) ReadVersion(ctx context.Context, storagePath string) ([]byte, error) {
-	return os.ReadFile(storagePath)
+	content, err := os.ReadFile(storagePath)
+	if err != nil || !strings.HasSuffix(storagePath, compressedSuffix) {
+		return content, err
+	}
+	content, err = gunzipBytes(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress version: %w", err)
+	}
+	return content, nil
 }
 
 // DeleteVersion deletes a version snapshot
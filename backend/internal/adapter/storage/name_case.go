@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CaseInsensitive reports whether the filesystem under the base path treats
+// names differing only in case as the same file. It is probed once, by
+// creating a temporary file and looking it up under an upper-case name.
+func (s *LocalFileStorage) CaseInsensitive() bool {
+	s.caseOnce.Do(func() {
+		insensitive, err := probeCaseInsensitive(s.basePath)
+		if err != nil {
+			log.Warn().Err(err).Str("path", s.basePath).Msg("Failed to probe filesystem case sensitivity, assuming case-sensitive")
+			return
+		}
+		s.caseInsensitive = insensitive
+	})
+	return s.caseInsensitive
+}
+
+func probeCaseInsensitive(dir string) (bool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, err
+	}
+	f, err := os.CreateTemp(dir, ".case-probe-")
+	if err != nil {
+		return false, err
+	}
+	name := f.Name()
+	f.Close()
+	defer os.Remove(name)
+
+	original, err := os.Stat(name)
+	if err != nil {
+		return false, err
+	}
+	upper, err := os.Stat(filepath.Join(dir, strings.ToUpper(filepath.Base(name))))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return os.SameFile(original, upper), nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Name policies for names that are unsafe on Windows/SMB-backed storage
+const (
+	// NamePolicyReject rejects unsafe names
+	NamePolicyReject = "reject"
+	// NamePolicySanitize rewrites unsafe names into safe ones
+	NamePolicySanitize = "sanitize"
+	// NamePolicyOff only enforces the rules every filesystem needs
+	NamePolicyOff = "off"
+)
+
+// maxNameLength is the longest name, in bytes, most filesystems accept
+const maxNameLength = 255
+
+// unsafeNameChars are characters Windows does not allow in names
+const unsafeNameChars = `<>:"\|?*`
+
+// reservedNames are Windows device names, reserved regardless of extension
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// ErrUnsafeName is returned for object names that cannot be stored
+var ErrUnsafeName = errors.New("unsafe name")
+
+// SanitizeName checks an object name before it reaches the filesystem. Empty
+// names, "." and "..", path separators, NUL bytes and over-long names are
+// always rejected. Reserved device names, characters Windows disallows,
+// control characters and trailing dots or spaces are rejected under
+// NamePolicyReject, rewritten under NamePolicySanitize, and allowed under
+// NamePolicyOff. The returned name is the one to store.
+func SanitizeName(name, policy string) (string, error) {
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("%w: name %q is not allowed", ErrUnsafeName, name)
+	}
+	if strings.ContainsAny(name, "/\x00") {
+		return "", fmt.Errorf("%w: name must not contain '/' or NUL", ErrUnsafeName)
+	}
+	if len(name) > maxNameLength {
+		return "", fmt.Errorf("%w: name is longer than %d bytes", ErrUnsafeName, maxNameLength)
+	}
+
+	switch policy {
+	case NamePolicyOff:
+		return name, nil
+	case NamePolicySanitize:
+		return sanitizeName(name)
+	default:
+		if reason := unsafeReason(name); reason != "" {
+			return "", fmt.Errorf("%w: %s", ErrUnsafeName, reason)
+		}
+		return name, nil
+	}
+}
+
+// unsafeReason explains why name is unsafe on Windows storage, or returns ""
+func unsafeReason(name string) string {
+	if i := strings.IndexFunc(name, isUnsafeRune); i >= 0 {
+		return fmt.Sprintf("name must not contain %q", name[i:i+1])
+	}
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return "name must not end with a dot or space"
+	}
+	if isReservedName(name) {
+		return fmt.Sprintf("%q is a reserved name", name)
+	}
+	return ""
+}
+
+func sanitizeName(name string) (string, error) {
+	name = strings.Map(func(r rune) rune {
+		if isUnsafeRune(r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		return "", fmt.Errorf("%w: name is empty after removing trailing dots and spaces", ErrUnsafeName)
+	}
+	if isReservedName(name) {
+		name = "_" + name
+	}
+	return name, nil
+}
+
+func isUnsafeRune(r rune) bool {
+	return r < 0x20 || strings.ContainsRune(unsafeNameChars, r)
+}
+
+// isReservedName reports whether name is a device name, ignoring case and
+// extension (e.g. "con.txt")
+func isReservedName(name string) bool {
+	base, _, _ := strings.Cut(name, ".")
+	return reservedNames[strings.ToUpper(strings.TrimRight(base, " "))]
+}
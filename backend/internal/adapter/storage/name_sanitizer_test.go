@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		policy string
+		want   string // Empty when the name is rejected
+	}{
+		{name: "plain name", input: "notes.py", policy: NamePolicyReject, want: "notes.py"},
+		{name: "unicode name", input: "données.csv", policy: NamePolicyReject, want: "données.csv"},
+		{name: "reserved name", input: "CON", policy: NamePolicyReject},
+		{name: "reserved name lower case", input: "nul", policy: NamePolicyReject},
+		{name: "reserved name with extension", input: "con.txt", policy: NamePolicyReject},
+		{name: "reserved name with double extension", input: "Com1.tar.gz", policy: NamePolicyReject},
+		{name: "reserved prefix is not reserved", input: "console.log", policy: NamePolicyReject, want: "console.log"},
+		{name: "COM0 is not reserved", input: "COM0", policy: NamePolicyReject, want: "COM0"},
+		{name: "unsafe character", input: "a:b", policy: NamePolicyReject},
+		{name: "control character", input: "a\tb", policy: NamePolicyReject},
+		{name: "trailing dot", input: "report.", policy: NamePolicyReject},
+		{name: "trailing space", input: "report ", policy: NamePolicyReject},
+		{name: "sanitize reserved name", input: "aux", policy: NamePolicySanitize, want: "_aux"},
+		{name: "sanitize reserved name with extension", input: "LPT9.md", policy: NamePolicySanitize, want: "_LPT9.md"},
+		{name: "sanitize unsafe characters", input: `a<b>c|d?.txt`, policy: NamePolicySanitize, want: "a_b_c_d_.txt"},
+		{name: "sanitize trailing dots and spaces", input: "report. .", policy: NamePolicySanitize, want: "report"},
+		{name: "sanitize to empty", input: " . ", policy: NamePolicySanitize},
+		{name: "sanitize reserved name exposed by trimming", input: "prn. ", policy: NamePolicySanitize, want: "_prn"},
+		{name: "off allows reserved name", input: "CON", policy: NamePolicyOff, want: "CON"},
+		{name: "off allows unsafe characters", input: "a:b?", policy: NamePolicyOff, want: "a:b?"},
+		{name: "empty", input: "", policy: NamePolicyOff},
+		{name: "dot", input: ".", policy: NamePolicyOff},
+		{name: "dot dot", input: "..", policy: NamePolicyOff},
+		{name: "slash", input: "a/b", policy: NamePolicyOff},
+		{name: "NUL byte", input: "a\x00b", policy: NamePolicySanitize},
+		{name: "too long", input: strings.Repeat("a", maxNameLength+1), policy: NamePolicyOff},
+		{name: "longest allowed", input: strings.Repeat("a", maxNameLength), policy: NamePolicyReject, want: strings.Repeat("a", maxNameLength)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SanitizeName(tt.input, tt.policy)
+			if tt.want == "" {
+				if !errors.Is(err, ErrUnsafeName) {
+					t.Fatalf("SanitizeName(%q, %q) = (%q, %v), want ErrUnsafeName", tt.input, tt.policy, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("SanitizeName(%q, %q) = (%q, %v), want %q", tt.input, tt.policy, got, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestSanitizeNameReservedNames checks every device name under each policy
+func TestSanitizeNameReservedNames(t *testing.T) {
+	for reserved := range reservedNames {
+		for _, name := range []string{reserved, strings.ToLower(reserved), reserved + ".txt"} {
+			if _, err := SanitizeName(name, NamePolicyReject); !errors.Is(err, ErrUnsafeName) {
+				t.Errorf("SanitizeName(%q, reject) error = %v, want ErrUnsafeName", name, err)
+			}
+			if got, err := SanitizeName(name, NamePolicySanitize); err != nil || got != "_"+name {
+				t.Errorf("SanitizeName(%q, sanitize) = (%q, %v), want %q", name, got, err, "_"+name)
+			}
+			if got, err := SanitizeName(name, NamePolicyOff); err != nil || got != name {
+				t.Errorf("SanitizeName(%q, off) = (%q, %v), want %q", name, got, err, name)
+			}
+		}
+	}
+}
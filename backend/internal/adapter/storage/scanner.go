@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// UploadScanner inspects uploaded content before it is committed.
+// Implementations should read r as a stream and return an error describing
+// why the file was rejected, e.g. to plug in ClamAV or a similar scanner.
+type UploadScanner interface {
+	Scan(ctx context.Context, name string, r io.Reader) error
+}
+
+// NewScanner creates the upload scanner of the given kind: "none", "basic"
+// (enforcing maxSize) or "command" (running command)
+func NewScanner(kind string, maxSize int64, command []string, timeout time.Duration) (UploadScanner, error) {
+	switch kind {
+	case "none":
+		return NewNoopScanner(), nil
+	case "basic":
+		return NewBasicScanner(maxSize, nil), nil
+	case "command":
+		if len(command) == 0 {
+			return nil, errors.New("the command upload scanner needs scanner_command")
+		}
+		return NewCommandScanner(command, timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown upload scanner %q", kind)
+	}
+}
+
+// NoopScanner accepts every upload
+type NoopScanner struct{}
+
+// NewNoopScanner creates a scanner that accepts every upload
+func NewNoopScanner() *NoopScanner {
+	return &NoopScanner{}
+}
+
+// Scan implements UploadScanner
+func (s *NoopScanner) Scan(ctx context.Context, name string, r io.Reader) error {
+	return nil
+}
+
+// BasicScanner rejects uploads that exceed a size limit or use a blocked extension
+type BasicScanner struct {
+	maxSize           int64
+	blockedExtensions []string
+}
+
+// NewBasicScanner creates a scanner enforcing maxSize (0 = unlimited) and
+// rejecting the given extensions, e.g. ".exe"
+func NewBasicScanner(maxSize int64, blockedExtensions []string) *BasicScanner {
+	return &BasicScanner{
+		maxSize:           maxSize,
+		blockedExtensions: blockedExtensions,
+	}
+}
+
+// Scan implements UploadScanner
+func (s *BasicScanner) Scan(ctx context.Context, name string, r io.Reader) error {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, blocked := range s.blockedExtensions {
+		if strings.EqualFold(blocked, ext) {
+			return fmt.Errorf("file extension %q is blocked", ext)
+		}
+	}
+
+	if s.maxSize <= 0 {
+		return nil
+	}
+
+	// Read at most one byte past the limit so large files are never fully consumed
+	n, err := io.Copy(io.Discard, io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+	if n > s.maxSize {
+		return fmt.Errorf("file exceeds maximum size of %d bytes", s.maxSize)
+	}
+
+	return nil
+}
+
+// maxScannerOutput bounds the scanner output kept for the rejection message
+const maxScannerOutput = 1024
+
+// CommandScanner runs an external program, such as clamdscan, with the upload
+// on its stdin. A non-zero exit status rejects the upload.
+type CommandScanner struct {
+	command []string
+	timeout time.Duration
+}
+
+// NewCommandScanner creates a scanner running command, the program followed
+// by its arguments, for at most timeout per upload
+func NewCommandScanner(command []string, timeout time.Duration) *CommandScanner {
+	return &CommandScanner{command: command, timeout: timeout}
+}
+
+// Scan implements UploadScanner
+func (s *CommandScanner) Scan(ctx context.Context, name string, r io.Reader) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.command[0], s.command[1:]...)
+	cmd.Stdin = r
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("scanner timed out after %s", s.timeout)
+	}
+
+	msg := strings.TrimSpace(output.String())
+	if len(msg) > maxScannerOutput {
+		msg = msg[:maxScannerOutput]
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return fmt.Errorf("failed to run scanner: %w", err)
+	}
+	if msg == "" {
+		return fmt.Errorf("scanner exited with status %d", exitErr.ExitCode())
+	}
+	return fmt.Errorf("scanner exited with status %d: %s", exitErr.ExitCode(), msg)
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrUploadOffset is returned when a chunk does not start where the
+	// assembled upload currently ends
+	ErrUploadOffset = errors.New("chunk offset does not match the received size")
+
+	// ErrUploadOverflow is returned when a chunk would grow the upload past
+	// its expected size
+	ErrUploadOverflow = errors.New("chunk exceeds the expected upload size")
+)
+
+// uploadFilePath returns the temporary file a chunked upload is assembled in
+func (s *LocalFileStorage) uploadFilePath(id string) string {
+	return filepath.Join(s.uploadPath, id+".part")
+}
+
+// UploadSize returns how many bytes of a chunked upload have been stored
+func (s *LocalFileStorage) UploadSize(ctx context.Context, id string) (int64, error) {
+	info, err := os.Stat(s.uploadFilePath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// AppendUpload appends a chunk read from r to a chunked upload and returns the
+// upload's new size. The chunk must start at offset, the current size, and
+// may hold at most maxBytes. Bytes written before r fails are kept, so the
+// client can resume from the returned size.
+func (s *LocalFileStorage) AppendUpload(ctx context.Context, id string, offset int64, r io.Reader, maxBytes int64) (int64, error) {
+	if err := os.MkdirAll(s.uploadPath, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	f, err := os.OpenFile(s.uploadFilePath(id), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if size != offset {
+		return size, ErrUploadOffset
+	}
+
+	n, err := io.Copy(f, io.LimitReader(r, maxBytes))
+	size += n
+	if err != nil {
+		return size, err
+	}
+
+	// Anything left in r would overflow the upload; drop the whole chunk
+	var extra [1]byte
+	if m, _ := io.ReadFull(r, extra[:]); m > 0 {
+		if err := f.Truncate(offset); err != nil {
+			return size, err
+		}
+		return offset, ErrUploadOverflow
+	}
+
+	log.Debug().Str("upload_id", id).Int64("offset", offset).Int64("size", size).Msg("Appended upload chunk")
+	return size, nil
+}
+
+// WriteUpload stores content as the temporary file of upload id, so it can be
+// scanned before CommitUpload moves it into the workspace
+func (s *LocalFileStorage) WriteUpload(ctx context.Context, id string, content []byte) error {
+	if err := os.MkdirAll(s.uploadPath, 0755); err != nil {
+		return fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	log.Debug().Str("upload_id", id).Int("size", len(content)).Msg("Writing upload")
+	return os.WriteFile(s.uploadFilePath(id), content, 0644)
+}
+
+// CopyToUpload copies the file or directory at srcPath in the workspace to
+// the temporary location of upload id, to be moved into place by CommitUpload
+func (s *LocalFileStorage) CopyToUpload(ctx context.Context, srcPath, id string) error {
+	if err := os.MkdirAll(s.uploadPath, 0755); err != nil {
+		return fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	fullSrcPath := s.GetFullPath(srcPath)
+	srcInfo, err := os.Stat(fullSrcPath)
+	if err != nil {
+		return err
+	}
+	if srcInfo.IsDir() {
+		return s.copyDirectory(fullSrcPath, s.uploadFilePath(id))
+	}
+	return s.copyFile(fullSrcPath, s.uploadFilePath(id))
+}
+
+// OpenUpload opens an assembled chunked upload for reading
+func (s *LocalFileStorage) OpenUpload(ctx context.Context, id string) (io.ReadCloser, error) {
+	return os.Open(s.uploadFilePath(id))
+}
+
+// UploadInode returns the inode of an upload's temporary file, which it
+// keeps when CommitUpload renames it into the workspace
+func (s *LocalFileStorage) UploadInode(ctx context.Context, id string) (int64, error) {
+	return inode(s.uploadFilePath(id))
+}
+
+// CommitUpload moves an assembled chunked upload, or a directory staged by
+// CopyToUpload, to path in the workspace
+func (s *LocalFileStorage) CommitUpload(ctx context.Context, id, path string) error {
+	fullPath := s.GetFullPath(path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+	log.Debug().Str("upload_id", id).Str("path", fullPath).Msg("Committing upload")
+	return os.Rename(s.uploadFilePath(id), fullPath)
+}
+
+// DeleteUpload removes the temporary file, or copied directory, of an upload,
+// if any
+func (s *LocalFileStorage) DeleteUpload(ctx context.Context, id string) error {
+	return os.RemoveAll(s.uploadFilePath(id))
+}
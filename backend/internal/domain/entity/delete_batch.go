@@ -0,0 +1,19 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeleteBatch records one delete operation: a root object and the
+// descendants that were soft-deleted with it
+type DeleteBatch struct {
+	ID           uuid.UUID  `json:"id"`
+	RootObjectID int64      `json:"root_object_id"`
+	RootName     string     `json:"root_name"`
+	RootPath     string     `json:"root_path"`
+	ItemCount    int        `json:"item_count"` // Objects deleted, including the root
+	DeletedBy    *uuid.UUID `json:"deleted_by,omitempty"`
+	DeletedAt    time.Time  `json:"deleted_at"`
+}
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// KernelSession records which user owns a running kernel so ownership
+// survives server restarts
+type KernelSession struct {
+	KernelID     string    `json:"kernel_id"`
+	UserID       uuid.UUID `json:"user_id"`
+	SpecName     string    `json:"spec_name"`
+	IsGateway    bool      `json:"is_gateway"`
+	InstanceID   string    `json:"instance_id"`
+	InstanceAddr string    `json:"instance_addr"`
+	ObjectID     *int64    `json:"object_id,omitempty"` // Notebook the kernel was started for
+	LastActivity time.Time `json:"last_activity"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	Owner *User `json:"owner,omitempty"` // Loaded by ListByObject only
+}
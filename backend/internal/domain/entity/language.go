@@ -0,0 +1,85 @@
+package entity
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// LanguagePlaintext is the language of files with no known mapping
+const LanguagePlaintext = "plaintext"
+
+// DefaultLanguages maps lower-case file extensions to the language clients use
+// for syntax highlighting
+var DefaultLanguages = map[string]string{
+	".py":       "python",
+	".pyi":      "python",
+	".r":        "r",
+	".sql":      "sql",
+	".scala":    "scala",
+	".java":     "java",
+	".go":       "go",
+	".js":       "javascript",
+	".mjs":      "javascript",
+	".ts":       "typescript",
+	".sh":       "shell",
+	".bash":     "shell",
+	".md":       "markdown",
+	".markdown": "markdown",
+	".json":     "json",
+	".yaml":     "yaml",
+	".yml":      "yaml",
+	".toml":     "toml",
+	".ini":      "ini",
+	".conf":     "ini",
+	".cfg":      "ini",
+	".xml":      "xml",
+	".html":     "html",
+	".css":      "css",
+}
+
+// DetectLanguage returns the highlighting language of a file. Notebooks report
+// the language recorded in their metadata; other files are looked up by
+// extension in languages, then in DefaultLanguages.
+func DetectLanguage(name string, objType ObjectType, content []byte, languages map[string]string) string {
+	if objType == ObjectTypeDirectory {
+		return ""
+	}
+	if objType == ObjectTypeNotebook {
+		if lang := notebookLanguage(content); lang != "" {
+			return lang
+		}
+		return LanguagePlaintext
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	if lang, ok := languages[ext]; ok {
+		return lang
+	}
+	if lang, ok := DefaultLanguages[ext]; ok {
+		return lang
+	}
+	return LanguagePlaintext
+}
+
+// notebookLanguage reads metadata.language_info.name, falling back to
+// metadata.kernelspec.language for notebooks that were never run
+func notebookLanguage(content []byte) string {
+	var notebook struct {
+		Metadata struct {
+			LanguageInfo struct {
+				Name string `json:"name"`
+			} `json:"language_info"`
+			Kernelspec struct {
+				Language string `json:"language"`
+			} `json:"kernelspec"`
+		} `json:"metadata"`
+	}
+	if len(content) == 0 || json.Unmarshal(content, &notebook) != nil {
+		return ""
+	}
+	if name := notebook.Metadata.LanguageInfo.Name; name != "" {
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(notebook.Metadata.Kernelspec.Language)
+}
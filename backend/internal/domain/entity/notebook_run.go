@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotebookRun records an execution of a notebook and the version that saved
+// its results
+type NotebookRun struct {
+	ID            uuid.UUID  `json:"id"`
+	ObjectID      int64      `json:"object_id"`
+	VersionID     *uuid.UUID `json:"version_id,omitempty"` // Unset once the version has been pruned
+	VersionNumber int        `json:"version_number"`
+	KernelID      string     `json:"kernel_id"`
+	DurationMs    int64      `json:"duration_ms"`
+	RunBy         *uuid.UUID `json:"run_by,omitempty"`
+	RanAt         time.Time  `json:"ran_at"`
+}
@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification types
+const (
+	NotificationPermissionGranted = "permission_granted" // An object was shared with the user
+	NotificationSharedCopy        = "shared_copy"        // Someone copied an object into the user's directory
+)
+
+// Notification is an in-app message for a user about someone else's action
+type Notification struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Type       string     `json:"type"`
+	ObjectID   *int64     `json:"object_id,omitempty"`
+	ObjectName string     `json:"object_name"`
+	ActorID    *uuid.UUID `json:"actor_id,omitempty"`
+	Role       Role       `json:"role,omitempty"` // Role granted, for permission_granted
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -21,6 +21,16 @@ const (
 	ObjectTypeFile      ObjectType = "file"
 )
 
+// IsValid checks if the object type is known
+func (t ObjectType) IsValid() bool {
+	switch t {
+	case ObjectTypeDirectory, ObjectTypeNotebook, ObjectTypePython, ObjectTypeSQL,
+		ObjectTypeMarkdown, ObjectTypeConfig, ObjectTypeFile:
+		return true
+	}
+	return false
+}
+
 // Object represents a file or directory entity
 // ID is the JuiceFS inode
 type Object struct {
@@ -30,19 +40,24 @@ type Object struct {
 	Path           string     `json:"path"`
 	ParentID       *int64     `json:"parent_id,omitempty"`
 	CreatorID      uuid.UUID  `json:"creator_id"`
+	LastModifiedBy *uuid.UUID `json:"last_modified_by,omitempty"`
 	Size           int64      `json:"size"`
 	ContentHash    string     `json:"content_hash,omitempty"`
+	MimeType       string     `json:"mime_type,omitempty"` // Overrides the MIME type inferred from the name
+	Language       string     `json:"language,omitempty"`  // Syntax highlighting language, empty for directories
 	Description    string     `json:"description,omitempty"`
 	CurrentVersion int        `json:"current_version"`
 	IsDeleted      bool       `json:"is_deleted"`
 	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	DeleteBatchID  *uuid.UUID `json:"delete_batch_id,omitempty"` // Shared by the objects removed in one delete
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 
 	// Relations (not stored in DB)
-	Creator  *User    `json:"creator,omitempty"`
-	Tags     []Tag    `json:"tags,omitempty"`
-	Children []Object `json:"children,omitempty"`
+	Creator      *User    `json:"creator,omitempty"`
+	LastModifier *User    `json:"last_modifier,omitempty"`
+	Tags         []Tag    `json:"tags,omitempty"`
+	Children     []Object `json:"children,omitempty"`
 }
 
 // ObjectCreate represents the data needed to create a new object
@@ -68,13 +83,29 @@ type ObjectMove struct {
 
 // ObjectFilter represents filter options for listing objects
 type ObjectFilter struct {
-	ParentID  *int64
-	Type      []ObjectType
-	CreatorID *uuid.UUID
-	IsDeleted *bool
-	Search    string
-	Page      int
-	PageSize  int
+	ParentID   *int64
+	Type       []ObjectType
+	CreatorID  *uuid.UUID
+	IsDeleted  *bool
+	Search     string
+	PathPrefix string // Restricts results to descendants of this path
+	Page       int
+	PageSize   int
+}
+
+// ObjectErasure is the stored content of objects erased with their creator's
+// account, removed from storage after their rows
+type ObjectErasure struct {
+	Paths        []string      // Object paths, including the user's workspace directory
+	VersionPaths []string      // Version snapshots
+	TrashBatches []uuid.UUID   // Trash batches holding only erased objects
+	TrashPaths   []TrashedPath // Erased files in trash batches shared with other users' objects
+}
+
+// TrashedPath is the content trashed from Path in a trash batch
+type TrashedPath struct {
+	BatchID uuid.UUID
+	Path    string
 }
 
 // ObjectResponse represents the object data returned to client
@@ -83,14 +114,22 @@ type ObjectResponse struct {
 	Name           string            `json:"name"`
 	Type           ObjectType        `json:"type"`
 	Path           string            `json:"path"`
-	FullPath       string            `json:"full_path"` // Databricks-style path: /Workspace/Users/{email}/...
+	FullPath       string            `json:"full_path"`    // Databricks-style path: /Workspace/Users/{email}/...
+	DisplayPath    string            `json:"display_path"` // Path relative to the user's workspace root: /...
 	ParentID       *int64            `json:"parent_id,omitempty"`
 	Size           int64             `json:"size"`
+	MimeType       string            `json:"mime_type,omitempty"`
+	Language       string            `json:"language,omitempty"` // Syntax highlighting language, e.g. python; plaintext if unknown
 	Description    string            `json:"description,omitempty"`
 	CurrentVersion int               `json:"current_version"`
 	Creator        *UserResponse     `json:"creator,omitempty"`
+	LastModifiedBy *UserResponse     `json:"last_modified_by,omitempty"`
 	Tags           []TagResponse     `json:"tags,omitempty"`
 	Children       []*ObjectResponse `json:"children,omitempty"`
+	HasChildren    *bool             `json:"has_children,omitempty"` // Set for directories in lazily loaded trees
+	ChildCount     *int64            `json:"child_count,omitempty"`  // Set for directories in lazily loaded trees
+	Truncated      bool              `json:"truncated,omitempty"`    // Set on directories whose children were cut off by the tree depth limit
+	Pinned         bool              `json:"pinned,omitempty"`       // Pinned to the top of its directory, in listings and trees
 	CreatedAt      time.Time         `json:"created_at"`
 	UpdatedAt      time.Time         `json:"updated_at"`
 }
@@ -103,8 +142,11 @@ func (o *Object) ToResponse() *ObjectResponse {
 		Type:           o.Type,
 		Path:           o.Path,
 		FullPath:       ConvertToFullPath(o.Path),
+		DisplayPath:    ConvertToDisplayPath(o.Path),
 		ParentID:       o.ParentID,
 		Size:           o.Size,
+		MimeType:       o.MimeType,
+		Language:       o.Language,
 		Description:    o.Description,
 		CurrentVersion: o.CurrentVersion,
 		CreatedAt:      o.CreatedAt,
@@ -115,6 +157,10 @@ func (o *Object) ToResponse() *ObjectResponse {
 		resp.Creator = o.Creator.ToResponse()
 	}
 
+	if o.LastModifier != nil {
+		resp.LastModifiedBy = o.LastModifier.ToResponse()
+	}
+
 	if len(o.Tags) > 0 {
 		resp.Tags = make([]TagResponse, len(o.Tags))
 		for i, tag := range o.Tags {
@@ -160,6 +206,23 @@ func ConvertToFullPath(internalPath string) string {
 	return "/Workspace/Users/" + email
 }
 
+// ConvertToDisplayPath strips the workspace root from an internal storage path
+// Internal path: /{appID}/{email}/xxx -> Display path: /xxx
+// Internal path: /{appID}/{email}     -> Display path: /
+// Paths missing the email directory only have the appID stripped.
+func ConvertToDisplayPath(internalPath string) string {
+	parts := strings.Split(strings.TrimPrefix(internalPath, "/"), "/")
+	if len(parts) < 2 {
+		return "/"
+	}
+
+	rest := parts[1:]
+	if strings.Contains(rest[0], "@") {
+		rest = rest[1:]
+	}
+	return "/" + strings.Join(rest, "/")
+}
+
 // IsDirectory checks if the object is a directory
 func (o *Object) IsDirectory() bool {
 	return o.Type == ObjectTypeDirectory
@@ -170,6 +233,19 @@ func (o *Object) IsFile() bool {
 	return o.Type != ObjectTypeDirectory
 }
 
+// MarkModifiedBy records userID as the last modifier. The loaded
+// LastModifier relation is kept only if it still refers to that user.
+func (o *Object) MarkModifiedBy(userID uuid.UUID) {
+	o.LastModifiedBy = &userID
+	switch {
+	case o.LastModifier != nil && o.LastModifier.ID == userID:
+	case o.Creator != nil && o.Creator.ID == userID:
+		o.LastModifier = o.Creator
+	default:
+		o.LastModifier = nil
+	}
+}
+
 // GetExtension returns the file extension
 func (o *Object) GetExtension() string {
 	return strings.ToLower(filepath.Ext(o.Name))
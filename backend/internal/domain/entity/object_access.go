@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ObjectAccess is a batch of content reads of one object waiting to be recorded
+type ObjectAccess struct {
+	ObjectID       int64
+	Count          int64
+	LastAccessedAt time.Time
+	Users          map[uuid.UUID]*UserAccess // Only collected while the access log is enabled
+}
+
+// UserAccess counts one user's reads within an ObjectAccess
+type UserAccess struct {
+	Count          int64
+	LastAccessedAt time.Time
+}
+
+// ObjectAccessStats summarizes how often and how recently an object's content was read
+type ObjectAccessStats struct {
+	ObjectID       int64      `json:"object_id"`
+	DownloadCount  int64      `json:"download_count"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	DistinctUsers  *int64     `json:"distinct_users,omitempty"` // Only reported while the access log is enabled
+}
@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ObjectNameHistory records a rename of an object
+type ObjectNameHistory struct {
+	ID        uuid.UUID  `json:"id"`
+	ObjectID  int64      `json:"object_id"`
+	OldName   string     `json:"old_name"`
+	NewName   string     `json:"new_name"`
+	RenamedBy *uuid.UUID `json:"renamed_by,omitempty"`
+	RenamedAt time.Time  `json:"renamed_at"`
+}
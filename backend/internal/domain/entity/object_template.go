@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ObjectTemplate is a file offered as a starting point for new files. Shared
+// templates, registered by administrators, are offered to every user; others
+// only to the user who registered them.
+type ObjectTemplate struct {
+	ID          int64      `json:"id"`
+	ObjectID    int64      `json:"object_id"`
+	ObjectName  string     `json:"object_name"` // Name of the template file
+	ObjectType  ObjectType `json:"object_type"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Shared      bool       `json:"shared"`
+	OwnerID     *uuid.UUID `json:"owner_id,omitempty"` // Unset for shared templates
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UploadSession tracks a chunked upload that is assembled in a temporary file
+// and becomes a file object once complete
+type UploadSession struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Name        string     `json:"name"`
+	Type        ObjectType `json:"type,omitempty"`
+	ParentID    *int64     `json:"parent_id,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Size        int64      `json:"size"`           // Expected total size in bytes
+	Hash        string     `json:"hash,omitempty"` // Expected SHA-256 of the content, verified on completion
+	Received    int64      `json:"received"`       // Bytes received so far; the offset of the next chunk
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+}
+
+// IsExpired reports whether the session expired before it was completed
+func (s *UploadSession) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
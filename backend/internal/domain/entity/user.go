@@ -10,8 +10,9 @@ import (
 type UserStatus string
 
 const (
-	UserStatusActive   UserStatus = "active"
-	UserStatusDisabled UserStatus = "disabled"
+	UserStatusActive          UserStatus = "active"
+	UserStatusDisabled        UserStatus = "disabled"
+	UserStatusPendingDeletion UserStatus = "pending_deletion" // Deleted, erased at DeleteAfter unless restored
 )
 
 // User represents the user entity
@@ -24,6 +25,7 @@ type User struct {
 	DisplayName  string     `json:"display_name,omitempty"`
 	AvatarURL    string     `json:"avatar_url,omitempty"`
 	Status       UserStatus `json:"status"`
+	DeleteAfter  *time.Time `json:"delete_after,omitempty"` // When a pending deletion erases the account
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 }
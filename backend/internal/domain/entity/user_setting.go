@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserSetting is a user preference stored as opaque JSON
+type UserSetting struct {
+	UserID    uuid.UUID       `json:"-"`
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
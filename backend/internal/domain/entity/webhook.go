@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook subscribes a URL to change events of an object. A webhook on a
+// directory also receives the events of its descendants.
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	ObjectID  int64     `json:"object_id"`
+	CreatorID uuid.UUID `json:"creator_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // HMAC key for payload signatures; only returned on registration
+	Events    []string  `json:"events"`           // Subscribed event types; empty means all
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Accepts reports whether the webhook subscribes to the event type
+func (w *Webhook) Accepts(eventType string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, eventType)
+}
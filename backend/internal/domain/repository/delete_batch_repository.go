@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// DeleteBatchRepository defines the interface for delete batch data access
+type DeleteBatchRepository interface {
+	// Create records a delete operation
+	Create(ctx context.Context, batch *entity.DeleteBatch) error
+
+	// ListByUser lists the delete operations performed by a user, newest first
+	ListByUser(ctx context.Context, userID uuid.UUID, page, pageSize int) ([]entity.DeleteBatch, int64, error)
+
+	// Delete removes the record of a delete operation
+	Delete(ctx context.Context, id uuid.UUID) error
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// KernelSessionRepository defines the interface for kernel session data access
+type KernelSessionRepository interface {
+	// Create creates or replaces a kernel session
+	Create(ctx context.Context, session *entity.KernelSession) error
+
+	// GetByKernelID retrieves a kernel session by kernel ID
+	GetByKernelID(ctx context.Context, kernelID string) (*entity.KernelSession, error)
+
+	// List lists all kernel sessions
+	List(ctx context.Context) ([]entity.KernelSession, error)
+
+	// ListByUser lists kernel sessions owned by a user
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.KernelSession, error)
+
+	// ListByObject lists kernel sessions started for an object, with their owners
+	ListByObject(ctx context.Context, objectID int64) ([]entity.KernelSession, error)
+
+	// SetObject records the object a kernel was started for
+	SetObject(ctx context.Context, kernelID string, objectID int64) error
+
+	// UpdateLastActivity updates the last activity time of a kernel session
+	UpdateLastActivity(ctx context.Context, kernelID string, at time.Time) error
+
+	// Delete deletes a kernel session
+	Delete(ctx context.Context, kernelID string) error
+}
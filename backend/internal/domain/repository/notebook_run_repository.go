@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// NotebookRunRepository defines the interface for notebook run data access
+type NotebookRunRepository interface {
+	// Create records a notebook run
+	Create(ctx context.Context, run *entity.NotebookRun) error
+
+	// ListByObject lists the runs of a notebook, newest first
+	ListByObject(ctx context.Context, objectID int64, page, pageSize int) ([]entity.NotebookRun, int64, error)
+
+	// ExistsForVersion reports whether a run saved the given version
+	ExistsForVersion(ctx context.Context, versionID uuid.UUID) (bool, error)
+}
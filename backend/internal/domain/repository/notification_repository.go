@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// NotificationRepository defines the interface for notification data access
+type NotificationRepository interface {
+	// Create stores a notification
+	Create(ctx context.Context, notification *entity.Notification) error
+
+	// ListByUser lists a user's notifications, newest first, optionally only unread ones
+	ListByUser(ctx context.Context, userID uuid.UUID, unreadOnly bool, page, pageSize int) ([]entity.Notification, int64, error)
+
+	// MarkRead marks the given notifications of a user as read, or all of them
+	// when ids is empty, and returns how many changed
+	MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// ObjectAccessRepository defines the interface for object access statistics.
+// Counters live on the objects table; per-user reads go to the access log.
+type ObjectAccessRepository interface {
+	// Record adds batched reads to the objects' counters and their users to the access log
+	Record(ctx context.Context, accesses []*entity.ObjectAccess) error
+
+	// GetStats returns the recorded access statistics of an object. Distinct
+	// users are counted only when withUsers is set.
+	GetStats(ctx context.Context, objectID int64, withUsers bool) (*entity.ObjectAccessStats, error)
+}
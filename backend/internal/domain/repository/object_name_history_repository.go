@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// ObjectNameHistoryRepository defines the interface for object rename history data access
+type ObjectNameHistoryRepository interface {
+	// Create records a rename
+	Create(ctx context.Context, history *entity.ObjectNameHistory) error
+
+	// ListByObject lists the renames of an object, newest first
+	ListByObject(ctx context.Context, objectID int64) ([]entity.ObjectNameHistory, error)
+}
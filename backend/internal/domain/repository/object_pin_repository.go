@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// ObjectPinRepository defines the interface for object pin data access.
+// Pins shared by all users are stored under uuid.Nil.
+type ObjectPinRepository interface {
+	// Pin pins an object for a user; pinning an already pinned object is not an error
+	Pin(ctx context.Context, objectID int64, userID uuid.UUID) error
+
+	// Unpin removes a user's pin of an object
+	Unpin(ctx context.Context, objectID int64, userID uuid.UUID) error
+
+	// ListPinned returns which of objectIDs the user has pinned
+	ListPinned(ctx context.Context, userID uuid.UUID, objectIDs []int64) (map[int64]bool, error)
+}
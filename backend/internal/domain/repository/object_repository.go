@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -22,17 +23,35 @@ type ObjectRepository interface {
 	// Update updates an object
 	Update(ctx context.Context, obj *entity.Object) error
 
+	// UpdateIfUnmodified updates an object only if its updated_at still equals expectedUpdatedAt
+	UpdateIfUnmodified(ctx context.Context, obj *entity.Object, expectedUpdatedAt time.Time) error
+
 	// Delete soft deletes an object
 	Delete(ctx context.Context, id int64) error
 
+	// DeleteTree soft deletes an object and its descendants, tagging them with batchID
+	DeleteTree(ctx context.Context, obj *entity.Object, batchID uuid.UUID) error
+
+	// GetDeletedByID retrieves a soft-deleted object by ID
+	GetDeletedByID(ctx context.Context, id int64) (*entity.Object, error)
+
+	// ListDeleteBatch lists the soft-deleted objects of one delete operation, ordered by path
+	ListDeleteBatch(ctx context.Context, batchID uuid.UUID) ([]entity.Object, error)
+
+	// RestoreBatch clears the deletion of all objects of one delete operation
+	RestoreBatch(ctx context.Context, batchID uuid.UUID) error
+
 	// HardDelete permanently deletes an object
 	HardDelete(ctx context.Context, id int64) error
 
 	// List lists objects with filter
 	List(ctx context.Context, filter *entity.ObjectFilter) ([]entity.Object, int64, error)
 
-	// ListChildren lists direct children of an object
-	ListChildren(ctx context.Context, parentID *int64, page, pageSize int) ([]entity.Object, int64, error)
+	// ListChildren lists direct children of an object; with pinnedBy set, objects pinned under that user ID come first
+	ListChildren(ctx context.Context, parentID *int64, pinnedBy *uuid.UUID, page, pageSize int) ([]entity.Object, int64, error)
+
+	// CountChildren counts the non-deleted direct children of each parent; parents without children are omitted
+	CountChildren(ctx context.Context, parentIDs []int64) (map[int64]int64, error)
 
 	// GetTree retrieves the directory tree starting from a path
 	GetTree(ctx context.Context, parentID *int64, depth int) ([]entity.Object, error)
@@ -40,6 +59,10 @@ type ObjectRepository interface {
 	// ExistsByPath checks if an object exists at the given path
 	ExistsByPath(ctx context.Context, path string) (bool, error)
 
+	// ExistsByPathFold checks if an object other than excludeID exists at the
+	// given path, ignoring case
+	ExistsByPathFold(ctx context.Context, path string, excludeID int64) (bool, error)
+
 	// ExistsInParent checks if an object with given name exists in parent
 	ExistsInParent(ctx context.Context, parentID *int64, name string) (bool, error)
 
@@ -49,12 +72,27 @@ type ObjectRepository interface {
 	// ListByCreator retrieves all objects created by a user (no pagination)
 	ListByCreator(ctx context.Context, creatorID uuid.UUID) ([]entity.Object, error)
 
-	// Search searches objects by name
-	Search(ctx context.Context, query string, types []entity.ObjectType, page, pageSize int) ([]entity.Object, int64, error)
+	// GetByContentHash retrieves a user's files whose content has the given hash, at most limit
+	GetByContentHash(ctx context.Context, creatorID uuid.UUID, hash string, limit int) ([]entity.Object, error)
+
+	// ListRootsByCreator retrieves a user's top-level objects, those without a parent
+	ListRootsByCreator(ctx context.Context, creatorID uuid.UUID) ([]entity.Object, error)
+
+	// Search searches objects by name, within the descendants of pathPrefix if it is not empty
+	Search(ctx context.Context, query string, types []entity.ObjectType, pathPrefix string, page, pageSize int) ([]entity.Object, int64, error)
 
 	// UpdatePath updates the path of an object (used for move/rename)
 	UpdatePath(ctx context.Context, id int64, newPath string) error
 
 	// GetDescendants retrieves all descendant objects of a directory
 	GetDescendants(ctx context.Context, parentPath string) ([]entity.Object, error)
+
+	// TransferTree makes to the creator of the objects from created at rootPath or below it
+	TransferTree(ctx context.Context, rootPath string, from, to uuid.UUID) error
+
+	// EraseByCreator removes the objects created by a user, deleted ones
+	// included, and everything under workspacePath. It returns the version
+	// snapshots and trashed content left in storage. Trash batches holding
+	// only erased objects are removed; others keep their remaining objects.
+	EraseByCreator(ctx context.Context, creatorID uuid.UUID, workspacePath string) (*entity.ObjectErasure, error)
 }
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// ObjectTemplateRepository defines the interface for object template data
+// access. Templates of deleted objects are not returned.
+type ObjectTemplateRepository interface {
+	// Create registers a template
+	Create(ctx context.Context, template *entity.ObjectTemplate) error
+
+	// GetByID retrieves a template by ID
+	GetByID(ctx context.Context, id int64) (*entity.ObjectTemplate, error)
+
+	// ListForUser lists the shared templates and the user's own, by name
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]entity.ObjectTemplate, error)
+
+	// Delete removes a template; the template file is kept
+	Delete(ctx context.Context, id int64) error
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -22,6 +23,9 @@ type PermissionRepository interface {
 	// Update updates a permission
 	Update(ctx context.Context, perm *entity.Permission) error
 
+	// UpdateIfUnmodified updates a permission only if its updated_at still equals expectedUpdatedAt
+	UpdateIfUnmodified(ctx context.Context, perm *entity.Permission, expectedUpdatedAt time.Time) error
+
 	// Delete deletes a permission
 	Delete(ctx context.Context, id uuid.UUID) error
 
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// UploadSessionRepository defines the interface for upload session data access
+type UploadSessionRepository interface {
+	// Create creates an upload session
+	Create(ctx context.Context, session *entity.UploadSession) error
+
+	// GetByID retrieves an upload session by ID
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.UploadSession, error)
+
+	// Update updates an upload session
+	Update(ctx context.Context, session *entity.UploadSession) error
+
+	// Delete deletes an upload session
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	// ListExpired lists up to limit sessions that expired before the given time
+	ListExpired(ctx context.Context, before time.Time, limit int) ([]entity.UploadSession, error)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -36,6 +37,13 @@ type UserRepository interface {
 
 	// ExistsByUsername checks if a user exists by username
 	ExistsByUsername(ctx context.Context, username string) (bool, error)
+
+	// ListDueForErase lists up to limit users pending deletion whose grace period ended before the given time
+	ListDueForErase(ctx context.Context, before time.Time, limit int) ([]*entity.User, error)
+
+	// Erase removes a user whose objects are gone. Versions the user saved of
+	// other objects are attributed to their creators.
+	Erase(ctx context.Context, id uuid.UUID) error
 }
 
 // RefreshTokenRepository defines the interface for refresh token data access
@@ -52,6 +60,6 @@ type RefreshTokenRepository interface {
 	// RevokeAllForUser revokes all refresh tokens for a user
 	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
 
-	// DeleteExpired deletes all expired refresh tokens
-	DeleteExpired(ctx context.Context) error
+	// DeleteExpired deletes all expired refresh tokens and returns how many were removed
+	DeleteExpired(ctx context.Context) (int64, error)
 }
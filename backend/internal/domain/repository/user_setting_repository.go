@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// UserSettingRepository defines the interface for user setting data access
+type UserSettingRepository interface {
+	// Get retrieves a setting by user and key
+	Get(ctx context.Context, userID uuid.UUID, key string) (*entity.UserSetting, error)
+
+	// ListByUser lists all settings of a user
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.UserSetting, error)
+
+	// CountByUser counts the settings of a user
+	CountByUser(ctx context.Context, userID uuid.UUID) (int64, error)
+
+	// Upsert creates or replaces a setting
+	Upsert(ctx context.Context, setting *entity.UserSetting) error
+}
@@ -25,6 +25,9 @@ type VersionRepository interface {
 	// ListByObject lists all versions for an object
 	ListByObject(ctx context.Context, objectID int64, page, pageSize int) ([]entity.Version, int64, error)
 
+	// UpdateSnapshot replaces the content hash and size of an existing version
+	UpdateSnapshot(ctx context.Context, id uuid.UUID, contentHash string, size int64) error
+
 	// Delete deletes a version
 	Delete(ctx context.Context, id uuid.UUID) error
 
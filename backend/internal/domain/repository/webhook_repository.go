@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// WebhookRepository defines the interface for webhook data access
+type WebhookRepository interface {
+	// Create registers a webhook
+	Create(ctx context.Context, webhook *entity.Webhook) error
+
+	// GetByID gets a webhook by ID
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Webhook, error)
+
+	// ListByCreator lists the webhooks registered by a user, newest first
+	ListByCreator(ctx context.Context, creatorID uuid.UUID, page, pageSize int) ([]entity.Webhook, int64, error)
+
+	// ListMatching lists the webhooks on the given object or on a
+	// non-deleted object at one of the given paths
+	ListMatching(ctx context.Context, objectID int64, paths []string) ([]entity.Webhook, error)
+
+	// Delete removes a webhook
+	Delete(ctx context.Context, id uuid.UUID) error
+}
@@ -2,6 +2,10 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -13,30 +17,51 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Storage  StorageConfig  `mapstructure:"storage"`
-	Log      LogConfig      `mapstructure:"log"`
-	Kernel   KernelConfig   `mapstructure:"kernel"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	JWT         JWTConfig         `mapstructure:"jwt"`
+	Storage     StorageConfig     `mapstructure:"storage"`
+	Log         LogConfig         `mapstructure:"log"`
+	Kernel      KernelConfig      `mapstructure:"kernel"`
+	Maintenance MaintenanceConfig `mapstructure:"maintenance"`
+	Pagination  PaginationConfig  `mapstructure:"pagination"`
+	Webhook     WebhookConfig     `mapstructure:"webhook"`
+	Cache       CacheConfig       `mapstructure:"cache"`
+	Permission  PermissionConfig  `mapstructure:"permission"`
+	AccessStats AccessStatsConfig `mapstructure:"access_stats"`
+	Export      ExportConfig      `mapstructure:"export"`
+	Account     AccountConfig     `mapstructure:"account"`
 }
 
 type ServerConfig struct {
-	Host string `mapstructure:"host"`
-	Port int    `mapstructure:"port"`
-	Mode string `mapstructure:"mode"`
+	Host               string `mapstructure:"host"`
+	Port               int    `mapstructure:"port"`
+	Mode               string `mapstructure:"mode"`
+	InstanceID         string `mapstructure:"instance_id"`           // Unique ID of this instance (default: hostname)
+	AdvertiseAddress   string `mapstructure:"advertise_address"`     // Address other instances and load balancers use to reach this one
+	MaxBodySize        int64  `mapstructure:"max_body_size"`         // Max JSON body size in bytes for auth/metadata routes (default: 1MB)
+	MaxContentBodySize int64  `mapstructure:"max_content_body_size"` // Max JSON body size in bytes for content-save routes (default: 50MB)
+	MaxUploadBodySize  int64  `mapstructure:"max_upload_body_size"`  // Max multipart body size in bytes for file upload and workspace import routes (default: 1GB)
+	ReadTimeout        int    `mapstructure:"read_timeout"`          // Seconds to read a whole request, body included (default: 30)
+	ReadHeaderTimeout  int    `mapstructure:"read_header_timeout"`   // Seconds to read request headers; guards against slowloris (default: 10)
+	WriteTimeout       int    `mapstructure:"write_timeout"`         // Seconds to write a response; WebSocket and streaming routes are exempt (default: 30)
+	IdleTimeout        int    `mapstructure:"idle_timeout"`          // Seconds a keep-alive connection may stay idle (default: 60)
 }
 
 type DatabaseConfig struct {
-	Host            string `mapstructure:"host"`
-	Port            int    `mapstructure:"port"`
-	User            string `mapstructure:"user"`
-	Password        string `mapstructure:"password"`
-	DBName          string `mapstructure:"dbname"`
-	SSLMode         string `mapstructure:"sslmode"`
-	MaxIdleConns    int    `mapstructure:"max_idle_conns"`
-	MaxOpenConns    int    `mapstructure:"max_open_conns"`
-	ConnMaxLifetime int    `mapstructure:"conn_max_lifetime"`
+	Host               string `mapstructure:"host"`
+	Port               int    `mapstructure:"port"`
+	User               string `mapstructure:"user"`
+	Password           string `mapstructure:"password"`
+	DBName             string `mapstructure:"dbname"`
+	SSLMode            string `mapstructure:"sslmode"`
+	MaxIdleConns       int    `mapstructure:"max_idle_conns"`       // Idle connections kept in the pool (default: 10)
+	MaxOpenConns       int    `mapstructure:"max_open_conns"`       // Open connections allowed at once (default: 25)
+	ConnMaxLifetime    int    `mapstructure:"conn_max_lifetime"`    // Seconds a connection is reused before being replaced (default: 3600)
+	ConnMaxIdleTime    int    `mapstructure:"conn_max_idle_time"`   // Seconds an idle connection is kept before being closed (default: 300)
+	PingTimeout        int    `mapstructure:"ping_timeout"`         // Seconds the startup and readiness pings may take (default: 5)
+	LogQueries         bool   `mapstructure:"log_queries"`          // Log every SQL query at debug level; keep off in production (default: false)
+	SlowQueryThreshold int    `mapstructure:"slow_query_threshold"` // Milliseconds after which a query is logged at warn level (default: 200, negative disables)
 }
 
 type JWTConfig struct {
@@ -44,11 +69,39 @@ type JWTConfig struct {
 	AccessTokenExpiry  int    `mapstructure:"access_token_expiry"`
 	RefreshTokenExpiry int    `mapstructure:"refresh_token_expiry"`
 	Issuer             string `mapstructure:"issuer"`
+	CookieAuth         bool   `mapstructure:"cookie_auth"`   // Also accept the access token from an HttpOnly cookie, guarded by a double-submit CSRF token (default: false)
+	CookieSecure       bool   `mapstructure:"cookie_secure"` // Send auth cookies only over HTTPS
+	CookieDomain       string `mapstructure:"cookie_domain"` // Domain attribute of auth cookies (default: request host)
 }
 
 type StorageConfig struct {
-	BasePath    string `mapstructure:"base_path"`
-	VersionPath string `mapstructure:"version_path"`
+	BasePath          string   `mapstructure:"base_path"`
+	VersionPath       string   `mapstructure:"version_path"`
+	TrashPath         string   `mapstructure:"trash_path"`         // Deleted content kept for restore; must share base_path's filesystem (default: <base_path>/.trash)
+	AllowedExtensions []string `mapstructure:"allowed_extensions"` // If non-empty, only these extensions may be stored, e.g. [".py", ".ipynb"]
+	DeniedExtensions  []string `mapstructure:"denied_extensions"`  // Extensions that may never be stored, e.g. [".exe"]
+	DeniedMIMETypes   []string `mapstructure:"denied_mime_types"`  // Detected MIME types that may never be stored
+	AllowedMIMETypes  []string `mapstructure:"allowed_mime_types"` // If non-empty, the only MIME types a content type override may set
+	MaxUploadSize     int64    `mapstructure:"max_upload_size"`    // Max upload size in bytes enforced by the upload scanner (0 = unlimited)
+	Scanner           string   `mapstructure:"scanner"`            // Upload scanner: none, basic (size limit) or command (default: basic)
+	ScannerCommand    []string `mapstructure:"scanner_command"`    // Program and arguments the command scanner runs with the upload on stdin; a non-zero exit rejects it
+	ScannerTimeout    int      `mapstructure:"scanner_timeout"`    // Seconds the command scanner may take per upload (default: 60)
+	NamePolicy        string   `mapstructure:"name_policy"`        // Handling of names unsafe on Windows/SMB storage: reject, sanitize or off (default: reject)
+	NameCase          string   `mapstructure:"name_case"`          // Name uniqueness within a directory: auto, sensitive or insensitive (default: auto, probed from base_path's filesystem)
+	VersionCoalesce   int      `mapstructure:"version_coalesce"`   // Seconds during which auto-saves by one user update the latest version in place (default: 60, negative disables)
+	VersionKeepMin    int      `mapstructure:"version_keep_min"`   // Most recent versions that bulk version deletion never removes (default: 3)
+	NotebookMaxCells  int      `mapstructure:"notebook_max_cells"` // Max cells a notebook patch may grow a notebook to (default: 5000)
+	NotebookMaxBytes  int64    `mapstructure:"notebook_max_bytes"` // Max size in bytes a notebook patch may grow a notebook to (default: 20MB)
+	NotebookMaxOps    int      `mapstructure:"notebook_max_ops"`   // Max operations per notebook patch request (default: 500)
+	NotebookFormat    string   `mapstructure:"notebook_format"`    // How saved notebooks are serialized: preserve, compact or canonical (default: preserve)
+	CompressVersions  bool     `mapstructure:"compress_versions"`  // Gzip version snapshots of text-like files (default: false)
+	CompressTypes     []string `mapstructure:"compress_types"`     // Extensions compressed when compress_versions is on (empty = common text formats)
+	UploadPath        string   `mapstructure:"upload_path"`        // Partial chunked uploads; must share base_path's filesystem (default: <base_path>/.uploads)
+	UploadSessionTTL  int      `mapstructure:"upload_session_ttl"` // Seconds an idle chunked upload is kept before it expires (default: 86400)
+	TreeMaxDepth      int      `mapstructure:"tree_max_depth"`     // Deepest level a full directory tree returns; deeper directories are marked truncated (default: 10)
+	PinScope          string   `mapstructure:"pin_scope"`          // Whether pinning an object to the top of its directory is seen by everyone (object) or only the user who pinned it (user) (default: object)
+
+	Languages map[string]string `mapstructure:"languages"` // Extra extension to highlighting language mappings, keyed by extension without the dot, e.g. {"dat": "csv"}
 }
 
 type LogConfig struct {
@@ -59,9 +112,143 @@ type LogConfig struct {
 }
 
 type KernelConfig struct {
-	PythonPath       string        `mapstructure:"python_path"`
-	ExecutionTimeout int           `mapstructure:"execution_timeout"`
-	Gateway          GatewayConfig `mapstructure:"gateway"`
+	PythonPath       string             `mapstructure:"python_path"`
+	PythonCheck      string             `mapstructure:"python_check"` // Startup interpreter check failure policy: warn, fatal or off (default: warn)
+	ExecutionTimeout int                `mapstructure:"execution_timeout"`
+	StartupTimeout   int                `mapstructure:"startup_timeout"`    // Seconds to wait for a local kernel to become ready (default: 30)
+	OutputBufferSize int                `mapstructure:"output_buffer_size"` // Per-session output channel buffer (default: 100)
+	SpecCacheTTL     int                `mapstructure:"spec_cache_ttl"`     // Seconds to cache Jupyter-discovered kernel specs (default: 300)
+	ConnectionDir    string             `mapstructure:"connection_dir"`     // Base directory for local kernel connection files (default: <temp>/workspace-kernels)
+	HealthInterval   int                `mapstructure:"health_interval"`    // Seconds between kernel liveness probes (default: 30, negative disables)
+	HealthTimeout    int                `mapstructure:"health_timeout"`     // Seconds to wait for a probe reply before marking a kernel unresponsive (default: 10)
+	RunSaveInterval  int                `mapstructure:"run_save_interval"`  // Seconds between writes of partial outputs into a notebook run on the server (default: 5)
+	MaxReprLength    int                `mapstructure:"max_repr_length"`    // Characters of an expression result's text sent by local kernels; longer results are truncated (default: 100000, negative disables)
+	Gateway          GatewayConfig      `mapstructure:"gateway"`
+	WebSocket        WebSocketConfig    `mapstructure:"websocket"`
+	Magics           MagicConfig        `mapstructure:"magics"`
+	Capture          CaptureConfig      `mapstructure:"capture"`
+	DisableShell     bool               `mapstructure:"disable_shell"`  // Remove shell-executing and file-writing magics from local kernels, whatever magics allows (default: false)
+	Specs            []KernelSpecConfig `mapstructure:"specs"`          // Extra local kernel specs; take precedence over discovered specs
+	SpecAllowlist    []SpecAllowRule    `mapstructure:"spec_allowlist"` // Kernel specs each app may start (empty: all specs for all apps)
+}
+
+// CaptureConfig bounds the files an execution with capture_outputs imports
+// into the user's workspace
+type CaptureConfig struct {
+	ResultsDir   string `mapstructure:"results_dir"`    // Workspace folder captured files are imported into (default: results)
+	MaxFiles     int    `mapstructure:"max_files"`      // Files imported per execution; the rest are skipped (default: 20)
+	MaxFileSize  int64  `mapstructure:"max_file_size"`  // Bytes; larger files are skipped (default: 10485760)
+	MaxTotalSize int64  `mapstructure:"max_total_size"` // Bytes imported per execution (default: 52428800)
+}
+
+// SpecAllowRule limits the kernel specs an app may start
+type SpecAllowRule struct {
+	AppID string   `mapstructure:"app_id"` // "*" applies to apps without a rule of their own
+	Specs []string `mapstructure:"specs"`  // Kernel spec names the app may start
+}
+
+// MagicConfig limits the magic commands local kernels run. Magics are named
+// "%name" for line magics, "%%name" for cell magics and "!" for shell
+// escapes. Gateway kernels run their own magics and are not affected.
+type MagicConfig struct {
+	Preset string   `mapstructure:"preset"` // Magics enabled to start with: all, safe (no shell-executing magics) or none (default: all)
+	Allow  []string `mapstructure:"allow"`  // Magics enabled on top of the preset, e.g. ["%pip"]
+	Deny   []string `mapstructure:"deny"`   // Magics disabled on top of the preset; wins over allow
+}
+
+// KernelSpecConfig defines a named local kernel spec, e.g. a conda environment
+type KernelSpecConfig struct {
+	Name        string   `mapstructure:"name"`
+	DisplayName string   `mapstructure:"display_name"` // Defaults to name
+	Language    string   `mapstructure:"language"`     // Defaults to "python"
+	PythonPath  string   `mapstructure:"python_path"`  // Interpreter used to build the default argv
+	Argv        []string `mapstructure:"argv"`         // Overrides the default ipykernel argv
+	Env         []string `mapstructure:"env"`          // Default environment as KEY=VALUE entries
+}
+
+// PaginationConfig holds page size bounds for list endpoints
+type PaginationConfig struct {
+	DefaultPageSize int `mapstructure:"default_page_size"` // Page size when page_size is omitted (default: 20)
+	MaxPageSize     int `mapstructure:"max_page_size"`     // Largest accepted page_size (default: 100)
+}
+
+// WebSocketConfig holds limits for kernel WebSocket connections
+type WebSocketConfig struct {
+	MaxConnections        int `mapstructure:"max_connections"`          // Max concurrent connections across all users (0 = unlimited)
+	MaxConnectionsPerUser int `mapstructure:"max_connections_per_user"` // Max concurrent connections per user (0 = unlimited)
+	OutputRateLimit       int `mapstructure:"output_rate_limit"`        // Max kernel output messages per second per connection; stream output is coalesced beyond it (0 = unlimited)
+	IdleTimeout           int `mapstructure:"idle_timeout"`             // Seconds without messages in either direction before a connection is closed; its kernel keeps running (0 = never)
+}
+
+// MaintenanceConfig holds configuration for background maintenance jobs and
+// announced maintenance
+type MaintenanceConfig struct {
+	TokenCleanupInterval int      `mapstructure:"token_cleanup_interval"` // Expired token cleanup interval in seconds (default: 3600)
+	AdminEmails          []string `mapstructure:"admin_emails"`           // Users allowed to call admin endpoints
+	Enabled              bool     `mapstructure:"enabled"`                // Announce maintenance with Message as a banner (default: false)
+	Message              string   `mapstructure:"message"`                // Banner text, also returned by rejected writes
+	ReadOnly             bool     `mapstructure:"read_only"`              // While enabled, reject mutating requests (default: false)
+}
+
+// MaintenanceMode is the announced maintenance state shown to clients
+type MaintenanceMode struct {
+	Enabled  bool   `json:"enabled"`
+	Message  string `json:"message,omitempty"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+// WebhookConfig holds configuration for webhook delivery
+type WebhookConfig struct {
+	Workers         int `mapstructure:"workers"`          // Concurrent deliveries (default: 4)
+	QueueSize       int `mapstructure:"queue_size"`       // Pending deliveries before new ones are dropped (default: 1000)
+	MaxAttempts     int `mapstructure:"max_attempts"`     // Attempts per delivery, including the first (default: 5)
+	RetryBaseDelay  int `mapstructure:"retry_base_delay"` // Seconds before the first retry, doubled per attempt (default: 2)
+	RetryMaxDelay   int `mapstructure:"retry_max_delay"`  // Upper bound in seconds for the retry delay (default: 60)
+	DeliveryTimeout int `mapstructure:"delivery_timeout"` // Seconds to wait for a response to one attempt (default: 10)
+
+	AllowPrivateTargets bool `mapstructure:"allow_private_targets"` // Deliver to loopback, link-local and private addresses (default: false)
+}
+
+// PermissionConfig holds configuration for permission inheritance
+type PermissionConfig struct {
+	InheritOnCreate bool   `mapstructure:"inherit_on_create"` // Give new files and directories inherited copies of their parent's grants (default: false)
+	InheritedRole   string `mapstructure:"inherited_role"`    // Highest role such copies get: owner, editor or viewer (default: owner, keeping the parent's role)
+}
+
+// CacheConfig holds configuration for in-memory caches
+type CacheConfig struct {
+	ObjectEnabled bool `mapstructure:"object_enabled"` // Cache object metadata read by ID (default: false)
+	ObjectSize    int  `mapstructure:"object_size"`    // Max cached objects; least recently used ones are evicted (default: 10000)
+	ObjectTTL     int  `mapstructure:"object_ttl"`     // Seconds a cached object is served; bounds staleness from other instances (default: 10)
+}
+
+// AccessStatsConfig holds configuration for object download counters
+type AccessStatsConfig struct {
+	FlushInterval int  `mapstructure:"flush_interval"` // Seconds reads are buffered in memory before being written (default: 30)
+	MaxPending    int  `mapstructure:"max_pending"`    // Objects with buffered reads that trigger an early write (default: 10000)
+	LogEnabled    bool `mapstructure:"log_enabled"`    // Record which users read each object, for distinct user counts (default: false)
+}
+
+// ExportConfig holds configuration for file exports
+type ExportConfig struct {
+	PDFCommand     []string `mapstructure:"pdf_command"`      // HTML-to-PDF converter with {input} and {output} placeholders; empty disables PDF export
+	PDFTimeout     int      `mapstructure:"pdf_timeout"`      // Seconds one PDF conversion may run (default: 120)
+	JobTTL         int      `mapstructure:"job_ttl"`          // Seconds finished export jobs are kept for download (default: 600)
+	MaxRunningJobs int      `mapstructure:"max_running_jobs"` // Export jobs converting at once; others wait (default: 2)
+
+	Backends map[string]ExportBackendConfig `mapstructure:"backends"` // External storage users may push copies of objects to, keyed by name
+}
+
+// AccountConfig holds configuration for account deletion
+type AccountConfig struct {
+	DeletionGracePeriod int    `mapstructure:"deletion_grace_period"` // Days a deleted account stays disabled, and can be restored, before it is erased (default: 30, negative erases right away)
+	SharedObjects       string `mapstructure:"shared_objects"`        // What erasing an account does with objects shared with other users: transfer or delete (default: transfer)
+}
+
+// ExportBackendConfig holds configuration for one external storage backend
+type ExportBackendConfig struct {
+	Type string `mapstructure:"type"` // Storage type; only local, a directory such as a mounted bucket, is supported (default: local)
+	Path string `mapstructure:"path"` // Root directory exported files are written under
 }
 
 // GatewayConfig holds configuration for remote Jupyter Gateway
@@ -80,6 +267,12 @@ type GatewayConfig struct {
 	ClientCert        string `mapstructure:"client_cert"`         // Client certificate path
 	ClientKey         string `mapstructure:"client_key"`          // Client key path
 	CACerts           string `mapstructure:"ca_certs"`            // CA certificates path
+	RetryMaxAttempts  int    `mapstructure:"retry_max_attempts"`  // Attempts per retryable request, including the first (default: 3, 1 disables retries)
+	RetryBaseDelayMs  int    `mapstructure:"retry_base_delay_ms"` // Delay in milliseconds before the first retry, doubled per attempt (default: 200)
+	RetryMaxDelayMs   int    `mapstructure:"retry_max_delay_ms"`  // Upper bound in milliseconds for a single retry delay (default: 5000)
+	RetryJitter       int    `mapstructure:"retry_jitter"`        // Random +/- percentage applied to each retry delay, up to 100 (default: 20, negative disables)
+	BreakerThreshold  int    `mapstructure:"breaker_threshold"`   // Consecutive failed requests that open the circuit breaker (default: 5, negative disables)
+	BreakerCooldown   int    `mapstructure:"breaker_cooldown"`    // Seconds the open breaker fails requests fast before a trial request (default: 30)
 }
 
 var (
@@ -143,11 +336,59 @@ func (d *DatabaseConfig) GetDSN() string {
 	)
 }
 
+// GetMaxIdleConns returns how many idle connections the pool keeps
+func (d *DatabaseConfig) GetMaxIdleConns() int {
+	if d.MaxIdleConns <= 0 {
+		return 10
+	}
+	return d.MaxIdleConns
+}
+
+// GetMaxOpenConns returns how many connections the pool may open at once
+func (d *DatabaseConfig) GetMaxOpenConns() int {
+	if d.MaxOpenConns <= 0 {
+		return 25
+	}
+	return d.MaxOpenConns
+}
+
 // GetConnMaxLifetime returns the connection max lifetime as time.Duration
 func (d *DatabaseConfig) GetConnMaxLifetime() time.Duration {
+	if d.ConnMaxLifetime <= 0 {
+		return time.Hour
+	}
 	return time.Duration(d.ConnMaxLifetime) * time.Second
 }
 
+// GetConnMaxIdleTime returns how long an idle connection is kept. Closing
+// idle connections early drops those left pointing at a failed-over primary.
+func (d *DatabaseConfig) GetConnMaxIdleTime() time.Duration {
+	if d.ConnMaxIdleTime <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(d.ConnMaxIdleTime) * time.Second
+}
+
+// GetPingTimeout returns how long a database ping may take
+func (d *DatabaseConfig) GetPingTimeout() time.Duration {
+	if d.PingTimeout <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(d.PingTimeout) * time.Second
+}
+
+// GetSlowQueryThreshold returns how long a query may take before it is logged
+// as slow, 0 when slow queries are not logged
+func (d *DatabaseConfig) GetSlowQueryThreshold() time.Duration {
+	if d.SlowQueryThreshold < 0 {
+		return 0
+	}
+	if d.SlowQueryThreshold == 0 {
+		return 200 * time.Millisecond
+	}
+	return time.Duration(d.SlowQueryThreshold) * time.Millisecond
+}
+
 // GetAccessTokenExpiry returns access token expiry as time.Duration
 func (j *JWTConfig) GetAccessTokenExpiry() time.Duration {
 	return time.Duration(j.AccessTokenExpiry) * time.Second
@@ -158,6 +399,651 @@ func (j *JWTConfig) GetRefreshTokenExpiry() time.Duration {
 	return time.Duration(j.RefreshTokenExpiry) * time.Second
 }
 
+// GetInstanceID returns the instance ID, falling back to the hostname
+func (s *ServerConfig) GetInstanceID() string {
+	if s.InstanceID != "" {
+		return s.InstanceID
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "default"
+	}
+	return hostname
+}
+
+// GetMaxBodySize returns the max JSON body size for auth and metadata routes
+func (s *ServerConfig) GetMaxBodySize() int64 {
+	if s.MaxBodySize <= 0 {
+		return 1 << 20
+	}
+	return s.MaxBodySize
+}
+
+// GetMaxContentBodySize returns the max JSON body size for content-save routes
+func (s *ServerConfig) GetMaxContentBodySize() int64 {
+	if s.MaxContentBodySize <= 0 {
+		return 50 << 20
+	}
+	return s.MaxContentBodySize
+}
+
+// GetMaxUploadBodySize returns the max multipart body size for file upload
+// and workspace import routes
+func (s *ServerConfig) GetMaxUploadBodySize() int64 {
+	if s.MaxUploadBodySize <= 0 {
+		return 1 << 30
+	}
+	return s.MaxUploadBodySize
+}
+
+// GetReadTimeout returns the time allowed to read a whole request
+func (s *ServerConfig) GetReadTimeout() time.Duration {
+	if s.ReadTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(s.ReadTimeout) * time.Second
+}
+
+// GetReadHeaderTimeout returns the time allowed to read request headers
+func (s *ServerConfig) GetReadHeaderTimeout() time.Duration {
+	if s.ReadHeaderTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(s.ReadHeaderTimeout) * time.Second
+}
+
+// GetWriteTimeout returns the time allowed to write a response
+func (s *ServerConfig) GetWriteTimeout() time.Duration {
+	if s.WriteTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(s.WriteTimeout) * time.Second
+}
+
+// GetIdleTimeout returns how long an idle keep-alive connection is kept open
+func (s *ServerConfig) GetIdleTimeout() time.Duration {
+	if s.IdleTimeout <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(s.IdleTimeout) * time.Second
+}
+
+// GetStartupTimeout returns how long to wait for a local kernel to become ready
+func (k *KernelConfig) GetStartupTimeout() time.Duration {
+	if k.StartupTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(k.StartupTimeout) * time.Second
+}
+
+// GetPythonCheck returns the startup interpreter check policy
+func (k *KernelConfig) GetPythonCheck() string {
+	switch k.PythonCheck {
+	case "fatal", "off":
+		return k.PythonCheck
+	default:
+		return "warn"
+	}
+}
+
+// GetNamePolicy returns how names unsafe on Windows/SMB storage are handled
+func (s *StorageConfig) GetNamePolicy() string {
+	switch s.NamePolicy {
+	case "sanitize", "off":
+		return s.NamePolicy
+	default:
+		return "reject"
+	}
+}
+
+// GetScanner returns the upload scanner to use
+func (s *StorageConfig) GetScanner() string {
+	switch s.Scanner {
+	case "none", "command":
+		return s.Scanner
+	default:
+		return "basic"
+	}
+}
+
+// GetScannerTimeout returns how long the command scanner may take per upload
+func (s *StorageConfig) GetScannerTimeout() time.Duration {
+	if s.ScannerTimeout <= 0 {
+		return time.Minute
+	}
+	return time.Duration(s.ScannerTimeout) * time.Second
+}
+
+// GetNameCase returns whether object names in a directory must be unique
+// ignoring case
+func (s *StorageConfig) GetNameCase() string {
+	switch s.NameCase {
+	case "sensitive", "insensitive":
+		return s.NameCase
+	default:
+		return "auto"
+	}
+}
+
+// GetTrashPath returns where deleted content is kept until it is restored
+func (s *StorageConfig) GetTrashPath() string {
+	if s.TrashPath == "" {
+		return filepath.Join(s.BasePath, ".trash")
+	}
+	return s.TrashPath
+}
+
+// GetUploadPath returns where chunked uploads are assembled
+func (s *StorageConfig) GetUploadPath() string {
+	if s.UploadPath == "" {
+		return filepath.Join(s.BasePath, ".uploads")
+	}
+	return s.UploadPath
+}
+
+// GetUploadSessionTTL returns how long a chunked upload may sit idle before it expires
+func (s *StorageConfig) GetUploadSessionTTL() time.Duration {
+	if s.UploadSessionTTL <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(s.UploadSessionTTL) * time.Second
+}
+
+// GetVersionCoalesce returns the window in which consecutive auto-saves are
+// coalesced into one version, or 0 if disabled
+func (s *StorageConfig) GetVersionCoalesce() time.Duration {
+	if s.VersionCoalesce < 0 {
+		return 0
+	}
+	if s.VersionCoalesce == 0 {
+		return time.Minute
+	}
+	return time.Duration(s.VersionCoalesce) * time.Second
+}
+
+// GetVersionKeepMin returns how many of the most recent versions are protected
+// from bulk deletion
+func (s *StorageConfig) GetVersionKeepMin() int {
+	if s.VersionKeepMin <= 0 {
+		return 3
+	}
+	return s.VersionKeepMin
+}
+
+// GetLanguages returns the configured highlighting language mappings keyed by
+// lower-case extension with its leading dot, as entity.DetectLanguage expects
+func (s *StorageConfig) GetLanguages() map[string]string {
+	if s == nil || len(s.Languages) == 0 {
+		return nil
+	}
+	languages := make(map[string]string, len(s.Languages))
+	for ext, lang := range s.Languages {
+		languages["."+strings.TrimPrefix(strings.ToLower(ext), ".")] = strings.ToLower(lang)
+	}
+	return languages
+}
+
+// defaultCompressTypes are text formats that compress well
+var defaultCompressTypes = []string{
+	".ipynb", ".py", ".r", ".sql", ".sh", ".js", ".ts", ".txt", ".md", ".log",
+	".csv", ".tsv", ".json", ".xml", ".yaml", ".yml", ".html", ".css",
+}
+
+// GetCompressTypes returns the extensions whose version snapshots are stored
+// gzipped, or nil if compression is off
+func (s *StorageConfig) GetCompressTypes() []string {
+	if !s.CompressVersions {
+		return nil
+	}
+	if len(s.CompressTypes) == 0 {
+		return defaultCompressTypes
+	}
+	return s.CompressTypes
+}
+
+// GetPinScope returns whether object pins are shared by everyone or per user
+func (s *StorageConfig) GetPinScope() string {
+	switch s.PinScope {
+	case "user":
+		return s.PinScope
+	default:
+		return "object"
+	}
+}
+
+// GetTreeMaxDepth returns the depth limit of full directory trees
+func (s *StorageConfig) GetTreeMaxDepth() int {
+	if s.TreeMaxDepth <= 0 {
+		return 10
+	}
+	return s.TreeMaxDepth
+}
+
+// GetNotebookMaxCells returns the cell count limit enforced by notebook patches
+func (s *StorageConfig) GetNotebookMaxCells() int {
+	if s.NotebookMaxCells <= 0 {
+		return 5000
+	}
+	return s.NotebookMaxCells
+}
+
+// GetNotebookMaxBytes returns the size limit enforced by notebook patches
+func (s *StorageConfig) GetNotebookMaxBytes() int64 {
+	if s.NotebookMaxBytes <= 0 {
+		return 20 << 20
+	}
+	return s.NotebookMaxBytes
+}
+
+// GetNotebookMaxOps returns the max number of operations in one notebook patch
+func (s *StorageConfig) GetNotebookMaxOps() int {
+	if s.NotebookMaxOps <= 0 {
+		return 500
+	}
+	return s.NotebookMaxOps
+}
+
+// GetNotebookFormat returns how saved notebooks are serialized
+func (s *StorageConfig) GetNotebookFormat() string {
+	if s == nil {
+		return "preserve"
+	}
+	switch s.NotebookFormat {
+	case "compact", "canonical":
+		return s.NotebookFormat
+	default:
+		return "preserve"
+	}
+}
+
+// GetSpecCacheTTL returns how long discovered kernel specs are cached
+func (k *KernelConfig) GetSpecCacheTTL() time.Duration {
+	if k.SpecCacheTTL <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(k.SpecCacheTTL) * time.Second
+}
+
+// GetDefaultPageSize returns the page size used when none is requested
+func (p *PaginationConfig) GetDefaultPageSize() int {
+	if p.DefaultPageSize <= 0 {
+		return 20
+	}
+	return min(p.DefaultPageSize, p.GetMaxPageSize())
+}
+
+// GetMaxPageSize returns the largest accepted page size
+func (p *PaginationConfig) GetMaxPageSize() int {
+	if p.MaxPageSize <= 0 {
+		return 100
+	}
+	return p.MaxPageSize
+}
+
+// GetConnectionDir returns the base directory for local kernel connection files
+func (k *KernelConfig) GetConnectionDir() string {
+	if k.ConnectionDir == "" {
+		return filepath.Join(os.TempDir(), "workspace-kernels")
+	}
+	return k.ConnectionDir
+}
+
+// GetPreset returns the set of magics enabled before allow and deny apply
+func (m *MagicConfig) GetPreset() string {
+	switch m.Preset {
+	case "safe", "none":
+		return m.Preset
+	default:
+		return "all"
+	}
+}
+
+// GetRunSaveInterval returns how often a notebook run writes its partial outputs
+func (k *KernelConfig) GetRunSaveInterval() time.Duration {
+	if k.RunSaveInterval <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(k.RunSaveInterval) * time.Second
+}
+
+// GetResultsDir returns the workspace folder captured files are imported
+// into, as a display path
+func (c *CaptureConfig) GetResultsDir() string {
+	dir := path.Clean("/" + strings.TrimSpace(c.ResultsDir))
+	if dir == "/" {
+		return "/results"
+	}
+	return dir
+}
+
+// GetMaxFiles returns how many captured files an execution may import
+func (c *CaptureConfig) GetMaxFiles() int {
+	if c.MaxFiles <= 0 {
+		return 20
+	}
+	return c.MaxFiles
+}
+
+// GetMaxFileSize returns the size in bytes of the largest file imported
+func (c *CaptureConfig) GetMaxFileSize() int64 {
+	if c.MaxFileSize <= 0 {
+		return 10 << 20
+	}
+	return c.MaxFileSize
+}
+
+// GetMaxTotalSize returns how many bytes an execution may import in total
+func (c *CaptureConfig) GetMaxTotalSize() int64 {
+	if c.MaxTotalSize <= 0 {
+		return 50 << 20
+	}
+	return c.MaxTotalSize
+}
+
+// GetOutputBufferSize returns the per-session kernel output buffer size
+func (k *KernelConfig) GetOutputBufferSize() int {
+	if k.OutputBufferSize <= 0 {
+		return 100
+	}
+	return k.OutputBufferSize
+}
+
+// IsSpecAllowed reports whether an app may start the named kernel spec. Apps
+// without a rule use the "*" rule; with no "*" rule they may start any spec.
+func (k *KernelConfig) IsSpecAllowed(appID, spec string) bool {
+	var fallback *SpecAllowRule
+	for i := range k.SpecAllowlist {
+		rule := &k.SpecAllowlist[i]
+		if rule.AppID == appID {
+			return slices.Contains(rule.Specs, spec)
+		}
+		if rule.AppID == "*" {
+			fallback = rule
+		}
+	}
+	return fallback == nil || slices.Contains(fallback.Specs, spec)
+}
+
+// GetHealthInterval returns the kernel liveness probe interval, or 0 if disabled
+func (k *KernelConfig) GetHealthInterval() time.Duration {
+	if k.HealthInterval < 0 {
+		return 0
+	}
+	if k.HealthInterval == 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(k.HealthInterval) * time.Second
+}
+
+// GetMaxReprLength returns the longest result text local kernels send, or 0
+// when results are not truncated
+func (k *KernelConfig) GetMaxReprLength() int {
+	if k.MaxReprLength < 0 {
+		return 0
+	}
+	if k.MaxReprLength == 0 {
+		return 100000
+	}
+	return k.MaxReprLength
+}
+
+// GetHealthTimeout returns how long a kernel liveness probe waits for a reply
+func (k *KernelConfig) GetHealthTimeout() time.Duration {
+	if k.HealthTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(k.HealthTimeout) * time.Second
+}
+
+// GetRetryMaxAttempts returns how many times a retryable gateway request is tried
+func (g *GatewayConfig) GetRetryMaxAttempts() int {
+	if g.RetryMaxAttempts <= 0 {
+		return 3
+	}
+	return g.RetryMaxAttempts
+}
+
+// GetRetryBaseDelay returns the delay before the first gateway request retry
+func (g *GatewayConfig) GetRetryBaseDelay() time.Duration {
+	if g.RetryBaseDelayMs <= 0 {
+		return 200 * time.Millisecond
+	}
+	return time.Duration(g.RetryBaseDelayMs) * time.Millisecond
+}
+
+// GetRetryMaxDelay returns the upper bound for a single gateway retry delay
+func (g *GatewayConfig) GetRetryMaxDelay() time.Duration {
+	if g.RetryMaxDelayMs <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(g.RetryMaxDelayMs) * time.Millisecond
+}
+
+// GetRetryJitter returns the random fraction, 0 to 1, applied to gateway retry delays
+func (g *GatewayConfig) GetRetryJitter() float64 {
+	switch {
+	case g.RetryJitter < 0:
+		return 0
+	case g.RetryJitter == 0:
+		return 0.2
+	case g.RetryJitter > 100:
+		return 1
+	default:
+		return float64(g.RetryJitter) / 100
+	}
+}
+
+// GetBreakerThreshold returns the consecutive failures that open the gateway
+// circuit breaker, or 0 if the breaker is disabled
+func (g *GatewayConfig) GetBreakerThreshold() int {
+	if g.BreakerThreshold < 0 {
+		return 0
+	}
+	if g.BreakerThreshold == 0 {
+		return 5
+	}
+	return g.BreakerThreshold
+}
+
+// GetBreakerCooldown returns how long the open gateway circuit breaker fails fast
+func (g *GatewayConfig) GetBreakerCooldown() time.Duration {
+	if g.BreakerCooldown <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(g.BreakerCooldown) * time.Second
+}
+
+// GetTokenCleanupInterval returns the expired token cleanup interval as time.Duration
+func (m *MaintenanceConfig) GetTokenCleanupInterval() time.Duration {
+	if m.TokenCleanupInterval <= 0 {
+		return time.Hour
+	}
+	return time.Duration(m.TokenCleanupInterval) * time.Second
+}
+
+// IsAdmin reports whether the given email belongs to an administrator
+func (m *MaintenanceConfig) IsAdmin(email string) bool {
+	for _, admin := range m.AdminEmails {
+		if email != "" && strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMode returns the announced maintenance state. Read-only mode applies
+// only while maintenance is enabled.
+func (m *MaintenanceConfig) GetMode() MaintenanceMode {
+	mu.RLock()
+	defer mu.RUnlock()
+	if !m.Enabled {
+		return MaintenanceMode{}
+	}
+	return MaintenanceMode{Enabled: true, Message: m.Message, ReadOnly: m.ReadOnly}
+}
+
+// SetMode changes the announced maintenance state at runtime. The change lasts
+// until the config file is next reloaded.
+func (m *MaintenanceConfig) SetMode(mode MaintenanceMode) {
+	mu.Lock()
+	defer mu.Unlock()
+	m.Enabled, m.Message, m.ReadOnly = mode.Enabled, mode.Message, mode.ReadOnly
+}
+
+// GetWorkers returns the number of concurrent webhook deliveries
+func (w *WebhookConfig) GetWorkers() int {
+	if w.Workers <= 0 {
+		return 4
+	}
+	return w.Workers
+}
+
+// GetQueueSize returns how many webhook deliveries may be pending
+func (w *WebhookConfig) GetQueueSize() int {
+	if w.QueueSize <= 0 {
+		return 1000
+	}
+	return w.QueueSize
+}
+
+// GetMaxAttempts returns the attempts per webhook delivery
+func (w *WebhookConfig) GetMaxAttempts() int {
+	if w.MaxAttempts <= 0 {
+		return 5
+	}
+	return w.MaxAttempts
+}
+
+// GetRetryBaseDelay returns the delay before the first webhook retry
+func (w *WebhookConfig) GetRetryBaseDelay() time.Duration {
+	if w.RetryBaseDelay <= 0 {
+		return 2 * time.Second
+	}
+	return time.Duration(w.RetryBaseDelay) * time.Second
+}
+
+// GetRetryMaxDelay returns the upper bound for the webhook retry delay
+func (w *WebhookConfig) GetRetryMaxDelay() time.Duration {
+	if w.RetryMaxDelay <= 0 {
+		return time.Minute
+	}
+	return time.Duration(w.RetryMaxDelay) * time.Second
+}
+
+// GetDeliveryTimeout returns how long one webhook attempt may take
+func (w *WebhookConfig) GetDeliveryTimeout() time.Duration {
+	if w.DeliveryTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(w.DeliveryTimeout) * time.Second
+}
+
+// GetInheritedRole returns the highest role a new object inherits from its parent's grants
+func (p *PermissionConfig) GetInheritedRole() string {
+	switch role := strings.ToLower(p.InheritedRole); role {
+	case "editor", "viewer":
+		return role
+	default:
+		return "owner"
+	}
+}
+
+// GetObjectSize returns how many objects the metadata cache holds
+func (c *CacheConfig) GetObjectSize() int {
+	if c.ObjectSize <= 0 {
+		return 10000
+	}
+	return c.ObjectSize
+}
+
+// GetObjectTTL returns how long a cached object is served before it is re-read
+func (c *CacheConfig) GetObjectTTL() time.Duration {
+	if c.ObjectTTL <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(c.ObjectTTL) * time.Second
+}
+
+// GetFlushInterval returns how long reads are buffered before being written
+func (a *AccessStatsConfig) GetFlushInterval() time.Duration {
+	if a.FlushInterval <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(a.FlushInterval) * time.Second
+}
+
+// GetMaxPending returns how many objects may have buffered reads before an early write
+func (a *AccessStatsConfig) GetMaxPending() int {
+	if a.MaxPending <= 0 {
+		return 10000
+	}
+	return a.MaxPending
+}
+
+// GetPDFTimeout returns how long one PDF conversion may run
+func (e *ExportConfig) GetPDFTimeout() time.Duration {
+	if e.PDFTimeout <= 0 {
+		return 120 * time.Second
+	}
+	return time.Duration(e.PDFTimeout) * time.Second
+}
+
+// GetJobTTL returns how long finished export jobs are kept
+func (e *ExportConfig) GetJobTTL() time.Duration {
+	if e.JobTTL <= 0 {
+		return 600 * time.Second
+	}
+	return time.Duration(e.JobTTL) * time.Second
+}
+
+// GetMaxRunningJobs returns how many export jobs may convert at once
+func (e *ExportConfig) GetMaxRunningJobs() int {
+	if e.MaxRunningJobs <= 0 {
+		return 2
+	}
+	return e.MaxRunningJobs
+}
+
+// GetDeletionGracePeriod returns how long a deleted account is kept disabled
+// before it is erased, or 0 when accounts are erased right away
+func (a *AccountConfig) GetDeletionGracePeriod() time.Duration {
+	switch {
+	case a.DeletionGracePeriod < 0:
+		return 0
+	case a.DeletionGracePeriod == 0:
+		return 30 * 24 * time.Hour
+	}
+	return time.Duration(a.DeletionGracePeriod) * 24 * time.Hour
+}
+
+// GetSharedObjects returns what erasing an account does with objects shared
+// with other users: "transfer" them to a collaborator, or "delete" them
+func (a *AccountConfig) GetSharedObjects() string {
+	if strings.ToLower(a.SharedObjects) == "delete" {
+		return "delete"
+	}
+	return "transfer"
+}
+
+// GetType returns the storage type of the backend, or "" when unsupported
+func (b *ExportBackendConfig) GetType() string {
+	switch b.Type {
+	case "", "local":
+		return "local"
+	default:
+		return ""
+	}
+}
+
+// GetIdleTimeout returns how long a connection may go without messages
+// before it is closed, or 0 when idle connections are kept open
+func (w *WebSocketConfig) GetIdleTimeout() time.Duration {
+	if w == nil || w.IdleTimeout <= 0 {
+		return 0
+	}
+	return time.Duration(w.IdleTimeout) * time.Second
+}
+
 // GetAddress returns the server address
 func (s *ServerConfig) GetAddress() string {
 	return fmt.Sprintf("%s:%d", s.Host, s.Port)
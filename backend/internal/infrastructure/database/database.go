@@ -1,24 +1,48 @@
 package database
 
 import (
+	"context"
+	"expvar"
 	"fmt"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	gormlogger "gorm.io/gorm/logger"
 
 	"github.com/leondli/workspace/internal/infrastructure/config"
 )
 
-var db *gorm.DB
+var (
+	db          *gorm.DB
+	pingTimeout time.Duration
+)
+
+// PoolStats is a snapshot of the connection pool
+type PoolStats struct {
+	MaxOpen           int   `json:"max_open"`
+	Open              int   `json:"open"`
+	InUse             int   `json:"in_use"`
+	Idle              int   `json:"idle"`
+	WaitCount         int64 `json:"wait_count"`           // Connections waited for because the pool was full
+	WaitDurationMs    int64 `json:"wait_duration_ms"`     // Total time spent waiting
+	MaxIdleClosed     int64 `json:"max_idle_closed"`      // Closed because of max_idle_conns
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"` // Closed because of conn_max_idle_time
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`  // Closed because of conn_max_lifetime
+}
+
+func init() {
+	expvar.Publish("db_pool", expvar.Func(func() any { return Stats() }))
+}
 
 // Init initializes the database connection
 func Init(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 	dsn := cfg.GetDSN()
 
 	gormConfig := &gorm.Config{
-		Logger: gormlogger.Default.LogMode(gormlogger.Info),
+		Logger: newQueryLogger(cfg),
+		// Translate driver errors (e.g. unique violations) into gorm.ErrDuplicatedKey
+		TranslateError: true,
 	}
 
 	var err error
@@ -33,14 +57,25 @@ func Init(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 	}
 
 	// Configure connection pool
-	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
-	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.GetMaxIdleConns())
+	sqlDB.SetMaxOpenConns(cfg.GetMaxOpenConns())
 	sqlDB.SetConnMaxLifetime(cfg.GetConnMaxLifetime())
+	sqlDB.SetConnMaxIdleTime(cfg.GetConnMaxIdleTime())
+
+	// gorm.Open does not always connect, so fail startup here rather than on the first request
+	pingTimeout = cfg.GetPingTimeout()
+	if err := Ping(context.Background()); err != nil {
+		sqlDB.Close()
+		db = nil
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
 
 	log.Info().
 		Str("host", cfg.Host).
 		Int("port", cfg.Port).
 		Str("dbname", cfg.DBName).
+		Int("max_open_conns", cfg.GetMaxOpenConns()).
+		Int("max_idle_conns", cfg.GetMaxIdleConns()).
 		Msg("Database connected successfully")
 
 	return db, nil
@@ -51,6 +86,43 @@ func GetDB() *gorm.DB {
 	return db
 }
 
+// Ping checks that the database is reachable, within the configured ping timeout
+func Ping(ctx context.Context) error {
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
+
+// Stats returns the current connection pool statistics, zero before Init
+func Stats() PoolStats {
+	if db == nil {
+		return PoolStats{}
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return PoolStats{}
+	}
+	s := sqlDB.Stats()
+	return PoolStats{
+		MaxOpen:           s.MaxOpenConnections,
+		Open:              s.OpenConnections,
+		InUse:             s.InUse,
+		Idle:              s.Idle,
+		WaitCount:         s.WaitCount,
+		WaitDurationMs:    s.WaitDuration.Milliseconds(),
+		MaxIdleClosed:     s.MaxIdleClosed,
+		MaxIdleTimeClosed: s.MaxIdleTimeClosed,
+		MaxLifetimeClosed: s.MaxLifetimeClosed,
+	}
+}
+
 // Close closes the database connection
 func Close() error {
 	if db != nil {
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/leondli/workspace/internal/infrastructure/config"
+)
+
+// unfilledPlaceholder is a numbered placeholder as the Postgres dialect
+// renders it when its parameter was filtered out: $1 becomes $1$
+var unfilledPlaceholder = regexp.MustCompile(`\$(\d+)\$`)
+
+// queryLogger sends GORM's logs to zerolog. Failed queries are logged at
+// error level, slow ones at warn and, when log_queries is on, every query at
+// debug. Bound parameters are left out of the logged SQL, so values such as
+// password hashes and tokens never reach the logs.
+type queryLogger struct {
+	cfg *config.DatabaseConfig
+}
+
+func newQueryLogger(cfg *config.DatabaseConfig) gormlogger.Interface {
+	return &queryLogger{cfg: cfg}
+}
+
+// LogMode is a no-op; what is logged comes from the database config
+func (l *queryLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+func (l *queryLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	log.Info().Msgf(msg, args...)
+}
+
+func (l *queryLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	log.Warn().Msgf(msg, args...)
+}
+
+func (l *queryLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	log.Error().Msgf(msg, args...)
+}
+
+// Trace logs a finished query
+func (l *queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	threshold := l.cfg.GetSlowQueryThreshold()
+
+	switch {
+	// Repositories turn a missing record into a not-found error
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := queryOf(fc)
+		log.Error().Err(err).Str("sql", sql).Int64("rows", rows).Dur("duration", elapsed).Msg("Query failed")
+	case threshold > 0 && elapsed > threshold:
+		sql, rows := queryOf(fc)
+		log.Warn().Str("sql", sql).Int64("rows", rows).Dur("duration", elapsed).
+			Str("threshold", threshold.String()).Msg("Slow query")
+	case l.cfg.LogQueries:
+		sql, rows := queryOf(fc)
+		log.Debug().Str("sql", sql).Int64("rows", rows).Dur("duration", elapsed).Msg("Query")
+	}
+}
+
+// queryOf returns a traced query's SQL, as sent with placeholders, and rows
+func queryOf(fc func() (string, int64)) (string, int64) {
+	sql, rows := fc()
+	return unfilledPlaceholder.ReplaceAllString(sql, "$$$1"), rows
+}
+
+// ParamsFilter drops bound parameters so logged SQL keeps its placeholders
+func (l *queryLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
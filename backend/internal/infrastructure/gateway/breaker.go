@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"errors"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/infrastructure/config"
+)
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// ErrCircuitOpen is returned without contacting the gateway while the circuit
+// breaker is open
+var ErrCircuitOpen = errors.New("gateway circuit breaker is open")
+
+// Runtime metrics for the gateway circuit breaker
+var (
+	circuitStateVar = expvar.NewString("gateway_circuit_state")
+	circuitOpensVar = expvar.NewInt("gateway_circuit_opens")
+)
+
+// circuitBreaker stops sending requests to a failing gateway. After the
+// configured number of consecutive failures it opens and fails requests fast
+// for a cooldown, then half-opens and lets a single trial request through:
+// success closes it again, failure reopens it.
+type circuitBreaker struct {
+	cfg *config.GatewayConfig
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	trial    bool // Whether the half-open trial request is in flight
+}
+
+func newCircuitBreaker(cfg *config.GatewayConfig) *circuitBreaker {
+	circuitStateVar.Set(CircuitClosed)
+	return &circuitBreaker{cfg: cfg, state: CircuitClosed}
+}
+
+// State returns the current breaker state
+func (b *circuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// allow returns ErrCircuitOpen if a request must not be sent now. Every
+// allowed request must be followed by success, failure or release.
+func (b *circuitBreaker) allow() error {
+	if b.cfg.GetBreakerThreshold() == 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.cfg.GetBreakerCooldown() {
+			return ErrCircuitOpen
+		}
+		b.setState(CircuitHalfOpen)
+		b.trial = true
+	case CircuitHalfOpen:
+		if b.trial {
+			return ErrCircuitOpen
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// success records a request the gateway answered
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+	if b.state != CircuitClosed {
+		log.Info().Msg("Gateway circuit breaker closed")
+		b.setState(CircuitClosed)
+	}
+}
+
+// failure records a request that failed because of the gateway
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	threshold := b.cfg.GetBreakerThreshold()
+	if b.state == CircuitHalfOpen || (threshold > 0 && b.failures >= threshold) {
+		b.open()
+	}
+}
+
+// release records a request whose outcome says nothing about the gateway,
+// e.g. one cancelled by the caller, freeing the half-open trial slot
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+func (b *circuitBreaker) open() {
+	if b.state != CircuitOpen {
+		log.Warn().Int("failures", b.failures).Dur("cooldown", b.cfg.GetBreakerCooldown()).Msg("Gateway circuit breaker opened")
+		circuitOpensVar.Add(1)
+	}
+	b.openedAt = time.Now()
+	b.trial = false
+	b.setState(CircuitOpen)
+}
+
+func (b *circuitBreaker) setState(state string) {
+	b.state = state
+	circuitStateVar.Set(state)
+}
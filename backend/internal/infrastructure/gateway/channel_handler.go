@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog/log"
 )
 
@@ -125,7 +126,7 @@ func (ch *ChannelHandler) readLoop() {
 		case <-ch.stopChan:
 			return
 		default:
-			data, err := ch.wsConn.ReadMessage()
+			msgType, data, err := ch.wsConn.ReadMessage()
 			if err != nil {
 				if ch.wsConn.IsClosed() {
 					return
@@ -134,14 +135,20 @@ func (ch *ChannelHandler) readLoop() {
 				continue
 			}
 			
-			var msg Message
-			if err := json.Unmarshal(data, &msg); err != nil {
+			var msg *Message
+			if msgType == websocket.BinaryMessage {
+				msg, err = UnmarshalBinaryMessage(data)
+			} else {
+				msg = &Message{}
+				err = json.Unmarshal(data, msg)
+			}
+			if err != nil {
 				log.Debug().Err(err).Str("kernel_id", ch.kernelID).Msg("Failed to parse message")
 				continue
 			}
 			
 			// Route message to appropriate channel
-			ch.routeMessage(&msg)
+			ch.routeMessage(msg)
 		}
 	}
 }
@@ -362,24 +369,34 @@ func (ch *ChannelHandler) sendMessage(msg *Message) error {
 // Request Methods
 // ============================================================================
 
-// Execute sends an execute_request and returns immediately
-func (ch *ChannelHandler) Execute(code string, silent, storeHistory, allowStdin, stopOnError bool) (string, error) {
+// Execute sends an execute_request and returns immediately. metadata, if
+// non-nil, becomes the request's message metadata.
+func (ch *ChannelHandler) Execute(code string, silent, storeHistory, allowStdin, stopOnError bool, userExpressions map[string]string, metadata map[string]interface{}) (string, error) {
 	ch.executionMu.Lock()
 	ch.executionCount++
 	ch.executionMu.Unlock()
 	
+	expressions := make(map[string]interface{}, len(userExpressions))
+	for name, expr := range userExpressions {
+		expressions[name] = expr
+	}
+	
 	content := &ExecuteRequestContent{
 		Code:            code,
 		Silent:          silent,
 		StoreHistory:    storeHistory,
-		UserExpressions: make(map[string]interface{}),
+		UserExpressions: expressions,
 		AllowStdin:      allowStdin,
 		StopOnError:     stopOnError,
 	}
 	
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+
 	msg := &Message{
 		Header:   NewHeader(MsgTypeExecuteRequest, ch.username, ch.sessionID),
-		Metadata: make(map[string]interface{}),
+		Metadata: metadata,
 		Content:  content,
 		Channel:  ChannelShell,
 	}
@@ -393,7 +410,7 @@ func (ch *ChannelHandler) Execute(code string, silent, storeHistory, allowStdin,
 
 // ExecuteSync sends an execute_request and waits for the reply
 func (ch *ChannelHandler) ExecuteSync(ctx context.Context, code string, silent, storeHistory bool) (*Message, error) {
-	msgID, err := ch.Execute(code, silent, storeHistory, false, true)
+	msgID, err := ch.Execute(code, silent, storeHistory, false, true, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -66,6 +66,7 @@ type Client struct {
 	customHeaders map[string]string
 	mu            sync.RWMutex
 	config        *config.GatewayConfig
+	breaker       *circuitBreaker
 }
 
 // NewClient creates a new Gateway client
@@ -148,6 +149,7 @@ func NewClient(cfg *config.GatewayConfig) (*Client, error) {
 		wsDialer:      wsDialer,
 		customHeaders: customHeaders,
 		config:        cfg,
+		breaker:       newCircuitBreaker(cfg),
 	}
 
 	return client, nil
@@ -193,14 +195,31 @@ func (c *Client) buildRequest(ctx context.Context, method, path string, body int
 	return req, nil
 }
 
-// doRequest executes an HTTP request and returns the response body
+// doRequest executes an HTTP request and returns the response body. Requests
+// fail fast with ErrCircuitOpen while the circuit breaker is open; transport
+// errors and 5xx responses count as breaker failures.
 func (c *Client) doRequest(req *http.Request) ([]byte, int, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, 0, err
+	}
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		if req.Context().Err() != nil {
+			c.breaker.release()
+		} else {
+			c.breaker.failure()
+		}
 		return nil, 0, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= http.StatusInternalServerError {
+		c.breaker.failure()
+	} else {
+		c.breaker.success()
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, resp.StatusCode, fmt.Errorf("failed to read response body: %w", err)
@@ -209,6 +228,11 @@ func (c *Client) doRequest(req *http.Request) ([]byte, int, error) {
 	return body, resp.StatusCode, nil
 }
 
+// CircuitState returns the state of the client's circuit breaker
+func (c *Client) CircuitState() string {
+	return c.breaker.State()
+}
+
 // GetKernelSpecs retrieves available kernel specifications from the gateway
 func (c *Client) GetKernelSpecs(ctx context.Context) (map[string]*KernelSpec, error) {
 	req, err := c.buildRequest(ctx, http.MethodGet, "/api/kernelspecs", nil)
@@ -216,7 +240,7 @@ func (c *Client) GetKernelSpecs(ctx context.Context) (map[string]*KernelSpec, er
 		return nil, err
 	}
 
-	body, statusCode, err := c.doRequest(req)
+	body, statusCode, err := c.doRequestWithRetry(req, true)
 	if err != nil {
 		return nil, err
 	}
@@ -249,7 +273,7 @@ func (c *Client) ListKernels(ctx context.Context) ([]*Kernel, error) {
 		return nil, err
 	}
 
-	body, statusCode, err := c.doRequest(req)
+	body, statusCode, err := c.doRequestWithRetry(req, true)
 	if err != nil {
 		return nil, err
 	}
@@ -289,7 +313,9 @@ func (c *Client) StartKernel(ctx context.Context, specName string, env map[strin
 		return nil, err
 	}
 
-	body, statusCode, err := c.doRequest(req)
+	// Starting a kernel is not idempotent: only retry when the gateway cannot
+	// have seen the request or answered with a transient error
+	body, statusCode, err := c.doRequestWithRetry(req, false)
 	if err != nil {
 		return nil, err
 	}
@@ -317,13 +343,13 @@ func (c *Client) GetKernel(ctx context.Context, kernelID string) (*Kernel, error
 		return nil, err
 	}
 
-	body, statusCode, err := c.doRequest(req)
+	body, statusCode, err := c.doRequestWithRetry(req, true)
 	if err != nil {
 		return nil, err
 	}
 
 	if statusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("kernel not found: %s", kernelID)
+		return nil, fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	if statusCode != http.StatusOK {
@@ -345,13 +371,13 @@ func (c *Client) DeleteKernel(ctx context.Context, kernelID string) error {
 		return err
 	}
 
-	_, statusCode, err := c.doRequest(req)
+	_, statusCode, err := c.doRequestWithRetry(req, true)
 	if err != nil {
 		return err
 	}
 
 	if statusCode == http.StatusNotFound {
-		return fmt.Errorf("kernel not found: %s", kernelID)
+		return fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	if statusCode != http.StatusNoContent && statusCode != http.StatusOK {
@@ -374,7 +400,7 @@ func (c *Client) InterruptKernel(ctx context.Context, kernelID string) error {
 	}
 
 	if statusCode == http.StatusNotFound {
-		return fmt.Errorf("kernel not found: %s", kernelID)
+		return fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	if statusCode != http.StatusNoContent && statusCode != http.StatusOK {
@@ -397,7 +423,7 @@ func (c *Client) RestartKernel(ctx context.Context, kernelID string) (*Kernel, e
 	}
 
 	if statusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("kernel not found: %s", kernelID)
+		return nil, fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	if statusCode != http.StatusOK {
@@ -512,13 +538,24 @@ func (ws *WebSocketConnection) SendMessage(msg interface{}) error {
 		return fmt.Errorf("WebSocket connection is closed")
 	}
 
+	// Messages carrying binary buffers use Jupyter's binary framing
+	if m, ok := msg.(*Message); ok && len(m.Buffers) > 0 {
+		buffers := m.Buffers
+		clone := *m
+		clone.Buffers = nil
+		data, err := json.Marshal(&clone)
+		if err != nil {
+			return err
+		}
+		return ws.conn.WriteMessage(websocket.BinaryMessage, SerializeBinaryMessage(data, buffers))
+	}
+
 	return ws.conn.WriteJSON(msg)
 }
 
-// ReadMessage reads a message from the kernel
-func (ws *WebSocketConnection) ReadMessage() ([]byte, error) {
-	_, data, err := ws.conn.ReadMessage()
-	return data, err
+// ReadMessage reads a message from the kernel, returning the WebSocket frame type
+func (ws *WebSocketConnection) ReadMessage() (int, []byte, error) {
+	return ws.conn.ReadMessage()
 }
 
 // Close closes the WebSocket connection
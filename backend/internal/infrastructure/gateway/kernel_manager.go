@@ -3,8 +3,10 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -24,11 +26,29 @@ type GatewayKernel struct {
 	wsConn          *WebSocketConnection
 	channelHandler  *ChannelHandler
 	outputChannels  map[string]chan *KernelOutputMessage
+	droppedCounts   map[string]*atomic.Uint64
 	channelMu       sync.RWMutex
 	stopChan        chan struct{}
 	client          *Client
 }
 
+// DroppedMessages returns the non-zero drop counts per output session
+func (gk *GatewayKernel) DroppedMessages() map[string]uint64 {
+	gk.channelMu.RLock()
+	defer gk.channelMu.RUnlock()
+
+	var counts map[string]uint64
+	for sessionID, counter := range gk.droppedCounts {
+		if n := counter.Load(); n > 0 {
+			if counts == nil {
+				counts = make(map[string]uint64)
+			}
+			counts[sessionID] = n
+		}
+	}
+	return counts
+}
+
 // KernelOutputMessage represents an output message from the kernel
 type KernelOutputMessage struct {
 	MsgID    string                 `json:"msg_id"`
@@ -37,6 +57,25 @@ type KernelOutputMessage struct {
 	Content  map[string]interface{} `json:"content"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 	Channel  string                 `json:"channel,omitempty"`
+	Buffers  [][]byte               `json:"-"`
+}
+
+// ErrKernelNotFound indicates the kernel is unknown to this manager or the gateway
+var ErrKernelNotFound = errors.New("kernel not found")
+
+// LaunchError reports a kernel that the gateway failed to start or that never
+// became ready, with the gateway's response or last observed state as detail
+type LaunchError struct {
+	KernelID string
+	Reason   string
+	Detail   string
+}
+
+func (e *LaunchError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("%s: %s", e.Reason, e.Detail)
+	}
+	return e.Reason
 }
 
 // KernelManager manages gateway kernels
@@ -62,8 +101,11 @@ func (km *KernelManager) GetClient() *Client {
 func (km *KernelManager) StartKernel(ctx context.Context, specName, userID string) (*GatewayKernel, error) {
 	// Start kernel on gateway
 	kernel, err := km.client.StartKernel(ctx, specName, nil)
+	if errors.Is(err, ErrCircuitOpen) {
+		return nil, err
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to start kernel on gateway: %w", err)
+		return nil, &LaunchError{Reason: "failed to start kernel on gateway", Detail: err.Error()}
 	}
 
 	// Wait for kernel to be ready
@@ -79,30 +121,90 @@ func (km *KernelManager) StartKernel(ctx context.Context, specName, userID strin
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
 
+	var lastErr error
+	lastState := kernel.ExecutionState
 	for {
 		select {
 		case <-readyCtx.Done():
-			// Timeout, but kernel might still work
-			log.Warn().Str("kernel_id", kernel.ID).Msg("Kernel launch timeout, continuing anyway")
-			goto connect
+			// Report the last gateway error or state so the client learns why
+			detail := "last state: " + lastState
+			if lastErr != nil {
+				detail = lastErr.Error()
+			}
+			log.Warn().Str("kernel_id", kernel.ID).Str("detail", detail).Msg("Kernel launch timeout")
+			_ = km.client.DeleteKernel(context.Background(), kernel.ID)
+			return nil, &LaunchError{
+				KernelID: kernel.ID,
+				Reason:   fmt.Sprintf("kernel not ready after %ds", launchTimeout),
+				Detail:   detail,
+			}
 		case <-ticker.C:
 			k, err := km.client.GetKernel(ctx, kernel.ID)
 			if err != nil {
 				log.Debug().Err(err).Str("kernel_id", kernel.ID).Msg("Failed to get kernel status")
+				lastErr = err
 				continue
 			}
+			lastErr = nil
+			lastState = k.ExecutionState
 			if k.ExecutionState == "idle" || k.ExecutionState == "busy" {
 				goto connect
 			}
+			if k.ExecutionState == "dead" {
+				_ = km.client.DeleteKernel(context.Background(), kernel.ID)
+				return nil, &LaunchError{KernelID: kernel.ID, Reason: "kernel died during startup"}
+			}
 		}
 	}
 
 connect:
-	// Connect WebSocket to kernel channels
-	wsConn, err := km.client.ConnectWebSocket(ctx, kernel.ID)
+	gk, err := km.attach(ctx, kernel, userID)
 	if err != nil {
 		// Clean up the kernel if WebSocket connection fails
 		_ = km.client.DeleteKernel(ctx, kernel.ID)
+		return nil, err
+	}
+
+	log.Info().
+		Str("kernel_id", kernel.ID).
+		Str("name", kernel.Name).
+		Str("user_id", userID).
+		Msg("Gateway kernel started successfully")
+
+	return gk, nil
+}
+
+// AttachKernel reconnects to a kernel that is already running on the gateway,
+// e.g. after a server restart, and registers it for the given user
+func (km *KernelManager) AttachKernel(ctx context.Context, kernelID, userID string) (*GatewayKernel, error) {
+	if gk, exists := km.GetKernel(kernelID); exists {
+		return gk, nil
+	}
+
+	kernel, err := km.client.GetKernel(ctx, kernelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kernel from gateway: %w", err)
+	}
+
+	gk, err := km.attach(ctx, kernel, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("kernel_id", kernel.ID).
+		Str("name", kernel.Name).
+		Str("user_id", userID).
+		Msg("Gateway kernel reattached")
+
+	return gk, nil
+}
+
+// attach connects the kernel channels WebSocket and starts forwarding messages
+func (km *KernelManager) attach(ctx context.Context, kernel *Kernel, userID string) (*GatewayKernel, error) {
+	// Connect WebSocket to kernel channels
+	wsConn, err := km.client.ConnectWebSocket(ctx, kernel.ID)
+	if err != nil {
 		return nil, fmt.Errorf("failed to connect WebSocket: %w", err)
 	}
 
@@ -118,6 +220,7 @@ connect:
 		SessionID:      sessionID,
 		wsConn:         wsConn,
 		outputChannels: make(map[string]chan *KernelOutputMessage),
+		droppedCounts:  make(map[string]*atomic.Uint64),
 		stopChan:       make(chan struct{}),
 		client:         km.client,
 	}
@@ -131,12 +234,6 @@ connect:
 	// Start reading messages from channel handler and broadcasting
 	go km.forwardChannelMessages(gk)
 
-	log.Info().
-		Str("kernel_id", kernel.ID).
-		Str("name", kernel.Name).
-		Str("user_id", userID).
-		Msg("Gateway kernel started successfully")
-
 	return gk, nil
 }
 
@@ -197,25 +294,49 @@ func (km *KernelManager) broadcastMessage(gk *GatewayKernel, msg *Message) {
 		Content:  contentMap,
 		Metadata: msg.Metadata,
 		Channel:  string(msg.Channel),
+		Buffers:  msg.Buffers,
 	}
 
 	// Broadcast to all registered channels
 	gk.channelMu.RLock()
-	for _, ch := range gk.outputChannels {
+	for sessionID, ch := range gk.outputChannels {
 		select {
 		case ch <- outputMsg:
 		default:
-			// Channel full, skip
+			// Channel full, drop and count it
+			dropped := gk.droppedCounts[sessionID].Add(1)
+			if dropped == 1 || dropped%100 == 0 {
+				log.Warn().
+					Str("kernel_id", gk.ID).
+					Str("session_id", sessionID).
+					Uint64("dropped", dropped).
+					Msg("Kernel output buffer full, dropping messages")
+			}
 		}
 	}
 	gk.channelMu.RUnlock()
 }
 
+// SetExecutionState records a state the kernel did not report itself, e.g.
+// after a failed liveness probe, and broadcasts it as a status message
+func (km *KernelManager) SetExecutionState(kernelID, state string) error {
+	value, exists := km.kernels.Load(kernelID)
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
+	}
+
+	gk := value.(*GatewayKernel)
+	msg := NewMessage(MsgTypeStatus, map[string]interface{}{"execution_state": state}, gk.UserID, gk.SessionID)
+	msg.Channel = ChannelIOPub
+	km.broadcastMessage(gk, msg)
+	return nil
+}
+
 // StopKernel stops a kernel
 func (km *KernelManager) StopKernel(ctx context.Context, kernelID string) error {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return fmt.Errorf("kernel not found: %s", kernelID)
+		return fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -255,7 +376,7 @@ func (km *KernelManager) StopKernel(ctx context.Context, kernelID string) error
 func (km *KernelManager) RestartKernel(ctx context.Context, kernelID string) error {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return fmt.Errorf("kernel not found: %s", kernelID)
+		return fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -306,7 +427,7 @@ func (km *KernelManager) RestartKernel(ctx context.Context, kernelID string) err
 func (km *KernelManager) InterruptKernel(ctx context.Context, kernelID string) error {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return fmt.Errorf("kernel not found: %s", kernelID)
+		return fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -344,6 +465,16 @@ func (km *KernelManager) ListKernels(userID string) []*GatewayKernel {
 	return kernels
 }
 
+// ListAllKernels lists the kernels of all users
+func (km *KernelManager) ListAllKernels() []*GatewayKernel {
+	var kernels []*GatewayKernel
+	km.kernels.Range(func(key, value interface{}) bool {
+		kernels = append(kernels, value.(*GatewayKernel))
+		return true
+	})
+	return kernels
+}
+
 // RegisterOutputChannel registers a channel to receive kernel output
 func (km *KernelManager) RegisterOutputChannel(kernelID, sessionID string, ch chan *KernelOutputMessage) {
 	value, exists := km.kernels.Load(kernelID)
@@ -354,6 +485,7 @@ func (km *KernelManager) RegisterOutputChannel(kernelID, sessionID string, ch ch
 	gk := value.(*GatewayKernel)
 	gk.channelMu.Lock()
 	gk.outputChannels[sessionID] = ch
+	gk.droppedCounts[sessionID] = &atomic.Uint64{}
 	gk.channelMu.Unlock()
 }
 
@@ -367,6 +499,7 @@ func (km *KernelManager) UnregisterOutputChannel(kernelID, sessionID string) {
 	gk := value.(*GatewayKernel)
 	gk.channelMu.Lock()
 	delete(gk.outputChannels, sessionID)
+	delete(gk.droppedCounts, sessionID)
 	gk.channelMu.Unlock()
 }
 
@@ -374,11 +507,13 @@ func (km *KernelManager) UnregisterOutputChannel(kernelID, sessionID string) {
 // Execution Methods using Channel Handler
 // ============================================================================
 
-// ExecuteCode executes code on a kernel
-func (km *KernelManager) ExecuteCode(ctx context.Context, kernelID string, code string, msgID string, silent bool, storeHistory bool) error {
+// ExecuteCode executes code on a kernel. A positive lineOffset is passed as
+// "line_offset" request metadata; tracebacks only honour it when the kernel
+// supports that key.
+func (km *KernelManager) ExecuteCode(ctx context.Context, kernelID string, code string, msgID string, silent bool, storeHistory bool, stopOnError bool, userExpressions map[string]string, lineOffset int) error {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return fmt.Errorf("kernel not found: %s", kernelID)
+		return fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -391,8 +526,13 @@ func (km *KernelManager) ExecuteCode(ctx context.Context, kernelID string, code
 		return fmt.Errorf("channel handler not initialized")
 	}
 
+	var metadata map[string]interface{}
+	if lineOffset > 0 {
+		metadata = map[string]interface{}{"line_offset": lineOffset}
+	}
+
 	// Use channel handler to execute
-	_, err := gk.channelHandler.Execute(code, silent, storeHistory, false, true)
+	_, err := gk.channelHandler.Execute(code, silent, storeHistory, false, stopOnError, userExpressions, metadata)
 	return err
 }
 
@@ -400,7 +540,7 @@ func (km *KernelManager) ExecuteCode(ctx context.Context, kernelID string, code
 func (km *KernelManager) ExecuteSync(ctx context.Context, kernelID string, code string, silent, storeHistory bool) (*Message, error) {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return nil, fmt.Errorf("kernel not found: %s", kernelID)
+		return nil, fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -416,7 +556,7 @@ func (km *KernelManager) ExecuteSync(ctx context.Context, kernelID string, code
 func (km *KernelManager) Complete(ctx context.Context, kernelID string, code string, cursorPos int) (string, error) {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return "", fmt.Errorf("kernel not found: %s", kernelID)
+		return "", fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -432,7 +572,7 @@ func (km *KernelManager) Complete(ctx context.Context, kernelID string, code str
 func (km *KernelManager) CompleteSync(ctx context.Context, kernelID string, code string, cursorPos int) (*Message, error) {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return nil, fmt.Errorf("kernel not found: %s", kernelID)
+		return nil, fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -448,7 +588,7 @@ func (km *KernelManager) CompleteSync(ctx context.Context, kernelID string, code
 func (km *KernelManager) Inspect(ctx context.Context, kernelID string, code string, cursorPos int, detailLevel int) (string, error) {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return "", fmt.Errorf("kernel not found: %s", kernelID)
+		return "", fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -464,7 +604,7 @@ func (km *KernelManager) Inspect(ctx context.Context, kernelID string, code stri
 func (km *KernelManager) InspectSync(ctx context.Context, kernelID string, code string, cursorPos int, detailLevel int) (*Message, error) {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return nil, fmt.Errorf("kernel not found: %s", kernelID)
+		return nil, fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -480,7 +620,7 @@ func (km *KernelManager) InspectSync(ctx context.Context, kernelID string, code
 func (km *KernelManager) IsComplete(ctx context.Context, kernelID string, code string) (string, error) {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return "", fmt.Errorf("kernel not found: %s", kernelID)
+		return "", fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -496,7 +636,7 @@ func (km *KernelManager) IsComplete(ctx context.Context, kernelID string, code s
 func (km *KernelManager) IsCompleteSync(ctx context.Context, kernelID string, code string) (*Message, error) {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return nil, fmt.Errorf("kernel not found: %s", kernelID)
+		return nil, fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -512,7 +652,7 @@ func (km *KernelManager) IsCompleteSync(ctx context.Context, kernelID string, co
 func (km *KernelManager) KernelInfo(ctx context.Context, kernelID string) (*Message, error) {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return nil, fmt.Errorf("kernel not found: %s", kernelID)
+		return nil, fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -528,7 +668,7 @@ func (km *KernelManager) KernelInfo(ctx context.Context, kernelID string) (*Mess
 func (km *KernelManager) History(ctx context.Context, kernelID string, output, raw bool, accessType string, n int) (string, error) {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return "", fmt.Errorf("kernel not found: %s", kernelID)
+		return "", fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -544,7 +684,7 @@ func (km *KernelManager) History(ctx context.Context, kernelID string, output, r
 func (km *KernelManager) CommInfo(ctx context.Context, kernelID string, targetName string) (string, error) {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return "", fmt.Errorf("kernel not found: %s", kernelID)
+		return "", fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -560,7 +700,7 @@ func (km *KernelManager) CommInfo(ctx context.Context, kernelID string, targetNa
 func (km *KernelManager) Shutdown(ctx context.Context, kernelID string, restart bool) error {
 	value, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return fmt.Errorf("kernel not found: %s", kernelID)
+		return fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := value.(*GatewayKernel)
@@ -583,7 +723,7 @@ func (km *KernelManager) Shutdown(ctx context.Context, kernelID string, restart
 func (km *KernelManager) InputReply(ctx context.Context, kernelID string, value string) error {
 	v, exists := km.kernels.Load(kernelID)
 	if !exists {
-		return fmt.Errorf("kernel not found: %s", kernelID)
+		return fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
 	}
 
 	gk := v.(*GatewayKernel)
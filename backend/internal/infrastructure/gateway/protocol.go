@@ -1,6 +1,9 @@
 package gateway
 
 import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -523,3 +526,79 @@ const (
 	IsCompleteStatusInvalid    = "invalid"
 	IsCompleteStatusUnknown    = "unknown"
 )
+
+// ============================================================================
+// Binary Framing
+// ============================================================================
+
+// SerializeBinaryMessage packs a JSON message and its buffers into Jupyter's
+// binary WebSocket framing: a big-endian uint32 segment count, one uint32
+// offset per segment, then the JSON followed by each buffer.
+func SerializeBinaryMessage(jsonData []byte, buffers [][]byte) []byte {
+	segments := make([][]byte, 0, len(buffers)+1)
+	segments = append(segments, jsonData)
+	segments = append(segments, buffers...)
+
+	headerSize := 4 * (len(segments) + 1)
+	total := headerSize
+	for _, seg := range segments {
+		total += len(seg)
+	}
+
+	out := make([]byte, headerSize, total)
+	binary.BigEndian.PutUint32(out[0:4], uint32(len(segments)))
+	offset := headerSize
+	for i, seg := range segments {
+		binary.BigEndian.PutUint32(out[4*(i+1):], uint32(offset))
+		offset += len(seg)
+	}
+	for _, seg := range segments {
+		out = append(out, seg...)
+	}
+	return out
+}
+
+// DeserializeBinaryMessage splits a binary WebSocket frame produced by
+// SerializeBinaryMessage into its JSON segment and buffers
+func DeserializeBinaryMessage(data []byte) ([]byte, [][]byte, error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("binary message too short")
+	}
+	count := int(binary.BigEndian.Uint32(data[0:4]))
+	if count < 1 || len(data) < 4*(count+1) {
+		return nil, nil, fmt.Errorf("invalid binary message segment count: %d", count)
+	}
+
+	offsets := make([]int, count+1)
+	for i := 0; i < count; i++ {
+		offsets[i] = int(binary.BigEndian.Uint32(data[4*(i+1):]))
+	}
+	offsets[count] = len(data)
+
+	segments := make([][]byte, count)
+	for i := 0; i < count; i++ {
+		start, end := offsets[i], offsets[i+1]
+		if start < 4*(count+1) || end < start || end > len(data) {
+			return nil, nil, fmt.Errorf("invalid binary message offset for segment %d", i)
+		}
+		segments[i] = data[start:end]
+	}
+	return segments[0], segments[1:], nil
+}
+
+// UnmarshalBinaryMessage decodes a binary WebSocket frame into a Message,
+// attaching the trailing segments as its buffers
+func UnmarshalBinaryMessage(data []byte) (*Message, error) {
+	jsonData, buffers, err := DeserializeBinaryMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	var msg Message
+	if err := json.Unmarshal(jsonData, &msg); err != nil {
+		return nil, err
+	}
+	if len(buffers) > 0 {
+		msg.Buffers = buffers
+	}
+	return &msg, nil
+}
@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// retryableStatus reports whether a status code signals a transient failure of
+// the gateway or a proxy in front of it
+func retryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isDialError reports whether err happened while connecting, i.e. before the
+// request could have reached the gateway
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// doRequestWithRetry executes req like doRequest, retrying connection errors
+// and 502/503/504 responses with exponential backoff. Idempotent requests are
+// retried on any transport error; others only when the connection could not be
+// established, so a request the gateway may have processed is never repeated.
+// Retries stop early when the next delay would pass the context deadline.
+func (c *Client) doRequestWithRetry(req *http.Request, idempotent bool) ([]byte, int, error) {
+	ctx := req.Context()
+	maxAttempts := c.config.GetRetryMaxAttempts()
+
+	for attempt := 1; ; attempt++ {
+		body, statusCode, err := c.doRequest(req)
+
+		retry := false
+		if err != nil {
+			retry = ctx.Err() == nil && !errors.Is(err, ErrCircuitOpen) && (idempotent || isDialError(err))
+		} else {
+			retry = retryableStatus(statusCode)
+		}
+		if !retry || attempt >= maxAttempts {
+			return body, statusCode, err
+		}
+
+		delay := c.retryDelay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return body, statusCode, err
+		}
+
+		log.Debug().
+			Err(err).
+			Int("status", statusCode).
+			Str("method", req.Method).
+			Str("path", req.URL.Path).
+			Int("attempt", attempt).
+			Dur("delay", delay).
+			Msg("Retrying gateway request")
+
+		select {
+		case <-ctx.Done():
+			return nil, 0, fmt.Errorf("request failed: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+
+		if req, err = rewindRequest(req); err != nil {
+			return nil, 0, err
+		}
+	}
+}
+
+// retryDelay returns the backoff before the retry following attempt
+func (c *Client) retryDelay(attempt int) time.Duration {
+	maxDelay := c.config.GetRetryMaxDelay()
+	delay := c.config.GetRetryBaseDelay() << (attempt - 1)
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+
+	if jitter := c.config.GetRetryJitter(); jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * jitter * float64(delay))
+	}
+	return min(delay, maxDelay)
+}
+
+// rewindRequest returns a copy of req with a fresh body for another attempt
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		next.Body = body
+	}
+	return next, nil
+}
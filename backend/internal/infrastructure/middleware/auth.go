@@ -1,11 +1,14 @@
 package middleware
 
 import (
+	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 
+	"github.com/leondli/workspace/internal/infrastructure/config"
 	"github.com/leondli/workspace/pkg/jwt"
 	"github.com/leondli/workspace/pkg/response"
 )
@@ -25,45 +28,101 @@ const (
 	ContextEmail = "email"
 )
 
-// AuthMiddleware creates a JWT authentication middleware
-func AuthMiddleware(jwtManager *jwt.JWTManager) gin.HandlerFunc {
+// AuthMiddleware creates a JWT authentication middleware. The Bearer header
+// takes precedence; when cookie auth is enabled and no header is sent, the
+// access token cookie is used instead and mutating requests must carry a
+// matching CSRF token.
+func AuthMiddleware(jwtManager *jwt.JWTManager, jwtConfig *config.JWTConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var tokenString string
 		authHeader := c.GetHeader(AuthorizationHeader)
-		if authHeader == "" {
+		if authHeader != "" {
+			if !strings.HasPrefix(authHeader, BearerPrefix) {
+				response.Unauthorized(c, "invalid authorization header format")
+				c.Abort()
+				return
+			}
+			tokenString = strings.TrimPrefix(authHeader, BearerPrefix)
+		} else if cookie, err := c.Cookie(AccessTokenCookie); jwtConfig.CookieAuth && err == nil && cookie != "" {
+			if !checkCSRF(c) {
+				return
+			}
+			tokenString = cookie
+		} else {
 			response.Unauthorized(c, "missing authorization header")
 			c.Abort()
 			return
 		}
 
-		if !strings.HasPrefix(authHeader, BearerPrefix) {
-			response.Unauthorized(c, "invalid authorization header format")
-			c.Abort()
+		if !authenticate(c, jwtManager, tokenString) {
 			return
 		}
+		c.Next()
+	}
+}
 
-		tokenString := strings.TrimPrefix(authHeader, BearerPrefix)
-		claims, err := jwtManager.ValidateAccessToken(tokenString)
-		if err != nil {
-			log.Debug().Err(err).Msg("Token validation failed")
-			if err == jwt.ErrExpiredToken {
-				response.Unauthorized(c, "token has expired")
-			} else {
-				response.Unauthorized(c, "invalid token")
-			}
+// WebSocketAuth creates a JWT authentication middleware for WebSocket
+// handshakes, where browsers cannot set headers. Besides the Bearer header,
+// the access token is taken from the token query parameter or, when cookie
+// auth is enabled, from the access token cookie of a same-origin handshake.
+func WebSocketAuth(jwtManager *jwt.JWTManager, jwtConfig *config.JWTConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := c.Query("token")
+		if authHeader := c.GetHeader(AuthorizationHeader); strings.HasPrefix(authHeader, BearerPrefix) {
+			tokenString = strings.TrimPrefix(authHeader, BearerPrefix)
+		} else if tokenString == "" && jwtConfig.CookieAuth && sameOrigin(c.Request) {
+			tokenString, _ = c.Cookie(AccessTokenCookie)
+		}
+		if tokenString == "" {
+			response.Unauthorized(c, "missing access token")
 			c.Abort()
 			return
 		}
 
-		// Set user info in context
-		c.Set(ContextUserID, claims.UserID)
-		c.Set(ContextAppID, claims.AppID)
-		c.Set(ContextUsername, claims.Username)
-		c.Set(ContextEmail, claims.Email)
-
+		if !authenticate(c, jwtManager, tokenString) {
+			return
+		}
 		c.Next()
 	}
 }
 
+// authenticate validates an access token and sets the user info in context,
+// aborting with 401 when the token is not valid
+func authenticate(c *gin.Context, jwtManager *jwt.JWTManager, tokenString string) bool {
+	claims, err := jwtManager.ValidateAccessToken(tokenString)
+	if err != nil {
+		log.Debug().Err(err).Msg("Token validation failed")
+		if err == jwt.ErrExpiredToken {
+			response.Unauthorized(c, "token has expired")
+		} else if err == jwt.ErrRevokedToken {
+			response.Unauthorized(c, "token has been revoked")
+		} else {
+			response.Unauthorized(c, "invalid token")
+		}
+		c.Abort()
+		return false
+	}
+
+	// Set user info in context
+	c.Set(ContextUserID, claims.UserID)
+	c.Set(ContextAppID, claims.AppID)
+	c.Set(ContextUsername, claims.Username)
+	c.Set(ContextEmail, claims.Email)
+	return true
+}
+
+// sameOrigin reports whether a request carries no Origin header or one for
+// the host it was sent to. Browsers send cookies on cross-site WebSocket
+// handshakes, so cookie auth is only accepted for these.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
 // GetUserID retrieves the user ID from context
 func GetUserID(c *gin.Context) string {
 	userID, exists := c.Get(ContextUserID)
@@ -99,3 +158,17 @@ func GetEmail(c *gin.Context) string {
 	}
 	return email.(string)
 }
+
+// AdminMiddleware restricts access to users listed as administrators.
+// It must run after AuthMiddleware so the user's email is in context.
+func AdminMiddleware(maintenanceConfig *config.MaintenanceConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !maintenanceConfig.IsAdmin(GetEmail(c)) {
+			response.Forbidden(c, "admin access required")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	"github.com/leondli/workspace/pkg/response"
+)
+
+// readOnlyExemptPaths accept writes in read-only mode so users can still sign
+// in and out. Registration and password changes stay blocked.
+var readOnlyExemptPaths = map[string]bool{
+	"/api/v1/auth/login":   true,
+	"/api/v1/auth/refresh": true,
+	"/api/v1/auth/logout":  true,
+}
+
+// readOnlyExemptPrefixes accept writes in read-only mode: admin endpoints, so
+// read-only mode can be turned off
+var readOnlyExemptPrefixes = []string{"/api/v1/admin/"}
+
+// ReadOnly rejects mutating requests with 503 and the maintenance message
+// while maintenance is enabled in read-only mode. Reads are served as usual.
+func ReadOnly(maintenanceConfig *config.MaintenanceConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		mode := maintenanceConfig.GetMode()
+		if !mode.ReadOnly {
+			c.Next()
+			return
+		}
+		if readOnlyExemptPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+		for _, prefix := range readOnlyExemptPrefixes {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		message := mode.Message
+		if message == "" {
+			message = "the service is in read-only mode for maintenance"
+		}
+		response.Error(c, http.StatusServiceUnavailable, response.CodeUnavailable, message)
+		c.Abort()
+	}
+}
@@ -1,6 +1,10 @@
 package middleware
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"time"
 
@@ -111,6 +115,80 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
+// BodySizeLimit creates a middleware that rejects request bodies larger than
+// limit() bytes with 413. The body is buffered, so use UploadSizeLimit on
+// upload routes instead.
+func BodySizeLimit(limit func() int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		maxBytes := limit()
+		if c.Request.Body == nil || maxBytes <= 0 {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			response.PayloadTooLarge(c, fmt.Sprintf("request body exceeds %d bytes", maxBytes))
+			c.Abort()
+			return
+		}
+
+		// Buffer the body so oversized chunked requests are rejected up front
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				response.PayloadTooLarge(c, fmt.Sprintf("request body exceeds %d bytes", maxBytes))
+			} else {
+				response.BadRequest(c, "failed to read request body")
+			}
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		c.Next()
+	}
+}
+
+// UploadSizeLimit creates a middleware for upload routes that rejects request
+// bodies declared larger than limit() bytes with 413. The body is streamed to
+// the handler, which fails to read past the limit.
+func UploadSizeLimit(limit func() int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		maxBytes := limit()
+		if c.Request.Body == nil || maxBytes <= 0 {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			response.PayloadTooLarge(c, fmt.Sprintf("request body exceeds %d bytes", maxBytes))
+			c.Abort()
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+
+		c.Next()
+	}
+}
+
+// LongLived creates a middleware that clears the server's read and write
+// deadlines for the request, for WebSocket and streaming routes that outlive
+// the configured timeouts. The deadlines also stay cleared on hijacked
+// connections.
+func LongLived() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rc := http.NewResponseController(c.Writer)
+		if err := rc.SetReadDeadline(time.Time{}); err != nil {
+			log.Warn().Err(err).Str("path", c.Request.URL.Path).Msg("Failed to clear read deadline")
+		}
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			log.Warn().Err(err).Str("path", c.Request.URL.Path).Msg("Failed to clear write deadline")
+		}
+		c.Next()
+	}
+}
+
 // GetRequestID retrieves the request ID from context
 func GetRequestID(c *gin.Context) string {
 	return response.GetRequestID(c)
@@ -3,7 +3,6 @@ package server
 import (
 	"context"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
@@ -46,11 +45,12 @@ func (s *Server) Router() *gin.Engine {
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	s.httpServer = &http.Server{
-		Addr:         s.config.GetAddress(),
-		Handler:      s.router,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:              s.config.GetAddress(),
+		Handler:           s.router,
+		ReadTimeout:       s.config.GetReadTimeout(),
+		ReadHeaderTimeout: s.config.GetReadHeaderTimeout(),
+		WriteTimeout:      s.config.GetWriteTimeout(),
+		IdleTimeout:       s.config.GetIdleTimeout(),
 	}
 
 	log.Info().
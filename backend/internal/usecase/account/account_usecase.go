@@ -0,0 +1,274 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	"github.com/leondli/workspace/internal/usecase/kernel"
+	"github.com/leondli/workspace/internal/usecase/object"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+	"github.com/leondli/workspace/pkg/jwt"
+)
+
+// UseCase defines the account use case interface
+type UseCase interface {
+	DeleteAccount(ctx context.Context, userID uuid.UUID, confirm string) (*Deletion, error)
+	AdminDeleteAccount(ctx context.Context, userID uuid.UUID, mode DeletionMode) (*Deletion, error)
+	RestoreAccount(ctx context.Context, userID uuid.UUID) (*entity.UserResponse, error)
+	EraseDueAccounts(ctx context.Context) (int, error)
+	Wait()
+}
+
+// DeletionMode is how an account is deleted
+type DeletionMode string
+
+const (
+	DeletionModeDisable DeletionMode = "disable" // Blocked at once, erased when the grace period ends unless restored
+	DeletionModeErase   DeletionMode = "erase"   // Erased at once
+)
+
+// Deletion reports how an account was deleted
+type Deletion struct {
+	Mode        DeletionMode               `json:"mode"`
+	EraseAfter  *time.Time                 `json:"erase_after,omitempty"` // When a disabled account is erased
+	Transferred []object.TransferredObject `json:"transferred,omitempty"` // Shared objects given to other users
+}
+
+const (
+	// eraseBatch is how many accounts due for erasure one run erases
+	eraseBatch = 50
+
+	// purgeTimeout bounds removing one erased account's content from storage
+	purgeTimeout = 30 * time.Minute
+)
+
+type accountUseCase struct {
+	userRepo         repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	objectUseCase    object.UseCase
+	kernelUseCase    *kernel.UseCase
+	jwtManager       *jwt.JWTManager
+	cfg              *config.AccountConfig
+	purges           sync.WaitGroup
+}
+
+// NewUseCase creates a new account use case
+func NewUseCase(
+	userRepo repository.UserRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	objectUseCase object.UseCase,
+	kernelUseCase *kernel.UseCase,
+	jwtManager *jwt.JWTManager,
+	cfg *config.AccountConfig,
+) UseCase {
+	return &accountUseCase{
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		objectUseCase:    objectUseCase,
+		kernelUseCase:    kernelUseCase,
+		jwtManager:       jwtManager,
+		cfg:              cfg,
+	}
+}
+
+// DeleteAccount deletes the user's own account once confirm matches their
+// password. With a grace period configured the account is disabled and
+// erased when it ends; otherwise it is erased at once.
+func (u *accountUseCase) DeleteAccount(ctx context.Context, userID uuid.UUID, confirm string) (*Deletion, error) {
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(confirm)); err != nil {
+		return nil, apperrors.UnauthorizedError("invalid password")
+	}
+
+	mode := DeletionModeErase
+	if u.cfg.GetDeletionGracePeriod() > 0 {
+		mode = DeletionModeDisable
+	}
+	return u.delete(ctx, user, mode)
+}
+
+// AdminDeleteAccount deletes another user's account. An empty mode deletes
+// it as DeleteAccount does; an account already disabled for deletion can
+// still be erased at once.
+func (u *accountUseCase) AdminDeleteAccount(ctx context.Context, userID uuid.UUID, mode DeletionMode) (*Deletion, error) {
+	switch mode {
+	case "":
+		mode = DeletionModeErase
+		if u.cfg.GetDeletionGracePeriod() > 0 {
+			mode = DeletionModeDisable
+		}
+	case DeletionModeDisable:
+		if u.cfg.GetDeletionGracePeriod() == 0 {
+			return nil, apperrors.ValidationError("no deletion grace period is configured; erase the account instead")
+		}
+	case DeletionModeErase:
+	default:
+		return nil, apperrors.InvalidArgumentError(fmt.Sprintf("unknown deletion mode %q; use disable or erase", mode), "mode")
+	}
+
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return u.delete(ctx, user, mode)
+}
+
+// RestoreAccount reactivates an account disabled for deletion before it is
+// erased. Its sessions stay revoked, so the user signs in again.
+func (u *accountUseCase) RestoreAccount(ctx context.Context, userID uuid.UUID) (*entity.UserResponse, error) {
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != entity.UserStatusPendingDeletion {
+		return nil, apperrors.ValidationError("account is not pending deletion")
+	}
+
+	user.Status = entity.UserStatusActive
+	user.DeleteAfter = nil
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, apperrors.InternalError("failed to restore account", err)
+	}
+	log.Info().Str("user_id", user.ID.String()).Msg("Account restored from pending deletion")
+	return user.ToResponse(), nil
+}
+
+// EraseDueAccounts erases accounts whose grace period has ended, returning
+// how many were erased. An account that fails is retried on the next run.
+func (u *accountUseCase) EraseDueAccounts(ctx context.Context) (int, error) {
+	users, err := u.userRepo.ListDueForErase(ctx, time.Now(), eraseBatch)
+	if err != nil {
+		return 0, apperrors.InternalError("failed to list accounts due for erasure", err)
+	}
+
+	erased := 0
+	var firstErr error
+	for _, user := range users {
+		if _, err := u.erase(ctx, user); err != nil {
+			log.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to erase account")
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		erased++
+	}
+
+	if erased > 0 {
+		log.Info().Int("accounts", erased).Msg("Accounts past their deletion grace period erased")
+	}
+	return erased, firstErr
+}
+
+// Wait blocks until the storage purges of erased accounts have finished
+func (u *accountUseCase) Wait() {
+	u.purges.Wait()
+}
+
+func (u *accountUseCase) getUser(ctx context.Context, userID uuid.UUID) (*entity.User, error) {
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("user")
+		}
+		return nil, apperrors.InternalError("failed to get user", err)
+	}
+	return user, nil
+}
+
+// delete signs the user out everywhere, stops their kernels and disables the
+// account, then erases it unless mode leaves that to the grace period. The
+// account is disabled first so a failed erasure is retried by
+// EraseDueAccounts.
+func (u *accountUseCase) delete(ctx context.Context, user *entity.User, mode DeletionMode) (*Deletion, error) {
+	if err := u.refreshTokenRepo.RevokeAllForUser(ctx, user.ID); err != nil {
+		return nil, apperrors.InternalError("failed to revoke tokens", err)
+	}
+	u.jwtManager.RevokeUserTokens(user.ID.String())
+	u.stopKernels(ctx, user.ID)
+
+	eraseAfter := time.Now()
+	if mode == DeletionModeDisable {
+		eraseAfter = eraseAfter.Add(u.cfg.GetDeletionGracePeriod())
+	}
+	if user.Status != entity.UserStatusPendingDeletion || mode == DeletionModeErase {
+		user.Status = entity.UserStatusPendingDeletion
+		user.DeleteAfter = &eraseAfter
+		if err := u.userRepo.Update(ctx, user); err != nil {
+			return nil, apperrors.InternalError("failed to disable account", err)
+		}
+	}
+
+	if mode == DeletionModeDisable {
+		log.Info().Str("user_id", user.ID.String()).Time("erase_after", *user.DeleteAfter).Msg("Account disabled for deletion")
+		return &Deletion{Mode: mode, EraseAfter: user.DeleteAfter}, nil
+	}
+
+	transferred, err := u.erase(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &Deletion{Mode: mode, Transferred: transferred}, nil
+}
+
+// erase removes a disabled account: shared objects are transferred or
+// deleted as configured, the rest of the user's objects, grants, pins,
+// settings and sessions are deleted, and the workspace is purged from
+// storage in the background
+func (u *accountUseCase) erase(ctx context.Context, user *entity.User) ([]object.TransferredObject, error) {
+	transferred, err := u.objectUseCase.TransferShared(ctx, user, u.cfg.GetSharedObjects() == "delete")
+	if err != nil {
+		return nil, err
+	}
+	erasure, err := u.objectUseCase.EraseUserObjects(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.userRepo.Erase(ctx, user.ID); err != nil && !apperrors.IsNotFound(err) {
+		return nil, apperrors.InternalError("failed to erase account", err)
+	}
+	log.Info().Str("user_id", user.ID.String()).Int("transferred", len(transferred)).Msg("Account erased")
+
+	u.purges.Add(1)
+	go func() {
+		defer u.purges.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), purgeTimeout)
+		defer cancel()
+		if err := u.objectUseCase.PurgeErased(ctx, erasure); err != nil {
+			log.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to purge erased account from storage")
+			return
+		}
+		log.Info().Str("user_id", user.ID.String()).Msg("Erased account purged from storage")
+	}()
+	return transferred, nil
+}
+
+// stopKernels stops the user's kernels. Failures are logged and do not stop
+// the deletion; admins can stop leftover kernels from the kernel list.
+func (u *accountUseCase) stopKernels(ctx context.Context, userID uuid.UUID) {
+	if u.kernelUseCase == nil {
+		return
+	}
+	kernels, err := u.kernelUseCase.ListKernels(ctx, userID.String())
+	if err != nil {
+		log.Warn().Err(err).Str("user_id", userID.String()).Msg("Failed to list kernels of deleted account")
+		return
+	}
+	for _, k := range kernels {
+		if err := u.kernelUseCase.StopKernel(ctx, k.ID); err != nil {
+			log.Warn().Err(err).Str("kernel_id", k.ID).Msg("Failed to stop kernel of deleted account")
+		}
+	}
+}
@@ -22,6 +22,7 @@ type UseCase interface {
 	Login(ctx context.Context, input *LoginInput) (*AuthOutput, error)
 	RefreshToken(ctx context.Context, refreshToken string) (*AuthOutput, error)
 	Logout(ctx context.Context, userID uuid.UUID) error
+	ForceLogout(ctx context.Context, userID uuid.UUID) error
 	ChangePassword(ctx context.Context, userID uuid.UUID, oldPassword, newPassword string) error
 }
 
@@ -128,6 +129,9 @@ func (u *authUseCase) Register(ctx context.Context, input *RegisterInput) (*Auth
 	}
 
 	if err := u.userRepo.Create(ctx, user); err != nil {
+		if apperrors.IsAlreadyExists(err) {
+			return nil, err
+		}
 		return nil, apperrors.InternalError("failed to create user", err)
 	}
 
@@ -273,6 +277,24 @@ func (u *authUseCase) Logout(ctx context.Context, userID uuid.UUID) error {
 	return nil
 }
 
+// ForceLogout revokes all of a user's sessions, including access tokens that
+// have not yet expired
+func (u *authUseCase) ForceLogout(ctx context.Context, userID uuid.UUID) error {
+	if _, err := u.userRepo.GetByID(ctx, userID); err != nil {
+		if apperrors.IsNotFound(err) {
+			return apperrors.NotFoundError("user")
+		}
+		return apperrors.InternalError("failed to get user", err)
+	}
+
+	if err := u.refreshTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return apperrors.InternalError("failed to revoke tokens", err)
+	}
+	u.jwtManager.RevokeUserTokens(userID.String())
+
+	return nil
+}
+
 func (u *authUseCase) ChangePassword(ctx context.Context, userID uuid.UUID, oldPassword, newPassword string) error {
 	// Get user
 	user, err := u.userRepo.GetByID(ctx, userID)
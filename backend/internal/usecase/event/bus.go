@@ -0,0 +1,122 @@
+package event
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Bus passes published events to the subscribers registered for their type,
+// so use cases publish without knowing who consumes the events. Synchronous
+// subscribers run inside Publish, in registration order; asynchronous ones get
+// a queue and a goroutine of their own, and miss events while it is full.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions []*subscription
+	closed        bool
+	wg            sync.WaitGroup
+}
+
+type subscription struct {
+	name       string
+	subscriber Publisher
+	types      []string    // Empty subscribes to every type
+	queue      chan queued // Nil for synchronous delivery
+}
+
+type queued struct {
+	ctx   context.Context
+	event *Event
+}
+
+// NewBus creates a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers subscriber for events of the given types, or all events
+// when none are given. Delivery happens synchronously inside Publish.
+func (b *Bus) Subscribe(subscriber Publisher, types ...string) {
+	b.add(&subscription{subscriber: subscriber, types: types})
+}
+
+// SubscribeAsync registers subscriber like Subscribe, but delivers events from
+// a separate goroutine through a queue of queueSize events. name identifies
+// the subscriber in logs. The context passed to the subscriber keeps the
+// publisher's values but is not cancelled with it.
+func (b *Bus) SubscribeAsync(name string, subscriber Publisher, queueSize int, types ...string) {
+	sub := &subscription{
+		name:       name,
+		subscriber: subscriber,
+		types:      types,
+		queue:      make(chan queued, max(queueSize, 1)),
+	}
+	if !b.add(sub) {
+		return
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for item := range sub.queue {
+			sub.subscriber.Publish(item.ctx, item.event)
+		}
+	}()
+}
+
+func (b *Bus) add(sub *subscription) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return false
+	}
+	b.subscriptions = append(b.subscriptions, sub)
+	return true
+}
+
+// Publish passes event to every subscriber registered for its type
+func (b *Bus) Publish(ctx context.Context, event *Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+
+	publishedVar.Add(event.Type, 1)
+	for _, sub := range b.subscriptions {
+		if len(sub.types) > 0 && !slices.Contains(sub.types, event.Type) {
+			continue
+		}
+		if sub.queue == nil {
+			sub.subscriber.Publish(ctx, event)
+			continue
+		}
+		select {
+		case sub.queue <- queued{ctx: context.WithoutCancel(ctx), event: event}:
+		default:
+			droppedVar.Add(sub.name, 1)
+			log.Warn().Str("subscriber", sub.name).Str("event", event.Type).Msg("Event queue full, dropping event")
+		}
+	}
+}
+
+// Close stops accepting events and waits for asynchronous subscribers to
+// handle the events already queued
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	for _, sub := range b.subscriptions {
+		if sub.queue != nil {
+			close(sub.queue)
+		}
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
@@ -0,0 +1,79 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// Event types
+const (
+	ObjectCreated     = "object.created"
+	ObjectSaved       = "object.saved"
+	ObjectDeleted     = "object.deleted"
+	ObjectMoved       = "object.moved"
+	PermissionChanged = "permission.changed"
+	KernelStarted     = "kernel.started"
+	KernelStopped     = "kernel.stopped"
+)
+
+// Types lists the object event types, the ones webhooks can subscribe to
+var Types = []string{
+	ObjectCreated,
+	ObjectSaved,
+	ObjectDeleted,
+	ObjectMoved,
+	PermissionChanged,
+}
+
+// Event describes a change to an object or a kernel. Object events, with
+// their paths made relative to the workspace root, are also the webhook
+// payload.
+type Event struct {
+	ID          uuid.UUID         `json:"id"`
+	Type        string            `json:"type"`
+	ObjectID    int64             `json:"object_id,omitempty"`
+	ObjectType  entity.ObjectType `json:"object_type,omitempty"`
+	Name        string            `json:"name,omitempty"`
+	Path        string            `json:"path,omitempty"`
+	OldPath     string            `json:"old_path,omitempty"` // Path before a move or rename
+	ActorID     *uuid.UUID        `json:"actor_id,omitempty"`
+	RecipientID *uuid.UUID        `json:"recipient_id,omitempty"` // User the change is addressed to, e.g. the grantee
+	Role        entity.Role       `json:"role,omitempty"`         // Role granted, for permission changes
+	KernelID    string            `json:"kernel_id,omitempty"`    // For kernel events
+	OccurredAt  time.Time         `json:"occurred_at"`
+}
+
+// New creates an event of the given type for obj
+func New(eventType string, obj *entity.Object, actorID *uuid.UUID) *Event {
+	return &Event{
+		ID:         uuid.New(),
+		Type:       eventType,
+		ObjectID:   obj.ID,
+		ObjectType: obj.Type,
+		Name:       obj.Name,
+		Path:       obj.Path,
+		ActorID:    actorID,
+		OccurredAt: time.Now(),
+	}
+}
+
+// NewKernel creates a kernel event of the given type
+func NewKernel(eventType, kernelID string, actorID *uuid.UUID) *Event {
+	return &Event{
+		ID:         uuid.New(),
+		Type:       eventType,
+		KernelID:   kernelID,
+		ActorID:    actorID,
+		OccurredAt: time.Now(),
+	}
+}
+
+// Publisher receives change events. Publish must not fail the change that
+// caused the event, so implementations log their errors instead of returning them.
+type Publisher interface {
+	Publish(ctx context.Context, event *Event)
+}
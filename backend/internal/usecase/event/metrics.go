@@ -0,0 +1,9 @@
+package event
+
+import "expvar"
+
+// Runtime metrics for the event bus
+var (
+	publishedVar = expvar.NewMap("events_published") // Per event type
+	droppedVar   = expvar.NewMap("events_dropped")   // Per asynchronous subscriber, on a full queue
+)
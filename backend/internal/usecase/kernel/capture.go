@@ -0,0 +1,101 @@
+package kernel
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CapturedFile is a file an execution wrote to its capture directory
+type CapturedFile struct {
+	Path    string // Relative to the capture directory, slash-separated
+	Content []byte
+}
+
+// CaptureIssue records a file in a capture directory that was not collected
+type CaptureIssue struct {
+	Path   string
+	Reason string
+}
+
+// StartCapture creates an empty directory for an execution to run in, so the
+// files it writes can be collected once it replies. Set it as the request's
+// CaptureDir, and collect or discard it afterwards.
+func (uc *UseCase) StartCapture() (string, error) {
+	dir, err := os.MkdirTemp("", "kernel-capture-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	return dir, nil
+}
+
+// CollectCapture reads the files written to a capture directory and removes
+// it. Files beyond the configured count and size limits, and anything but
+// regular files, are skipped.
+func (uc *UseCase) CollectCapture(dir string) ([]CapturedFile, []CaptureIssue) {
+	defer uc.DiscardCapture(dir)
+
+	limits := &uc.cfg.Capture
+	var files []CapturedFile
+	var skipped []CaptureIssue
+	var total int64
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if p == dir || (err == nil && d.IsDir()) {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		skip := func(reason string) {
+			skipped = append(skipped, CaptureIssue{Path: rel, Reason: reason})
+		}
+		if err != nil {
+			skip("file could not be read")
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			skip("not a regular file")
+			return nil
+		}
+		if len(files) >= limits.GetMaxFiles() {
+			skip(fmt.Sprintf("more than %d files were written", limits.GetMaxFiles()))
+			return nil
+		}
+
+		content, err := readCapturedFile(p, limits.GetMaxFileSize())
+		switch {
+		case err != nil:
+			skip("file could not be read")
+		case int64(len(content)) > limits.GetMaxFileSize():
+			skip(fmt.Sprintf("file exceeds the %d byte limit", limits.GetMaxFileSize()))
+		case total+int64(len(content)) > limits.GetMaxTotalSize():
+			skip(fmt.Sprintf("captured files exceed the %d byte total limit", limits.GetMaxTotalSize()))
+		default:
+			total += int64(len(content))
+			files = append(files, CapturedFile{Path: rel, Content: content})
+		}
+		return nil
+	})
+	return files, skipped
+}
+
+// readCapturedFile reads at most limit+1 bytes of a file, enough to tell
+// whether it is over the limit
+func readCapturedFile(name string, limit int64) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, limit+1))
+}
+
+// DiscardCapture removes a capture directory without collecting its files
+func (uc *UseCase) DiscardCapture(dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		log.Warn().Err(err).Str("dir", dir).Msg("Failed to remove capture directory")
+	}
+}
@@ -0,0 +1,73 @@
+package kernel
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+)
+
+// createConnectionDir creates the private directory holding a local kernel's
+// connection file and wrapper script. Permissions are tightened even if the
+// directories already exist, since the connection file contains the HMAC key.
+func (uc *UseCase) createConnectionDir(kernelID string) (string, error) {
+	baseDir := uc.cfg.GetConnectionDir()
+	if err := os.MkdirAll(baseDir, 0700); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(baseDir, 0700); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(baseDir, kernelID)
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// SweepConnectionDirs removes connection directories left behind by local
+// kernels of a previous run that crashed. Directories of kernels owned by
+// other instances sharing the base directory are kept. Call it before
+// RestoreKernels, which drops this instance's stale local sessions.
+func (uc *UseCase) SweepConnectionDirs(ctx context.Context) error {
+	baseDir := uc.cfg.GetConnectionDir()
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		kernelID := entry.Name()
+		if _, running := uc.kernels.Load(kernelID); running {
+			continue
+		}
+		if uc.sessionRepo != nil {
+			if session, err := uc.sessionRepo.GetByKernelID(ctx, kernelID); err == nil && !uc.isLocalSession(session) {
+				continue
+			}
+		}
+
+		if err := os.RemoveAll(filepath.Join(baseDir, kernelID)); err != nil {
+			log.Warn().Err(err).Str("kernel_id", kernelID).Msg("Failed to remove stale kernel connection directory")
+			continue
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		log.Info().Int("removed", removed).Str("dir", baseDir).Msg("Removed stale kernel connection directories")
+	}
+	return nil
+}
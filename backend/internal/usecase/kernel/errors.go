@@ -0,0 +1,71 @@
+package kernel
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/leondli/workspace/internal/infrastructure/gateway"
+)
+
+// ErrKernelOnAnotherInstance indicates the kernel is owned by a different
+// server instance and requests must be routed there
+var ErrKernelOnAnotherInstance = errors.New("kernel is on another instance")
+
+// ErrKernelNotFound indicates no kernel with the given ID exists. It is the
+// gateway's sentinel so kernels the gateway no longer knows map the same way.
+var ErrKernelNotFound = gateway.ErrKernelNotFound
+
+// ErrGatewayUnavailable indicates the gateway circuit breaker is open and the
+// request was failed without contacting the gateway
+var ErrGatewayUnavailable = gateway.ErrCircuitOpen
+
+// ErrKernelSpecNotAllowed indicates the caller's app may not start the
+// requested kernel spec
+var ErrKernelSpecNotAllowed = errors.New("kernel spec is not allowed")
+
+// ErrKernelDead indicates the kernel process has exited and must be restarted
+var ErrKernelDead = errors.New("kernel is dead")
+
+// ErrCaptureUnsupported indicates an execution asked to capture the files it
+// writes on a gateway kernel, whose files are on another host
+var ErrCaptureUnsupported = errors.New("capture_outputs is supported by local kernels only")
+
+// ErrKernelStartFailed indicates a kernel never became ready
+var ErrKernelStartFailed = errors.New("kernel failed to start")
+
+// KernelStartError explains why a kernel failed to start. Output holds the
+// kernel's stderr for local kernels or the gateway's response for gateway kernels.
+type KernelStartError struct {
+	KernelID string
+	Reason   string
+	Output   string
+}
+
+func (e *KernelStartError) Error() string {
+	if e.Output != "" {
+		return fmt.Sprintf("kernel failed to start: %s: %s", e.Reason, e.Output)
+	}
+	return fmt.Sprintf("kernel failed to start: %s", e.Reason)
+}
+
+func (e *KernelStartError) Unwrap() error {
+	return ErrKernelStartFailed
+}
+
+// RemoteKernelError carries routing info for a kernel owned by another instance
+type RemoteKernelError struct {
+	KernelID     string
+	InstanceID   string
+	InstanceAddr string
+}
+
+func (e *RemoteKernelError) Error() string {
+	if e.InstanceAddr != "" {
+		return fmt.Sprintf("kernel %s is on another instance: %s (%s)", e.KernelID, e.InstanceID, e.InstanceAddr)
+	}
+	return fmt.Sprintf("kernel %s is on another instance: %s", e.KernelID, e.InstanceID)
+}
+
+func (e *RemoteKernelError) Unwrap() error {
+	return ErrKernelOnAnotherInstance
+}
@@ -0,0 +1,172 @@
+package kernel
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/infrastructure/gateway"
+)
+
+const (
+	// StatusUnresponsive marks a kernel that did not answer a liveness probe
+	StatusUnresponsive = "unresponsive"
+
+	// pongMsgType is the wrapper's reply to a liveness probe
+	pongMsgType = "pong"
+
+	// healthDisabledPoll is how often a disabled health check rechecks the
+	// config, so enabling it takes effect without a restart
+	healthDisabledPoll = time.Minute
+)
+
+// RunHealthChecks probes the kernels owned by this instance at the configured
+// interval until ctx is cancelled. Local kernels are pinged through their
+// wrapper loop, gateway kernels with a kernel_info request. Busy kernels are
+// skipped. A kernel that does not reply within the probe timeout is marked
+// unresponsive and a status message is sent to its subscribers; it returns to
+// idle once a later probe succeeds.
+func (uc *UseCase) RunHealthChecks(ctx context.Context) {
+	log.Info().Dur("interval", uc.cfg.GetHealthInterval()).Msg("Kernel health checks started")
+	for {
+		// Re-read the interval each cycle so config reloads take effect
+		interval := uc.cfg.GetHealthInterval()
+		enabled := interval > 0
+		if !enabled {
+			interval = healthDisabledPoll
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Info().Msg("Kernel health checks stopped")
+			return
+		case <-timer.C:
+			if enabled {
+				uc.probeKernels(ctx)
+			}
+		}
+	}
+}
+
+// probeKernels starts a probe for every kernel that has none in flight
+func (uc *UseCase) probeKernels(ctx context.Context) {
+	timeout := uc.cfg.GetHealthTimeout()
+
+	uc.kernels.Range(func(key, value interface{}) bool {
+		instance := value.(*KernelInstance)
+		uc.startProbe(key.(string), func() { uc.probeLocalKernel(ctx, instance, timeout) })
+		return true
+	})
+
+	if uc.gatewayEnabled && uc.gatewayManager != nil {
+		for _, gk := range uc.gatewayManager.ListAllKernels() {
+			uc.startProbe(gk.ID, func() { uc.probeGatewayKernel(ctx, gk, timeout) })
+		}
+	}
+}
+
+// startProbe runs probe in a new goroutine unless one is already running for
+// the kernel, so a slow probe never overlaps the next cycle
+func (uc *UseCase) startProbe(kernelID string, probe func()) {
+	if _, running := uc.probing.LoadOrStore(kernelID, struct{}{}); running {
+		return
+	}
+	go func() {
+		defer uc.probing.Delete(kernelID)
+		probe()
+	}()
+}
+
+// probeLocalKernel pings the wrapper loop of a local kernel. The wrapper runs
+// requests one at a time, so a ping queued behind an execution that started
+// meanwhile is not counted as a failure.
+func (uc *UseCase) probeLocalKernel(ctx context.Context, instance *KernelInstance, timeout time.Duration) {
+	switch instance.Info.Status {
+	case "busy", "starting", "dead":
+		return
+	}
+
+	// Discard a late reply to an earlier probe
+	select {
+	case <-instance.pongChan:
+	default:
+	}
+
+	instance.mu.Lock()
+	err := instance.stdin.Encode(map[string]string{"type": "ping", "msg_id": "ping_" + uuid.New().String()})
+	instance.mu.Unlock()
+	if err != nil {
+		// A broken pipe means the process is gone; the exit monitor marks it dead
+		return
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-instance.pongChan:
+		if instance.Info.Status == StatusUnresponsive {
+			log.Info().Str("kernel_id", instance.Info.ID).Msg("Kernel is responsive again")
+			uc.setLocalStatus(instance, "idle")
+		}
+	case <-timer.C:
+		switch instance.Info.Status {
+		case "busy", "dead", StatusUnresponsive:
+			return
+		}
+		log.Warn().Str("kernel_id", instance.Info.ID).Dur("timeout", timeout).Msg("Kernel did not answer liveness probe, marking unresponsive")
+		uc.setLocalStatus(instance, StatusUnresponsive)
+	case <-instance.stopChan:
+	case <-ctx.Done():
+	}
+}
+
+// setLocalStatus updates a local kernel's status and notifies its subscribers
+func (uc *UseCase) setLocalStatus(instance *KernelInstance, state string) {
+	instance.Info.Status = state
+	instance.broadcast(&KernelMessage{
+		MsgID:   "health_" + uuid.New().String(),
+		MsgType: "status",
+		Content: map[string]interface{}{"execution_state": state},
+	})
+}
+
+// probeGatewayKernel sends a kernel_info request to a gateway kernel
+func (uc *UseCase) probeGatewayKernel(ctx context.Context, gk *gateway.GatewayKernel, timeout time.Duration) {
+	switch gk.ExecutionState {
+	case "busy", "starting", "dead":
+		return
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, err := uc.gatewayManager.KernelInfo(probeCtx, gk.ID)
+	if ctx.Err() != nil {
+		return
+	}
+	if err == nil {
+		if gk.Status == StatusUnresponsive {
+			log.Info().Str("kernel_id", gk.ID).Msg("Gateway kernel is responsive again")
+			uc.setGatewayStatus(gk.ID, "idle")
+		}
+		return
+	}
+
+	if gk.ExecutionState == "busy" || gk.Status == StatusUnresponsive {
+		return
+	}
+	log.Warn().Err(err).Str("kernel_id", gk.ID).Dur("timeout", timeout).Msg("Gateway kernel did not answer liveness probe, marking unresponsive")
+	uc.setGatewayStatus(gk.ID, StatusUnresponsive)
+}
+
+// setGatewayStatus updates a gateway kernel's status and notifies its subscribers
+func (uc *UseCase) setGatewayStatus(kernelID, state string) {
+	if err := uc.gatewayManager.SetExecutionState(kernelID, state); err != nil {
+		log.Debug().Err(err).Str("kernel_id", kernelID).Msg("Failed to update gateway kernel status")
+	}
+}
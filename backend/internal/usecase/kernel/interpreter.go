@@ -0,0 +1,77 @@
+package kernel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// interpreterCheckTimeout bounds each interpreter probe at startup
+const interpreterCheckTimeout = 15 * time.Second
+
+// ValidateInterpreters checks that each local kernel spec's interpreter runs
+// and records its Python and ipykernel versions in the spec metadata. It
+// returns an error describing every interpreter that could not run; a missing
+// ipykernel is only logged since the built-in wrapper does not need it.
+func (uc *UseCase) ValidateInterpreters(ctx context.Context) error {
+	if uc.gatewayEnabled {
+		return nil
+	}
+	if len(uc.kernelSpecs) == 0 {
+		return fmt.Errorf("no Python interpreter found; set kernel.python_path")
+	}
+
+	var errs []error
+	for name, spec := range uc.kernelSpecs {
+		if len(spec.Argv) == 0 {
+			continue
+		}
+		interpreter := spec.Argv[0]
+
+		version, err := probeInterpreter(ctx, interpreter, "--version")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("kernel spec %s: interpreter %s is not runnable: %w", name, interpreter, err))
+			continue
+		}
+		if spec.Metadata == nil {
+			spec.Metadata = make(map[string]string)
+		}
+		spec.Metadata["python_version"] = strings.TrimPrefix(version, "Python ")
+
+		ipykernelVersion, err := probeInterpreter(ctx, interpreter, "-m", "ipykernel", "--version")
+		if err != nil {
+			log.Warn().Err(err).Str("kernel", name).Str("interpreter", interpreter).Msg("ipykernel is not available for interpreter")
+		} else {
+			spec.Metadata["ipykernel_version"] = ipykernelVersion
+		}
+
+		log.Info().
+			Str("kernel", name).
+			Str("interpreter", interpreter).
+			Str("python_version", spec.Metadata["python_version"]).
+			Str("ipykernel_version", spec.Metadata["ipykernel_version"]).
+			Msg("Validated kernel interpreter")
+	}
+
+	return errors.Join(errs...)
+}
+
+// probeInterpreter runs the interpreter with args and returns its trimmed output
+func probeInterpreter(ctx context.Context, interpreter string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, interpreterCheckTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, interpreter, args...).CombinedOutput()
+	if err != nil {
+		if len(output) > 0 {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -3,19 +3,24 @@ package kernel
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 
+	"github.com/leondli/workspace/internal/domain/repository"
 	"github.com/leondli/workspace/internal/infrastructure/config"
 	"github.com/leondli/workspace/internal/infrastructure/gateway"
+	"github.com/leondli/workspace/internal/usecase/event"
 )
 
 // KernelSpec represents a kernel specification
@@ -25,17 +30,24 @@ type KernelSpec struct {
 	Language    string            `json:"language"`
 	Argv        []string          `json:"argv"`
 	Env         map[string]string `json:"env,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"` // e.g. detected python_version
 }
 
 // KernelInfo represents a running kernel instance
 type KernelInfo struct {
 	ID             string    `json:"id"`
 	Name           string    `json:"name"`
-	Status         string    `json:"status"` // starting, idle, busy, dead
+	Status         string    `json:"status"` // starting, idle, busy, dead, unresponsive
 	ExecutionCount int       `json:"execution_count"`
 	LastActivity   time.Time `json:"last_activity"`
 	UserID         string    `json:"user_id"`
-	IsGateway      bool      `json:"is_gateway"` // Whether this kernel is managed by gateway
+	IsGateway      bool      `json:"is_gateway"`              // Whether this kernel is managed by gateway
+	InstanceID     string    `json:"instance_id,omitempty"`   // Server instance that owns this kernel
+	InstanceAddr   string    `json:"instance_addr,omitempty"` // Address of the owning instance, for routing
+	ObjectID       *int64    `json:"object_id,omitempty"`     // Notebook the kernel was started for
+	// AllowConcurrentExecution dispatches executions as they arrive instead
+	// of running them one at a time in submission order. Local kernels only.
+	AllowConcurrentExecution bool `json:"allow_concurrent_execution"`
 }
 
 // KernelStatus represents the current status of a kernel
@@ -46,15 +58,54 @@ type KernelStatus struct {
 	ExecutionCount   int       `json:"execution_count"`
 	LastActivity     time.Time `json:"last_activity"`
 	ConnectionStatus string    `json:"connection_status"`
+	// AllowConcurrentExecution reports whether executions run concurrently
+	AllowConcurrentExecution bool `json:"allow_concurrent_execution"`
+	// DroppedMessages counts messages dropped per output session because its buffer was full
+	DroppedMessages map[string]uint64 `json:"dropped_messages,omitempty"`
+}
+
+// KernelLogs holds the most recent stderr lines of a kernel
+type KernelLogs struct {
+	KernelID string   `json:"kernel_id"`
+	Lines    []string `json:"lines"`
 }
 
 // ExecuteRequest represents a code execution request
 type ExecuteRequest struct {
-	MsgID        string `json:"msg_id"`
-	Code         string `json:"code"`
-	Silent       bool   `json:"silent"`
-	StoreHistory bool   `json:"store_history"`
-	CellID       string `json:"cell_id,omitempty"`
+	MsgID           string            `json:"msg_id"`
+	Code            string            `json:"code"`
+	Silent          bool              `json:"silent"`
+	StoreHistory    bool              `json:"store_history"`
+	CellID          string            `json:"cell_id,omitempty"`
+	UserExpressions map[string]string `json:"user_expressions,omitempty"` // Named expressions evaluated after the code; results come back in execute_reply
+	StopOnError     *bool             `json:"stop_on_error,omitempty"`    // Abort queued executions if this one errors (default: true)
+	LineOffset      int               `json:"line_offset,omitempty"`      // Lines preceding Code in its cell when running a selection, so tracebacks report cell line numbers
+	Cwd             string            `json:"cwd,omitempty"`              // Directory to run in, relative to the user's workspace root; honoured by local kernels only
+	CaptureOutputs  bool              `json:"capture_outputs,omitempty"`  // Run in an empty directory and import the files written there into the workspace; local kernels only
+
+	// WorkDir is the storage path of the directory named by Cwd, set once the
+	// caller has checked the user may use it. Gateway kernels run on another
+	// host without the workspace files, so they ignore it.
+	WorkDir string `json:"-"`
+	// CaptureDir is the directory from StartCapture the execution runs in
+	// instead of WorkDir, set when CaptureOutputs is
+	CaptureDir string `json:"-"`
+}
+
+// maxLineOffset bounds LineOffset; the local kernel pads the code with that many lines
+const maxLineOffset = 100000
+
+// stopOnError returns whether queued executions are aborted when this one errors
+func (r *ExecuteRequest) stopOnError() bool {
+	return r.StopOnError == nil || *r.StopOnError
+}
+
+// lineOffset returns LineOffset, or 0 when it is out of range
+func (r *ExecuteRequest) lineOffset() int {
+	if r.LineOffset < 0 || r.LineOffset > maxLineOffset {
+		return 0
+	}
+	return r.LineOffset
 }
 
 // KernelMessage represents a message from the kernel
@@ -64,6 +115,7 @@ type KernelMessage struct {
 	ParentID string                 `json:"parent_id,omitempty"`
 	Content  map[string]interface{} `json:"content"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Buffers  [][]byte               `json:"-"`
 }
 
 // KernelInstance represents a running kernel process
@@ -74,26 +126,63 @@ type KernelInstance struct {
 	stdout         *json.Decoder
 	mu             sync.Mutex
 	outputChannels map[string]chan *KernelMessage
+	outputFilters  map[string]MessageFilter
+	droppedCounts  map[string]*atomic.Uint64
 	channelMu      sync.RWMutex
+	stderr         *lineTail
+	connectionDir  string
+	homeDir        string // Storage path of the owner's workspace root, where the kernel starts
 	stopChan       chan struct{}
+	pongChan       chan struct{} // Signalled when the wrapper answers a liveness probe
+}
+
+// droppedMessages returns the non-zero drop counts per output session
+func (ki *KernelInstance) droppedMessages() map[string]uint64 {
+	ki.channelMu.RLock()
+	defer ki.channelMu.RUnlock()
+
+	var counts map[string]uint64
+	for sessionID, counter := range ki.droppedCounts {
+		if n := counter.Load(); n > 0 {
+			if counts == nil {
+				counts = make(map[string]uint64)
+			}
+			counts[sessionID] = n
+		}
+	}
+	return counts
 }
 
 // UseCase handles kernel-related business logic
 type UseCase struct {
 	kernels        sync.Map // map[string]*KernelInstance (for local kernels)
 	kernelSpecs    map[string]*KernelSpec
+	specCacheMu    sync.Mutex
+	specCache      map[string]*KernelSpec // Jupyter-discovered specs
+	specCachedAt   time.Time
+	cfg            *config.KernelConfig
 	pythonPath     string
 	workspacePath  string
 	gatewayEnabled bool
 	gatewayManager *gateway.KernelManager
+	sessionRepo    repository.KernelSessionRepository
+	instanceID     string
+	instanceAddr   string
+	probing        sync.Map // map[string]struct{}: kernels with a liveness probe in flight
+	events         event.Publisher
 }
 
 // NewUseCase creates a new kernel use case
-func NewUseCase(pythonPath, workspacePath string) *UseCase {
+func NewUseCase(kernelCfg *config.KernelConfig, workspacePath string, sessionRepo repository.KernelSessionRepository, serverCfg *config.ServerConfig, events event.Publisher) *UseCase {
 	uc := &UseCase{
 		kernelSpecs:   make(map[string]*KernelSpec),
-		pythonPath:    pythonPath,
+		cfg:           kernelCfg,
+		pythonPath:    kernelCfg.PythonPath,
 		workspacePath: workspacePath,
+		sessionRepo:   sessionRepo,
+		instanceID:    serverCfg.GetInstanceID(),
+		instanceAddr:  serverCfg.AdvertiseAddress,
+		events:        events,
 	}
 
 	// Initialize default kernel specs
@@ -103,11 +192,17 @@ func NewUseCase(pythonPath, workspacePath string) *UseCase {
 }
 
 // NewUseCaseWithGateway creates a new kernel use case with gateway support
-func NewUseCaseWithGateway(pythonPath, workspacePath string, gatewayCfg *config.GatewayConfig) (*UseCase, error) {
+func NewUseCaseWithGateway(kernelCfg *config.KernelConfig, workspacePath string, sessionRepo repository.KernelSessionRepository, serverCfg *config.ServerConfig, events event.Publisher) (*UseCase, error) {
+	gatewayCfg := &kernelCfg.Gateway
 	uc := &UseCase{
 		kernelSpecs:   make(map[string]*KernelSpec),
-		pythonPath:    pythonPath,
+		cfg:           kernelCfg,
+		pythonPath:    kernelCfg.PythonPath,
 		workspacePath: workspacePath,
+		sessionRepo:   sessionRepo,
+		instanceID:    serverCfg.GetInstanceID(),
+		instanceAddr:  serverCfg.AdvertiseAddress,
+		events:        events,
 	}
 
 	// Initialize gateway if enabled
@@ -136,6 +231,22 @@ func NewUseCaseWithGateway(pythonPath, workspacePath string, gatewayCfg *config.
 	return uc, nil
 }
 
+// OutputBufferSize returns the configured per-session output channel buffer size
+func (uc *UseCase) OutputBufferSize() int {
+	return uc.cfg.GetOutputBufferSize()
+}
+
+// CaptureResultsDir returns the workspace folder, as a display path, that
+// files captured from executions are imported into
+func (uc *UseCase) CaptureResultsDir() string {
+	return uc.cfg.Capture.GetResultsDir()
+}
+
+// RunSaveInterval returns how often a notebook run writes its partial outputs
+func (uc *UseCase) RunSaveInterval() time.Duration {
+	return uc.cfg.GetRunSaveInterval()
+}
+
 // IsGatewayEnabled returns whether gateway mode is enabled
 func (uc *UseCase) IsGatewayEnabled() bool {
 	return uc.gatewayEnabled
@@ -149,6 +260,15 @@ func (uc *UseCase) GetGatewayURL() string {
 	return ""
 }
 
+// GatewayCircuitState returns the gateway circuit breaker state, or "" when
+// gateway mode is disabled
+func (uc *UseCase) GatewayCircuitState() string {
+	if uc.gatewayManager != nil {
+		return uc.gatewayManager.GetClient().CircuitState()
+	}
+	return ""
+}
+
 // initKernelSpecs initializes the available kernel specifications
 func (uc *UseCase) initKernelSpecs() {
 	// Find Python interpreter
@@ -168,16 +288,73 @@ func (uc *UseCase) initKernelSpecs() {
 			Name:        "python3",
 			DisplayName: "Python 3",
 			Language:    "python",
-			Argv:        []string{pythonPath, "-m", "ipykernel_launcher", "-f", "{connection_file}"},
+			Argv:        ipykernelArgv(pythonPath),
 		}
 	}
 
+	// Configured specs override the default and any discovered specs
+	for _, specCfg := range uc.cfg.Specs {
+		spec, err := kernelSpecFromConfig(specCfg)
+		if err != nil {
+			log.Warn().Err(err).Str("kernel", specCfg.Name).Msg("Skipping invalid kernel spec")
+			continue
+		}
+		uc.kernelSpecs[spec.Name] = spec
+	}
+
 	// Log available kernels
 	for name := range uc.kernelSpecs {
 		log.Info().Str("kernel", name).Msg("Registered kernel spec")
 	}
 }
 
+// ipykernelArgv returns the standard ipykernel launch command for an interpreter
+func ipykernelArgv(pythonPath string) []string {
+	return []string{pythonPath, "-m", "ipykernel_launcher", "-f", "{connection_file}"}
+}
+
+// kernelSpecFromConfig builds a kernel spec from its configuration, filling in
+// defaults for the display name, language and argv
+func kernelSpecFromConfig(specCfg config.KernelSpecConfig) (*KernelSpec, error) {
+	if specCfg.Name == "" {
+		return nil, fmt.Errorf("kernel spec name is required")
+	}
+
+	argv := specCfg.Argv
+	if len(argv) == 0 {
+		if specCfg.PythonPath == "" {
+			return nil, fmt.Errorf("kernel spec %s needs python_path or argv", specCfg.Name)
+		}
+		argv = ipykernelArgv(specCfg.PythonPath)
+	}
+
+	spec := &KernelSpec{
+		Name:        specCfg.Name,
+		DisplayName: specCfg.DisplayName,
+		Language:    specCfg.Language,
+		Argv:        argv,
+	}
+	if spec.DisplayName == "" {
+		spec.DisplayName = specCfg.Name
+	}
+	if spec.Language == "" {
+		spec.Language = "python"
+	}
+
+	if len(specCfg.Env) > 0 {
+		spec.Env = make(map[string]string, len(specCfg.Env))
+		for _, entry := range specCfg.Env {
+			key, value, ok := strings.Cut(entry, "=")
+			if !ok || key == "" {
+				return nil, fmt.Errorf("invalid env entry %q, expected KEY=VALUE", entry)
+			}
+			spec.Env[key] = value
+		}
+	}
+
+	return spec, nil
+}
+
 // ListKernelSpecs returns available kernel specifications
 func (uc *UseCase) ListKernelSpecs(ctx context.Context) (map[string]*KernelSpec, error) {
 	// If gateway is enabled, fetch specs from gateway
@@ -199,8 +376,8 @@ func (uc *UseCase) ListKernelSpecs(ctx context.Context) (map[string]*KernelSpec,
 	}
 
 	// Fall back to local specs
-	// Also try to discover installed kernels from Jupyter
-	discovered := uc.discoverJupyterKernels()
+	// Also include installed kernels discovered from Jupyter
+	discovered := uc.cachedJupyterKernels(false)
 
 	// Merge discovered kernels with default specs
 	result := make(map[string]*KernelSpec)
@@ -216,6 +393,40 @@ func (uc *UseCase) ListKernelSpecs(ctx context.Context) (map[string]*KernelSpec,
 	return result, nil
 }
 
+// AllowedSpecs returns the specs in specs that apps with appID may start
+func (uc *UseCase) AllowedSpecs(appID string, specs map[string]*KernelSpec) map[string]*KernelSpec {
+	allowed := make(map[string]*KernelSpec, len(specs))
+	for name, spec := range specs {
+		if uc.cfg.IsSpecAllowed(appID, name) {
+			allowed[name] = spec
+		}
+	}
+	return allowed
+}
+
+// RefreshKernelSpecs forces rediscovery of kernel specs, e.g. after a new
+// kernel was installed, and returns the refreshed list. In gateway mode the
+// specs are always fetched fresh from the gateway.
+func (uc *UseCase) RefreshKernelSpecs(ctx context.Context) (map[string]*KernelSpec, error) {
+	if !uc.gatewayEnabled || uc.gatewayManager == nil {
+		uc.cachedJupyterKernels(true)
+	}
+	return uc.ListKernelSpecs(ctx)
+}
+
+// cachedJupyterKernels returns discovered Jupyter kernels, rediscovering them
+// when the cache is older than the configured TTL or refresh is set
+func (uc *UseCase) cachedJupyterKernels(refresh bool) map[string]*KernelSpec {
+	uc.specCacheMu.Lock()
+	defer uc.specCacheMu.Unlock()
+
+	if refresh || uc.specCache == nil || time.Since(uc.specCachedAt) > uc.cfg.GetSpecCacheTTL() {
+		uc.specCache = uc.discoverJupyterKernels()
+		uc.specCachedAt = time.Now()
+	}
+	return uc.specCache
+}
+
 // discoverJupyterKernels discovers installed Jupyter kernels
 func (uc *UseCase) discoverJupyterKernels() map[string]*KernelSpec {
 	specs := make(map[string]*KernelSpec)
@@ -256,24 +467,63 @@ func (uc *UseCase) discoverJupyterKernels() map[string]*KernelSpec {
 	return specs
 }
 
-// StartKernel starts a new kernel instance
-func (uc *UseCase) StartKernel(ctx context.Context, specName string, userID string) (*KernelInfo, error) {
+// StartKernel starts a kernel of specName for userID, failing with
+// ErrKernelSpecNotAllowed if appID may not start the spec. An empty appID
+// skips the check, e.g. when restarting a kernel that was already admitted.
+// homeDir is the storage path of the user's workspace root; local kernels
+// start in it, so relative paths stay in the user's own files.
+// allowConcurrent lets a local kernel run executions concurrently; gateway
+// kernels always run them one at a time.
+func (uc *UseCase) StartKernel(ctx context.Context, specName string, userID string, appID string, homeDir string, allowConcurrent bool) (*KernelInfo, error) {
+	if appID != "" && !uc.cfg.IsSpecAllowed(appID, specName) {
+		return nil, fmt.Errorf("%w: %s", ErrKernelSpecNotAllowed, specName)
+	}
+
 	// If gateway is enabled, start kernel on gateway
+	var info *KernelInfo
+	var err error
 	if uc.gatewayEnabled && uc.gatewayManager != nil {
-		return uc.startGatewayKernel(ctx, specName, userID)
+		if allowConcurrent {
+			log.Info().Str("spec", specName).Msg("Gateway kernels execute one cell at a time, ignoring concurrent execution")
+		}
+		info, err = uc.startGatewayKernel(ctx, specName, userID)
+	} else {
+		// Fall back to local kernel
+		info, err = uc.startLocalKernel(ctx, specName, userID, homeDir, allowConcurrent)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// Fall back to local kernel
-	return uc.startLocalKernel(ctx, specName, userID)
+	var actorID *uuid.UUID
+	if id, err := uuid.Parse(userID); err == nil {
+		actorID = &id
+	}
+	uc.publish(ctx, event.NewKernel(event.KernelStarted, info.ID, actorID))
+	return info, nil
+}
+
+// publish emits a kernel event if an event publisher is configured
+func (uc *UseCase) publish(ctx context.Context, e *event.Event) {
+	if uc.events != nil {
+		uc.events.Publish(ctx, e)
+	}
 }
 
 // startGatewayKernel starts a kernel on the remote gateway
 func (uc *UseCase) startGatewayKernel(ctx context.Context, specName string, userID string) (*KernelInfo, error) {
 	gk, err := uc.gatewayManager.StartKernel(ctx, specName, userID)
 	if err != nil {
+		var launchErr *gateway.LaunchError
+		if errors.As(err, &launchErr) {
+			return nil, &KernelStartError{KernelID: launchErr.KernelID, Reason: launchErr.Reason, Output: launchErr.Detail}
+		}
 		return nil, err
 	}
 
+	// Persist ownership so the kernel can be reattached after a restart
+	uc.saveSession(ctx, gk.ID, userID, specName, true)
+
 	return &KernelInfo{
 		ID:             gk.ID,
 		Name:           gk.Name,
@@ -282,16 +532,20 @@ func (uc *UseCase) startGatewayKernel(ctx context.Context, specName string, user
 		LastActivity:   gk.LastActivity,
 		UserID:         userID,
 		IsGateway:      true,
+		InstanceID:     uc.instanceID,
+		InstanceAddr:   uc.instanceAddr,
 	}, nil
 }
 
 // startLocalKernel starts a kernel locally
-func (uc *UseCase) startLocalKernel(ctx context.Context, specName string, userID string) (*KernelInfo, error) {
+func (uc *UseCase) startLocalKernel(ctx context.Context, specName string, userID string, homeDir string, allowConcurrent bool) (*KernelInfo, error) {
 	spec, exists := uc.kernelSpecs[specName]
 	if !exists {
-		// Try discovered specs
-		discovered := uc.discoverJupyterKernels()
-		spec, exists = discovered[specName]
+		// Try discovered specs, rediscovering once in case it was just installed
+		spec, exists = uc.cachedJupyterKernels(false)[specName]
+		if !exists {
+			spec, exists = uc.cachedJupyterKernels(true)[specName]
+		}
 		if !exists {
 			return nil, fmt.Errorf("kernel spec not found: %s", specName)
 		}
@@ -299,9 +553,9 @@ func (uc *UseCase) startLocalKernel(ctx context.Context, specName string, userID
 
 	kernelID := uuid.New().String()
 
-	// Create connection file directory
-	connectionDir := filepath.Join(os.TempDir(), "workspace-kernels", kernelID)
-	if err := os.MkdirAll(connectionDir, 0755); err != nil {
+	// Create connection file directory; the connection file holds the HMAC key
+	connectionDir, err := uc.createConnectionDir(kernelID)
+	if err != nil {
 		return nil, fmt.Errorf("failed to create connection directory: %w", err)
 	}
 
@@ -322,7 +576,7 @@ func (uc *UseCase) startLocalKernel(ctx context.Context, specName string, userID
 	}
 
 	connectionData, _ := json.Marshal(connectionInfo)
-	if err := os.WriteFile(connectionFile, connectionData, 0644); err != nil {
+	if err := os.WriteFile(connectionFile, connectionData, 0600); err != nil {
 		return nil, fmt.Errorf("failed to write connection file: %w", err)
 	}
 
@@ -339,14 +593,40 @@ func (uc *UseCase) startLocalKernel(ctx context.Context, specName string, userID
 		return nil, fmt.Errorf("failed to create kernel wrapper script")
 	}
 
+	// Start in the user's workspace root rather than the shared base path,
+	// which holds every user's files
+	workDir := uc.workspacePath
+	if homeDir != "" {
+		workDir = filepath.Join(uc.workspacePath, filepath.FromSlash(path.Clean("/"+homeDir)))
+		if err := os.MkdirAll(workDir, 0755); err != nil {
+			os.RemoveAll(connectionDir)
+			return nil, fmt.Errorf("failed to create kernel working directory: %w", err)
+		}
+	} else {
+		log.Warn().Str("user_id", userID).Msg("No workspace root for kernel, starting in the shared workspace path")
+	}
+
 	// Use background context so kernel won't be killed when HTTP request ends
 	cmd := exec.Command(args[0], "-u", wrapperScript)
-	cmd.Dir = uc.workspacePath
+	cmd.Dir = workDir
 
 	// Set environment
 	cmd.Env = append(os.Environ(), 
 		"PYTHONUNBUFFERED=1",
 		fmt.Sprintf("KERNEL_ID=%s", kernelID),
+		"KERNEL_HOME_DIR="+workDir,
+		fmt.Sprintf("KERNEL_MAX_REPR_LENGTH=%d", uc.cfg.GetMaxReprLength()),
+	)
+	if allowConcurrent {
+		cmd.Env = append(cmd.Env, "KERNEL_CONCURRENT_EXECUTION=1")
+	}
+	if uc.cfg.DisableShell {
+		cmd.Env = append(cmd.Env, "KERNEL_SHELL_DISABLED=1")
+	}
+	cmd.Env = append(cmd.Env,
+		"KERNEL_MAGICS_PRESET="+uc.cfg.Magics.GetPreset(),
+		"KERNEL_MAGICS_ALLOW="+strings.Join(uc.cfg.Magics.Allow, ","),
+		"KERNEL_MAGICS_DENY="+strings.Join(uc.cfg.Magics.Deny, ","),
 	)
 	if spec.Env != nil {
 		for k, v := range spec.Env {
@@ -382,6 +662,10 @@ func (uc *UseCase) startLocalKernel(ctx context.Context, specName string, userID
 		ExecutionCount: 0,
 		LastActivity:   time.Now(),
 		UserID:         userID,
+		InstanceID:     uc.instanceID,
+		InstanceAddr:   uc.instanceAddr,
+
+		AllowConcurrentExecution: allowConcurrent,
 	}
 
 	instance := &KernelInstance{
@@ -390,7 +674,13 @@ func (uc *UseCase) startLocalKernel(ctx context.Context, specName string, userID
 		stdin:          json.NewEncoder(stdinPipe),
 		stdout:         json.NewDecoder(stdoutPipe),
 		outputChannels: make(map[string]chan *KernelMessage),
+		outputFilters:  make(map[string]MessageFilter),
+		droppedCounts:  make(map[string]*atomic.Uint64),
+		stderr:         newLineTail(kernelLogLines),
+		connectionDir:  connectionDir,
+		homeDir:        homeDir,
 		stopChan:       make(chan struct{}),
+		pongChan:       make(chan struct{}, 1),
 	}
 
 	uc.kernels.Store(kernelID, instance)
@@ -398,18 +688,11 @@ func (uc *UseCase) startLocalKernel(ctx context.Context, specName string, userID
 	// Create a channel to receive the ready signal
 	readyChan := make(chan bool, 1)
 	
-	// Start goroutine to handle stderr
+	// Start goroutine to handle stderr, keeping the tail for startup failures
+	stderrDone := make(chan struct{})
 	go func() {
-		buf := make([]byte, 1024)
-		for {
-			n, err := stderrPipe.Read(buf)
-			if err != nil {
-				return
-			}
-			if n > 0 {
-				log.Debug().Str("kernel_id", kernelID).Str("stderr", string(buf[:n])).Msg("Kernel stderr")
-			}
-		}
+		defer close(stderrDone)
+		readKernelStderr(kernelID, stderrPipe, instance.stderr)
 	}()
 
 	// Start goroutine to read kernel output and wait for ready signal
@@ -435,19 +718,28 @@ func (uc *UseCase) startLocalKernel(ctx context.Context, specName string, userID
 	}()
 
 	// Wait for kernel to be ready with timeout
+	startupTimeout := uc.cfg.GetStartupTimeout()
+	failStart := func(reason string) error {
+		// Kill the process and wait briefly for stderr to drain so the
+		// client learns why, e.g. a missing ipykernel module
+		cmd.Process.Kill()
+		select {
+		case <-stderrDone:
+		case <-time.After(time.Second):
+		}
+		uc.kernels.Delete(kernelID)
+		os.RemoveAll(connectionDir)
+		return &KernelStartError{KernelID: kernelID, Reason: reason, Output: instance.stderr.String()}
+	}
 	select {
 	case ready := <-readyChan:
 		if !ready {
-			// Kill the process if kernel failed to start
-			cmd.Process.Kill()
-			uc.kernels.Delete(kernelID)
-			return nil, fmt.Errorf("kernel failed to start")
+			return nil, failStart("kernel process exited before becoming ready")
 		}
 		kernelInfo.Status = "idle"
-	case <-time.After(10 * time.Second):
-		// Timeout - kernel may still work, just set to idle
-		log.Warn().Str("kernel_id", kernelID).Msg("Kernel ready timeout, continuing anyway")
-		kernelInfo.Status = "idle"
+	case <-time.After(startupTimeout):
+		log.Warn().Str("kernel_id", kernelID).Dur("timeout", startupTimeout).Msg("Kernel ready timeout")
+		return nil, failStart(fmt.Sprintf("kernel not ready after %s", startupTimeout))
 	}
 
 	// Start goroutine to monitor process status
@@ -463,8 +755,12 @@ func (uc *UseCase) startLocalKernel(ctx context.Context, specName string, userID
 			inst := val.(*KernelInstance)
 			inst.Info.Status = "dead"
 		}
+		// The connection file is useless once the process is gone, however it exited
+		os.RemoveAll(connectionDir)
 	}()
 
+	uc.saveSession(ctx, kernelID, userID, specName, false)
+
 	return kernelInfo, nil
 }
 
@@ -482,15 +778,82 @@ import subprocess
 import re
 import time
 import contextlib
+import threading
+import base64
+import builtins
+import warnings
 from datetime import datetime
 
+# Pyplot figures are sent as PNG when a cell finishes, as with IPython's
+# inline backend, so plotting must not open windows
+os.environ.setdefault("MPLBACKEND", "Agg")
+warnings.filterwarnings("ignore", message=".*non-interactive, and thus cannot be shown")
+
 # Global namespace for code execution
 _globals = {"__name__": "__main__", "__builtins__": __builtins__}
 _locals = _globals
 
+# Requests submitted before this time are aborted (set when a cell errors with stop_on_error)
+_abort_before = 0.0
+
+# Run each execution on its own thread instead of one at a time in order.
+# The working directory is shared, so concurrent cells should not change it.
+CONCURRENT_EXECUTION = os.environ.get("KERNEL_CONCURRENT_EXECUTION") == "1"
+
+# Serializes protocol writes and execution counting across execution threads
+_send_lock = threading.Lock()
+_count_lock = threading.Lock()
+
+class _ThreadStream(io.TextIOBase):
+    """Routes writes to the capture buffer of the writing thread, so
+    concurrent executions each capture only their own output."""
+
+    def __init__(self, default):
+        self._default = default
+        self._local = threading.local()
+
+    def _target(self):
+        buffer = getattr(self._local, "buffer", None)
+        return self._default if buffer is None else buffer
+
+    def writable(self):
+        return True
+
+    def write(self, s):
+        return self._target().write(s)
+
+    def flush(self):
+        self._target().flush()
+
+if CONCURRENT_EXECUTION:
+    sys.stdout = _ThreadStream(sys.stdout)
+    sys.stderr = _ThreadStream(sys.stderr)
+
+@contextlib.contextmanager
+def capture_output(stdout_buffer, stderr_buffer):
+    """Capture the output of the running execution."""
+    if not CONCURRENT_EXECUTION:
+        with contextlib.redirect_stdout(stdout_buffer), contextlib.redirect_stderr(stderr_buffer):
+            yield
+        return
+    sys.stdout._local.buffer = stdout_buffer
+    sys.stderr._local.buffer = stderr_buffer
+    try:
+        yield
+    finally:
+        sys.stdout._local.buffer = None
+        sys.stderr._local.buffer = None
+
+# Set on shared deployments: every magic that runs a shell command or writes
+# files fails instead, whatever the magic allow list says
+SHELL_DISABLED = os.environ.get("KERNEL_SHELL_DISABLED") == "1"
+SHELL_DISABLED_ERROR = "shell execution is disabled on this server"
+
 # Magic command handlers
 def magic_sh(args, msg_id):
     """Execute shell command: %sh <command> or !<command>"""
+    if SHELL_DISABLED:
+        return None, SHELL_DISABLED_ERROR
     result = subprocess.run(args, shell=True, capture_output=True, text=True)
     output = ""
     if result.stdout:
@@ -500,8 +863,8 @@ def magic_sh(args, msg_id):
     return output, None
 
 def magic_cd(args, msg_id):
-    """Change directory: %cd <path>"""
-    path = args.strip() if args else os.path.expanduser("~")
+    """Change directory: %cd <path>, by default to the user's workspace root"""
+    path = args.strip() if args else os.environ.get("KERNEL_HOME_DIR") or os.path.expanduser("~")
     path = os.path.expanduser(path)
     path = os.path.expandvars(path)
     try:
@@ -533,6 +896,8 @@ def magic_env(args, msg_id):
 
 def magic_pip(args, msg_id):
     """Run pip command: %pip <args>"""
+    if SHELL_DISABLED:
+        return None, SHELL_DISABLED_ERROR
     cmd = f"{sys.executable} -m pip {args}"
     result = subprocess.run(cmd, shell=True, capture_output=True, text=True)
     output = ""
@@ -690,6 +1055,8 @@ def magic_tail(args, msg_id):
 # Cell magic handlers (%%magic)
 def cell_magic_sh(code, msg_id):
     """Execute entire cell as shell script: %%sh"""
+    if SHELL_DISABLED:
+        return None, SHELL_DISABLED_ERROR
     result = subprocess.run(code, shell=True, capture_output=True, text=True)
     output = ""
     if result.stdout:
@@ -700,6 +1067,8 @@ def cell_magic_sh(code, msg_id):
 
 def cell_magic_bash(code, msg_id):
     """Execute entire cell as bash script: %%bash"""
+    if SHELL_DISABLED:
+        return None, SHELL_DISABLED_ERROR
     result = subprocess.run(code, shell=True, executable='/bin/bash', capture_output=True, text=True)
     output = ""
     if result.stdout:
@@ -714,6 +1083,8 @@ def cell_magic_time(code, msg_id):
 
 def cell_magic_writefile(code, msg_id, args):
     """Write cell to file: %%writefile <filename>"""
+    if SHELL_DISABLED:
+        return None, SHELL_DISABLED_ERROR
     filename = args.strip()
     if not filename:
         return None, "Usage: %%writefile <filename>"
@@ -754,6 +1125,33 @@ CELL_MAGICS = {
     'writefile': cell_magic_writefile,
 }
 
+# Magics that run shell commands, disabled by the "safe" preset
+SHELL_MAGICS = {
+    '!', '%sh', '%pip', '%ls', '%cat', '%mkdir', '%rm', '%cp', '%mv', '%head', '%tail',
+    '%%sh', '%%bash',
+}
+
+def _magic_names(var):
+    """Parse a comma-separated list of magic names from the environment."""
+    return {name.strip() for name in os.environ.get(var, "").split(",") if name.strip()}
+
+def _disabled_magics():
+    """Resolve the configured preset, allow and deny lists to the disabled magics."""
+    preset = os.environ.get("KERNEL_MAGICS_PRESET", "all")
+    if preset == "none":
+        disabled = {'!'} | {'%' + name for name in LINE_MAGICS} | {'%%' + name for name in CELL_MAGICS}
+    elif preset == "safe":
+        disabled = set(SHELL_MAGICS)
+    else:
+        disabled = set()
+    return (disabled - _magic_names("KERNEL_MAGICS_ALLOW")) | _magic_names("KERNEL_MAGICS_DENY")
+
+DISABLED_MAGICS = _disabled_magics()
+
+def magic_disabled_error(name):
+    """Error reported for a magic the server configuration disables."""
+    return f"{name} is disabled on this server"
+
 def process_magic(code, msg_id):
     """Process magic commands in code. Returns (processed_code, output, error)"""
     lines = code.split('\n')
@@ -768,6 +1166,8 @@ def process_magic(code, msg_id):
             cell_code = '\n'.join(lines[1:])
             
             if magic_name in CELL_MAGICS:
+                if f"%%{magic_name}" in DISABLED_MAGICS:
+                    return None, None, magic_disabled_error(f"%%{magic_name}")
                 if magic_name == 'writefile':
                     return None, *cell_magic_writefile(cell_code, msg_id, magic_args)
                 return None, *CELL_MAGICS[magic_name](cell_code, msg_id)
@@ -783,6 +1183,8 @@ def process_magic(code, msg_id):
         
         # Handle !command (shell shortcut)
         if stripped.startswith('!'):
+            if '!' in DISABLED_MAGICS:
+                return None, None, magic_disabled_error("Shell escape !")
             cmd = stripped[1:]
             out, err = magic_sh(cmd, msg_id)
             if err:
@@ -798,6 +1200,8 @@ def process_magic(code, msg_id):
             magic_args = match.group(2)
             
             if magic_name in LINE_MAGICS:
+                if f"%{magic_name}" in DISABLED_MAGICS:
+                    return None, None, magic_disabled_error(f"%{magic_name}")
                 out, err = LINE_MAGICS[magic_name](magic_args, msg_id)
                 if err:
                     return None, None, err
@@ -815,11 +1219,156 @@ def process_magic(code, msg_id):
     
     return remaining_code, magic_output, None
 
-def execute_code(code, msg_id):
-    """Execute code and capture outputs."""
+def evaluate_user_expressions(expressions):
+    """Evaluate user expressions in the kernel namespace, as in execute_reply."""
+    results = {}
+    for name, expr in (expressions or {}).items():
+        try:
+            value = eval(expr, _globals, _locals)
+            results[name] = {
+                "status": "ok",
+                "data": {"text/plain": format_repr(value)},
+                "metadata": {}
+            }
+        except Exception as e:
+            results[name] = {
+                "status": "error",
+                "ename": type(e).__name__,
+                "evalue": str(e),
+                "traceback": traceback.format_exc().split('\n')
+            }
+    return results
+
+def abort_execution(msg_id):
+    """Reply to a queued request that was skipped because an earlier cell failed."""
+    send_message({
+        "msg_id": f"{msg_id}_reply",
+        "msg_type": "execute_reply",
+        "parent_id": msg_id,
+        "content": {"status": "aborted"}
+    })
+
+# Longest result text sent to clients (0 = unlimited), so evaluating a huge
+# object does not flood the output channel
+MAX_REPR_LENGTH = int(os.environ.get("KERNEL_MAX_REPR_LENGTH") or 0)
+
+def format_repr(value):
+    """Return repr(value) as sent in text/plain, truncated to MAX_REPR_LENGTH with a note."""
+    text = repr(value)
+    if MAX_REPR_LENGTH and len(text) > MAX_REPR_LENGTH:
+        text = (text[:MAX_REPR_LENGTH] + "...\n"
+                f"[output truncated: showing {MAX_REPR_LENGTH} of {len(text)} characters]")
+    return text
+
+# Rich representations sent next to text/plain, by the method producing them
+RICH_REPRS = (
+    ("_repr_html_", "text/html"),
+    ("_repr_markdown_", "text/markdown"),
+    ("_repr_svg_", "image/svg+xml"),
+    ("_repr_png_", "image/png"),
+    ("_repr_jpeg_", "image/jpeg"),
+)
+
+def is_figure(value):
+    """Whether value is a matplotlib figure, without importing matplotlib."""
+    mpl_figure = sys.modules.get("matplotlib.figure")
+    return mpl_figure is not None and isinstance(value, mpl_figure.Figure)
+
+def figure_png(fig):
+    """Render a matplotlib figure as base64 PNG and close it, so it is not sent again."""
+    buf = io.BytesIO()
+    try:
+        fig.savefig(buf, format="png", bbox_inches="tight")
+    finally:
+        plt = sys.modules.get("matplotlib.pyplot")
+        if plt is not None:
+            plt.close(fig)
+    return base64.b64encode(buf.getvalue()).decode("ascii")
+
+def format_data(value):
+    """Return the MIME bundle for a value: text/plain plus the rich
+    representations it provides through _repr_*_ methods, as in IPython.
+    Text representations longer than MAX_REPR_LENGTH are left out."""
+    data = {"text/plain": format_repr(value)}
+    if is_figure(value):
+        data["image/png"] = figure_png(value)
+        return data
+    for method, mime in RICH_REPRS:
+        repr_method = getattr(value, method, None)
+        if not callable(repr_method):
+            continue
+        try:
+            rich = repr_method()
+        except Exception:
+            continue
+        if isinstance(rich, tuple):
+            # (data, metadata)
+            rich = rich[0] if rich else None
+        if isinstance(rich, bytes):
+            if mime in ("image/png", "image/jpeg"):
+                rich = base64.b64encode(rich).decode("ascii")
+            else:
+                rich = rich.decode("utf-8", "replace")
+        if not isinstance(rich, str):
+            continue
+        if MAX_REPR_LENGTH and not mime.startswith("image/") and len(rich) > MAX_REPR_LENGTH:
+            continue
+        data[mime] = rich
+    return data
+
+# msg_id of the execution running on each thread, for display()
+_current = threading.local()
+_display_count = 0
+
+def send_display(msg_id, data):
+    """Send a display_data message for an execution."""
+    global _display_count
+    with _count_lock:
+        _display_count += 1
+        n = _display_count
+    send_message({
+        "msg_id": f"{msg_id}_display_{n}",
+        "msg_type": "display_data",
+        "parent_id": msg_id,
+        "content": {"data": data, "metadata": {}}
+    })
+
+def display(*objs):
+    """Show objects in the cell output with their rich representations, as IPython's display() does."""
+    msg_id = getattr(_current, "msg_id", None) or "display"
+    for obj in objs:
+        send_display(msg_id, format_data(obj))
+
+# A builtin, so it survives %reset
+builtins.display = display
+
+def send_figures(msg_id):
+    """Send the open pyplot figures as PNG and close them, as IPython's inline
+    backend does when a cell finishes."""
+    plt = sys.modules.get("matplotlib.pyplot")
+    if plt is None:
+        return
+    for num in plt.get_fignums():
+        fig = plt.figure(num)
+        try:
+            png = figure_png(fig)
+        except Exception:
+            continue
+        send_display(msg_id, {"text/plain": format_repr(fig), "image/png": png})
+
+def compile_cell(code, mode, line_offset=0):
+    """Compile cell code; blank lines in front make tracebacks count from line_offset."""
+    return compile("\n" * line_offset + code, '<cell>', mode)
+
+def execute_code(code, msg_id, user_expressions=None, stop_on_error=True, line_offset=0, cwd=None):
+    """Execute code and capture outputs, in cwd if given."""
+    global _abort_before
     outputs = []
-    execution_count = getattr(execute_code, 'count', 0) + 1
-    execute_code.count = execution_count
+    prev_cwd = None
+    _current.msg_id = msg_id
+    with _count_lock:
+        execution_count = getattr(execute_code, 'count', 0) + 1
+        execute_code.count = execution_count
     
     # Send execution state busy
     send_message({
@@ -841,6 +1390,11 @@ def execute_code(code, msg_id):
     })
     
     try:
+        # Run in the requested directory, restored afterwards
+        if cwd:
+            prev_cwd = os.getcwd()
+            os.chdir(cwd)
+
         # Process magic commands first
         remaining_code, magic_output, magic_error = process_magic(code, msg_id)
         
@@ -865,10 +1419,10 @@ def execute_code(code, msg_id):
             stdout_capture = io.StringIO()
             stderr_capture = io.StringIO()
             
-            with contextlib.redirect_stdout(stdout_capture), contextlib.redirect_stderr(stderr_capture):
+            with capture_output(stdout_capture, stderr_capture):
                 # Try to compile as expression first (for display output)
                 try:
-                    compiled = compile(remaining_code, '<cell>', 'eval')
+                    compiled = compile_cell(remaining_code, 'eval', line_offset)
                     result = eval(compiled, _globals, _locals)
                     if result is not None:
                         # Send execute_result
@@ -877,14 +1431,14 @@ def execute_code(code, msg_id):
                             "msg_type": "execute_result",
                             "parent_id": msg_id,
                             "content": {
-                                "data": {"text/plain": repr(result)},
+                                "data": format_data(result),
                                 "metadata": {},
                                 "execution_count": execution_count
                             }
                         })
                 except SyntaxError:
                     # Not an expression, execute as statement
-                    compiled = compile(remaining_code, '<cell>', 'exec')
+                    compiled = compile_cell(remaining_code, 'exec', line_offset)
                     exec(compiled, _globals, _locals)
             
             # Send captured stdout
@@ -912,6 +1466,9 @@ def execute_code(code, msg_id):
                         "text": stderr_text
                     }
                 })
+
+            # Send figures the cell plotted
+            send_figures(msg_id)
         
         # Send execute_reply success
         send_message({
@@ -920,13 +1477,22 @@ def execute_code(code, msg_id):
             "parent_id": msg_id,
             "content": {
                 "status": "ok",
-                "execution_count": execution_count
+                "execution_count": execution_count,
+                "user_expressions": evaluate_user_expressions(user_expressions)
             }
         })
         
     except Exception as e:
+        # Abort everything queued behind this cell
+        if stop_on_error:
+            _abort_before = time.time()
+
         # Send error
         tb = traceback.format_exc()
+        try:
+            send_figures(msg_id)
+        except Exception:
+            pass
         send_message({
             "msg_id": f"{msg_id}_error",
             "msg_type": "error",
@@ -947,11 +1513,18 @@ def execute_code(code, msg_id):
                 "status": "error",
                 "execution_count": execution_count,
                 "ename": type(e).__name__,
-                "evalue": str(e)
+                "evalue": str(e),
+                "user_expressions": {}
             }
         })
     
     finally:
+        if prev_cwd is not None:
+            try:
+                os.chdir(prev_cwd)
+            except OSError:
+                pass
+
         # Send execution state idle
         send_message({
             "msg_id": f"{msg_id}_status_idle",
@@ -962,8 +1535,13 @@ def execute_code(code, msg_id):
 
 
 def send_message(msg):
-    """Send a message to stdout as JSON."""
-    print(json.dumps(msg), flush=True)
+    """Send a message to the server as one JSON line. It goes to the process
+    stdout even while cell output is captured, and is safe to call from
+    execution threads."""
+    line = json.dumps(msg) + "\n"
+    with _send_lock:
+        sys.__stdout__.write(line)
+        sys.__stdout__.flush()
 
 
 def main():
@@ -986,7 +1564,26 @@ def main():
             if msg_type == "execute":
                 code = request.get("code", "")
                 msg_id = request.get("msg_id", "unknown")
-                execute_code(code, msg_id)
+                if request.get("submitted_at", 0) < _abort_before:
+                    abort_execution(msg_id)
+                    continue
+                args = (code, msg_id, request.get("user_expressions"), request.get("stop_on_error", True), request.get("line_offset", 0), request.get("cwd"))
+                if CONCURRENT_EXECUTION:
+                    threading.Thread(target=execute_code, args=args, daemon=True).start()
+                else:
+                    execute_code(*args)
+            elif msg_type == "ping":
+                # Liveness probe: answering proves the main loop is not stuck
+                msg_id = request.get("msg_id", "ping")
+                send_message({
+                    "msg_id": f"{msg_id}_pong",
+                    "msg_type": "pong",
+                    "parent_id": msg_id,
+                    "content": {}
+                })
+            elif msg_type == "reset":
+                # Runs after the execution in progress, as if the user ran %reset -f
+                magic_reset("-f", request.get("msg_id", "reset"))
             elif msg_type == "interrupt":
                 # Handle interrupt (not fully implemented in this simple version)
                 pass
@@ -1036,6 +1633,15 @@ func (uc *UseCase) readKernelOutput(instance *KernelInstance) {
 				continue
 			}
 
+			// Probe replies are internal and do not count as activity
+			if msg.MsgType == pongMsgType {
+				select {
+				case instance.pongChan <- struct{}{}:
+				default:
+				}
+				continue
+			}
+
 			// Update kernel info
 			instance.Info.LastActivity = time.Now()
 			if msg.MsgType == "status" {
@@ -1044,16 +1650,32 @@ func (uc *UseCase) readKernelOutput(instance *KernelInstance) {
 				}
 			}
 
-			// Broadcast to all registered channels
-			instance.channelMu.RLock()
-			for _, ch := range instance.outputChannels {
-				select {
-				case ch <- &msg:
-				default:
-					// Channel full, skip
-				}
+			instance.broadcast(&msg)
+		}
+	}
+}
+
+// broadcast sends a message to all registered output channels
+func (ki *KernelInstance) broadcast(msg *KernelMessage) {
+	ki.channelMu.RLock()
+	defer ki.channelMu.RUnlock()
+
+	for sessionID, ch := range ki.outputChannels {
+		if !ki.outputFilters[sessionID].Allows(msg.MsgType) {
+			continue
+		}
+		select {
+		case ch <- msg:
+		default:
+			// Channel full, drop and count it
+			dropped := ki.droppedCounts[sessionID].Add(1)
+			if dropped == 1 || dropped%100 == 0 {
+				log.Warn().
+					Str("kernel_id", ki.Info.ID).
+					Str("session_id", sessionID).
+					Uint64("dropped", dropped).
+					Msg("Kernel output buffer full, dropping messages")
 			}
-			instance.channelMu.RUnlock()
 		}
 	}
 }
@@ -1063,14 +1685,19 @@ func (uc *UseCase) StopKernel(ctx context.Context, kernelID string) error {
 	// Try gateway first if enabled
 	if uc.gatewayEnabled && uc.gatewayManager != nil {
 		if _, exists := uc.gatewayManager.GetKernel(kernelID); exists {
-			return uc.gatewayManager.StopKernel(ctx, kernelID)
+			if err := uc.gatewayManager.StopKernel(ctx, kernelID); err != nil {
+				return err
+			}
+			uc.deleteSession(ctx, kernelID)
+			uc.publish(ctx, event.NewKernel(event.KernelStopped, kernelID, nil))
+			return nil
 		}
 	}
 
 	// Fall back to local kernel
 	value, exists := uc.kernels.Load(kernelID)
 	if !exists {
-		return fmt.Errorf("kernel not found: %s", kernelID)
+		return uc.kernelNotFound(ctx, kernelID)
 	}
 
 	instance := value.(*KernelInstance)
@@ -1103,11 +1730,12 @@ func (uc *UseCase) StopKernel(ctx context.Context, kernelID string) error {
 
 	// Clean up
 	uc.kernels.Delete(kernelID)
+	uc.deleteSession(ctx, kernelID)
 
 	// Clean up connection directory
-	connectionDir := filepath.Join(os.TempDir(), "workspace-kernels", kernelID)
-	os.RemoveAll(connectionDir)
+	os.RemoveAll(instance.connectionDir)
 
+	uc.publish(ctx, event.NewKernel(event.KernelStopped, kernelID, nil))
 	return nil
 }
 
@@ -1123,12 +1751,15 @@ func (uc *UseCase) RestartKernel(ctx context.Context, kernelID string) error {
 	// Fall back to local kernel
 	value, exists := uc.kernels.Load(kernelID)
 	if !exists {
-		return fmt.Errorf("kernel not found: %s", kernelID)
+		return uc.kernelNotFound(ctx, kernelID)
 	}
 
 	instance := value.(*KernelInstance)
 	specName := instance.Info.Name
 	userID := instance.Info.UserID
+	allowConcurrent := instance.Info.AllowConcurrentExecution
+	objectID := instance.Info.ObjectID
+	homeDir := instance.homeDir
 
 	// Stop existing kernel
 	if err := uc.StopKernel(ctx, kernelID); err != nil {
@@ -1136,7 +1767,7 @@ func (uc *UseCase) RestartKernel(ctx context.Context, kernelID string) error {
 	}
 
 	// Start new kernel with same ID
-	newInfo, err := uc.StartKernel(ctx, specName, userID)
+	newInfo, err := uc.StartKernel(ctx, specName, userID, "", homeDir, allowConcurrent)
 	if err != nil {
 		return err
 	}
@@ -1147,6 +1778,13 @@ func (uc *UseCase) RestartKernel(ctx context.Context, kernelID string) error {
 		uc.kernels.Delete(newInfo.ID)
 		newInstance.Info.ID = kernelID
 		uc.kernels.Store(kernelID, newInstance)
+
+		// Keep the persisted session keyed by the original ID
+		uc.deleteSession(ctx, newInfo.ID)
+		uc.saveSession(ctx, kernelID, userID, specName, false)
+		if objectID != nil {
+			uc.AssociateObject(ctx, kernelID, *objectID)
+		}
 	}
 
 	return nil
@@ -1164,7 +1802,7 @@ func (uc *UseCase) InterruptKernel(ctx context.Context, kernelID string) error {
 	// Fall back to local kernel
 	value, exists := uc.kernels.Load(kernelID)
 	if !exists {
-		return fmt.Errorf("kernel not found: %s", kernelID)
+		return uc.kernelNotFound(ctx, kernelID)
 	}
 
 	instance := value.(*KernelInstance)
@@ -1177,6 +1815,41 @@ func (uc *UseCase) InterruptKernel(ctx context.Context, kernelID string) error {
 	return nil
 }
 
+// ResetKernel clears the variables defined in a kernel without restarting
+// it. The reset runs once the execution in progress finishes; modules stay
+// loaded, so re-running imports is cheap.
+func (uc *UseCase) ResetKernel(ctx context.Context, kernelID string) error {
+	uc.touchSession(ctx, kernelID)
+
+	// Try gateway first if enabled
+	if uc.gatewayEnabled && uc.gatewayManager != nil {
+		if _, exists := uc.gatewayManager.GetKernel(kernelID); exists {
+			return uc.gatewayManager.ExecuteCode(ctx, kernelID, "%reset -f", "reset_"+uuid.New().String(), true, false, false, nil, 0)
+		}
+	}
+
+	// Fall back to local kernel
+	value, exists := uc.kernels.Load(kernelID)
+	if !exists {
+		return uc.kernelNotFound(ctx, kernelID)
+	}
+
+	instance := value.(*KernelInstance)
+	if instance.Info.Status == "dead" {
+		return fmt.Errorf("%w, please restart", ErrKernelDead)
+	}
+
+	instance.mu.Lock()
+	err := instance.stdin.Encode(map[string]string{"type": "reset", "msg_id": "reset_" + uuid.New().String()})
+	instance.mu.Unlock()
+	if err != nil {
+		instance.Info.Status = "dead"
+		return fmt.Errorf("%w: failed to send reset request: %w", ErrKernelDead, err)
+	}
+
+	return nil
+}
+
 // GetKernelStatus returns the status of a kernel
 func (uc *UseCase) GetKernelStatus(ctx context.Context, kernelID string) (*KernelStatus, error) {
 	// Try gateway first if enabled
@@ -1189,6 +1862,7 @@ func (uc *UseCase) GetKernelStatus(ctx context.Context, kernelID string) (*Kerne
 				ExecutionCount:   0,
 				LastActivity:     gk.LastActivity,
 				ConnectionStatus: "connected",
+				DroppedMessages:  gk.DroppedMessages(),
 			}, nil
 		}
 	}
@@ -1196,7 +1870,7 @@ func (uc *UseCase) GetKernelStatus(ctx context.Context, kernelID string) (*Kerne
 	// Fall back to local kernel
 	value, exists := uc.kernels.Load(kernelID)
 	if !exists {
-		return nil, fmt.Errorf("kernel not found: %s", kernelID)
+		return nil, uc.kernelNotFound(ctx, kernelID)
 	}
 
 	instance := value.(*KernelInstance)
@@ -1208,6 +1882,9 @@ func (uc *UseCase) GetKernelStatus(ctx context.Context, kernelID string) (*Kerne
 		ExecutionCount:   instance.Info.ExecutionCount,
 		LastActivity:     instance.Info.LastActivity,
 		ConnectionStatus: "connected",
+		DroppedMessages:  instance.droppedMessages(),
+
+		AllowConcurrentExecution: instance.Info.AllowConcurrentExecution,
 	}
 
 	// Check if process is still running
@@ -1219,6 +1896,24 @@ func (uc *UseCase) GetKernelStatus(ctx context.Context, kernelID string) (*Kerne
 	return status, nil
 }
 
+// GetKernelLogs returns the most recent stderr lines of a kernel. Gateway
+// kernels log on the gateway, so no lines are available for them here.
+func (uc *UseCase) GetKernelLogs(ctx context.Context, kernelID string) (*KernelLogs, error) {
+	if uc.gatewayEnabled && uc.gatewayManager != nil {
+		if _, exists := uc.gatewayManager.GetKernel(kernelID); exists {
+			return &KernelLogs{KernelID: kernelID, Lines: []string{}}, nil
+		}
+	}
+
+	value, exists := uc.kernels.Load(kernelID)
+	if !exists {
+		return nil, uc.kernelNotFound(ctx, kernelID)
+	}
+
+	instance := value.(*KernelInstance)
+	return &KernelLogs{KernelID: kernelID, Lines: instance.stderr.Lines()}, nil
+}
+
 // ListKernels returns all kernels for a user
 func (uc *UseCase) ListKernels(ctx context.Context, userID string) ([]*KernelInfo, error) {
 	var kernels []*KernelInfo
@@ -1227,15 +1922,7 @@ func (uc *UseCase) ListKernels(ctx context.Context, userID string) ([]*KernelInf
 	if uc.gatewayEnabled && uc.gatewayManager != nil {
 		gatewayKernels := uc.gatewayManager.ListKernels(userID)
 		for _, gk := range gatewayKernels {
-			kernels = append(kernels, &KernelInfo{
-				ID:             gk.ID,
-				Name:           gk.Name,
-				Status:         gk.Status,
-				ExecutionCount: 0,
-				LastActivity:   gk.LastActivity,
-				UserID:         gk.UserID,
-				IsGateway:      true,
-			})
+			kernels = append(kernels, uc.gatewayKernelInfo(gk))
 		}
 	}
 
@@ -1248,16 +1935,66 @@ func (uc *UseCase) ListKernels(ctx context.Context, userID string) ([]*KernelInf
 		return true
 	})
 
+	// Include kernels owned by other instances so clients can route to them
+	kernels = append(kernels, uc.listRemoteKernels(ctx, userID)...)
+
 	return kernels, nil
 }
 
-// RegisterOutputChannel registers a channel to receive kernel output
-func (uc *UseCase) RegisterOutputChannel(kernelID, sessionID string, ch chan *KernelMessage) {
+// ListAllKernels returns the kernels of all users: gateway and local kernels
+// of this instance, plus kernels other instances recorded in the session store
+func (uc *UseCase) ListAllKernels(ctx context.Context) ([]*KernelInfo, error) {
+	kernels := []*KernelInfo{}
+
+	if uc.gatewayEnabled && uc.gatewayManager != nil {
+		for _, gk := range uc.gatewayManager.ListAllKernels() {
+			kernels = append(kernels, uc.gatewayKernelInfo(gk))
+		}
+	}
+
+	uc.kernels.Range(func(key, value interface{}) bool {
+		kernels = append(kernels, value.(*KernelInstance).Info)
+		return true
+	})
+
+	if uc.sessionRepo != nil {
+		sessions, err := uc.sessionRepo.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list kernel sessions: %w", err)
+		}
+		for i := range sessions {
+			if !uc.isLocalSession(&sessions[i]) {
+				kernels = append(kernels, remoteKernelInfo(&sessions[i]))
+			}
+		}
+	}
+
+	return kernels, nil
+}
+
+// gatewayKernelInfo converts a gateway kernel owned by this instance to KernelInfo
+func (uc *UseCase) gatewayKernelInfo(gk *gateway.GatewayKernel) *KernelInfo {
+	return &KernelInfo{
+		ID:             gk.ID,
+		Name:           gk.Name,
+		Status:         gk.Status,
+		ExecutionCount: 0,
+		LastActivity:   gk.LastActivity,
+		UserID:         gk.UserID,
+		IsGateway:      true,
+		InstanceID:     uc.instanceID,
+		InstanceAddr:   uc.instanceAddr,
+	}
+}
+
+// RegisterOutputChannel registers a channel to receive kernel output. Only
+// messages passing filter are sent to it; a nil filter forwards everything.
+func (uc *UseCase) RegisterOutputChannel(kernelID, sessionID string, ch chan *KernelMessage, filter MessageFilter) {
 	// Try gateway first if enabled
 	if uc.gatewayEnabled && uc.gatewayManager != nil {
 		if _, exists := uc.gatewayManager.GetKernel(kernelID); exists {
 			// Create adapter channel for gateway
-			gatewayCh := make(chan *gateway.KernelOutputMessage, 100)
+			gatewayCh := make(chan *gateway.KernelOutputMessage, uc.OutputBufferSize())
 			uc.gatewayManager.RegisterOutputChannel(kernelID, sessionID, gatewayCh)
 
 			// Start goroutine to convert gateway messages to KernelMessage
@@ -1266,12 +2003,16 @@ func (uc *UseCase) RegisterOutputChannel(kernelID, sessionID string, ch chan *Ke
 					if msg == nil {
 						return
 					}
+					if !filter.Allows(msg.MsgType) {
+						continue
+					}
 					ch <- &KernelMessage{
 						MsgID:    msg.MsgID,
 						MsgType:  msg.MsgType,
 						ParentID: msg.ParentID,
 						Content:  msg.Content,
 						Metadata: msg.Metadata,
+						Buffers:  msg.Buffers,
 					}
 				}
 			}()
@@ -1288,6 +2029,8 @@ func (uc *UseCase) RegisterOutputChannel(kernelID, sessionID string, ch chan *Ke
 	instance := value.(*KernelInstance)
 	instance.channelMu.Lock()
 	instance.outputChannels[sessionID] = ch
+	instance.outputFilters[sessionID] = filter
+	instance.droppedCounts[sessionID] = &atomic.Uint64{}
 	instance.channelMu.Unlock()
 }
 
@@ -1310,50 +2053,75 @@ func (uc *UseCase) UnregisterOutputChannel(kernelID, sessionID string) {
 	instance := value.(*KernelInstance)
 	instance.channelMu.Lock()
 	delete(instance.outputChannels, sessionID)
+	delete(instance.outputFilters, sessionID)
+	delete(instance.droppedCounts, sessionID)
 	instance.channelMu.Unlock()
 }
 
 // ExecuteCode executes code on a kernel
 func (uc *UseCase) ExecuteCode(ctx context.Context, kernelID, sessionID string, req *ExecuteRequest) error {
+	uc.touchSession(ctx, kernelID)
+
 	// Try gateway first if enabled
 	if uc.gatewayEnabled && uc.gatewayManager != nil {
 		if _, exists := uc.gatewayManager.GetKernel(kernelID); exists {
-			return uc.gatewayManager.ExecuteCode(ctx, kernelID, req.Code, req.MsgID, req.Silent, req.StoreHistory)
+			if req.CaptureDir != "" {
+				return ErrCaptureUnsupported
+			}
+			if req.WorkDir != "" {
+				log.Debug().Str("kernel_id", kernelID).Str("cwd", req.Cwd).Msg("Ignoring cwd for gateway kernel")
+			}
+			return uc.gatewayManager.ExecuteCode(ctx, kernelID, req.Code, req.MsgID, req.Silent, req.StoreHistory, req.stopOnError(), req.UserExpressions, req.lineOffset())
 		}
 	}
 
 	// Fall back to local kernel
 	value, exists := uc.kernels.Load(kernelID)
 	if !exists {
-		return fmt.Errorf("kernel not found: %s", kernelID)
+		return uc.kernelNotFound(ctx, kernelID)
 	}
 
 	instance := value.(*KernelInstance)
 
 	// Check if kernel process is still running
 	if instance.Info.Status == "dead" {
-		return fmt.Errorf("kernel is dead, please restart")
+		return fmt.Errorf("%w, please restart", ErrKernelDead)
 	}
 
 	// Check if process has exited
 	if instance.Process.ProcessState != nil && instance.Process.ProcessState.Exited() {
 		instance.Info.Status = "dead"
-		return fmt.Errorf("kernel process has exited")
+		return fmt.Errorf("%w: process has exited", ErrKernelDead)
+	}
+
+	// The kernel changes into cwd for this execution only
+	var cwd string
+	switch {
+	case req.CaptureDir != "":
+		cwd = req.CaptureDir
+	case req.WorkDir != "":
+		cwd = filepath.Join(uc.workspacePath, filepath.FromSlash(req.WorkDir))
 	}
 
-	// Send execute request to kernel
+	// Send execute request to kernel. submitted_at lets the kernel abort
+	// requests that were queued before a failing cell finished.
 	instance.mu.Lock()
 	err := instance.stdin.Encode(map[string]interface{}{
-		"type":   "execute",
-		"msg_id": req.MsgID,
-		"code":   req.Code,
+		"type":             "execute",
+		"msg_id":           req.MsgID,
+		"code":             req.Code,
+		"user_expressions": req.UserExpressions,
+		"stop_on_error":    req.stopOnError(),
+		"line_offset":      req.lineOffset(),
+		"cwd":              cwd,
+		"submitted_at":     float64(time.Now().UnixNano()) / 1e9,
 	})
 	instance.mu.Unlock()
 
 	if err != nil {
 		// Mark kernel as dead if we can't write to it
 		instance.Info.Status = "dead"
-		return fmt.Errorf("failed to send execute request: %w", err)
+		return fmt.Errorf("%w: failed to send execute request: %w", ErrKernelDead, err)
 	}
 
 	return nil
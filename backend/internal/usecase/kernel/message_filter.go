@@ -0,0 +1,29 @@
+package kernel
+
+import "strings"
+
+// MessageFilter is an allowlist of kernel message types forwarded to an output
+// session. A nil filter forwards every message.
+type MessageFilter map[string]bool
+
+// ParseMessageFilter builds a filter from a comma-separated list of message
+// types, e.g. "stream,execute_result,error". An empty list yields nil.
+func ParseMessageFilter(msgTypes string) MessageFilter {
+	var filter MessageFilter
+	for _, msgType := range strings.Split(msgTypes, ",") {
+		msgType = strings.TrimSpace(msgType)
+		if msgType == "" {
+			continue
+		}
+		if filter == nil {
+			filter = make(MessageFilter)
+		}
+		filter[msgType] = true
+	}
+	return filter
+}
+
+// Allows reports whether messages of msgType pass the filter
+func (f MessageFilter) Allows(msgType string) bool {
+	return f == nil || f[msgType]
+}
@@ -0,0 +1,196 @@
+package kernel
+
+import (
+	"time"
+)
+
+// streamCoalesceWindow is how long a stream message waits for more output
+// of the same stream before it is sent
+const streamCoalesceWindow = 50 * time.Millisecond
+
+// unlimitedMsgTypes bypass the rate limiter so execution completion and
+// kernel state changes are never delayed
+var unlimitedMsgTypes = map[string]bool{
+	"execute_reply": true,
+	"status":        true,
+	"input_request": true,
+}
+
+// RateLimitOutput forwards kernel messages from in, sending at most rate
+// messages per second. Consecutive stream messages with the same name and
+// parent are merged into one while waiting, so a tight print loop yields a few
+// large messages instead of thousands of small ones. Message order is
+// preserved. A rate of 0 or less returns in unchanged. The returned channel is
+// closed when in is closed, a nil message is received, or done is closed.
+func RateLimitOutput(in <-chan *KernelMessage, rate int, done <-chan struct{}) <-chan *KernelMessage {
+	if rate <= 0 {
+		return in
+	}
+
+	out := make(chan *KernelMessage, cap(in))
+	l := &outputLimiter{
+		out:    out,
+		done:   done,
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+	go l.run(in)
+	return out
+}
+
+// outputLimiter is a token bucket holding up to one second of messages
+type outputLimiter struct {
+	out    chan<- *KernelMessage
+	done   <-chan struct{}
+	rate   float64
+	tokens float64
+	last   time.Time
+
+	pending *KernelMessage // Stream message being coalesced
+	merged  bool           // Whether pending is a private copy that may be modified
+}
+
+func (l *outputLimiter) run(in <-chan *KernelMessage) {
+	defer close(l.out)
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+	for {
+		select {
+		case msg, ok := <-in:
+			if !ok || msg == nil {
+				l.flush(false)
+				return
+			}
+			if !l.handle(msg, timer) {
+				return
+			}
+		case <-timer.C:
+			if l.pending == nil {
+				continue
+			}
+			if l.take() {
+				if !l.flush(false) {
+					return
+				}
+				continue
+			}
+			// Out of tokens: keep coalescing until the next token is due
+			timer.Reset(l.untilToken())
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// handle processes one incoming message, returning false once done is closed
+func (l *outputLimiter) handle(msg *KernelMessage, timer *time.Timer) bool {
+	if msg.MsgType == "stream" {
+		if l.canMerge(msg) {
+			l.merge(msg)
+			return true
+		}
+		if !l.flush(true) {
+			return false
+		}
+		l.pending = msg
+		l.merged = false
+		timer.Reset(streamCoalesceWindow)
+		return true
+	}
+
+	if unlimitedMsgTypes[msg.MsgType] {
+		// Earlier output must not trail the reply, so it goes out unthrottled too
+		return l.flush(false) && l.send(msg)
+	}
+
+	if !l.flush(true) {
+		return false
+	}
+	return l.wait() && l.send(msg)
+}
+
+func (l *outputLimiter) canMerge(msg *KernelMessage) bool {
+	if l.pending == nil || l.pending.ParentID != msg.ParentID {
+		return false
+	}
+	_, ok := msg.Content["text"].(string)
+	return ok && l.pending.Content["name"] == msg.Content["name"]
+}
+
+// merge appends msg's text to pending. Messages are shared between sessions,
+// so pending is copied before it is first modified.
+func (l *outputLimiter) merge(msg *KernelMessage) {
+	if !l.merged {
+		content := make(map[string]interface{}, len(l.pending.Content))
+		for k, v := range l.pending.Content {
+			content[k] = v
+		}
+		copied := *l.pending
+		copied.Content = content
+		l.pending = &copied
+		l.merged = true
+	}
+	text, _ := l.pending.Content["text"].(string)
+	l.pending.Content["text"] = text + msg.Content["text"].(string)
+}
+
+// flush sends the pending stream message, waiting for a token if limited
+func (l *outputLimiter) flush(limited bool) bool {
+	if l.pending == nil {
+		return true
+	}
+	msg := l.pending
+	l.pending = nil
+	if limited && !l.wait() {
+		return false
+	}
+	return l.send(msg)
+}
+
+func (l *outputLimiter) send(msg *KernelMessage) bool {
+	select {
+	case l.out <- msg:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+// refill adds the tokens accrued since the last refill
+func (l *outputLimiter) refill() {
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+}
+
+// take consumes a token if one is available
+func (l *outputLimiter) take() bool {
+	l.refill()
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+func (l *outputLimiter) untilToken() time.Duration {
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// wait blocks until a token is available, returning false if done is closed
+func (l *outputLimiter) wait() bool {
+	for !l.take() {
+		select {
+		case <-time.After(l.untilToken()):
+		case <-l.done:
+			return false
+		}
+	}
+	return true
+}
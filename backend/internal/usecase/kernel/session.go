@@ -0,0 +1,290 @@
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// saveSession persists the kernel owner. Failures are logged rather than
+// returned since the kernel itself is already running.
+func (uc *UseCase) saveSession(ctx context.Context, kernelID, userID, specName string, isGateway bool) {
+	if uc.sessionRepo == nil {
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		log.Warn().Str("kernel_id", kernelID).Str("user_id", userID).Msg("Not persisting kernel session for non-UUID user")
+		return
+	}
+
+	session := &entity.KernelSession{
+		KernelID:     kernelID,
+		UserID:       uid,
+		SpecName:     specName,
+		IsGateway:    isGateway,
+		InstanceID:   uc.instanceID,
+		InstanceAddr: uc.instanceAddr,
+	}
+	if err := uc.sessionRepo.Create(ctx, session); err != nil {
+		log.Error().Err(err).Str("kernel_id", kernelID).Msg("Failed to persist kernel session")
+	}
+}
+
+// AssociateObject records the notebook a kernel was started for. Failures are
+// logged rather than returned since the kernel itself is already running.
+func (uc *UseCase) AssociateObject(ctx context.Context, kernelID string, objectID int64) {
+	if value, exists := uc.kernels.Load(kernelID); exists {
+		value.(*KernelInstance).Info.ObjectID = &objectID
+	}
+	if uc.sessionRepo == nil {
+		return
+	}
+	if err := uc.sessionRepo.SetObject(ctx, kernelID, objectID); err != nil {
+		log.Error().Err(err).Str("kernel_id", kernelID).Int64("object_id", objectID).Msg("Failed to associate kernel session with object")
+	}
+}
+
+// ObjectKernel is a kernel started for a notebook, with its owner
+type ObjectKernel struct {
+	*KernelInfo
+	Owner *entity.UserResponse `json:"owner,omitempty"`
+}
+
+// ListKernelsForObject returns the kernels started for a notebook by any
+// user. Kernels of this instance report their live status; those of other
+// instances are listed as remote.
+func (uc *UseCase) ListKernelsForObject(ctx context.Context, objectID int64) ([]ObjectKernel, error) {
+	kernels := []ObjectKernel{}
+	if uc.sessionRepo == nil {
+		uc.kernels.Range(func(key, value interface{}) bool {
+			info := value.(*KernelInstance).Info
+			if info.ObjectID != nil && *info.ObjectID == objectID {
+				kernels = append(kernels, ObjectKernel{KernelInfo: info})
+			}
+			return true
+		})
+		return kernels, nil
+	}
+
+	sessions, err := uc.sessionRepo.ListByObject(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		session := &sessions[i]
+		info := uc.liveKernelInfo(session.KernelID)
+		if info == nil {
+			if uc.isLocalSession(session) {
+				// Left behind by a kernel that is gone
+				continue
+			}
+			info = remoteKernelInfo(session)
+		}
+
+		kernelInfo := *info
+		kernelInfo.ObjectID = session.ObjectID
+		kernel := ObjectKernel{KernelInfo: &kernelInfo}
+		if session.Owner != nil {
+			kernel.Owner = session.Owner.ToResponse()
+		}
+		kernels = append(kernels, kernel)
+	}
+	return kernels, nil
+}
+
+// liveKernelInfo returns a kernel running on this instance, or nil
+func (uc *UseCase) liveKernelInfo(kernelID string) *KernelInfo {
+	if uc.gatewayEnabled && uc.gatewayManager != nil {
+		if gk, exists := uc.gatewayManager.GetKernel(kernelID); exists {
+			return uc.gatewayKernelInfo(gk)
+		}
+	}
+	if value, exists := uc.kernels.Load(kernelID); exists {
+		return value.(*KernelInstance).Info
+	}
+	return nil
+}
+
+// deleteSession removes a persisted kernel session
+func (uc *UseCase) deleteSession(ctx context.Context, kernelID string) {
+	if uc.sessionRepo == nil {
+		return
+	}
+	if err := uc.sessionRepo.Delete(ctx, kernelID); err != nil {
+		log.Error().Err(err).Str("kernel_id", kernelID).Msg("Failed to delete kernel session")
+	}
+}
+
+// touchSession records activity on a persisted kernel session
+func (uc *UseCase) touchSession(ctx context.Context, kernelID string) {
+	if uc.sessionRepo == nil {
+		return
+	}
+	if err := uc.sessionRepo.UpdateLastActivity(ctx, kernelID, time.Now()); err != nil {
+		log.Debug().Err(err).Str("kernel_id", kernelID).Msg("Failed to update kernel session activity")
+	}
+}
+
+// GetKernelOwner returns the owner of a kernel, falling back to the persisted
+// session when the kernel is not tracked in memory
+func (uc *UseCase) GetKernelOwner(ctx context.Context, kernelID string) (string, error) {
+	if uc.gatewayManager != nil {
+		if gk, exists := uc.gatewayManager.GetKernel(kernelID); exists {
+			return gk.UserID, nil
+		}
+	}
+	if value, exists := uc.kernels.Load(kernelID); exists {
+		return value.(*KernelInstance).Info.UserID, nil
+	}
+
+	if uc.sessionRepo != nil {
+		session, err := uc.sessionRepo.GetByKernelID(ctx, kernelID)
+		if err == nil {
+			return session.UserID.String(), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
+}
+
+// isLocalSession reports whether a session belongs to this instance.
+// Sessions recorded before instance tracking have an empty instance ID.
+func (uc *UseCase) isLocalSession(session *entity.KernelSession) bool {
+	return session.InstanceID == "" || session.InstanceID == uc.instanceID
+}
+
+// kernelNotFound builds the error for a kernel missing from this instance,
+// returning a RemoteKernelError when another instance owns it
+func (uc *UseCase) kernelNotFound(ctx context.Context, kernelID string) error {
+	if uc.sessionRepo != nil {
+		session, err := uc.sessionRepo.GetByKernelID(ctx, kernelID)
+		if err == nil && !uc.isLocalSession(session) {
+			return &RemoteKernelError{
+				KernelID:     kernelID,
+				InstanceID:   session.InstanceID,
+				InstanceAddr: session.InstanceAddr,
+			}
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrKernelNotFound, kernelID)
+}
+
+// LocateKernel returns nil if the kernel runs on this instance, a
+// RemoteKernelError if another instance owns it, or a not found error
+func (uc *UseCase) LocateKernel(ctx context.Context, kernelID string) error {
+	if uc.gatewayManager != nil {
+		if _, exists := uc.gatewayManager.GetKernel(kernelID); exists {
+			return nil
+		}
+	}
+	if _, exists := uc.kernels.Load(kernelID); exists {
+		return nil
+	}
+	return uc.kernelNotFound(ctx, kernelID)
+}
+
+// listRemoteKernels returns the user's kernels owned by other instances
+func (uc *UseCase) listRemoteKernels(ctx context.Context, userID string) []*KernelInfo {
+	if uc.sessionRepo == nil {
+		return nil
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil
+	}
+
+	sessions, err := uc.sessionRepo.ListByUser(ctx, uid)
+	if err != nil {
+		log.Warn().Err(err).Str("user_id", userID).Msg("Failed to list kernel sessions")
+		return nil
+	}
+
+	var kernels []*KernelInfo
+	for i := range sessions {
+		session := &sessions[i]
+		if uc.isLocalSession(session) {
+			continue
+		}
+		kernels = append(kernels, remoteKernelInfo(session))
+	}
+	return kernels
+}
+
+// remoteKernelInfo describes a kernel owned by another instance from its session
+func remoteKernelInfo(session *entity.KernelSession) *KernelInfo {
+	return &KernelInfo{
+		ID:           session.KernelID,
+		Name:         session.SpecName,
+		Status:       "remote",
+		LastActivity: session.LastActivity,
+		UserID:       session.UserID.String(),
+		IsGateway:    session.IsGateway,
+		InstanceID:   session.InstanceID,
+		InstanceAddr: session.InstanceAddr,
+		ObjectID:     session.ObjectID,
+	}
+}
+
+// RestoreKernels loads this instance's persisted kernel sessions on boot.
+// Local kernel processes do not survive a restart, so their sessions are
+// dropped; gateway kernels that are still alive are reattached with their
+// original owner. Sessions whose kernels no longer exist on the gateway are
+// removed. Sessions owned by other instances are left untouched.
+func (uc *UseCase) RestoreKernels(ctx context.Context) error {
+	if uc.sessionRepo == nil {
+		return nil
+	}
+
+	sessions, err := uc.sessionRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if uc.isLocalSession(&session) && !session.IsGateway {
+			uc.deleteSession(ctx, session.KernelID)
+		}
+	}
+
+	if !uc.gatewayEnabled || uc.gatewayManager == nil {
+		return nil
+	}
+
+	remoteKernels, err := uc.gatewayManager.GetClient().ListKernels(ctx)
+	if err != nil {
+		return err
+	}
+
+	alive := make(map[string]bool, len(remoteKernels))
+	for _, k := range remoteKernels {
+		alive[k.ID] = true
+	}
+
+	restored := 0
+	for _, session := range sessions {
+		if !uc.isLocalSession(&session) || !session.IsGateway {
+			continue
+		}
+
+		if !alive[session.KernelID] {
+			uc.deleteSession(ctx, session.KernelID)
+			continue
+		}
+
+		if _, err := uc.gatewayManager.AttachKernel(ctx, session.KernelID, session.UserID.String()); err != nil {
+			log.Warn().Err(err).Str("kernel_id", session.KernelID).Msg("Failed to reattach gateway kernel")
+			continue
+		}
+		restored++
+	}
+
+	log.Info().Int("restored", restored).Int("sessions", len(sessions)).Msg("Gateway kernels restored")
+	return nil
+}
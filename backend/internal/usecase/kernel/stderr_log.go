@@ -0,0 +1,88 @@
+package kernel
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// kernelLogLines is how many stderr lines are kept per local kernel for
+// startup error reports and the kernel logs endpoint
+const kernelLogLines = 200
+
+// stderrErrorPattern matches lines that obviously report a problem, e.g.
+// "ModuleNotFoundError: No module named 'ipykernel'" or "CRITICAL ..."
+var stderrErrorPattern = regexp.MustCompile(`(?i)(error|exception|fatal|critical)\b`)
+
+// lineTail keeps the last max lines added to it
+type lineTail struct {
+	mu    sync.Mutex
+	lines []string
+	max   int
+}
+
+func newLineTail(max int) *lineTail {
+	return &lineTail{max: max}
+}
+
+func (t *lineTail) Add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.max {
+		t.lines = append(t.lines[:0], t.lines[len(t.lines)-t.max:]...)
+	}
+}
+
+// Lines returns a copy of the kept lines, oldest first
+func (t *lineTail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.lines...)
+}
+
+func (t *lineTail) String() string {
+	return strings.Join(t.Lines(), "\n")
+}
+
+// readKernelStderr logs a kernel's stderr line by line until the pipe closes,
+// keeping the most recent lines in tail. Tracebacks and error lines are logged
+// at warn level, everything else at debug.
+func readKernelStderr(kernelID string, r io.Reader, tail *lineTail) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+
+	inTraceback := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		tail.Add(line)
+
+		level := zerolog.DebugLevel
+		switch {
+		case strings.HasPrefix(line, "Traceback (most recent call last)"):
+			inTraceback = true
+			level = zerolog.WarnLevel
+		case inTraceback:
+			level = zerolog.WarnLevel
+			// Frames are indented; the first unindented line is the exception itself
+			if line != "" && line[0] != ' ' && line[0] != '\t' {
+				inTraceback = false
+			}
+		case stderrErrorPattern.MatchString(line):
+			level = zerolog.WarnLevel
+		}
+		log.WithLevel(level).Str("kernel_id", kernelID).Str("line", line).Msg("Kernel stderr")
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Debug().Err(err).Str("kernel_id", kernelID).Msg("Stopped parsing kernel stderr")
+		// Keep draining so the kernel never blocks writing to a full pipe
+		io.Copy(io.Discard, r)
+	}
+}
@@ -0,0 +1,101 @@
+package maintenance
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/adapter/storage"
+	"github.com/leondli/workspace/internal/domain/repository"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// UseCase defines the maintenance use case interface
+type UseCase interface {
+	CleanupTokens(ctx context.Context) (*CleanupResult, error)
+	CleanupUploads(ctx context.Context) (int, error)
+	EraseAccounts(ctx context.Context) (int, error)
+}
+
+// AccountEraser erases accounts whose deletion grace period has ended
+type AccountEraser interface {
+	EraseDueAccounts(ctx context.Context) (int, error)
+}
+
+// CleanupResult reports how many expired tokens were removed
+type CleanupResult struct {
+	RefreshTokens int64 `json:"refresh_tokens"`
+}
+
+// uploadCleanupBatch is how many expired upload sessions are removed per query
+const uploadCleanupBatch = 100
+
+type maintenanceUseCase struct {
+	refreshTokenRepo  repository.RefreshTokenRepository
+	uploadSessionRepo repository.UploadSessionRepository
+	storage           *storage.LocalFileStorage
+	accounts          AccountEraser
+}
+
+// NewUseCase creates a new maintenance use case
+func NewUseCase(
+	refreshTokenRepo repository.RefreshTokenRepository,
+	uploadSessionRepo repository.UploadSessionRepository,
+	fileStorage *storage.LocalFileStorage,
+	accounts AccountEraser,
+) UseCase {
+	return &maintenanceUseCase{
+		refreshTokenRepo:  refreshTokenRepo,
+		uploadSessionRepo: uploadSessionRepo,
+		storage:           fileStorage,
+		accounts:          accounts,
+	}
+}
+
+func (u *maintenanceUseCase) CleanupTokens(ctx context.Context) (*CleanupResult, error) {
+	removed, err := u.refreshTokenRepo.DeleteExpired(ctx)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to delete expired refresh tokens", err)
+	}
+
+	result := &CleanupResult{RefreshTokens: removed}
+	log.Info().Int64("refresh_tokens", result.RefreshTokens).Msg("Expired tokens cleaned up")
+	return result, nil
+}
+
+// CleanupUploads removes expired chunked upload sessions together with the
+// content they received, returning how many were removed
+func (u *maintenanceUseCase) CleanupUploads(ctx context.Context) (int, error) {
+	removed := 0
+	now := time.Now()
+	for {
+		sessions, err := u.uploadSessionRepo.ListExpired(ctx, now, uploadCleanupBatch)
+		if err != nil {
+			return removed, apperrors.InternalError("failed to list expired upload sessions", err)
+		}
+		for _, session := range sessions {
+			if err := u.storage.DeleteUpload(ctx, session.ID.String()); err != nil {
+				return removed, apperrors.InternalError("failed to delete upload file", err)
+			}
+			if err := u.uploadSessionRepo.Delete(ctx, session.ID); err != nil {
+				return removed, apperrors.InternalError("failed to delete upload session", err)
+			}
+			removed++
+		}
+		if len(sessions) < uploadCleanupBatch {
+			break
+		}
+	}
+
+	if removed > 0 {
+		log.Info().Int("upload_sessions", removed).Msg("Expired upload sessions cleaned up")
+	}
+	return removed, nil
+}
+
+// EraseAccounts erases accounts whose deletion grace period has ended,
+// returning how many were erased
+func (u *maintenanceUseCase) EraseAccounts(ctx context.Context) (int, error) {
+	return u.accounts.EraseDueAccounts(ctx)
+}
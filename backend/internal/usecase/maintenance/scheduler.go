@@ -0,0 +1,81 @@
+package maintenance
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/infrastructure/config"
+)
+
+// cleanupTimeout bounds a single scheduled cleanup run
+const cleanupTimeout = 5 * time.Minute
+
+// Scheduler periodically runs token and upload cleanup, and erases accounts
+// past their deletion grace period, in the background
+type Scheduler struct {
+	useCase  UseCase
+	cfg      *config.MaintenanceConfig
+	stopChan chan struct{}
+	doneChan chan struct{}
+	stopOnce sync.Once
+	started  atomic.Bool
+}
+
+// NewScheduler creates a new maintenance scheduler
+func NewScheduler(useCase UseCase, cfg *config.MaintenanceConfig) *Scheduler {
+	return &Scheduler{
+		useCase:  useCase,
+		cfg:      cfg,
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+}
+
+// Start runs the scheduler loop in a new goroutine
+func (s *Scheduler) Start() {
+	if s.started.CompareAndSwap(false, true) {
+		go s.run()
+	}
+}
+
+// Stop signals the scheduler to exit and waits for any in-flight run to finish
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+	if s.started.Load() {
+		<-s.doneChan
+	}
+}
+
+func (s *Scheduler) run() {
+	defer close(s.doneChan)
+
+	log.Info().Dur("interval", s.cfg.GetTokenCleanupInterval()).Msg("Token cleanup scheduler started")
+	for {
+		// Re-read the interval each cycle so config reloads take effect
+		timer := time.NewTimer(s.cfg.GetTokenCleanupInterval())
+		select {
+		case <-s.stopChan:
+			timer.Stop()
+			log.Info().Msg("Token cleanup scheduler stopped")
+			return
+		case <-timer.C:
+			ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+			if _, err := s.useCase.CleanupTokens(ctx); err != nil {
+				log.Error().Err(err).Msg("Scheduled token cleanup failed")
+			}
+			if _, err := s.useCase.CleanupUploads(ctx); err != nil {
+				log.Error().Err(err).Msg("Scheduled upload cleanup failed")
+			}
+			if _, err := s.useCase.EraseAccounts(ctx); err != nil {
+				log.Error().Err(err).Msg("Scheduled account erasure failed")
+			}
+			cancel()
+		}
+	}
+}
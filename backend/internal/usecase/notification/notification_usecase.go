@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+	"github.com/leondli/workspace/internal/usecase/event"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// UseCase defines the notification use case interface. Notifications are
+// created from published events rather than by other use cases directly.
+type UseCase interface {
+	event.Publisher
+	ListNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, page, pageSize int) ([]entity.Notification, int64, error)
+	MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error)
+}
+
+// MarkReadInput represents the notifications to mark as read
+type MarkReadInput struct {
+	IDs []uuid.UUID `json:"ids" binding:"max=1000"` // Empty marks all notifications read
+}
+
+type notificationUseCase struct {
+	notificationRepo repository.NotificationRepository
+}
+
+// NewUseCase creates a new notification use case
+func NewUseCase(notificationRepo repository.NotificationRepository) UseCase {
+	return &notificationUseCase{notificationRepo: notificationRepo}
+}
+
+// Publish notifies the recipient of an event caused by another user: the
+// grantee of a permission, or the owner of a directory something was copied into
+func (u *notificationUseCase) Publish(ctx context.Context, e *event.Event) {
+	if e.RecipientID == nil || (e.ActorID != nil && *e.ActorID == *e.RecipientID) {
+		return
+	}
+
+	var notificationType string
+	switch e.Type {
+	case event.PermissionChanged:
+		notificationType = entity.NotificationPermissionGranted
+	case event.ObjectCreated:
+		notificationType = entity.NotificationSharedCopy
+	default:
+		return
+	}
+
+	objectID := e.ObjectID
+	notification := &entity.Notification{
+		UserID:     *e.RecipientID,
+		Type:       notificationType,
+		ObjectID:   &objectID,
+		ObjectName: e.Name,
+		ActorID:    e.ActorID,
+		Role:       e.Role,
+	}
+	if err := u.notificationRepo.Create(ctx, notification); err != nil {
+		log.Error().Err(err).Str("type", notificationType).Str("user_id", e.RecipientID.String()).Msg("Failed to create notification")
+	}
+}
+
+func (u *notificationUseCase) ListNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, page, pageSize int) ([]entity.Notification, int64, error) {
+	notifications, total, err := u.notificationRepo.ListByUser(ctx, userID, unreadOnly, page, pageSize)
+	if err != nil {
+		return nil, 0, apperrors.InternalError("failed to list notifications", err)
+	}
+	return notifications, total, nil
+}
+
+func (u *notificationUseCase) MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error) {
+	count, err := u.notificationRepo.MarkRead(ctx, userID, ids)
+	if err != nil {
+		return 0, apperrors.InternalError("failed to mark notifications read", err)
+	}
+	return count, nil
+}
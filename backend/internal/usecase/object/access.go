@@ -0,0 +1,177 @@
+package object
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// accessFlushTimeout bounds a single write of buffered reads
+const accessFlushTimeout = 30 * time.Second
+
+// AccessRecorder counts content reads in memory and writes them in batches,
+// so reading a file does not cost a database write. Reads buffered when the
+// process dies are lost.
+type AccessRecorder struct {
+	repo     repository.ObjectAccessRepository
+	cfg      *config.AccessStatsConfig
+	mu       sync.Mutex
+	pending  map[int64]*entity.ObjectAccess
+	flushNow chan struct{}
+	stopChan chan struct{}
+	doneChan chan struct{}
+	stopOnce sync.Once
+	started  atomic.Bool
+}
+
+// NewAccessRecorder creates a new access recorder
+func NewAccessRecorder(repo repository.ObjectAccessRepository, cfg *config.AccessStatsConfig) *AccessRecorder {
+	return &AccessRecorder{
+		repo:     repo,
+		cfg:      cfg,
+		pending:  make(map[int64]*entity.ObjectAccess),
+		flushNow: make(chan struct{}, 1),
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+}
+
+// Start runs the flush loop in a new goroutine
+func (r *AccessRecorder) Start() {
+	if r.started.CompareAndSwap(false, true) {
+		go r.run()
+	}
+}
+
+// Stop signals the flush loop to exit and waits for it to write what is buffered
+func (r *AccessRecorder) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+	})
+	if r.started.Load() {
+		<-r.doneChan
+	}
+}
+
+func (r *AccessRecorder) run() {
+	defer close(r.doneChan)
+
+	for {
+		// Re-read the interval each cycle so config reloads take effect
+		timer := time.NewTimer(r.cfg.GetFlushInterval())
+		select {
+		case <-r.stopChan:
+			timer.Stop()
+			r.flush()
+			return
+		case <-r.flushNow:
+			timer.Stop()
+			r.flush()
+		case <-timer.C:
+			r.flush()
+		}
+	}
+}
+
+// Record counts one read of an object. The user is kept only while the
+// access log is enabled.
+func (r *AccessRecorder) Record(objectID int64, userID uuid.UUID) {
+	now := time.Now()
+
+	r.mu.Lock()
+	access, ok := r.pending[objectID]
+	if !ok {
+		access = &entity.ObjectAccess{ObjectID: objectID}
+		r.pending[objectID] = access
+	}
+	access.Count++
+	access.LastAccessedAt = now
+	if r.cfg.LogEnabled && userID != uuid.Nil {
+		if access.Users == nil {
+			access.Users = make(map[uuid.UUID]*entity.UserAccess)
+		}
+		user, ok := access.Users[userID]
+		if !ok {
+			user = &entity.UserAccess{}
+			access.Users[userID] = user
+		}
+		user.Count++
+		user.LastAccessedAt = now
+	}
+	full := len(r.pending) >= r.cfg.GetMaxPending()
+	r.mu.Unlock()
+
+	if full {
+		select {
+		case r.flushNow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flush writes the buffered reads. A failed write is logged and dropped, as
+// the counters are informational.
+func (r *AccessRecorder) flush() {
+	r.mu.Lock()
+	if len(r.pending) == 0 {
+		r.mu.Unlock()
+		return
+	}
+	batch := make([]*entity.ObjectAccess, 0, len(r.pending))
+	for _, access := range r.pending {
+		batch = append(batch, access)
+	}
+	r.pending = make(map[int64]*entity.ObjectAccess)
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), accessFlushTimeout)
+	defer cancel()
+	if err := r.repo.Record(ctx, batch); err != nil {
+		log.Error().Err(err).Int("objects", len(batch)).Msg("Failed to record object accesses")
+	}
+}
+
+// addPending adds the reads of stats' object that are buffered but not yet written
+func (r *AccessRecorder) addPending(stats *entity.ObjectAccessStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	access, ok := r.pending[stats.ObjectID]
+	if !ok {
+		return
+	}
+	stats.DownloadCount += access.Count
+	if stats.LastAccessedAt == nil || access.LastAccessedAt.After(*stats.LastAccessedAt) {
+		last := access.LastAccessedAt
+		stats.LastAccessedAt = &last
+	}
+}
+
+// RecordAccess counts a read of an object's content by a user
+func (u *objectUseCase) RecordAccess(objectID int64, userID uuid.UUID) {
+	u.accessRecorder.Record(objectID, userID)
+}
+
+// GetAccessStats returns how often and how recently an object's content was
+// read, including reads not yet written. Distinct users are reported while
+// the access log is enabled and count written reads only.
+func (u *objectUseCase) GetAccessStats(ctx context.Context, objectID int64) (*entity.ObjectAccessStats, error) {
+	stats, err := u.accessRecorder.repo.GetStats(ctx, objectID, u.accessRecorder.cfg.LogEnabled)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get access statistics", err)
+	}
+	u.accessRecorder.addPending(stats)
+	return stats, nil
+}
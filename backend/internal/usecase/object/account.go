@@ -0,0 +1,251 @@
+package object
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// TransferredObject is an object given to another user when its creator's
+// account was erased
+type TransferredObject struct {
+	ObjectID int64     `json:"object_id"`
+	Path     string    `json:"path"` // After the transfer
+	To       uuid.UUID `json:"to"`
+}
+
+// TransferShared gives away the objects of a user being erased that other
+// users need. Files and directories the user shared from their workspace
+// move to the root of the workspace of the collaborator with the highest
+// role, who becomes their owner; objects the user created in directories
+// shared by others stay there and pass to the directory's creator. With
+// deleteShared, nothing is transferred and EraseUserObjects removes them all.
+func (u *objectUseCase) TransferShared(ctx context.Context, user *entity.User, deleteShared bool) ([]TransferredObject, error) {
+	if deleteShared {
+		return nil, nil
+	}
+
+	objects, err := u.objectRepo.ListByCreator(ctx, user.ID)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to list objects", err)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Path < objects[j].Path })
+
+	workspace := user.GetWorkspacePath()
+	var transferred []TransferredObject
+	var moved []string
+	for i := range objects {
+		obj := &objects[i]
+		if underAny(obj.Path, moved) {
+			continue
+		}
+
+		var to *entity.User
+		if underAny(obj.Path, []string{workspace}) {
+			to, err = u.successor(ctx, obj.ID, user.ID)
+		} else {
+			to, err = u.directoryCreator(ctx, obj, user.ID)
+		}
+		if err != nil {
+			return transferred, err
+		}
+		if to == nil {
+			continue
+		}
+
+		t, err := u.transfer(ctx, obj, user, to, workspace)
+		if err != nil {
+			return transferred, err
+		}
+		transferred = append(transferred, *t)
+		moved = append(moved, obj.Path)
+	}
+	return transferred, nil
+}
+
+// successor returns the collaborator an object shared by userID goes to: the
+// active user with the highest role granted on it, the earliest granted on a
+// tie, or nil when it is not shared
+func (u *objectUseCase) successor(ctx context.Context, objectID int64, userID uuid.UUID) (*entity.User, error) {
+	perms, err := u.permissionRepo.ListByObject(ctx, objectID)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to list permissions", err)
+	}
+
+	var best *entity.Permission
+	for i := range perms {
+		p := &perms[i]
+		if p.UserID == userID || p.User == nil || p.User.Status != entity.UserStatusActive {
+			continue
+		}
+		if best == nil || p.Role.Priority() > best.Role.Priority() ||
+			(p.Role.Priority() == best.Role.Priority() && p.CreatedAt.Before(best.CreatedAt)) {
+			best = p
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	return best.User, nil
+}
+
+// directoryCreator returns the active creator of the directory an object of
+// userID lies in, or nil when that is userID too
+func (u *objectUseCase) directoryCreator(ctx context.Context, obj *entity.Object, userID uuid.UUID) (*entity.User, error) {
+	if obj.ParentID == nil {
+		return nil, nil
+	}
+	parent, err := u.objectRepo.GetByID(ctx, *obj.ParentID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, apperrors.InternalError("failed to get parent directory", err)
+	}
+	if parent.CreatorID == userID || parent.Creator == nil || parent.Creator.Status != entity.UserStatusActive {
+		return nil, nil
+	}
+	return parent.Creator, nil
+}
+
+// transfer makes to the creator and owner of obj and the objects user created
+// under it. Objects in user's workspace first move to the root of to's.
+func (u *objectUseCase) transfer(ctx context.Context, obj *entity.Object, user, to *entity.User, workspace string) (*TransferredObject, error) {
+	if underAny(obj.Path, []string{workspace}) {
+		name, err := u.freeRootName(ctx, to, obj.Name, user.Username)
+		if err != nil {
+			return nil, err
+		}
+		moved, err := u.Move(ctx, obj.ID, &MoveInput{NewName: &name, UserID: to.ID, AppID: to.AppID, Email: to.Email})
+		if err != nil {
+			return nil, err
+		}
+		obj.Path = moved.Path
+	}
+
+	if err := u.objectRepo.TransferTree(ctx, obj.Path, user.ID, to.ID); err != nil {
+		return nil, apperrors.InternalError("failed to transfer objects", err)
+	}
+
+	perm, err := u.permissionRepo.GetByObjectAndUser(ctx, obj.ID, to.ID)
+	switch {
+	case err == nil:
+		perm.Role = entity.RoleOwner
+		perm.IsInherited = false
+		err = u.permissionRepo.Update(ctx, perm)
+	case apperrors.IsNotFound(err):
+		err = u.permissionRepo.Create(ctx, &entity.Permission{
+			ID:       uuid.New(),
+			ObjectID: obj.ID,
+			UserID:   to.ID,
+			Role:     entity.RoleOwner,
+		})
+	}
+	if err != nil {
+		return nil, apperrors.InternalError("failed to grant ownership", err)
+	}
+
+	log.Info().Int64("object_id", obj.ID).Str("from", user.ID.String()).Str("to", to.ID.String()).Str("path", obj.Path).Msg("Object transferred from erased account")
+	return &TransferredObject{ObjectID: obj.ID, Path: obj.Path, To: to.ID}, nil
+}
+
+// freeRootName picks a name for an object moving to the root of to's
+// workspace, adding "_from_<username>" (and a number) when name is taken
+func (u *objectUseCase) freeRootName(ctx context.Context, to *entity.User, name, username string) (string, error) {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 0; n <= maxDuplicateAttempts; n++ {
+		candidate := name
+		if n == 1 {
+			candidate = base + "_from_" + username + ext
+		} else if n > 1 {
+			candidate = fmt.Sprintf("%s_from_%s_%d%s", base, username, n, ext)
+		}
+		exists, err := u.pathTaken(ctx, to.GetWorkspacePath()+"/"+candidate, 0)
+		if err != nil {
+			return "", apperrors.InternalError("failed to check path", err)
+		}
+		if !exists {
+			return candidate, nil
+		}
+	}
+	return "", apperrors.AlreadyExistsError("transferred object name")
+}
+
+// EraseUserObjects removes the rows of the objects a user created, deleted
+// ones included, and of everything left in the user's workspace. The content
+// is left in storage for PurgeErased.
+func (u *objectUseCase) EraseUserObjects(ctx context.Context, user *entity.User) (*entity.ObjectErasure, error) {
+	objects, err := u.objectRepo.ListByCreator(ctx, user.ID)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to list objects", err)
+	}
+
+	workspace := user.GetWorkspacePath()
+	erasure, err := u.objectRepo.EraseByCreator(ctx, user.ID, workspace)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to erase objects", err)
+	}
+
+	// Objects left in other users' directories are purged one by one
+	erasure.Paths = []string{workspace}
+	for _, obj := range objects {
+		if !underAny(obj.Path, erasure.Paths) {
+			erasure.Paths = append(erasure.Paths, obj.Path)
+		}
+	}
+	return erasure, nil
+}
+
+// PurgeErased removes the content of erased objects from storage. Failures
+// are logged and the rest is still removed.
+func (u *objectUseCase) PurgeErased(ctx context.Context, erasure *entity.ObjectErasure) error {
+	failed := 0
+	for _, p := range erasure.Paths {
+		if err := u.storage.Delete(ctx, p); err != nil {
+			log.Warn().Err(err).Str("path", p).Msg("Failed to purge erased content")
+			failed++
+		}
+	}
+	for _, p := range erasure.VersionPaths {
+		if err := u.storage.DeleteVersion(ctx, p); err != nil && !os.IsNotExist(err) {
+			log.Warn().Err(err).Str("path", p).Msg("Failed to purge erased version")
+			failed++
+		}
+	}
+	for _, batchID := range erasure.TrashBatches {
+		if err := u.storage.DeleteTrash(ctx, batchID.String()); err != nil {
+			log.Warn().Err(err).Str("batch_id", batchID.String()).Msg("Failed to purge erased trash")
+			failed++
+		}
+	}
+	for _, trashed := range erasure.TrashPaths {
+		if err := u.storage.DeleteTrashPath(ctx, trashed.BatchID.String(), trashed.Path); err != nil {
+			log.Warn().Err(err).Str("batch_id", trashed.BatchID.String()).Str("path", trashed.Path).Msg("Failed to purge erased trash content")
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d erased items could not be removed from storage", failed)
+	}
+	return nil
+}
+
+// underAny reports whether p is one of roots or lies below one
+func underAny(p string, roots []string) bool {
+	for _, root := range roots {
+		if p == root || strings.HasPrefix(p, root+"/") {
+			return true
+		}
+	}
+	return false
+}
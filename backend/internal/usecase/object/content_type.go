@@ -0,0 +1,110 @@
+package object
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// SetContentTypeInput represents a correction of a file's detected type
+type SetContentTypeInput struct {
+	Type     entity.ObjectType `json:"type"`      // Empty keeps the current type
+	MimeType string            `json:"mime_type"` // Empty clears the override
+}
+
+// SetContentType overrides the app type and MIME type of an existing file,
+// for names the extension-based inference gets wrong. The content is left as is.
+func (u *objectUseCase) SetContentType(ctx context.Context, objectID int64, userID uuid.UUID, objectType entity.ObjectType, mimeType string) (*entity.ObjectResponse, error) {
+	obj, err := u.objectRepo.GetByID(ctx, objectID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+	if obj.IsDirectory() {
+		return nil, apperrors.ValidationError("cannot set the content type of a directory")
+	}
+
+	allowed, err := u.permissionRepo.HasPermission(ctx, objectID, userID, entity.RoleEditor)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check permission", err)
+	}
+	if !allowed {
+		return nil, apperrors.ForbiddenError("no permission to edit this object")
+	}
+
+	if objectType != "" {
+		if !objectType.IsValid() || objectType == entity.ObjectTypeDirectory {
+			return nil, apperrors.InvalidArgumentError(fmt.Sprintf("invalid object type %q", objectType), "type")
+		}
+		if objectType != obj.Type {
+			obj.Type = objectType
+			// A notebook's language comes from its metadata
+			var content []byte
+			if objectType == entity.ObjectTypeNotebook {
+				if content, err = u.storage.ReadFile(ctx, obj.Path); err != nil {
+					return nil, apperrors.InternalError("failed to read file", err)
+				}
+			}
+			u.setLanguage(obj, content)
+		}
+	}
+
+	normalized, err := u.normalizeMimeType(mimeType)
+	if err != nil {
+		return nil, err
+	}
+	obj.MimeType = normalized
+
+	obj.MarkModifiedBy(userID)
+	if err := u.objectRepo.Update(ctx, obj); err != nil {
+		return nil, apperrors.InternalError("failed to update object", err)
+	}
+
+	return obj.ToResponse(), nil
+}
+
+// normalizeMimeType parses a MIME type override and checks it against the
+// configured MIME type policy. Parameters such as charset are kept.
+func (u *objectUseCase) normalizeMimeType(mimeType string) (string, error) {
+	if strings.TrimSpace(mimeType) == "" {
+		return "", nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(mimeType)
+	if err != nil || !strings.Contains(mediaType, "/") {
+		return "", apperrors.InvalidArgumentError(fmt.Sprintf("invalid MIME type %q", mimeType), "mime_type")
+	}
+
+	if u.storageConfig != nil {
+		if len(u.storageConfig.AllowedMIMETypes) > 0 && !containsFold(u.storageConfig.AllowedMIMETypes, mediaType) {
+			return "", apperrors.ValidationError(fmt.Sprintf("MIME type %q is not allowed", mediaType))
+		}
+		if containsFold(u.storageConfig.DeniedMIMETypes, mediaType) {
+			return "", apperrors.ValidationError(fmt.Sprintf("MIME type %q is not allowed", mediaType))
+		}
+	}
+
+	normalized := mime.FormatMediaType(mediaType, params)
+	if normalized == "" || len(normalized) > 255 {
+		return "", apperrors.InvalidArgumentError(fmt.Sprintf("invalid MIME type %q", mimeType), "mime_type")
+	}
+	return normalized, nil
+}
+
+// setLanguage records the syntax highlighting language of obj. content may be
+// nil when only the name changed; notebooks then keep their language, which
+// comes from their metadata rather than the name.
+func (u *objectUseCase) setLanguage(obj *entity.Object, content []byte) {
+	if content == nil && obj.Type == entity.ObjectTypeNotebook && obj.Language != "" {
+		return
+	}
+	obj.Language = entity.DetectLanguage(obj.Name, obj.Type, content, u.storageConfig.GetLanguages())
+}
@@ -0,0 +1,209 @@
+package object
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/pkg/charset"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+	"github.com/leondli/workspace/pkg/textdiff"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change
+const diffContextLines = 3
+
+// Cell diff operations
+const (
+	CellUnchanged = "unchanged"
+	CellAdded     = "added"
+	CellRemoved   = "removed"
+	CellModified  = "modified"
+)
+
+// VersionDiff describes the changes between an object's stored content and
+// other content
+type VersionDiff struct {
+	ObjectID  int64           `json:"object_id"`
+	Version   int             `json:"version"` // Version of the stored content
+	Identical bool            `json:"identical"`
+	Binary    bool            `json:"binary,omitempty"` // Either side is binary; no hunks are produced
+	Additions int             `json:"additions"`
+	Deletions int             `json:"deletions"`
+	Hunks     []textdiff.Hunk `json:"hunks,omitempty"` // Line diff of non-notebook content
+	Cells     []CellDiff      `json:"cells,omitempty"` // Cell diff of notebooks
+}
+
+// CellDiff describes how one notebook cell changed. Only cell types and
+// sources are compared; outputs and metadata are ignored.
+type CellDiff struct {
+	Op       string          `json:"op"` // unchanged, added, removed or modified
+	CellType string          `json:"cell_type"`
+	OldIndex *int            `json:"old_index,omitempty"`
+	NewIndex *int            `json:"new_index,omitempty"`
+	Hunks    []textdiff.Hunk `json:"hunks,omitempty"` // Source diff; absent for unchanged cells
+}
+
+// DiffAgainst compares the object's stored content with candidate without
+// saving anything, so editors can review changes before a save. Notebooks
+// are compared cell by cell.
+func (u *objectUseCase) DiffAgainst(ctx context.Context, objectID int64, candidate []byte) (*VersionDiff, error) {
+	obj, err := u.objectRepo.GetByID(ctx, objectID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+	if obj.IsDirectory() {
+		return nil, apperrors.ValidationError("cannot diff a directory")
+	}
+
+	stored, err := u.storage.ReadFile(ctx, obj.Path)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to read file", err)
+	}
+
+	diff := &VersionDiff{
+		ObjectID:  obj.ID,
+		Version:   obj.CurrentVersion,
+		Identical: bytes.Equal(stored, candidate),
+	}
+
+	oldText, _, oldOK := charset.ToUTF8(stored)
+	newText, _, newOK := charset.ToUTF8(candidate)
+	if !oldOK || !newOK {
+		diff.Binary = true
+		return diff, nil
+	}
+
+	if obj.Type == entity.ObjectTypeNotebook {
+		oldCells, oldErr := parseNotebookCells(oldText)
+		newCells, newErr := parseNotebookCells(newText)
+		if oldErr == nil && newErr == nil {
+			diff.Cells = diffCells(oldCells, newCells)
+			for _, cell := range diff.Cells {
+				for _, hunk := range cell.Hunks {
+					added, deleted := textdiff.Stats(hunk.Lines)
+					diff.Additions += added
+					diff.Deletions += deleted
+				}
+			}
+			return diff, nil
+		}
+	}
+
+	lines := textdiff.Diff(textdiff.SplitLines(string(oldText)), textdiff.SplitLines(string(newText)))
+	diff.Additions, diff.Deletions = textdiff.Stats(lines)
+	diff.Hunks = textdiff.Hunks(lines, diffContextLines)
+	return diff, nil
+}
+
+// notebookCell is the part of a cell compared by diffCells
+type notebookCell struct {
+	CellType string
+	Source   string
+}
+
+func parseNotebookCells(content []byte) ([]notebookCell, error) {
+	var notebook NotebookData
+	if err := json.Unmarshal(content, &notebook); err != nil {
+		return nil, err
+	}
+
+	cells := make([]notebookCell, len(notebook.Cells))
+	for i, cell := range notebook.Cells {
+		cells[i].CellType, _ = cell["cell_type"].(string)
+		switch source := cell["source"].(type) {
+		case string:
+			cells[i].Source = source
+		case []any:
+			var sb strings.Builder
+			for _, line := range source {
+				if s, ok := line.(string); ok {
+					sb.WriteString(s)
+				}
+			}
+			cells[i].Source = sb.String()
+		}
+	}
+	return cells, nil
+}
+
+// diffCells matches cells by type and source, then pairs up runs of removed
+// and added cells as modifications
+func diffCells(oldCells, newCells []notebookCell) []CellDiff {
+	key := func(cell notebookCell) string { return cell.CellType + "\x00" + cell.Source }
+	oldKeys := make([]string, len(oldCells))
+	for i, cell := range oldCells {
+		oldKeys[i] = key(cell)
+	}
+	newKeys := make([]string, len(newCells))
+	for i, cell := range newCells {
+		newKeys[i] = key(cell)
+	}
+
+	var cells []CellDiff
+	var removed, added []int
+	flush := func() {
+		paired := min(len(removed), len(added))
+		for i := 0; i < paired; i++ {
+			cells = append(cells, cellChange(CellModified, oldCells, removed[i], newCells, added[i]))
+		}
+		for _, i := range removed[paired:] {
+			cells = append(cells, cellChange(CellRemoved, oldCells, i, nil, -1))
+		}
+		for _, i := range added[paired:] {
+			cells = append(cells, cellChange(CellAdded, nil, -1, newCells, i))
+		}
+		removed, added = removed[:0], added[:0]
+	}
+
+	oldIndex, newIndex := 0, 0
+	for _, line := range textdiff.Diff(oldKeys, newKeys) {
+		switch line.Op {
+		case textdiff.OpEqual:
+			flush()
+			oi, ni := oldIndex, newIndex
+			cells = append(cells, CellDiff{
+				Op:       CellUnchanged,
+				CellType: oldCells[oi].CellType,
+				OldIndex: &oi,
+				NewIndex: &ni,
+			})
+			oldIndex++
+			newIndex++
+		case textdiff.OpDelete:
+			removed = append(removed, oldIndex)
+			oldIndex++
+		case textdiff.OpInsert:
+			added = append(added, newIndex)
+			newIndex++
+		}
+	}
+	flush()
+	return cells
+}
+
+// cellChange builds the diff of a changed cell; an index of -1 means the
+// cell is absent on that side
+func cellChange(op string, oldCells []notebookCell, oldIndex int, newCells []notebookCell, newIndex int) CellDiff {
+	cell := CellDiff{Op: op}
+	var oldSource, newSource string
+	if oldIndex >= 0 {
+		cell.OldIndex = &oldIndex
+		cell.CellType = oldCells[oldIndex].CellType
+		oldSource = oldCells[oldIndex].Source
+	}
+	if newIndex >= 0 {
+		cell.NewIndex = &newIndex
+		cell.CellType = newCells[newIndex].CellType
+		newSource = newCells[newIndex].Source
+	}
+
+	lines := textdiff.Diff(textdiff.SplitLines(oldSource), textdiff.SplitLines(newSource))
+	cell.Hunks = textdiff.Hunks(lines, diffContextLines)
+	return cell
+}
@@ -0,0 +1,185 @@
+package object
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+const (
+	// exportFilesDir is the archive directory holding workspace files, keeping
+	// them apart from the manifest
+	exportFilesDir = "files"
+	// exportManifestName is the archive entry holding object metadata
+	exportManifestName = "manifest.json"
+	// exportMaxVersions caps the versions listed per object in the manifest
+	exportMaxVersions = 1000
+)
+
+// ExportManifest describes the objects in a workspace export
+type ExportManifest struct {
+	ExportedAt time.Time              `json:"exported_at"`
+	Objects    []ExportManifestObject `json:"objects"`
+	Skipped    []ExportSkippedFile    `json:"skipped,omitempty"`
+}
+
+// ExportManifestObject holds the metadata of one exported object
+type ExportManifestObject struct {
+	ID             int64                    `json:"id"`
+	Path           string                   `json:"path"` // Display path, relative to the workspace root
+	Type           entity.ObjectType        `json:"type"`
+	Size           int64                    `json:"size"`
+	Description    string                   `json:"description,omitempty"`
+	Tags           []string                 `json:"tags,omitempty"`
+	CurrentVersion int                      `json:"current_version"`
+	Versions       []entity.VersionResponse `json:"versions,omitempty"`
+	CreatedAt      time.Time                `json:"created_at"`
+	UpdatedAt      time.Time                `json:"updated_at"`
+}
+
+// ExportSkippedFile records a file left out of an export
+type ExportSkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// ExportWorkspace streams a .tar.gz of all the user's non-deleted objects under
+// files/ at their display paths, followed by manifest.json. Files that cannot
+// be read are skipped and listed in the manifest. The archive is produced
+// while it is read, so callers must drain or close the returned reader.
+func (u *objectUseCase) ExportWorkspace(ctx context.Context, userID uuid.UUID) (io.ReadCloser, error) {
+	objects, err := u.objectRepo.ListByCreator(ctx, userID)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to list objects", err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(u.writeExport(ctx, pw, objects))
+	}()
+	return pr, nil
+}
+
+func (u *objectUseCase) writeExport(ctx context.Context, w io.Writer, objects []entity.Object) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest := &ExportManifest{
+		ExportedAt: time.Now().UTC(),
+		Objects:    make([]ExportManifestObject, 0, len(objects)),
+	}
+
+	for i := range objects {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		obj := &objects[i]
+		displayPath := entity.ConvertToDisplayPath(obj.Path)
+		if displayPath == "/" {
+			continue
+		}
+		name := exportFilesDir + displayPath
+
+		entry, err := u.exportManifestObject(ctx, obj, displayPath)
+		if err != nil {
+			return err
+		}
+
+		if obj.IsDirectory() {
+			if err := tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     name + "/",
+				Mode:     0755,
+				ModTime:  obj.UpdatedAt,
+			}); err != nil {
+				return err
+			}
+			manifest.Objects = append(manifest.Objects, *entry)
+			continue
+		}
+
+		content, err := u.storage.ReadFile(ctx, obj.Path)
+		if err != nil {
+			log.Warn().Err(err).Int64("object_id", obj.ID).Msg("Skipping unreadable file in workspace export")
+			manifest.Skipped = append(manifest.Skipped, ExportSkippedFile{Path: displayPath, Reason: err.Error()})
+			continue
+		}
+
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			ModTime:  obj.UpdatedAt,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return err
+		}
+		entry.Size = int64(len(content))
+		manifest.Objects = append(manifest.Objects, *entry)
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     exportManifestName,
+		Mode:     0644,
+		Size:     int64(len(manifestJSON)),
+		ModTime:  manifest.ExportedAt,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifestJSON); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (u *objectUseCase) exportManifestObject(ctx context.Context, obj *entity.Object, displayPath string) (*ExportManifestObject, error) {
+	entry := &ExportManifestObject{
+		ID:             obj.ID,
+		Path:           displayPath,
+		Type:           obj.Type,
+		Size:           obj.Size,
+		Description:    obj.Description,
+		CurrentVersion: obj.CurrentVersion,
+		CreatedAt:      obj.CreatedAt,
+		UpdatedAt:      obj.UpdatedAt,
+	}
+	for _, tag := range obj.Tags {
+		entry.Tags = append(entry.Tags, tag.Name)
+	}
+
+	if obj.IsDirectory() {
+		return entry, nil
+	}
+
+	versions, _, err := u.versionRepo.ListByObject(ctx, obj.ID, 1, exportMaxVersions)
+	if err != nil {
+		return nil, fmt.Errorf("list versions of object %d: %w", obj.ID, err)
+	}
+	for i := range versions {
+		entry.Versions = append(entry.Versions, *versions[i].ToResponse())
+	}
+	return entry, nil
+}
@@ -0,0 +1,158 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/adapter/storage"
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// ExportToBackendInput is the request to push a copy of an object to a
+// storage backend
+type ExportToBackendInput struct {
+	Backend    string `json:"backend" binding:"required"` // Name from export-backends
+	DestPrefix string `json:"dest_prefix"`                // Directory in the backend to copy into; empty for its root
+}
+
+// backend returns the storage of the configured backend with the given name.
+// Backends are looked up per job, so config reloads take effect.
+func (j *ExportJobs) backend(name string) (storage.FileStorage, error) {
+	b, ok := j.cfg.Backends[name]
+	if !ok {
+		return nil, apperrors.InvalidArgumentError(fmt.Sprintf("storage backend %q is not available", name), "backend")
+	}
+	if b.GetType() == "" || b.Path == "" {
+		return nil, apperrors.UnavailableError(fmt.Sprintf("storage backend %q is misconfigured on this server; check export.backends", name))
+	}
+	return storage.NewLocalFileStorage(b.Path, "", "", "", nil), nil
+}
+
+// GetExportBackends returns the names of the storage backends objects can be
+// pushed to, as configured by the operator
+func (u *objectUseCase) GetExportBackends(ctx context.Context) []string {
+	names := make([]string, 0, len(u.exportJobs.cfg.Backends))
+	for name, b := range u.exportJobs.cfg.Backends {
+		if b.GetType() != "" && b.Path != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExportToBackend starts copying a file, or a directory with everything in it,
+// to a storage backend under destPrefix. Files already at the destination are
+// replaced. The job lists each file copied and why any failed.
+func (u *objectUseCase) ExportToBackend(ctx context.Context, objectID int64, userID uuid.UUID, backend string, destPrefix string) (*ExportJob, error) {
+	obj, err := u.objectRepo.GetByID(ctx, objectID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+
+	allowed, err := u.permissionRepo.HasPermission(ctx, objectID, userID, entity.RoleViewer)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check permission", err)
+	}
+	if !allowed {
+		return nil, apperrors.ForbiddenError("no permission to read this object")
+	}
+
+	target, err := u.exportJobs.backend(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []entity.Object{*obj}
+	if obj.IsDirectory() {
+		descendants, err := u.objectRepo.GetDescendants(ctx, obj.Path)
+		if err != nil {
+			return nil, apperrors.InternalError("failed to list directory", err)
+		}
+		objects = append(objects, descendants...)
+	}
+
+	// Joining onto the root keeps the destination inside the backend
+	dest := strings.TrimPrefix(path.Join("/", destPrefix, obj.Name), "/")
+	job := &ExportJob{
+		ID:          uuid.New().String(),
+		ObjectID:    objectID,
+		Backend:     backend,
+		Destination: dest,
+		Status:      ExportJobPending,
+		CreatedAt:   time.Now(),
+		userID:      userID,
+	}
+	snapshot := *job
+	u.exportJobs.start(job, func(ctx context.Context) (*ExportedObject, []ExportedFile, error) {
+		files, err := u.pushObjects(ctx, target, obj.Path, objects, dest)
+		return nil, files, err
+	})
+	return &snapshot, nil
+}
+
+// pushObjects copies objects, which lie under rootPath, to dest in target.
+// A file that fails is reported and the rest are still copied.
+func (u *objectUseCase) pushObjects(ctx context.Context, target storage.FileStorage, rootPath string, objects []entity.Object, dest string) ([]ExportedFile, error) {
+	var files []ExportedFile
+	failed := 0
+	for i := range objects {
+		if err := ctx.Err(); err != nil {
+			return files, err
+		}
+
+		obj := &objects[i]
+		destPath := dest + strings.TrimPrefix(obj.Path, rootPath)
+		if obj.IsDirectory() {
+			if err := target.CreateDirectory(ctx, destPath); err != nil {
+				log.Warn().Err(err).Str("dest", destPath).Msg("Failed to create directory in export backend")
+				return files, fmt.Errorf("failed to create directory %s in the backend", destPath)
+			}
+			continue
+		}
+
+		file := ExportedFile{Path: destPath}
+		size, err := u.pushFile(ctx, target, obj.Path, destPath)
+		if err != nil {
+			file.Error = err.Error()
+			failed++
+		}
+		file.Size = size
+		files = append(files, file)
+	}
+
+	if failed > 0 {
+		return files, fmt.Errorf("%d of %d files failed to export", failed, len(files))
+	}
+	return files, nil
+}
+
+// pushFile streams one stored file to destPath in target. Errors are logged
+// and reported without the storage paths behind them.
+func (u *objectUseCase) pushFile(ctx context.Context, target storage.FileStorage, srcPath, destPath string) (int64, error) {
+	src, err := u.storage.OpenFile(ctx, srcPath)
+	if err != nil {
+		log.Warn().Err(err).Str("path", srcPath).Msg("Failed to read file for backend export")
+		return 0, errors.New("failed to read file")
+	}
+	defer src.Close()
+
+	n, err := target.WriteStream(ctx, destPath, src)
+	if err != nil {
+		log.Warn().Err(err).Str("path", srcPath).Str("dest", destPath).Msg("Failed to write file to export backend")
+		return 0, errors.New("failed to write file to the backend")
+	}
+	return n, nil
+}
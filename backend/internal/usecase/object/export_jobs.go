@@ -0,0 +1,208 @@
+package object
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// ExportJobStatus is the state of an export job
+type ExportJobStatus string
+
+const (
+	ExportJobPending ExportJobStatus = "pending" // Waiting for a conversion slot
+	ExportJobRunning ExportJobStatus = "running"
+	ExportJobDone    ExportJobStatus = "done" // Ready for download
+	ExportJobFailed  ExportJobStatus = "failed"
+)
+
+// ExportJob is an export run in the background: a conversion to an export
+// format, or a copy pushed to an external storage backend
+type ExportJob struct {
+	ID          string          `json:"id"`
+	ObjectID    int64           `json:"object_id"`
+	Format      string          `json:"format,omitempty"`
+	Backend     string          `json:"backend,omitempty"`
+	Destination string          `json:"destination,omitempty"` // Path in the backend the object is copied to
+	Status      ExportJobStatus `json:"status"`
+	Error       string          `json:"error,omitempty"`
+	Files       []ExportedFile  `json:"files,omitempty"` // Files pushed to a backend, including failed ones
+	CreatedAt   time.Time       `json:"created_at"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+
+	userID uuid.UUID
+	result *ExportedObject
+}
+
+// ExportedFile is the outcome of copying one file to a storage backend
+type ExportedFile struct {
+	Path  string `json:"path"` // In the backend
+	Size  int64  `json:"size"`
+	Error string `json:"error,omitempty"`
+}
+
+// StartExportJobInput is the request to start an export job
+type StartExportJobInput struct {
+	Format string `json:"format" binding:"required"` // Export format ID from export-formats
+}
+
+// ExportJobs runs slow exports in the background and keeps their results
+// until they expire. Jobs are held in memory by the instance that started
+// them, so they are lost on restart and are not visible to other instances.
+type ExportJobs struct {
+	cfg    *config.ExportConfig
+	mu     sync.Mutex
+	jobs   map[string]*ExportJob
+	slots  chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewExportJobs creates a new export job runner
+func NewExportJobs(cfg *config.ExportConfig) *ExportJobs {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ExportJobs{
+		cfg:    cfg,
+		jobs:   make(map[string]*ExportJob),
+		slots:  make(chan struct{}, cfg.GetMaxRunningJobs()),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Stop cancels running and waiting jobs
+func (j *ExportJobs) Stop() {
+	j.cancel()
+}
+
+// start runs export in a new goroutine once a slot is free. Export returns
+// the file to download, or the files it pushed to a backend.
+func (j *ExportJobs) start(job *ExportJob, export func(ctx context.Context) (*ExportedObject, []ExportedFile, error)) {
+	j.mu.Lock()
+	j.expire()
+	j.jobs[job.ID] = job
+	j.mu.Unlock()
+
+	go func() {
+		var result *ExportedObject
+		var files []ExportedFile
+		var err error
+		select {
+		case j.slots <- struct{}{}:
+			j.setStatus(job, ExportJobRunning)
+			result, files, err = export(j.ctx)
+			<-j.slots
+		case <-j.ctx.Done():
+			err = j.ctx.Err()
+		}
+		j.finish(job, result, files, err)
+	}()
+}
+
+func (j *ExportJobs) setStatus(job *ExportJob, status ExportJobStatus) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job.Status = status
+}
+
+func (j *ExportJobs) finish(job *ExportJob, result *ExportedObject, files []ExportedFile, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Files = files
+	if err != nil {
+		job.Status = ExportJobFailed
+		job.Error = err.Error()
+		if appErr := apperrors.GetAppError(err); appErr != nil {
+			job.Error = appErr.Message
+		}
+		log.Warn().Err(err).Str("job_id", job.ID).Int64("object_id", job.ObjectID).Str("format", job.Format).Str("backend", job.Backend).Msg("Export job failed")
+		return
+	}
+	job.Status = ExportJobDone
+	job.result = result
+}
+
+// get returns a copy of a user's job with its result
+func (j *ExportJobs) get(jobID string, userID uuid.UUID) (*ExportJob, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.expire()
+	job, ok := j.jobs[jobID]
+	if !ok || job.userID != userID {
+		return nil, apperrors.NotFoundError("export job")
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// expire drops jobs finished longer ago than the job TTL; callers hold mu
+func (j *ExportJobs) expire() {
+	cutoff := time.Now().Add(-j.cfg.GetJobTTL())
+	for id, job := range j.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(j.jobs, id)
+		}
+	}
+}
+
+// StartExportJob starts exporting an object in the background, which async
+// formats require. The content is read now, so later edits do not change the
+// export.
+func (u *objectUseCase) StartExportJob(ctx context.Context, objectID int64, userID uuid.UUID, formatID string) (*ExportJob, error) {
+	obj, content, e, err := u.exporterFor(ctx, objectID, formatID)
+	if err != nil {
+		return nil, err
+	}
+	if e.ready != nil {
+		if err := e.ready(u); err != nil {
+			return nil, err
+		}
+	}
+
+	job := &ExportJob{
+		ID:        uuid.New().String(),
+		ObjectID:  objectID,
+		Format:    formatID,
+		Status:    ExportJobPending,
+		CreatedAt: time.Now(),
+		userID:    userID,
+	}
+	snapshot := *job
+	u.exportJobs.start(job, func(ctx context.Context) (*ExportedObject, []ExportedFile, error) {
+		exported, err := e.run(ctx, u, obj, content)
+		return exported, nil, err
+	})
+	return &snapshot, nil
+}
+
+// GetExportJob returns the state of an export job started by the user
+func (u *objectUseCase) GetExportJob(ctx context.Context, jobID string, userID uuid.UUID) (*ExportJob, error) {
+	return u.exportJobs.get(jobID, userID)
+}
+
+// DownloadExportJob returns the exported file of a finished export job
+func (u *objectUseCase) DownloadExportJob(ctx context.Context, jobID string, userID uuid.UUID) (*ExportedObject, error) {
+	job, err := u.exportJobs.get(jobID, userID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case job.Status == ExportJobFailed:
+		return nil, apperrors.BadRequestError("export job failed: " + job.Error)
+	case job.Status != ExportJobDone:
+		return nil, apperrors.BadRequestError("export job has not finished")
+	case job.result == nil:
+		return nil, apperrors.BadRequestError("export job pushed files to a storage backend and has nothing to download")
+	}
+	return job.result, nil
+}
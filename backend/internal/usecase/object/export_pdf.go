@@ -0,0 +1,82 @@
+package object
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// maxConverterOutput caps the converter output quoted in a failed export
+const maxConverterOutput = 500
+
+// pdfReady fails with an error for operators when no PDF converter is set up
+func pdfReady(cfg *config.ExportConfig) error {
+	if cfg == nil || len(cfg.PDFCommand) == 0 {
+		return apperrors.UnavailableError("PDF export is not configured on this server; set export.pdf_command to an HTML-to-PDF converter")
+	}
+	return nil
+}
+
+// htmlToPDF converts an HTML page to PDF with the configured converter,
+// which reads the page from {input} and writes the PDF to {output}
+func htmlToPDF(ctx context.Context, cfg *config.ExportConfig, page []byte) ([]byte, error) {
+	if err := pdfReady(cfg); err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "export-pdf-")
+	if err != nil {
+		return nil, apperrors.InternalError("failed to create conversion directory", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.html")
+	output := filepath.Join(dir, "output.pdf")
+	if err := os.WriteFile(input, page, 0o600); err != nil {
+		return nil, apperrors.InternalError("failed to write conversion input", err)
+	}
+	args := make([]string, len(cfg.PDFCommand))
+	for i, arg := range cfg.PDFCommand {
+		args[i] = strings.NewReplacer("{input}", input, "{output}", output).Replace(arg)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.GetPDFTimeout())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	if err := cmd.Run(); err != nil {
+		switch {
+		case errors.Is(err, exec.ErrNotFound), errors.Is(err, fs.ErrNotExist):
+			return nil, apperrors.UnavailableError(fmt.Sprintf("PDF converter %q is not installed on this server; check export.pdf_command", args[0]))
+		case ctx.Err() == context.DeadlineExceeded:
+			return nil, apperrors.InternalError(fmt.Sprintf("PDF conversion timed out after %s", cfg.GetPDFTimeout()), err)
+		}
+		message := strings.TrimSpace(out.String())
+		if len(message) > maxConverterOutput {
+			message = message[len(message)-maxConverterOutput:]
+		}
+		if message == "" {
+			message = err.Error()
+		}
+		return nil, apperrors.InternalError("PDF conversion failed: "+message, err)
+	}
+
+	pdf, err := os.ReadFile(output)
+	if err != nil {
+		return nil, apperrors.InternalError("PDF converter did not write {output}; check export.pdf_command", err)
+	}
+	return pdf, nil
+}
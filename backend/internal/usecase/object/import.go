@@ -0,0 +1,471 @@
+package object
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// ImportConflictStrategy decides what happens to an archive file whose path
+// already exists in the workspace. Existing directories are always merged into.
+type ImportConflictStrategy string
+
+const (
+	ImportConflictSkip      ImportConflictStrategy = "skip"
+	ImportConflictOverwrite ImportConflictStrategy = "overwrite"
+	ImportConflictRename    ImportConflictStrategy = "rename"
+)
+
+// IsValid reports whether s is a known conflict strategy
+func (s ImportConflictStrategy) IsValid() bool {
+	return s == ImportConflictSkip || s == ImportConflictOverwrite || s == ImportConflictRename
+}
+
+const (
+	// maxImportEntries bounds the number of archive entries
+	maxImportEntries = 10000
+	// maxImportBytes bounds the total uncompressed size of imported files
+	maxImportBytes = 5 << 30
+	// maxImportManifestSize bounds the size of manifest.json
+	maxImportManifestSize = 16 << 20
+)
+
+// ImportResult reports what a workspace import did, by display path
+type ImportResult struct {
+	Created     []string          `json:"created"`
+	Overwritten []string          `json:"overwritten,omitempty"`
+	Renamed     map[string]string `json:"renamed,omitempty"` // Archive path -> path it was imported as
+	Skipped     []ImportIssue     `json:"skipped,omitempty"`
+}
+
+// ImportIssue records an archive entry that was not imported
+type ImportIssue struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// ImportedFile is a file to create with ImportFiles
+type ImportedFile struct {
+	Path    string // Relative to the target directory, slash-separated
+	Content []byte
+}
+
+// ImportedObject is a file created by ImportFiles
+type ImportedObject struct {
+	ObjectID int64  `json:"object_id"`
+	Path     string `json:"path"` // Display path it was created at
+}
+
+// workspaceImport holds the state of one ImportWorkspace call
+type workspaceImport struct {
+	u        *objectUseCase
+	userID   uuid.UUID
+	appID    string
+	email    string
+	strategy ImportConflictStrategy
+	result   *ImportResult
+
+	dirs     map[string]int64     // Display path -> directory object ID
+	imported map[string]int64     // Archive display path -> object created or overwritten from it
+	tags     map[string]uuid.UUID // Tag name -> ID
+}
+
+// ImportWorkspace extracts a tarball produced by ExportWorkspace into the
+// user's workspace, recreating directories and files and re-applying the
+// descriptions and tags from its manifest. Entries with unsafe paths, files
+// over the upload size limit and conflicting paths (per strategy) are skipped
+// and reported in the result.
+func (u *objectUseCase) ImportWorkspace(ctx context.Context, userID uuid.UUID, appID, email string, r io.Reader, strategy ImportConflictStrategy) (*ImportResult, error) {
+	if !strategy.IsValid() {
+		return nil, apperrors.InvalidArgumentError("conflict must be one of skip, overwrite, rename", "conflict")
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, apperrors.InvalidArgumentError("archive is not a gzip file", "file")
+	}
+	defer gz.Close()
+
+	imp := &workspaceImport{
+		u:        u,
+		userID:   userID,
+		appID:    appID,
+		email:    email,
+		strategy: strategy,
+		result:   &ImportResult{Created: []string{}},
+		dirs:     make(map[string]int64),
+		imported: make(map[string]int64),
+		tags:     make(map[string]uuid.UUID),
+	}
+
+	var manifest *ExportManifest
+	var entries int
+	var total int64
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, apperrors.InvalidArgumentError("archive is corrupt", "file")
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		entries++
+		if entries > maxImportEntries {
+			return nil, apperrors.ResourceExhaustedError(fmt.Sprintf("archive has more than %d entries", maxImportEntries))
+		}
+
+		if hdr.Name == exportManifestName {
+			manifest = imp.readManifest(tr, hdr)
+			continue
+		}
+
+		displayPath, ok := importEntryPath(hdr.Name)
+		if !ok {
+			imp.skip(hdr.Name, "path is outside the workspace")
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := imp.importDir(ctx, displayPath); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if maxSize := u.storageConfig.MaxUploadSize; maxSize > 0 && hdr.Size > maxSize {
+				imp.skip(displayPath, fmt.Sprintf("file exceeds the %d byte upload limit", maxSize))
+				continue
+			}
+			total += hdr.Size
+			if total > maxImportBytes {
+				return nil, apperrors.ResourceExhaustedError(fmt.Sprintf("archive content exceeds %d bytes", int64(maxImportBytes)))
+			}
+			content, err := io.ReadAll(io.LimitReader(tr, hdr.Size))
+			if err != nil {
+				return nil, apperrors.InvalidArgumentError("archive is corrupt", "file")
+			}
+			if err := imp.importFile(ctx, displayPath, content); err != nil {
+				return nil, err
+			}
+		default:
+			imp.skip(displayPath, "unsupported entry type")
+		}
+	}
+
+	if manifest != nil {
+		if err := imp.applyManifest(ctx, manifest); err != nil {
+			return nil, err
+		}
+	}
+
+	return imp.result, nil
+}
+
+// ImportFiles creates files under dir, a display path in the user's
+// workspace, creating missing directories. Names already taken get an
+// "_imported" suffix as in a workspace import with the rename strategy. Files
+// with unsafe paths or that cannot be created are skipped and reported.
+func (u *objectUseCase) ImportFiles(ctx context.Context, userID uuid.UUID, appID, email, dir string, files []ImportedFile) ([]ImportedObject, []ImportIssue, error) {
+	imp := &workspaceImport{
+		u:        u,
+		userID:   userID,
+		appID:    appID,
+		email:    email,
+		strategy: ImportConflictRename,
+		result:   &ImportResult{Created: []string{}},
+		dirs:     make(map[string]int64),
+		imported: make(map[string]int64),
+	}
+
+	objects := make([]ImportedObject, 0, len(files))
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return objects, imp.result.Skipped, err
+		}
+		displayPath, ok := importEntryPath(exportFilesDir + "/" + path.Join(strings.TrimPrefix(dir, "/"), file.Path))
+		if !ok || !strings.HasPrefix(displayPath, path.Clean("/"+dir)+"/") {
+			imp.skip(path.Join("/", dir, file.Path), "path is outside the target directory")
+			continue
+		}
+		if err := imp.importFile(ctx, displayPath, file.Content); err != nil {
+			return objects, imp.result.Skipped, err
+		}
+		if id, ok := imp.imported[displayPath]; ok {
+			created := displayPath
+			if renamed, ok := imp.result.Renamed[displayPath]; ok {
+				created = renamed
+			}
+			objects = append(objects, ImportedObject{ObjectID: id, Path: created})
+		}
+	}
+	return objects, imp.result.Skipped, nil
+}
+
+// importEntryPath maps an archive entry name under files/ to a display path,
+// rejecting absolute paths and "." or ".." segments
+func importEntryPath(name string) (string, bool) {
+	rest, ok := strings.CutPrefix(name, exportFilesDir+"/")
+	if !ok {
+		return "", false
+	}
+	rest = strings.TrimSuffix(rest, "/")
+	if rest == "" {
+		return "", false
+	}
+	for _, segment := range strings.Split(rest, "/") {
+		if segment == "" || segment == "." || segment == ".." || strings.Contains(segment, "\\") {
+			return "", false
+		}
+	}
+	return "/" + rest, true
+}
+
+func (imp *workspaceImport) skip(displayPath, reason string) {
+	imp.result.Skipped = append(imp.result.Skipped, ImportIssue{Path: displayPath, Reason: reason})
+}
+
+// skipOnClientError records err as the reason displayPath was skipped if it is
+// caused by the entry itself, and returns it otherwise
+func (imp *workspaceImport) skipOnClientError(displayPath string, err error) error {
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) && appErr.HTTPCode < 500 {
+		imp.skip(displayPath, appErr.Message)
+		return nil
+	}
+	return err
+}
+
+func (imp *workspaceImport) readManifest(tr *tar.Reader, hdr *tar.Header) *ExportManifest {
+	if hdr.Size > maxImportManifestSize {
+		imp.skip(exportManifestName, "manifest is too large")
+		return nil
+	}
+	var manifest ExportManifest
+	if err := json.NewDecoder(io.LimitReader(tr, hdr.Size)).Decode(&manifest); err != nil {
+		imp.skip(exportManifestName, "manifest is not valid JSON")
+		return nil
+	}
+	return &manifest
+}
+
+func (imp *workspaceImport) internalPath(displayPath string) string {
+	return "/" + imp.appID + "/" + imp.email + displayPath
+}
+
+// parentID returns the directory object for the parent of displayPath,
+// creating missing directories; nil means the workspace root
+func (imp *workspaceImport) parentID(ctx context.Context, displayPath string) (*int64, error) {
+	dir := path.Dir(displayPath)
+	if dir == "/" {
+		return nil, nil
+	}
+	id, err := imp.ensureDir(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// ensureDir returns the directory at displayPath, creating it and its
+// parents as needed. Existing directories are reused.
+func (imp *workspaceImport) ensureDir(ctx context.Context, displayPath string) (int64, error) {
+	if id, ok := imp.dirs[displayPath]; ok {
+		return id, nil
+	}
+
+	existing, err := imp.u.objectRepo.GetByPath(ctx, imp.internalPath(displayPath))
+	if err == nil {
+		if !existing.IsDirectory() {
+			return 0, apperrors.ValidationError(fmt.Sprintf("a file already exists at %s", displayPath))
+		}
+		imp.dirs[displayPath] = existing.ID
+		return existing.ID, nil
+	}
+	if !apperrors.IsNotFound(err) {
+		return 0, apperrors.InternalError("failed to get object", err)
+	}
+
+	parentID, err := imp.parentID(ctx, displayPath)
+	if err != nil {
+		return 0, err
+	}
+	dir, err := imp.u.CreateDirectory(ctx, imp.userID, imp.appID, imp.email, &CreateDirectoryInput{
+		Name:     path.Base(displayPath),
+		ParentID: parentID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	imp.dirs[displayPath] = dir.ID
+	imp.imported[displayPath] = dir.ID
+	imp.result.Created = append(imp.result.Created, displayPath)
+	return dir.ID, nil
+}
+
+func (imp *workspaceImport) importDir(ctx context.Context, displayPath string) error {
+	if _, err := imp.ensureDir(ctx, displayPath); err != nil {
+		return imp.skipOnClientError(displayPath, err)
+	}
+	return nil
+}
+
+func (imp *workspaceImport) importFile(ctx context.Context, displayPath string, content []byte) error {
+	parentID, err := imp.parentID(ctx, displayPath)
+	if err != nil {
+		return imp.skipOnClientError(displayPath, err)
+	}
+
+	targetPath := displayPath
+	existing, err := imp.u.objectRepo.GetByPath(ctx, imp.internalPath(displayPath))
+	switch {
+	case err == nil:
+		switch {
+		case imp.strategy == ImportConflictRename:
+			targetPath, err = imp.freePath(ctx, displayPath)
+			if err != nil {
+				return imp.skipOnClientError(displayPath, err)
+			}
+		case existing.IsDirectory():
+			imp.skip(displayPath, "a directory already exists at this path")
+			return nil
+		case imp.strategy == ImportConflictOverwrite:
+			if _, err := imp.u.SaveContent(ctx, existing.ID, imp.userID, content, "Imported from workspace archive"); err != nil {
+				return imp.skipOnClientError(displayPath, err)
+			}
+			imp.imported[displayPath] = existing.ID
+			imp.result.Overwritten = append(imp.result.Overwritten, displayPath)
+			return nil
+		default:
+			imp.skip(displayPath, "already exists")
+			return nil
+		}
+	case !apperrors.IsNotFound(err):
+		return apperrors.InternalError("failed to get object", err)
+	}
+
+	created, err := imp.u.CreateFile(ctx, imp.userID, imp.appID, imp.email, &CreateFileInput{
+		Name:     path.Base(targetPath),
+		ParentID: parentID,
+		Content:  content,
+	})
+	if err != nil {
+		return imp.skipOnClientError(displayPath, err)
+	}
+
+	imp.imported[displayPath] = created.ID
+	imp.result.Created = append(imp.result.Created, targetPath)
+	if targetPath != displayPath {
+		if imp.result.Renamed == nil {
+			imp.result.Renamed = make(map[string]string)
+		}
+		imp.result.Renamed[displayPath] = targetPath
+	}
+	return nil
+}
+
+// freePath picks the first free "_imported" name next to displayPath
+// (name_imported, name_imported_2, ...), keeping the file extension
+func (imp *workspaceImport) freePath(ctx context.Context, displayPath string) (string, error) {
+	dir, name := path.Dir(displayPath), path.Base(displayPath)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	for n := 1; n <= maxDuplicateAttempts; n++ {
+		suffix := "_imported"
+		if n > 1 {
+			suffix = fmt.Sprintf("_imported_%d", n)
+		}
+		candidate := path.Join(dir, base+suffix+ext)
+		exists, err := imp.u.pathTaken(ctx, imp.internalPath(candidate), 0)
+		if err != nil {
+			return "", apperrors.InternalError("failed to check path", err)
+		}
+		if !exists {
+			return candidate, nil
+		}
+	}
+	return "", apperrors.AlreadyExistsError("imported file name")
+}
+
+// applyManifest restores descriptions and tags of the objects created or
+// overwritten by the import
+func (imp *workspaceImport) applyManifest(ctx context.Context, manifest *ExportManifest) error {
+	for _, entry := range manifest.Objects {
+		id, ok := imp.imported[entry.Path]
+		if !ok || (entry.Description == "" && len(entry.Tags) == 0) {
+			continue
+		}
+
+		obj, err := imp.u.objectRepo.GetByID(ctx, id)
+		if err != nil {
+			return apperrors.InternalError("failed to get object", err)
+		}
+
+		if entry.Description != "" && entry.Description != obj.Description {
+			obj.Description = entry.Description
+			obj.MarkModifiedBy(imp.userID)
+			if err := imp.u.objectRepo.Update(ctx, obj); err != nil {
+				return apperrors.InternalError("failed to update object", err)
+			}
+		}
+
+		hasTag := make(map[string]bool, len(obj.Tags))
+		for _, tag := range obj.Tags {
+			hasTag[tag.Name] = true
+		}
+		for _, name := range entry.Tags {
+			if hasTag[name] {
+				continue
+			}
+			tagID, err := imp.tagID(ctx, name)
+			if err != nil {
+				return err
+			}
+			if err := imp.u.tagRepo.AddToObject(ctx, id, tagID); err != nil {
+				return apperrors.InternalError("failed to add tag to object", err)
+			}
+			hasTag[name] = true
+		}
+	}
+	return nil
+}
+
+// tagID returns the ID of the tag with the given name, creating it if needed
+func (imp *workspaceImport) tagID(ctx context.Context, name string) (uuid.UUID, error) {
+	if id, ok := imp.tags[name]; ok {
+		return id, nil
+	}
+
+	tag, err := imp.u.tagRepo.GetByName(ctx, name)
+	if apperrors.IsNotFound(err) {
+		tag = &entity.Tag{Name: name, Color: "#808080"}
+		err = imp.u.tagRepo.Create(ctx, tag)
+		if apperrors.IsAlreadyExists(err) {
+			// Created concurrently
+			tag, err = imp.u.tagRepo.GetByName(ctx, name)
+		}
+	}
+	if err != nil {
+		return uuid.Nil, apperrors.InternalError("failed to get tag", err)
+	}
+
+	imp.tags[name] = tag.ID
+	return tag.ID, nil
+}
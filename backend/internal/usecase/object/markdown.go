@@ -0,0 +1,132 @@
+package object
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// Targets of relative links rewritten to point at workspace objects; the
+// editor routes are those of the web client
+const (
+	markdownFileLink     = "/editor/files/%d"
+	markdownNotebookLink = "/editor/notebooks/%d"
+	markdownImageLink    = "/api/v1/objects/%d/download"
+)
+
+// markdownPolicy strips everything but safe markup from rendered markdown, so
+// stored documents cannot inject scripts. Highlighted code keeps the inline
+// colors chroma writes.
+var markdownPolicy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowStyles("color", "background-color", "font-weight", "font-style", "text-decoration").OnElements("pre", "span")
+	return p
+}()
+
+// RenderMarkdown renders a markdown object to sanitized HTML. Fenced code is
+// highlighted, and relative links and images that resolve to objects in the
+// workspace are rewritten to open them.
+func (u *objectUseCase) RenderMarkdown(ctx context.Context, objectID int64) (string, error) {
+	obj, content, err := u.readContent(ctx, objectID)
+	if err != nil {
+		return "", err
+	}
+	if obj.Type != entity.ObjectTypeMarkdown {
+		return "", apperrors.ValidationError("object is not a markdown file")
+	}
+
+	return markdownToHTML(content, util.Prioritized(&markdownLinkRewriter{ctx: ctx, u: u, dir: path.Dir(obj.Path)}, 100))
+}
+
+// markdownToHTML renders markdown to sanitized HTML with highlighted fenced
+// code, applying transformers to the parsed document
+func markdownToHTML(content []byte, transformers ...util.PrioritizedValue) (string, error) {
+	md := goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+			highlighting.NewHighlighting(
+				highlighting.WithStyle("github"),
+				highlighting.WithFormatOptions(chromahtml.TabWidth(4)),
+			),
+		),
+		goldmark.WithParserOptions(parser.WithASTTransformers(transformers...)),
+	)
+
+	var buf bytes.Buffer
+	if err := md.Convert(content, &buf); err != nil {
+		return "", apperrors.InternalError("failed to render markdown", err)
+	}
+	return markdownPolicy.Sanitize(buf.String()), nil
+}
+
+// markdownLinkRewriter points relative link and image destinations of a
+// markdown document at the objects they name, resolved against dir. Links to
+// missing objects are left as they are.
+type markdownLinkRewriter struct {
+	ctx context.Context
+	u   *objectUseCase
+	dir string
+}
+
+func (r *markdownLinkRewriter) Transform(doc *ast.Document, _ text.Reader, _ parser.Context) {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Link:
+			if dest, ok := r.rewrite(node.Destination, false); ok {
+				node.Destination = dest
+			}
+		case *ast.Image:
+			if dest, ok := r.rewrite(node.Destination, true); ok {
+				node.Destination = dest
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+func (r *markdownLinkRewriter) rewrite(destination []byte, image bool) ([]byte, bool) {
+	u, err := url.Parse(string(destination))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || strings.HasPrefix(u.Path, "/") {
+		return nil, false
+	}
+
+	target, err := r.u.objectRepo.GetByPath(r.ctx, path.Join(r.dir, u.Path))
+	if err != nil {
+		return nil, false
+	}
+
+	var link string
+	switch {
+	case image:
+		link = fmt.Sprintf(markdownImageLink, target.ID)
+	case target.Type == entity.ObjectTypeNotebook:
+		link = fmt.Sprintf(markdownNotebookLink, target.ID)
+	case target.IsDirectory():
+		return nil, false
+	default:
+		link = fmt.Sprintf(markdownFileLink, target.ID)
+	}
+	if u.Fragment != "" {
+		link += "#" + u.EscapedFragment()
+	}
+	return []byte(link), true
+}
@@ -0,0 +1,44 @@
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// formatNotebook serializes notebook content in the configured notebook
+// format, so every save path stores the same bytes for the same notebook.
+// Compact drops all whitespace; canonical sorts keys and indents by one
+// space with a trailing newline, as Jupyter itself writes notebooks. Content
+// that is not a single JSON document is returned unchanged.
+func (u *objectUseCase) formatNotebook(content []byte) []byte {
+	format := u.storageConfig.GetNotebookFormat()
+	if format == "preserve" {
+		return content
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return content
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return content
+	}
+
+	// Maps are encoded with sorted keys; numbers keep their original text
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if format == "canonical" {
+		enc.SetIndent("", " ")
+	}
+	if err := enc.Encode(doc); err != nil {
+		return content
+	}
+	if format == "compact" {
+		return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,72 @@
+package object
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// contentUnchanged reports whether content is equivalent to what is stored for obj.
+// Notebooks are compared by their canonical hash, so re-saves that only reorder
+// JSON keys or touch volatile metadata do not create new versions.
+func (u *objectUseCase) contentUnchanged(ctx context.Context, obj *entity.Object, content []byte, contentHash string) bool {
+	if contentHash == obj.ContentHash {
+		return true
+	}
+	if obj.Type != entity.ObjectTypeNotebook || obj.ContentHash == "" {
+		return false
+	}
+
+	newHash, ok := notebookCanonicalHash(content)
+	if !ok {
+		return false
+	}
+	existing, err := u.storage.ReadFile(ctx, obj.Path)
+	if err != nil {
+		return false
+	}
+	// Only trust the stored file if it is still the content recorded on the object
+	if u.storage.CalculateHash(existing) != obj.ContentHash {
+		return false
+	}
+	oldHash, ok := notebookCanonicalHash(existing)
+	return ok && oldHash == newHash
+}
+
+// notebookCanonicalHash hashes a notebook with stable key order and volatile
+// metadata removed. It returns false if content is not a valid notebook.
+func notebookCanonicalHash(content []byte) (string, bool) {
+	var notebook map[string]any
+	if err := json.Unmarshal(content, &notebook); err != nil {
+		return "", false
+	}
+
+	if metadata, ok := notebook["metadata"].(map[string]any); ok {
+		if languageInfo, ok := metadata["language_info"].(map[string]any); ok {
+			delete(languageInfo, "version")
+		}
+	}
+	if cells, ok := notebook["cells"].([]any); ok {
+		for _, c := range cells {
+			cell, ok := c.(map[string]any)
+			if !ok {
+				continue
+			}
+			if metadata, ok := cell["metadata"].(map[string]any); ok {
+				// Execution timing recorded by JupyterLab
+				delete(metadata, "execution")
+			}
+		}
+	}
+
+	// encoding/json writes map keys in sorted order
+	canonical, err := json.Marshal(notebook)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), true
+}
@@ -0,0 +1,99 @@
+package object
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// NotebookKernelspec identifies the kernel a notebook runs on
+type NotebookKernelspec struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Language    string `json:"language"`
+}
+
+// SetNotebookKernelspec records spec as the notebook's metadata.kernelspec and
+// language_info so reopening it selects the same kernel. A version is saved
+// only if the metadata changed.
+func (u *objectUseCase) SetNotebookKernelspec(ctx context.Context, objectID int64, userID uuid.UUID, spec *NotebookKernelspec) (*entity.ObjectResponse, error) {
+	obj, err := u.objectRepo.GetByID(ctx, objectID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+	if obj.Type != entity.ObjectTypeNotebook {
+		return nil, apperrors.ValidationError("object is not a notebook")
+	}
+
+	allowed, err := u.permissionRepo.HasPermission(ctx, objectID, userID, entity.RoleEditor)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check permission", err)
+	}
+	if !allowed {
+		return nil, apperrors.ForbiddenError("no permission to edit this notebook")
+	}
+
+	content, err := u.storage.ReadFile(ctx, obj.Path)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to read file", err)
+	}
+
+	var notebook map[string]any
+	if err := json.Unmarshal(content, &notebook); err != nil {
+		return nil, apperrors.ValidationError("invalid notebook JSON")
+	}
+	if !applyKernelspec(notebook, spec) {
+		return obj.ToResponse(), nil
+	}
+
+	updated, err := json.MarshalIndent(notebook, "", "  ")
+	if err != nil {
+		return nil, apperrors.InternalError("failed to encode notebook", err)
+	}
+	return u.SaveContent(ctx, objectID, userID, updated, "Switch kernel to "+spec.DisplayName)
+}
+
+// applyKernelspec updates the kernelspec and language_info metadata of
+// notebook to match spec, keeping unrelated keys. It returns false if the
+// metadata already matched.
+func applyKernelspec(notebook map[string]any, spec *NotebookKernelspec) bool {
+	metadata, ok := notebook["metadata"].(map[string]any)
+	if !ok {
+		metadata = map[string]any{}
+		notebook["metadata"] = metadata
+	}
+
+	changed := false
+	kernelspec, ok := metadata["kernelspec"].(map[string]any)
+	if !ok {
+		kernelspec = map[string]any{}
+		metadata["kernelspec"] = kernelspec
+	}
+	for key, value := range map[string]string{
+		"name":         spec.Name,
+		"display_name": spec.DisplayName,
+		"language":     spec.Language,
+	} {
+		if kernelspec[key] != value {
+			kernelspec[key] = value
+			changed = true
+		}
+	}
+
+	// language_info is written by the kernel itself; only reset it when the
+	// language differs, dropping details such as the version that no longer apply
+	languageInfo, _ := metadata["language_info"].(map[string]any)
+	if spec.Language != "" && (languageInfo == nil || languageInfo["name"] != spec.Language) {
+		metadata["language_info"] = map[string]any{"name": spec.Language}
+		changed = true
+	}
+
+	return changed
+}
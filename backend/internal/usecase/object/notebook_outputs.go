@@ -0,0 +1,108 @@
+package object
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+// clearCopiedOutputs strips outputs from the notebook already copied to obj.Path
+// and updates obj's size and hash to match. Files that are not valid notebooks
+// are left as copied.
+func (u *objectUseCase) clearCopiedOutputs(ctx context.Context, obj *entity.Object) error {
+	if obj.Type != entity.ObjectTypeNotebook {
+		return nil
+	}
+
+	content, err := u.storage.ReadFile(ctx, obj.Path)
+	if err != nil {
+		return err
+	}
+	cleared, ok := clearNotebookOutputs(content)
+	if !ok {
+		return nil
+	}
+	cleared = u.formatNotebook(cleared)
+	if err := u.storage.WriteFile(ctx, obj.Path, cleared); err != nil {
+		return err
+	}
+
+	obj.Size = int64(len(cleared))
+	obj.ContentHash = u.storage.CalculateHash(cleared)
+	return nil
+}
+
+// clearStagedOutputs is clearCopiedOutputs for a notebook copy staged as
+// upload uploadID, before it is moved into the workspace
+func (u *objectUseCase) clearStagedOutputs(ctx context.Context, obj *entity.Object, uploadID string) error {
+	if obj.Type != entity.ObjectTypeNotebook {
+		return nil
+	}
+
+	content, err := u.readUpload(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+	cleared, ok := clearNotebookOutputs(content)
+	if !ok {
+		return nil
+	}
+	cleared = u.formatNotebook(cleared)
+	if err := u.storage.WriteUpload(ctx, uploadID, cleared); err != nil {
+		return err
+	}
+
+	obj.Size = int64(len(cleared))
+	obj.ContentHash = u.storage.CalculateHash(cleared)
+	return nil
+}
+
+// GetContentWithoutOutputs returns a file's content with notebook outputs and
+// execution counts stripped. The stored file is not modified. Content of other
+// file types, and notebooks that fail to parse, is returned unchanged.
+func (u *objectUseCase) GetContentWithoutOutputs(ctx context.Context, objectID int64) ([]byte, error) {
+	obj, content, err := u.readContent(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Type != entity.ObjectTypeNotebook {
+		return content, nil
+	}
+
+	if cleared, ok := clearNotebookOutputs(content); ok {
+		return cleared, nil
+	}
+	return content, nil
+}
+
+// clearNotebookOutputs empties the outputs and execution counts of all code cells.
+// It returns false if content is not a valid notebook.
+func clearNotebookOutputs(content []byte) ([]byte, bool) {
+	var notebook map[string]any
+	if err := json.Unmarshal(content, &notebook); err != nil {
+		return nil, false
+	}
+	cells, ok := notebook["cells"].([]any)
+	if !ok {
+		return nil, false
+	}
+
+	for _, c := range cells {
+		cell, ok := c.(map[string]any)
+		if !ok || cell["cell_type"] != "code" {
+			continue
+		}
+		cell["outputs"] = []any{}
+		cell["execution_count"] = nil
+		if metadata, ok := cell["metadata"].(map[string]any); ok {
+			delete(metadata, "execution")
+		}
+	}
+
+	cleared, err := json.MarshalIndent(notebook, "", "  ")
+	if err != nil {
+		return nil, false
+	}
+	return cleared, true
+}
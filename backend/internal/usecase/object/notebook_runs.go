@@ -0,0 +1,85 @@
+package object
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// RecordRunInput describes a finished notebook execution whose results were saved
+type RecordRunInput struct {
+	KernelID      string `json:"kernel_id" binding:"required,max=255"`
+	DurationMs    int64  `json:"duration_ms" binding:"min=0"`
+	VersionNumber int    `json:"version_number" binding:"min=0"` // Version that saved the results (default: current version)
+}
+
+// RecordRun links a notebook execution to the version holding its results.
+// A version with a recorded run is no longer overwritten by coalesced
+// auto-saves, so the run's results stay available for comparison.
+func (u *objectUseCase) RecordRun(ctx context.Context, objectID int64, userID uuid.UUID, input *RecordRunInput) (*entity.NotebookRun, error) {
+	obj, err := u.objectRepo.GetByID(ctx, objectID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+	if obj.Type != entity.ObjectTypeNotebook {
+		return nil, apperrors.ValidationError("object is not a notebook")
+	}
+
+	allowed, err := u.permissionRepo.HasPermission(ctx, objectID, userID, entity.RoleEditor)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check permission", err)
+	}
+	if !allowed {
+		return nil, apperrors.ForbiddenError("no permission to edit this notebook")
+	}
+
+	versionNumber := input.VersionNumber
+	if versionNumber == 0 {
+		versionNumber = obj.CurrentVersion
+	}
+	if versionNumber == 0 {
+		return nil, apperrors.ValidationError("notebook has no saved version")
+	}
+	version, err := u.versionRepo.GetByObjectAndNumber(ctx, objectID, versionNumber)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("version")
+		}
+		return nil, apperrors.InternalError("failed to get version", err)
+	}
+
+	run := &entity.NotebookRun{
+		ObjectID:      objectID,
+		VersionID:     &version.ID,
+		VersionNumber: version.VersionNumber,
+		KernelID:      input.KernelID,
+		DurationMs:    input.DurationMs,
+		RunBy:         &userID,
+	}
+	if err := u.notebookRunRepo.Create(ctx, run); err != nil {
+		return nil, apperrors.InternalError("failed to record notebook run", err)
+	}
+	return run, nil
+}
+
+// ListRuns returns the recorded executions of a notebook, newest first
+func (u *objectUseCase) ListRuns(ctx context.Context, objectID int64, page, pageSize int) ([]entity.NotebookRun, int64, error) {
+	if _, err := u.objectRepo.GetByID(ctx, objectID); err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, 0, apperrors.NotFoundError("object")
+		}
+		return nil, 0, apperrors.InternalError("failed to get object", err)
+	}
+
+	runs, total, err := u.notebookRunRepo.ListByObject(ctx, objectID, page, pageSize)
+	if err != nil {
+		return nil, 0, apperrors.InternalError("failed to list notebook runs", err)
+	}
+	return runs, total, nil
+}
@@ -0,0 +1,182 @@
+package object
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// notebookRunMessage is the version message of the results of a notebook run
+const notebookRunMessage = "Run all cells"
+
+// NotebookCell is a code cell of a stored notebook, in notebook order
+type NotebookCell struct {
+	Index  int    `json:"index"`
+	CellID string `json:"cell_id,omitempty"`
+	Source string `json:"source"`
+}
+
+// CellOutputs holds the results of running a notebook cell so far. Outputs
+// are nbformat output objects.
+type CellOutputs struct {
+	Index          int
+	CellID         string
+	ExecutionCount *int
+	Outputs        []map[string]any
+}
+
+// GetCodeCells returns the code cells of a notebook the user may edit, for
+// running the notebook
+func (u *objectUseCase) GetCodeCells(ctx context.Context, objectID int64, userID uuid.UUID) ([]NotebookCell, error) {
+	obj, content, err := u.readContent(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Type != entity.ObjectTypeNotebook {
+		return nil, apperrors.ValidationError("object is not a notebook")
+	}
+
+	allowed, err := u.permissionRepo.HasPermission(ctx, objectID, userID, entity.RoleEditor)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check permission", err)
+	}
+	if !allowed {
+		return nil, apperrors.ForbiddenError("no permission to edit this notebook")
+	}
+
+	var notebook NotebookData
+	if err := json.Unmarshal(content, &notebook); err != nil {
+		return nil, apperrors.ValidationError("invalid notebook format")
+	}
+
+	var cells []NotebookCell
+	for i, cell := range notebook.Cells {
+		if cell["cell_type"] != "code" {
+			continue
+		}
+		id, _ := cell["id"].(string)
+		cells = append(cells, NotebookCell{Index: i, CellID: id, Source: notebookText(cell["source"])})
+	}
+	return cells, nil
+}
+
+// notebookText joins multiline notebook text, stored as a string or a list of lines
+func notebookText(v any) string {
+	switch text := v.(type) {
+	case string:
+		return text
+	case []any:
+		var b strings.Builder
+		for _, line := range text {
+			if s, ok := line.(string); ok {
+				b.WriteString(s)
+			}
+		}
+		return b.String()
+	}
+	return ""
+}
+
+// WriteCellOutputs stores the outputs of running cells into a notebook's file
+// without creating a version, so clients opening the notebook during a long
+// run see the results so far. The file is re-read under the object's lock and
+// only the outputs of the given cells are replaced, leaving edits made
+// meanwhile in place. Cells are found by ID, or by index when they have none;
+// cells deleted since the run started are skipped.
+func (u *objectUseCase) WriteCellOutputs(ctx context.Context, objectID int64, results []CellOutputs) error {
+	defer u.locks.lock(objectID)()
+
+	obj, content, err := u.readContent(ctx, objectID)
+	if err != nil {
+		return err
+	}
+	if obj.Type != entity.ObjectTypeNotebook {
+		return apperrors.ValidationError("object is not a notebook")
+	}
+
+	var notebook NotebookData
+	if err := json.Unmarshal(content, &notebook); err != nil {
+		return apperrors.ValidationError("invalid notebook format")
+	}
+
+	changed := false
+	for _, result := range results {
+		cell := findRunCell(notebook.Cells, result)
+		if cell == nil {
+			continue
+		}
+		outputs := result.Outputs
+		if outputs == nil {
+			outputs = []map[string]any{}
+		}
+		cell["outputs"] = outputs
+		if result.ExecutionCount != nil {
+			cell["execution_count"] = *result.ExecutionCount
+		} else {
+			cell["execution_count"] = nil
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	updated, err := json.MarshalIndent(notebook, "", "  ")
+	if err != nil {
+		return apperrors.InternalError("failed to serialize notebook", err)
+	}
+	if err := u.storage.WriteFile(ctx, obj.Path, u.formatNotebook(updated)); err != nil {
+		return apperrors.InternalError("failed to write file", err)
+	}
+	return nil
+}
+
+// findRunCell returns the code cell result was produced by, or nil
+func findRunCell(cells []map[string]any, result CellOutputs) map[string]any {
+	if result.CellID != "" {
+		for _, cell := range cells {
+			if id, _ := cell["id"].(string); id == result.CellID {
+				return cell
+			}
+		}
+		return nil
+	}
+	if result.Index < 0 || result.Index >= len(cells) {
+		return nil
+	}
+	cell := cells[result.Index]
+	if _, hasID := cell["id"]; hasID || cell["cell_type"] != "code" {
+		return nil
+	}
+	return cell
+}
+
+// FinishNotebookRun saves the notebook holding the results of a run as a
+// single version and records the run against it. Outputs written during the
+// run are in the stored file, which the object record does not yet reflect.
+func (u *objectUseCase) FinishNotebookRun(ctx context.Context, objectID int64, userID uuid.UUID, kernelID string, duration time.Duration) (*entity.NotebookRun, error) {
+	resp, err := func() (*entity.ObjectResponse, error) {
+		defer u.locks.lock(objectID)()
+
+		_, content, err := u.readContent(ctx, objectID)
+		if err != nil {
+			return nil, err
+		}
+		return u.saveContent(ctx, objectID, userID, content, notebookRunMessage)
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	return u.RecordRun(ctx, objectID, userID, &RecordRunInput{
+		KernelID:      kernelID,
+		DurationMs:    duration.Milliseconds(),
+		VersionNumber: resp.CurrentVersion,
+	})
+}
@@ -0,0 +1,383 @@
+package object
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// ExportFormat describes a form an object can be exported in
+type ExportFormat struct {
+	ID        string `json:"id"`        // Passed as the format of an export request
+	Label     string `json:"label"`     // Menu label, e.g. "HTML"
+	MimeType  string `json:"mime_type"` // Of the exported file
+	Extension string `json:"extension"` // Of the exported file, with the dot
+	Async     bool   `json:"async"`     // Exported through an export job rather than directly
+}
+
+// ExportedObject is an object converted to an export format
+type ExportedObject struct {
+	Name     string
+	MimeType string
+	Content  []byte
+}
+
+// objectExporter converts objects of the types it applies to into one format
+type objectExporter struct {
+	id      string
+	label   string
+	applies func(obj *entity.Object) bool
+	// format describes the exported file of obj
+	format func(obj *entity.Object) ExportFormat
+	export func(ctx context.Context, u *objectUseCase, obj *entity.Object, content []byte) ([]byte, error)
+	// async formats are too slow to convert within a request and are only
+	// served by export jobs
+	async bool
+	// ready, when set, fails if the server is not set up for the format
+	ready func(u *objectUseCase) error
+}
+
+// objectExporters lists the export formats in menu order. A format added here
+// is offered by GetExportFormats and served by ExportObject.
+var objectExporters = []objectExporter{
+	{
+		id:      "original",
+		label:   "Original file",
+		applies: func(obj *entity.Object) bool { return true },
+		format: func(obj *entity.Object) ExportFormat {
+			return ExportFormat{MimeType: exportMimeType(obj), Extension: path.Ext(obj.Name)}
+		},
+		export: func(_ context.Context, _ *objectUseCase, _ *entity.Object, content []byte) ([]byte, error) {
+			return content, nil
+		},
+	},
+	{
+		id:      "ipynb-clean",
+		label:   "Notebook without outputs",
+		applies: isNotebook,
+		format: func(obj *entity.Object) ExportFormat {
+			return ExportFormat{MimeType: "application/x-ipynb+json", Extension: ".ipynb"}
+		},
+		export: func(_ context.Context, _ *objectUseCase, _ *entity.Object, content []byte) ([]byte, error) {
+			cleared, ok := clearNotebookOutputs(content)
+			if !ok {
+				return nil, apperrors.ValidationError("invalid notebook format")
+			}
+			return cleared, nil
+		},
+	},
+	{
+		id:      "script",
+		label:   "Script",
+		applies: isNotebook,
+		format: func(obj *entity.Object) ExportFormat {
+			return ExportFormat{MimeType: "text/plain; charset=utf-8", Extension: scriptExtension(obj.Language)}
+		},
+		export: func(_ context.Context, _ *objectUseCase, _ *entity.Object, content []byte) ([]byte, error) {
+			return notebookToScript(content)
+		},
+	},
+	{
+		id:      "html",
+		label:   "HTML",
+		applies: isNotebook,
+		format: func(obj *entity.Object) ExportFormat {
+			return ExportFormat{MimeType: "text/html; charset=utf-8", Extension: ".html"}
+		},
+		export: func(_ context.Context, _ *objectUseCase, obj *entity.Object, content []byte) ([]byte, error) {
+			return notebookToHTML(obj, content)
+		},
+	},
+	{
+		id:    "html",
+		label: "HTML",
+		applies: func(obj *entity.Object) bool {
+			return obj.Type == entity.ObjectTypeMarkdown
+		},
+		format: func(obj *entity.Object) ExportFormat {
+			return ExportFormat{MimeType: "text/html; charset=utf-8", Extension: ".html"}
+		},
+		export: func(_ context.Context, _ *objectUseCase, obj *entity.Object, content []byte) ([]byte, error) {
+			return markdownDocument(obj, content)
+		},
+	},
+	{
+		id:    "pdf",
+		label: "PDF",
+		applies: func(obj *entity.Object) bool {
+			return obj.Type == entity.ObjectTypeNotebook || obj.Type == entity.ObjectTypeMarkdown
+		},
+		format: func(obj *entity.Object) ExportFormat {
+			return ExportFormat{MimeType: "application/pdf", Extension: ".pdf"}
+		},
+		export: func(ctx context.Context, u *objectUseCase, obj *entity.Object, content []byte) ([]byte, error) {
+			var page []byte
+			var err error
+			if isNotebook(obj) {
+				page, err = notebookToHTML(obj, content)
+			} else {
+				page, err = markdownDocument(obj, content)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return htmlToPDF(ctx, u.exportJobs.cfg, page)
+		},
+		async: true,
+		ready: func(u *objectUseCase) error { return pdfReady(u.exportJobs.cfg) },
+	},
+}
+
+func isNotebook(obj *entity.Object) bool {
+	return obj.Type == entity.ObjectTypeNotebook
+}
+
+// exportMimeType returns the MIME type a file is served with
+func exportMimeType(obj *entity.Object) string {
+	if obj.MimeType != "" {
+		return obj.MimeType
+	}
+	return "application/octet-stream"
+}
+
+// exportersFor returns the exporters that apply to obj, and fails for directories
+func exportersFor(obj *entity.Object) ([]objectExporter, error) {
+	if obj.IsDirectory() {
+		return nil, apperrors.ValidationError("directories cannot be exported")
+	}
+	var exporters []objectExporter
+	for _, e := range objectExporters {
+		if e.applies(obj) {
+			exporters = append(exporters, e)
+		}
+	}
+	return exporters, nil
+}
+
+// GetExportFormats returns the formats an object can be exported in
+func (u *objectUseCase) GetExportFormats(ctx context.Context, objectID int64) ([]ExportFormat, error) {
+	obj, err := u.objectRepo.GetByID(ctx, objectID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+	exporters, err := exportersFor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	formats := make([]ExportFormat, 0, len(exporters))
+	for _, e := range exporters {
+		format := e.format(obj)
+		format.ID, format.Label, format.Async = e.id, e.label, e.async
+		formats = append(formats, format)
+	}
+	return formats, nil
+}
+
+// ExportObject converts an object to the export format with the given ID.
+// The stored file is not modified. Async formats are served by export jobs.
+func (u *objectUseCase) ExportObject(ctx context.Context, objectID int64, formatID string) (*ExportedObject, error) {
+	obj, content, e, err := u.exporterFor(ctx, objectID, formatID)
+	if err != nil {
+		return nil, err
+	}
+	if e.async {
+		return nil, apperrors.InvalidArgumentError(fmt.Sprintf("format %q is converted in the background; start an export job instead", formatID), "format")
+	}
+	return e.run(ctx, u, obj, content)
+}
+
+// exporterFor reads an object's content and finds its exporter for formatID
+func (u *objectUseCase) exporterFor(ctx context.Context, objectID int64, formatID string) (*entity.Object, []byte, *objectExporter, error) {
+	obj, content, err := u.readContent(ctx, objectID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	exporters, err := exportersFor(obj)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for i := range exporters {
+		if exporters[i].id == formatID {
+			return obj, content, &exporters[i], nil
+		}
+	}
+	return nil, nil, nil, apperrors.InvalidArgumentError(fmt.Sprintf("format %q is not available for this object", formatID), "format")
+}
+
+// run exports obj and names the exported file after it
+func (e *objectExporter) run(ctx context.Context, u *objectUseCase, obj *entity.Object, content []byte) (*ExportedObject, error) {
+	exported, err := e.export(ctx, u, obj, content)
+	if err != nil {
+		return nil, err
+	}
+	format := e.format(obj)
+	return &ExportedObject{
+		Name:     strings.TrimSuffix(obj.Name, path.Ext(obj.Name)) + format.Extension,
+		MimeType: format.MimeType,
+		Content:  exported,
+	}, nil
+}
+
+// scriptExtensions maps notebook languages to the extension of their scripts
+var scriptExtensions = map[string]string{
+	"python": ".py",
+	"r":      ".r",
+	"julia":  ".jl",
+}
+
+func scriptExtension(language string) string {
+	if ext, ok := scriptExtensions[strings.ToLower(language)]; ok {
+		return ext
+	}
+	return ".txt"
+}
+
+// notebookToScript joins the code cells of a notebook into a script, with
+// markdown cells kept as comments
+func notebookToScript(content []byte) ([]byte, error) {
+	var notebook NotebookData
+	if err := json.Unmarshal(content, &notebook); err != nil {
+		return nil, apperrors.ValidationError("invalid notebook format")
+	}
+
+	var buf bytes.Buffer
+	for _, cell := range notebook.Cells {
+		source := strings.TrimRight(notebookText(cell["source"]), "\n")
+		if source == "" {
+			continue
+		}
+		switch cell["cell_type"] {
+		case "code":
+			buf.WriteString(source)
+		case "markdown":
+			for i, line := range strings.Split(source, "\n") {
+				if i > 0 {
+					buf.WriteByte('\n')
+				}
+				buf.WriteString(strings.TrimRight("# "+line, " "))
+			}
+		default:
+			continue
+		}
+		buf.WriteString("\n\n")
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// ansiEscape matches the terminal color codes in kernel tracebacks
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// notebookToHTML renders a notebook to a standalone HTML page. Markdown and
+// HTML outputs are sanitized like rendered markdown; images are embedded.
+func notebookToHTML(obj *entity.Object, content []byte) ([]byte, error) {
+	var notebook NotebookData
+	if err := json.Unmarshal(content, &notebook); err != nil {
+		return nil, apperrors.ValidationError("invalid notebook format")
+	}
+
+	var body strings.Builder
+	for _, cell := range notebook.Cells {
+		source := notebookText(cell["source"])
+		switch cell["cell_type"] {
+		case "markdown":
+			rendered, err := markdownToHTML([]byte(source))
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&body, "<div class=\"cell markdown\">%s</div>\n", rendered)
+		case "code":
+			body.WriteString("<div class=\"cell code\">\n")
+			fmt.Fprintf(&body, "<pre class=\"input\"><code>%s</code></pre>\n", html.EscapeString(source))
+			outputs, _ := cell["outputs"].([]any)
+			for _, o := range outputs {
+				if output, ok := o.(map[string]any); ok {
+					body.WriteString(outputHTML(output))
+				}
+			}
+			body.WriteString("</div>\n")
+		case "raw":
+			fmt.Fprintf(&body, "<pre class=\"cell raw\">%s</pre>\n", html.EscapeString(source))
+		}
+	}
+	return htmlDocument(obj.Name, body.String()), nil
+}
+
+// markdownDocument renders a markdown file to a standalone HTML page
+func markdownDocument(obj *entity.Object, content []byte) ([]byte, error) {
+	body, err := markdownToHTML(content)
+	if err != nil {
+		return nil, err
+	}
+	return htmlDocument(obj.Name, body), nil
+}
+
+// outputHTML renders one nbformat output, preferring images, then HTML, then text
+func outputHTML(output map[string]any) string {
+	switch output["output_type"] {
+	case "stream":
+		return fmt.Sprintf("<pre class=\"output stream\">%s</pre>\n", html.EscapeString(notebookText(output["text"])))
+	case "error":
+		traceback, _ := output["traceback"].([]any)
+		lines := make([]string, 0, len(traceback))
+		for _, line := range traceback {
+			if s, ok := line.(string); ok {
+				lines = append(lines, ansiEscape.ReplaceAllString(s, ""))
+			}
+		}
+		return fmt.Sprintf("<pre class=\"output error\">%s</pre>\n", html.EscapeString(strings.Join(lines, "\n")))
+	case "execute_result", "display_data":
+		data, _ := output["data"].(map[string]any)
+		for _, mimeType := range []string{"image/png", "image/jpeg", "image/gif"} {
+			encoded := strings.Join(strings.Fields(notebookText(data[mimeType])), "")
+			if encoded == "" {
+				continue
+			}
+			if _, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+				return fmt.Sprintf("<div class=\"output image\"><img src=\"data:%s;base64,%s\"></div>\n", mimeType, encoded)
+			}
+		}
+		if rich := notebookText(data["text/html"]); rich != "" {
+			return fmt.Sprintf("<div class=\"output html\">%s</div>\n", markdownPolicy.Sanitize(rich))
+		}
+		if text := notebookText(data["text/plain"]); text != "" {
+			return fmt.Sprintf("<pre class=\"output text\">%s</pre>\n", html.EscapeString(text))
+		}
+	}
+	return ""
+}
+
+// htmlDocument wraps rendered HTML in a standalone page titled title
+func htmlDocument(title, body string) []byte {
+	return []byte(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>` + html.EscapeString(title) + `</title>
+<style>
+body { max-width: 960px; margin: 2em auto; padding: 0 1em; font-family: sans-serif; line-height: 1.5; }
+pre { padding: 0.5em; overflow-x: auto; background: #f6f8fa; }
+.cell { margin-bottom: 1em; }
+.output { margin: 0.25em 0 0 1.5em; }
+pre.output { background: none; }
+pre.error { color: #b00020; }
+img { max-width: 100%; }
+</style>
+</head>
+<body>
+` + body + `</body>
+</html>
+`)
+}
@@ -0,0 +1,42 @@
+package object
+
+import "sync"
+
+// objectLocks serializes writers that read, modify and write back an object's
+// stored file, so their changes do not overwrite each other. The locks are
+// held within this process only.
+type objectLocks struct {
+	mu    sync.Mutex
+	locks map[int64]*objectLock
+}
+
+type objectLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lock acquires the lock of an object and returns the function releasing it
+func (l *objectLocks) lock(objectID int64) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[int64]*objectLock)
+	}
+	ol, ok := l.locks[objectID]
+	if !ok {
+		ol = &objectLock{}
+		l.locks[objectID] = ol
+	}
+	ol.refs++
+	l.mu.Unlock()
+
+	ol.mu.Lock()
+	return func() {
+		ol.mu.Unlock()
+
+		l.mu.Lock()
+		if ol.refs--; ol.refs == 0 {
+			delete(l.locks, objectID)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -2,16 +2,26 @@ package object
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 
 	"github.com/leondli/workspace/internal/adapter/storage"
 	"github.com/leondli/workspace/internal/domain/entity"
 	"github.com/leondli/workspace/internal/domain/repository"
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	"github.com/leondli/workspace/internal/usecase/event"
 	apperrors "github.com/leondli/workspace/pkg/errors"
 )
 
@@ -22,20 +32,70 @@ type UseCase interface {
 
 	// File operations
 	CreateFile(ctx context.Context, creatorID uuid.UUID, appID, email string, input *CreateFileInput) (*entity.ObjectResponse, error)
+	StartUpload(ctx context.Context, userID uuid.UUID, appID, email string, input *StartUploadInput) (*entity.UploadSession, error)
+	GetUpload(ctx context.Context, id, userID uuid.UUID) (*entity.UploadSession, error)
+	AppendUpload(ctx context.Context, id, userID uuid.UUID, offset int64, r io.Reader) (*entity.UploadSession, error)
+	CompleteUpload(ctx context.Context, id, userID uuid.UUID, appID, email string) (*entity.ObjectResponse, error)
+	AbortUpload(ctx context.Context, id, userID uuid.UUID) error
 	GetContent(ctx context.Context, objectID int64) ([]byte, error)
+	GetContentWithoutOutputs(ctx context.Context, objectID int64) ([]byte, error)
+	GetContents(ctx context.Context, userID uuid.UUID, ids []int64) (map[int64][]byte, error)
 	SaveContent(ctx context.Context, objectID int64, userID uuid.UUID, content []byte, message string) (*entity.ObjectResponse, error)
 	PatchNotebook(ctx context.Context, objectID int64, userID uuid.UUID, input *PatchNotebookInput) (*entity.ObjectResponse, error)
+	DiffAgainst(ctx context.Context, objectID int64, candidate []byte) (*VersionDiff, error)
+	SetNotebookKernelspec(ctx context.Context, objectID int64, userID uuid.UUID, spec *NotebookKernelspec) (*entity.ObjectResponse, error)
+	SetContentType(ctx context.Context, objectID int64, userID uuid.UUID, objectType entity.ObjectType, mimeType string) (*entity.ObjectResponse, error)
+	SetPinned(ctx context.Context, objectID int64, userID uuid.UUID, pinned bool) (*entity.ObjectResponse, error)
+	RenderMarkdown(ctx context.Context, objectID int64) (string, error)
+	GetExportFormats(ctx context.Context, objectID int64) ([]ExportFormat, error)
+	ExportObject(ctx context.Context, objectID int64, formatID string) (*ExportedObject, error)
+	StartExportJob(ctx context.Context, objectID int64, userID uuid.UUID, formatID string) (*ExportJob, error)
+	GetExportJob(ctx context.Context, jobID string, userID uuid.UUID) (*ExportJob, error)
+	DownloadExportJob(ctx context.Context, jobID string, userID uuid.UUID) (*ExportedObject, error)
+	GetExportBackends(ctx context.Context) []string
+	ListTemplates(ctx context.Context, userID uuid.UUID) ([]entity.ObjectTemplate, error)
+	RegisterTemplate(ctx context.Context, userID uuid.UUID, shared bool, input *RegisterTemplateInput) (*entity.ObjectTemplate, error)
+	DeleteTemplate(ctx context.Context, templateID int64, userID uuid.UUID, asAdmin bool) error
+	CreateFromTemplate(ctx context.Context, userID uuid.UUID, appID, email string, input *CreateFromTemplateInput) (*entity.ObjectResponse, error)
+	ExportToBackend(ctx context.Context, objectID int64, userID uuid.UUID, backend string, destPrefix string) (*ExportJob, error)
+	ValidateContent(ctx context.Context, objectType entity.ObjectType, name string, content []byte) (*ValidationResult, error)
+	RecordAccess(objectID int64, userID uuid.UUID)
+	GetAccessStats(ctx context.Context, objectID int64) (*entity.ObjectAccessStats, error)
 
 	// Common operations
 	GetByID(ctx context.Context, id int64) (*entity.ObjectResponse, error)
 	GetByPath(ctx context.Context, path string) (*entity.ObjectResponse, error)
+	ResolveByDisplayPath(ctx context.Context, userID uuid.UUID, appID, email, displayPath string) (*entity.ObjectResponse, error)
+	GetByContentHash(ctx context.Context, userID uuid.UUID, hash string) ([]entity.ObjectResponse, error)
+	GetAncestors(ctx context.Context, objectID int64) ([]entity.ObjectResponse, error)
+	GetNameHistory(ctx context.Context, objectID int64) ([]entity.ObjectNameHistory, error)
 	List(ctx context.Context, filter *entity.ObjectFilter) ([]entity.ObjectResponse, int64, error)
-	ListChildren(ctx context.Context, parentID *int64, page, pageSize int) ([]entity.ObjectResponse, int64, error)
+	ListChildren(ctx context.Context, userID uuid.UUID, parentID *int64, page, pageSize int) ([]entity.ObjectResponse, int64, error)
 	GetTree(ctx context.Context, userID uuid.UUID, appID, email string, depth int) ([]entity.ObjectResponse, error)
+	GetTreeLevel(ctx context.Context, userID uuid.UUID, parentID *int64) ([]entity.ObjectResponse, error)
 	Update(ctx context.Context, id int64, input *UpdateInput) (*entity.ObjectResponse, error)
-	Delete(ctx context.Context, id int64) error
+	Delete(ctx context.Context, id int64, userID uuid.UUID) error
+	RestoreTree(ctx context.Context, objectID int64) (*RestoreResult, error)
+	ListTrash(ctx context.Context, userID uuid.UUID, page, pageSize int) ([]entity.DeleteBatch, int64, error)
+	RecordRun(ctx context.Context, objectID int64, userID uuid.UUID, input *RecordRunInput) (*entity.NotebookRun, error)
+	ListRuns(ctx context.Context, objectID int64, page, pageSize int) ([]entity.NotebookRun, int64, error)
+	GetCodeCells(ctx context.Context, objectID int64, userID uuid.UUID) ([]NotebookCell, error)
+	WriteCellOutputs(ctx context.Context, objectID int64, results []CellOutputs) error
+	FinishNotebookRun(ctx context.Context, objectID int64, userID uuid.UUID, kernelID string, duration time.Duration) (*entity.NotebookRun, error)
 	Move(ctx context.Context, id int64, input *MoveInput) (*entity.ObjectResponse, error)
 	Copy(ctx context.Context, id int64, creatorID uuid.UUID, appID, email string, input *CopyInput) (*entity.ObjectResponse, error)
+	PlanDelete(ctx context.Context, id int64) (*OperationPlan, error)
+	PlanMove(ctx context.Context, id int64, input *MoveInput) (*OperationPlan, error)
+	PlanCopy(ctx context.Context, id int64, appID, email string, input *CopyInput) (*OperationPlan, error)
+	Duplicate(ctx context.Context, id int64, creatorID uuid.UUID, appID, email string) (*entity.ObjectResponse, error)
+	ExportWorkspace(ctx context.Context, userID uuid.UUID) (io.ReadCloser, error)
+	ImportWorkspace(ctx context.Context, userID uuid.UUID, appID, email string, r io.Reader, strategy ImportConflictStrategy) (*ImportResult, error)
+	ImportFiles(ctx context.Context, userID uuid.UUID, appID, email, dir string, files []ImportedFile) ([]ImportedObject, []ImportIssue, error)
+
+	// Account erasure
+	TransferShared(ctx context.Context, user *entity.User, deleteShared bool) ([]TransferredObject, error)
+	EraseUserObjects(ctx context.Context, user *entity.User) (*entity.ObjectErasure, error)
+	PurgeErased(ctx context.Context, erasure *entity.ObjectErasure) error
 }
 
 // CreateDirectoryInput represents directory creation input
@@ -56,8 +116,10 @@ type CreateFileInput struct {
 
 // UpdateInput represents object update input
 type UpdateInput struct {
-	Name        *string `json:"name"`
-	Description *string `json:"description"`
+	Name        *string    `json:"name"`
+	Description *string    `json:"description"`
+	UpdatedAt   *time.Time `json:"updated_at"` // Optional updated_at the client last saw; stale values are rejected
+	UserID      uuid.UUID  `json:"-"`          // Set by handler, not from JSON
 }
 
 // CellOperation represents a single cell operation for notebook incremental update
@@ -84,17 +146,38 @@ type MoveInput struct {
 	Email          string    `json:"-"`  // Set by handler, not from JSON
 }
 
+// BatchContentInput represents a request for the content of several files
+type BatchContentInput struct {
+	IDs []int64 `json:"ids" binding:"required,min=1,max=100"`
+}
+
 // CopyInput represents object copy input
 type CopyInput struct {
 	TargetParentID *int64  `json:"target_parent_id"`
 	NewName        *string `json:"new_name"`
+	// ClearOutputs strips cell outputs and execution counts from copied notebooks
+	ClearOutputs bool `json:"clear_outputs"`
 }
 
 type objectUseCase struct {
-	objectRepo     repository.ObjectRepository
-	versionRepo    repository.VersionRepository
-	permissionRepo repository.PermissionRepository
-	storage        *storage.LocalFileStorage
+	objectRepo        repository.ObjectRepository
+	versionRepo       repository.VersionRepository
+	permissionRepo    repository.PermissionRepository
+	nameHistoryRepo   repository.ObjectNameHistoryRepository
+	deleteBatchRepo   repository.DeleteBatchRepository
+	notebookRunRepo   repository.NotebookRunRepository
+	uploadSessionRepo repository.UploadSessionRepository
+	tagRepo           repository.TagRepository
+	pinRepo           repository.ObjectPinRepository
+	templateRepo      repository.ObjectTemplateRepository
+	storage           *storage.LocalFileStorage
+	scanner           storage.UploadScanner
+	accessRecorder    *AccessRecorder
+	exportJobs        *ExportJobs
+	locks             objectLocks
+	events            event.Publisher
+	storageConfig     *config.StorageConfig
+	permissionConfig  *config.PermissionConfig
 }
 
 // NewUseCase creates a new object use case
@@ -102,44 +185,128 @@ func NewUseCase(
 	objectRepo repository.ObjectRepository,
 	versionRepo repository.VersionRepository,
 	permissionRepo repository.PermissionRepository,
-	storage *storage.LocalFileStorage,
+	nameHistoryRepo repository.ObjectNameHistoryRepository,
+	deleteBatchRepo repository.DeleteBatchRepository,
+	notebookRunRepo repository.NotebookRunRepository,
+	uploadSessionRepo repository.UploadSessionRepository,
+	tagRepo repository.TagRepository,
+	pinRepo repository.ObjectPinRepository,
+	templateRepo repository.ObjectTemplateRepository,
+	fileStorage *storage.LocalFileStorage,
+	scanner storage.UploadScanner,
+	accessRecorder *AccessRecorder,
+	exportJobs *ExportJobs,
+	storageConfig *config.StorageConfig,
+	permissionConfig *config.PermissionConfig,
+	events event.Publisher,
 ) UseCase {
+	if scanner == nil {
+		scanner = storage.NewNoopScanner()
+	}
 	return &objectUseCase{
-		objectRepo:     objectRepo,
-		versionRepo:    versionRepo,
-		permissionRepo: permissionRepo,
-		storage:        storage,
+		objectRepo:        objectRepo,
+		versionRepo:       versionRepo,
+		permissionRepo:    permissionRepo,
+		nameHistoryRepo:   nameHistoryRepo,
+		deleteBatchRepo:   deleteBatchRepo,
+		notebookRunRepo:   notebookRunRepo,
+		uploadSessionRepo: uploadSessionRepo,
+		tagRepo:           tagRepo,
+		pinRepo:           pinRepo,
+		templateRepo:      templateRepo,
+		storage:           fileStorage,
+		scanner:           scanner,
+		accessRecorder:    accessRecorder,
+		exportJobs:        exportJobs,
+		storageConfig:     storageConfig,
+		permissionConfig:  permissionConfig,
+		events:            events,
 	}
 }
 
-func (u *objectUseCase) CreateDirectory(ctx context.Context, creatorID uuid.UUID, appID, email string, input *CreateDirectoryInput) (*entity.ObjectResponse, error) {
-	var path string
-	var parentID *int64
+// publish emits a change event for obj; oldPath is set for moves
+func (u *objectUseCase) publish(ctx context.Context, eventType string, obj *entity.Object, oldPath string, actorID uuid.UUID) {
+	u.publishEvent(ctx, objectEvent(eventType, obj, oldPath, actorID))
+}
+
+func (u *objectUseCase) publishEvent(ctx context.Context, e *event.Event) {
+	if u.events != nil {
+		u.events.Publish(ctx, e)
+	}
+}
 
-	if input.ParentID != nil {
+func objectEvent(eventType string, obj *entity.Object, oldPath string, actorID uuid.UUID) *event.Event {
+	e := event.New(eventType, obj, nil)
+	e.OldPath = oldPath
+	if actorID != uuid.Nil {
+		e.ActorID = &actorID
+	}
+	return e
+}
+
+// resolveCreatePath builds the path for a new object and resolves its parent.
+// The parent comes from parentID when given; otherwise the object is created in
+// the user directory and its parent is derived from the path, so ParentID is
+// always recorded whenever the containing directory is itself an object.
+func (u *objectUseCase) resolveCreatePath(ctx context.Context, appID, email string, parentID *int64, name string) (string, *int64, error) {
+	if parentID != nil {
 		// 如果指定了父目录，在父目录下创建
-		parent, err := u.objectRepo.GetByID(ctx, *input.ParentID)
+		parent, err := u.objectRepo.GetByID(ctx, *parentID)
 		if err != nil {
 			if apperrors.IsNotFound(err) {
-				return nil, apperrors.NotFoundError("parent directory")
+				return "", nil, apperrors.NotFoundError("parent directory")
 			}
-			return nil, apperrors.InternalError("failed to get parent directory", err)
+			return "", nil, apperrors.InternalError("failed to get parent directory", err)
 		}
 		if !parent.IsDirectory() {
-			return nil, apperrors.ValidationError("parent is not a directory")
+			return "", nil, apperrors.ValidationError("parent is not a directory")
 		}
-		path = parent.Path + "/" + input.Name
-		parentID = input.ParentID
-	} else {
-		// Build path - 在用户目录下创建
-		// 用户目录路径: /{appID}/{email}/{name}
-		userDir := "/" + appID + "/" + email
-		path = userDir + "/" + input.Name
+		return parent.Path + "/" + name, &parent.ID, nil
+	}
+
+	// Build path - 在用户目录下创建
+	// 用户目录路径: /{appID}/{email}/{name}
+	userDir := "/" + appID + "/" + email
+	path := userDir + "/" + name
+
+	// 确保用户目录存在
+	if err := u.storage.CreateDirectory(ctx, userDir); err != nil {
+		// 忽略已存在的错误
+	}
 
-		// 确保用户目录存在
-		if err := u.storage.CreateDirectory(ctx, userDir); err != nil {
-			// 忽略已存在的错误
+	// Derive the parent from the path when the containing directory is tracked
+	parent, err := u.objectRepo.GetByPath(ctx, filepath.Dir(path))
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return path, nil, nil
 		}
+		return "", nil, apperrors.InternalError("failed to get parent directory", err)
+	}
+	if !parent.IsDirectory() {
+		return "", nil, apperrors.ValidationError("parent is not a directory")
+	}
+	return path, &parent.ID, nil
+}
+
+func (u *objectUseCase) CreateDirectory(ctx context.Context, creatorID uuid.UUID, appID, email string, input *CreateDirectoryInput) (*entity.ObjectResponse, error) {
+	name, err := u.sanitizeName(input.Name)
+	if err != nil {
+		return nil, err
+	}
+	input.Name = name
+
+	path, parentID, err := u.resolveCreatePath(ctx, appID, email, input.ParentID, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check before touching storage, where a case-only variant is the same file
+	taken, err := u.pathTaken(ctx, path, 0)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check path", err)
+	}
+	if taken {
+		return nil, apperrors.AlreadyExistsError("object with this name")
 	}
 
 	// Create directory in storage
@@ -165,57 +332,66 @@ func (u *objectUseCase) CreateDirectory(ctx context.Context, creatorID uuid.UUID
 	}
 
 	if err := u.objectRepo.Create(ctx, obj); err != nil {
+		// A unique violation means a concurrent request created the same path;
+		// keep its storage entry and report the conflict
+		if apperrors.IsAlreadyExists(err) {
+			return nil, err
+		}
 		// Rollback storage
 		_ = u.storage.Delete(ctx, path)
 		return nil, apperrors.InternalError("failed to create object", err)
 	}
 
+	u.inheritParentPermissions(ctx, obj)
+	u.publish(ctx, event.ObjectCreated, obj, "", creatorID)
 	return obj.ToResponse(), nil
 }
 
 func (u *objectUseCase) CreateFile(ctx context.Context, creatorID uuid.UUID, appID, email string, input *CreateFileInput) (*entity.ObjectResponse, error) {
+	name, err := u.sanitizeName(input.Name)
+	if err != nil {
+		return nil, err
+	}
+	input.Name = name
+
+	if err := u.checkFileType(input.Name, input.Content); err != nil {
+		return nil, err
+	}
+
 	// Infer type from extension if not provided
 	if input.Type == "" {
 		input.Type = entity.InferTypeFromExtension(input.Name)
 	}
 
-	var path string
-	var parentID *int64
-
-	if input.ParentID != nil {
-		// 如果指定了父目录，在父目录下创建
-		parent, err := u.objectRepo.GetByID(ctx, *input.ParentID)
-		if err != nil {
-			if apperrors.IsNotFound(err) {
-				return nil, apperrors.NotFoundError("parent directory")
-			}
-			return nil, apperrors.InternalError("failed to get parent directory", err)
-		}
-		if !parent.IsDirectory() {
-			return nil, apperrors.ValidationError("parent is not a directory")
-		}
-		path = parent.Path + "/" + input.Name
-		parentID = input.ParentID
-	} else {
-		// Build path - 在用户目录下创建
-		// 用户目录路径: /{appID}/{email}/{name}
-		userDir := "/" + appID + "/" + email
-		path = userDir + "/" + input.Name
+	path, parentID, err := u.resolveCreatePath(ctx, appID, email, input.ParentID, input.Name)
+	if err != nil {
+		return nil, err
+	}
 
-		// 确保用户目录存在
-		if err := u.storage.CreateDirectory(ctx, userDir); err != nil {
-			// 忽略已存在的错误
-		}
+	// Check before touching storage, where a case-only variant is the same file
+	taken, err := u.pathTaken(ctx, path, 0)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check path", err)
+	}
+	if taken {
+		return nil, apperrors.AlreadyExistsError("object with this name")
 	}
 
-	// Write file to storage
-	if err := u.storage.WriteFile(ctx, path, input.Content); err != nil {
+	// Stage and scan the content outside the workspace, so kernels never see
+	// a file the scanner rejects
+	uploadID := uuid.NewString()
+	if err := u.storage.WriteUpload(ctx, uploadID, input.Content); err != nil {
 		return nil, apperrors.InternalError("failed to write file to storage", err)
 	}
+	if err := u.scanUpload(ctx, uploadID, input.Name); err != nil {
+		_ = u.storage.DeleteUpload(ctx, uploadID)
+		return nil, err
+	}
 
-	// Get inode and size
-	inode, err := u.storage.GetInode(ctx, path)
+	// The inode survives the rename into the workspace
+	inode, err := u.storage.UploadInode(ctx, uploadID)
 	if err != nil {
+		_ = u.storage.DeleteUpload(ctx, uploadID)
 		return nil, apperrors.InternalError("failed to get inode", err)
 	}
 
@@ -234,37 +410,104 @@ func (u *objectUseCase) CreateFile(ctx context.Context, creatorID uuid.UUID, app
 		Description:    input.Description,
 		CurrentVersion: 1,
 	}
+	u.setLanguage(obj, input.Content)
 
-	if err := u.objectRepo.Create(ctx, obj); err != nil {
-		_ = u.storage.Delete(ctx, path)
-		return nil, apperrors.InternalError("failed to create object", err)
+	if err := u.createStaged(ctx, obj, uploadID); err != nil {
+		_ = u.storage.DeleteUpload(ctx, uploadID)
+		return nil, err
 	}
 
+	u.inheritParentPermissions(ctx, obj)
+	u.publish(ctx, event.ObjectCreated, obj, "", creatorID)
 	return obj.ToResponse(), nil
 }
 
 func (u *objectUseCase) GetContent(ctx context.Context, objectID int64) ([]byte, error) {
+	_, content, err := u.readContent(ctx, objectID)
+	return content, err
+}
+
+// readContent returns a file object together with its stored content
+func (u *objectUseCase) readContent(ctx context.Context, objectID int64) (*entity.Object, []byte, error) {
 	obj, err := u.objectRepo.GetByID(ctx, objectID)
 	if err != nil {
 		if apperrors.IsNotFound(err) {
-			return nil, apperrors.NotFoundError("object")
+			return nil, nil, apperrors.NotFoundError("object")
 		}
-		return nil, apperrors.InternalError("failed to get object", err)
+		return nil, nil, apperrors.InternalError("failed to get object", err)
 	}
 
 	if obj.IsDirectory() {
-		return nil, apperrors.ValidationError("cannot read content of a directory")
+		return nil, nil, apperrors.ValidationError("cannot read content of a directory")
 	}
 
 	content, err := u.storage.ReadFile(ctx, obj.Path)
 	if err != nil {
-		return nil, apperrors.InternalError("failed to read file", err)
+		return nil, nil, apperrors.InternalError("failed to read file", err)
 	}
 
-	return content, nil
+	return obj, content, nil
+}
+
+// maxBatchContentBytes caps the total content size returned by GetContents
+const maxBatchContentBytes = 10 * 1024 * 1024
+
+// GetContents returns the content of several files keyed by object ID.
+// Directories, missing objects and objects the user cannot read are skipped.
+func (u *objectUseCase) GetContents(ctx context.Context, userID uuid.UUID, ids []int64) (map[int64][]byte, error) {
+	contents := make(map[int64][]byte, len(ids))
+	var total int64
+
+	for _, id := range ids {
+		if _, done := contents[id]; done {
+			continue
+		}
+
+		obj, err := u.objectRepo.GetByID(ctx, id)
+		if err != nil {
+			if apperrors.IsNotFound(err) {
+				continue
+			}
+			return nil, apperrors.InternalError("failed to get object", err)
+		}
+		if obj.IsDirectory() {
+			continue
+		}
+
+		allowed, err := u.permissionRepo.HasPermission(ctx, id, userID, entity.RoleViewer)
+		if err != nil {
+			return nil, apperrors.InternalError("failed to check permission", err)
+		}
+		if !allowed {
+			continue
+		}
+
+		// Check the recorded size first so oversized files are never read
+		if total+obj.Size > maxBatchContentBytes {
+			return nil, apperrors.ResourceExhaustedError(fmt.Sprintf("batch content exceeds %d bytes, request fewer objects", maxBatchContentBytes))
+		}
+
+		content, err := u.storage.ReadFile(ctx, obj.Path)
+		if err != nil {
+			return nil, apperrors.InternalError("failed to read file", err)
+		}
+		total += int64(len(content))
+		if total > maxBatchContentBytes {
+			return nil, apperrors.ResourceExhaustedError(fmt.Sprintf("batch content exceeds %d bytes, request fewer objects", maxBatchContentBytes))
+		}
+		contents[id] = content
+	}
+
+	return contents, nil
 }
 
 func (u *objectUseCase) SaveContent(ctx context.Context, objectID int64, userID uuid.UUID, content []byte, message string) (*entity.ObjectResponse, error) {
+	defer u.locks.lock(objectID)()
+	return u.saveContent(ctx, objectID, userID, content, message)
+}
+
+// saveContent saves content while the caller holds the object's lock
+func (u *objectUseCase) saveContent(ctx context.Context, objectID int64, userID uuid.UUID, content []byte, message string) (*entity.ObjectResponse, error) {
 	obj, err := u.objectRepo.GetByID(ctx, objectID)
 	if err != nil {
 		if apperrors.IsNotFound(err) {
@@ -277,11 +520,15 @@ func (u *objectUseCase) SaveContent(ctx context.Context, objectID int64, userID
 		return nil, apperrors.ValidationError("cannot write content to a directory")
 	}
 
+	if obj.Type == entity.ObjectTypeNotebook {
+		content = u.formatNotebook(content)
+	}
+
 	// Calculate hash
 	contentHash := u.storage.CalculateHash(content)
 
 	// Skip if content hasn't changed
-	if contentHash == obj.ContentHash {
+	if u.contentUnchanged(ctx, obj, content, contentHash) {
 		return obj.ToResponse(), nil
 	}
 
@@ -290,6 +537,24 @@ func (u *objectUseCase) SaveContent(ctx context.Context, objectID int64, userID
 		return nil, apperrors.InternalError("failed to write file", err)
 	}
 
+	// Fold an auto-save burst into the version it started
+	coalesced, err := u.coalesceVersion(ctx, obj, userID, content, contentHash, message)
+	if err != nil {
+		return nil, err
+	}
+	if coalesced {
+		obj.Size = int64(len(content))
+		obj.ContentHash = contentHash
+		u.setLanguage(obj, content)
+		obj.MarkModifiedBy(userID)
+
+		if err := u.objectRepo.Update(ctx, obj); err != nil {
+			return nil, apperrors.InternalError("failed to update object", err)
+		}
+		u.publish(ctx, event.ObjectSaved, obj, "", userID)
+		return obj.ToResponse(), nil
+	}
+
 	// Get next version number
 	nextVersion, err := u.versionRepo.GetNextVersionNumber(ctx, objectID)
 	if err != nil {
@@ -321,14 +586,56 @@ func (u *objectUseCase) SaveContent(ctx context.Context, objectID int64, userID
 	obj.Size = int64(len(content))
 	obj.ContentHash = contentHash
 	obj.CurrentVersion = nextVersion
+	u.setLanguage(obj, content)
+	obj.MarkModifiedBy(userID)
 
 	if err := u.objectRepo.Update(ctx, obj); err != nil {
 		return nil, apperrors.InternalError("failed to update object", err)
 	}
 
+	u.publish(ctx, event.ObjectSaved, obj, "", userID)
+
 	return obj.ToResponse(), nil
 }
 
+// coalesceVersion overwrites the object's current version with content instead
+// of creating a new one when both are auto-saves (no message) by the same user,
+// the current version was created within the coalescing window and no
+// notebook run was recorded against it. It reports whether the content was
+// stored that way.
+func (u *objectUseCase) coalesceVersion(ctx context.Context, obj *entity.Object, userID uuid.UUID, content []byte, contentHash, message string) (bool, error) {
+	window := u.storageConfig.GetVersionCoalesce()
+	if window <= 0 || message != "" || obj.CurrentVersion == 0 {
+		return false, nil
+	}
+
+	latest, err := u.versionRepo.GetLatest(ctx, obj.ID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, apperrors.InternalError("failed to get latest version", err)
+	}
+	if latest.VersionNumber != obj.CurrentVersion || latest.CreatorID != userID ||
+		latest.Message != "" || time.Since(latest.CreatedAt) > window {
+		return false, nil
+	}
+	// Keep the results of a recorded notebook run
+	if hasRun, err := u.notebookRunRepo.ExistsForVersion(ctx, latest.ID); err != nil {
+		return false, apperrors.InternalError("failed to check notebook runs", err)
+	} else if hasRun {
+		return false, nil
+	}
+
+	if err := u.storage.OverwriteVersion(ctx, latest.StoragePath, content); err != nil {
+		return false, apperrors.InternalError("failed to save version", err)
+	}
+	if err := u.versionRepo.UpdateSnapshot(ctx, latest.ID, contentHash, int64(len(content))); err != nil {
+		return false, apperrors.InternalError("failed to update version", err)
+	}
+	return true, nil
+}
+
 // NotebookData represents the notebook JSON structure
 type NotebookData struct {
 	Cells         []map[string]any `json:"cells"`
@@ -338,6 +645,8 @@ type NotebookData struct {
 }
 
 func (u *objectUseCase) PatchNotebook(ctx context.Context, objectID int64, userID uuid.UUID, input *PatchNotebookInput) (*entity.ObjectResponse, error) {
+	defer u.locks.lock(objectID)()
+
 	obj, err := u.objectRepo.GetByID(ctx, objectID)
 	if err != nil {
 		if apperrors.IsNotFound(err) {
@@ -355,6 +664,10 @@ func (u *objectUseCase) PatchNotebook(ctx context.Context, objectID int64, userI
 		return nil, apperrors.ValidationError("only notebook files can be patched incrementally")
 	}
 
+	if maxOps := u.storageConfig.GetNotebookMaxOps(); len(input.Operations) > maxOps {
+		return nil, apperrors.ResourceExhaustedError(fmt.Sprintf("at most %d operations are allowed per patch", maxOps))
+	}
+
 	// Read current content
 	currentContent, err := u.storage.ReadFile(ctx, obj.Path)
 	if err != nil {
@@ -366,6 +679,7 @@ func (u *objectUseCase) PatchNotebook(ctx context.Context, objectID int64, userI
 	if err := json.Unmarshal(currentContent, &notebook); err != nil {
 		return nil, apperrors.ValidationError("invalid notebook format")
 	}
+	originalCells := len(notebook.Cells)
 
 	// Apply operations
 	for _, op := range input.Operations {
@@ -457,12 +771,22 @@ func (u *objectUseCase) PatchNotebook(ctx context.Context, objectID int64, userI
 	if err != nil {
 		return nil, apperrors.InternalError("failed to serialize notebook", err)
 	}
+	newContent = u.formatNotebook(newContent)
+
+	// Refuse to grow a notebook past the limits; patches that shrink an
+	// already oversized notebook are still allowed
+	if maxCells := u.storageConfig.GetNotebookMaxCells(); len(notebook.Cells) > maxCells && len(notebook.Cells) > originalCells {
+		return nil, apperrors.ResourceExhaustedError(fmt.Sprintf("notebook would exceed %d cells", maxCells))
+	}
+	if maxBytes := u.storageConfig.GetNotebookMaxBytes(); int64(len(newContent)) > maxBytes && len(newContent) > len(currentContent) {
+		return nil, apperrors.ResourceExhaustedError(fmt.Sprintf("notebook would exceed %d bytes", maxBytes))
+	}
 
 	// Calculate hash
 	contentHash := u.storage.CalculateHash(newContent)
 
 	// Skip if content hasn't changed
-	if contentHash == obj.ContentHash {
+	if u.contentUnchanged(ctx, obj, newContent, contentHash) {
 		return obj.ToResponse(), nil
 	}
 
@@ -506,11 +830,15 @@ func (u *objectUseCase) PatchNotebook(ctx context.Context, objectID int64, userI
 	obj.Size = int64(len(newContent))
 	obj.ContentHash = contentHash
 	obj.CurrentVersion = nextVersion
+	u.setLanguage(obj, newContent)
+	obj.MarkModifiedBy(userID)
 
 	if err := u.objectRepo.Update(ctx, obj); err != nil {
 		return nil, apperrors.InternalError("failed to update object", err)
 	}
 
+	u.publish(ctx, event.ObjectSaved, obj, "", userID)
+
 	return obj.ToResponse(), nil
 }
 
@@ -536,6 +864,117 @@ func (u *objectUseCase) GetByPath(ctx context.Context, path string) (*entity.Obj
 	return obj.ToResponse(), nil
 }
 
+// ResolveByDisplayPath looks up an object by its path relative to the user's
+// workspace root, the inverse of ObjectResponse.DisplayPath. Objects the user
+// cannot view are reported as not found.
+func (u *objectUseCase) ResolveByDisplayPath(ctx context.Context, userID uuid.UUID, appID, email, displayPath string) (*entity.ObjectResponse, error) {
+	// Cleaning a rooted path drops ".." segments, so the lookup cannot leave the workspace root
+	cleaned := path.Clean("/" + displayPath)
+	if cleaned == "/" {
+		return nil, apperrors.InvalidArgumentError("path must name an object inside the workspace", "path")
+	}
+
+	obj, err := u.objectRepo.GetByPath(ctx, "/"+appID+"/"+email+cleaned)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+
+	allowed, err := u.permissionRepo.HasPermission(ctx, obj.ID, userID, entity.RoleViewer)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check permission", err)
+	}
+	if !allowed {
+		return nil, apperrors.NotFoundError("object")
+	}
+
+	return obj.ToResponse(), nil
+}
+
+// maxHashMatches caps the objects returned by a content hash lookup
+const maxHashMatches = 100
+
+// GetByContentHash returns the user's files whose content has the given
+// SHA-256 hex digest, most recently updated first, so a client can skip
+// uploading content it already stored
+func (u *objectUseCase) GetByContentHash(ctx context.Context, userID uuid.UUID, hash string) ([]entity.ObjectResponse, error) {
+	hash = strings.ToLower(hash)
+	if len(hash) != sha256.Size*2 {
+		return nil, apperrors.InvalidArgumentError("hash must be a hex-encoded SHA-256 digest", "hash")
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return nil, apperrors.InvalidArgumentError("hash must be a hex-encoded SHA-256 digest", "hash")
+	}
+
+	objects, err := u.objectRepo.GetByContentHash(ctx, userID, hash, maxHashMatches)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to find objects by hash", err)
+	}
+
+	responses := make([]entity.ObjectResponse, len(objects))
+	for i := range objects {
+		responses[i] = *objects[i].ToResponse()
+	}
+	return responses, nil
+}
+
+// GetAncestors returns the ancestry of an object in root→object order,
+// including the object itself. Parents are followed via ParentID; objects
+// without a parent link fall back to resolving ancestors from their path.
+func (u *objectUseCase) GetAncestors(ctx context.Context, objectID int64) ([]entity.ObjectResponse, error) {
+	obj, err := u.objectRepo.GetByID(ctx, objectID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+
+	// Collected in object→root order and reversed at the end
+	chain := []entity.ObjectResponse{*obj.ToResponse()}
+	seen := map[int64]bool{obj.ID: true}
+
+	current := obj
+	for current.ParentID != nil && !seen[*current.ParentID] {
+		parent, err := u.objectRepo.GetByID(ctx, *current.ParentID)
+		if err != nil {
+			if apperrors.IsNotFound(err) {
+				break
+			}
+			return nil, apperrors.InternalError("failed to get parent", err)
+		}
+		seen[parent.ID] = true
+		chain = append(chain, *parent.ToResponse())
+		current = parent
+	}
+
+	// Resolve remaining ancestors from the path for objects without ParentID
+	if current.ParentID == nil {
+		for dir := filepath.Dir(current.Path); dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+			parent, err := u.objectRepo.GetByPath(ctx, dir)
+			if err != nil {
+				if apperrors.IsNotFound(err) {
+					continue
+				}
+				return nil, apperrors.InternalError("failed to get parent", err)
+			}
+			if seen[parent.ID] {
+				continue
+			}
+			seen[parent.ID] = true
+			chain = append(chain, *parent.ToResponse())
+		}
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+
+	return chain, nil
+}
+
 func (u *objectUseCase) List(ctx context.Context, filter *entity.ObjectFilter) ([]entity.ObjectResponse, int64, error) {
 	objects, total, err := u.objectRepo.List(ctx, filter)
 	if err != nil {
@@ -550,8 +989,10 @@ func (u *objectUseCase) List(ctx context.Context, filter *entity.ObjectFilter) (
 	return responses, total, nil
 }
 
-func (u *objectUseCase) ListChildren(ctx context.Context, parentID *int64, page, pageSize int) ([]entity.ObjectResponse, int64, error) {
-	objects, total, err := u.objectRepo.ListChildren(ctx, parentID, page, pageSize)
+// ListChildren lists the children of a directory, those pinned for userID first
+func (u *objectUseCase) ListChildren(ctx context.Context, userID uuid.UUID, parentID *int64, page, pageSize int) ([]entity.ObjectResponse, int64, error) {
+	pinOwner := u.pinOwner(userID)
+	objects, total, err := u.objectRepo.ListChildren(ctx, parentID, &pinOwner, page, pageSize)
 	if err != nil {
 		return nil, 0, apperrors.InternalError("failed to list children", err)
 	}
@@ -561,9 +1002,98 @@ func (u *objectUseCase) ListChildren(ctx context.Context, parentID *int64, page,
 		responses[i] = *obj.ToResponse()
 	}
 
+	if err := u.fillChildCounts(ctx, responses); err != nil {
+		return nil, 0, err
+	}
+	if err := u.fillPinned(ctx, userID, responses); err != nil {
+		return nil, 0, err
+	}
+
 	return responses, total, nil
 }
 
+// maxTreeLevelSize caps the children returned for one level of a lazy tree
+const maxTreeLevelSize = 5000
+
+// GetTreeLevel returns one level of the user's tree: their top-level objects
+// when parentID is nil, otherwise the children of that directory. Directories
+// carry child count hints so clients can load deeper levels on demand.
+func (u *objectUseCase) GetTreeLevel(ctx context.Context, userID uuid.UUID, parentID *int64) ([]entity.ObjectResponse, error) {
+	if parentID == nil {
+		objects, err := u.objectRepo.ListRootsByCreator(ctx, userID)
+		if err != nil {
+			return nil, apperrors.InternalError("failed to get tree", err)
+		}
+
+		responses := make([]entity.ObjectResponse, len(objects))
+		for i, obj := range objects {
+			responses[i] = *obj.ToResponse()
+		}
+		if err := u.fillChildCounts(ctx, responses); err != nil {
+			return nil, err
+		}
+		if err := u.fillPinned(ctx, userID, responses); err != nil {
+			return nil, err
+		}
+		slices.SortStableFunc(responses, func(a, b entity.ObjectResponse) int {
+			return comparePinned(&a, &b)
+		})
+		return responses, nil
+	}
+
+	parent, err := u.objectRepo.GetByID(ctx, *parentID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+
+	allowed, err := u.permissionRepo.HasPermission(ctx, parent.ID, userID, entity.RoleViewer)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check permission", err)
+	}
+	if !allowed {
+		return nil, apperrors.NotFoundError("object")
+	}
+	if !parent.IsDirectory() {
+		return nil, apperrors.InvalidArgumentError("parent must be a directory", "parent_id")
+	}
+
+	responses, _, err := u.ListChildren(ctx, userID, parentID, 1, maxTreeLevelSize)
+	return responses, err
+}
+
+// fillChildCounts sets the child count hints of the directories in responses
+// using a single grouped count query
+func (u *objectUseCase) fillChildCounts(ctx context.Context, responses []entity.ObjectResponse) error {
+	var dirIDs []int64
+	for i := range responses {
+		if responses[i].Type == entity.ObjectTypeDirectory {
+			dirIDs = append(dirIDs, responses[i].ID)
+		}
+	}
+	if len(dirIDs) == 0 {
+		return nil
+	}
+
+	counts, err := u.objectRepo.CountChildren(ctx, dirIDs)
+	if err != nil {
+		return apperrors.InternalError("failed to count children", err)
+	}
+
+	for i := range responses {
+		if responses[i].Type != entity.ObjectTypeDirectory {
+			continue
+		}
+		count := counts[responses[i].ID]
+		hasChildren := count > 0
+		responses[i].ChildCount = &count
+		responses[i].HasChildren = &hasChildren
+	}
+	return nil
+}
+
 func (u *objectUseCase) GetTree(ctx context.Context, userID uuid.UUID, appID, email string, depth int) ([]entity.ObjectResponse, error) {
 	// 获取用户目录下的所有对象
 	objects, err := u.objectRepo.ListByCreator(ctx, userID)
@@ -572,70 +1102,124 @@ func (u *objectUseCase) GetTree(ctx context.Context, userID uuid.UUID, appID, em
 	}
 
 	// 构建树形结构
-	return buildTree(objects), nil
-}
+	maxDepth := 10
+	if u.storageConfig != nil {
+		maxDepth = u.storageConfig.GetTreeMaxDepth()
+	}
+	if depth <= 0 || depth > maxDepth {
+		depth = maxDepth
+	}
+
+	ids := make([]int64, len(objects))
+	for i := range objects {
+		ids[i] = objects[i].ID
+	}
+	pinned, err := u.pinRepo.ListPinned(ctx, u.pinOwner(userID), ids)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to get pins", err)
+	}
 
-// buildTree converts a flat list of objects into a tree structure
-func buildTree(objects []entity.Object) []entity.ObjectResponse {
-	// Create a map for quick lookup
-	responseMap := make(map[int64]*entity.ObjectResponse)
+	return buildTree(objects, depth, pinned), nil
+}
 
-	// First pass: create ObjectResponse for each object
+// buildTree converts a flat list of objects into a tree at most maxDepth
+// levels deep, marking directories whose children were cut off as truncated
+// and objects in pinned as pinned.
+// Parent pointers are not trusted to form a tree: objects only reachable
+// through a parent cycle (A→B→A) are listed as roots, and no object is placed
+// twice, so a corrupted hierarchy cannot loop.
+func buildTree(objects []entity.Object, maxDepth int, pinned map[int64]bool) []entity.ObjectResponse {
+	index := make(map[int64]bool, len(objects))
 	for i := range objects {
-		obj := &objects[i]
-		resp := obj.ToResponse()
-		resp.Children = []*entity.ObjectResponse{} // Initialize empty children slice
-		responseMap[obj.ID] = resp
+		index[objects[i].ID] = true
 	}
 
-	// Second pass: build parent-child relationships
-	var roots []*entity.ObjectResponse
+	// Group objects by parent. Objects without a parent, or whose parent is
+	// not in the list (might be deleted), are roots.
+	children := make(map[int64][]*entity.Object)
+	var roots []*entity.Object
 	for i := range objects {
 		obj := &objects[i]
-		resp := responseMap[obj.ID]
-
-		if obj.ParentID == nil {
-			// No parent, this is a root node
-			roots = append(roots, resp)
+		if obj.ParentID != nil && *obj.ParentID != obj.ID && index[*obj.ParentID] {
+			children[*obj.ParentID] = append(children[*obj.ParentID], obj)
 		} else {
-			// Has parent, add to parent's children
-			if parent, ok := responseMap[*obj.ParentID]; ok {
-				parent.Children = append(parent.Children, resp)
-			} else {
-				// Parent not found (might be deleted), treat as root
-				roots = append(roots, resp)
+			roots = append(roots, obj)
+		}
+	}
+
+	// Objects not reachable from a root sit on a parent cycle; break each
+	// cycle by promoting its first object to a root
+	reachable := make(map[int64]bool, len(objects))
+	var mark func(id int64)
+	mark = func(id int64) {
+		reachable[id] = true
+		for _, child := range children[id] {
+			if !reachable[child.ID] {
+				mark(child.ID)
 			}
 		}
 	}
+	for _, root := range roots {
+		mark(root.ID)
+	}
+	for i := range objects {
+		if obj := &objects[i]; !reachable[obj.ID] {
+			log.Warn().Int64("object_id", obj.ID).Msg("Object parent chain forms a cycle, listing it as a tree root")
+			roots = append(roots, obj)
+			mark(obj.ID)
+		}
+	}
 
-	// Sort: directories first, then by name
-	sortChildren(roots)
+	placed := make(map[int64]bool, len(objects))
+	var build func(obj *entity.Object, level int) *entity.ObjectResponse
+	build = func(obj *entity.Object, level int) *entity.ObjectResponse {
+		placed[obj.ID] = true
+		resp := obj.ToResponse()
+		resp.Pinned = pinned[obj.ID]
+		for _, child := range children[obj.ID] {
+			if placed[child.ID] {
+				continue
+			}
+			if level >= maxDepth {
+				resp.Truncated = true
+				break
+			}
+			resp.Children = append(resp.Children, build(child, level+1))
+		}
+		return resp
+	}
 
-	// Convert to non-pointer slice and set empty children to nil for cleaner JSON
-	result := make([]entity.ObjectResponse, len(roots))
+	tree := make([]*entity.ObjectResponse, len(roots))
 	for i, root := range roots {
-		result[i] = *cleanupTree(root)
+		tree[i] = build(root, 1)
+	}
+
+	// Sort: pinned first, then directories, then by name
+	sortChildren(tree)
+
+	result := make([]entity.ObjectResponse, len(tree))
+	for i, root := range tree {
+		result[i] = *root
 	}
 
 	return result
 }
 
-// sortChildren recursively sorts children: directories first, then by name
+// sortChildren recursively sorts children: pinned first, then directories,
+// then by name
 func sortChildren(items []*entity.ObjectResponse) {
-	// Sort current level
-	for i := 0; i < len(items)-1; i++ {
-		for j := i + 1; j < len(items); j++ {
-			// Directories first
-			if items[i].Type != entity.ObjectTypeDirectory && items[j].Type == entity.ObjectTypeDirectory {
-				items[i], items[j] = items[j], items[i]
-			} else if items[i].Type == items[j].Type || (items[i].Type != entity.ObjectTypeDirectory && items[j].Type != entity.ObjectTypeDirectory) {
-				// Same type or both are files: sort by name
-				if items[i].Name > items[j].Name {
-					items[i], items[j] = items[j], items[i]
-				}
+	slices.SortStableFunc(items, func(a, b *entity.ObjectResponse) int {
+		if c := comparePinned(a, b); c != 0 {
+			return c
+		}
+		if aDir, bDir := a.Type == entity.ObjectTypeDirectory, b.Type == entity.ObjectTypeDirectory; aDir != bDir {
+			if aDir {
+				return -1
 			}
+			return 1
 		}
-	}
+		return strings.Compare(a.Name, b.Name)
+	})
 
 	// Recursively sort children
 	for _, item := range items {
@@ -645,16 +1229,130 @@ func sortChildren(items []*entity.ObjectResponse) {
 	}
 }
 
-// cleanupTree sets empty children slices to nil for cleaner JSON output
-func cleanupTree(item *entity.ObjectResponse) *entity.ObjectResponse {
-	if len(item.Children) == 0 {
-		item.Children = nil
-	} else {
-		for i, child := range item.Children {
-			item.Children[i] = cleanupTree(child)
+// checkFileType validates a file name, and its content when given, against the
+// configured extension and MIME type policy
+func (u *objectUseCase) checkFileType(name string, content []byte) error {
+	if u.storageConfig == nil {
+		return nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	if len(u.storageConfig.AllowedExtensions) > 0 && !containsFold(u.storageConfig.AllowedExtensions, ext) {
+		return apperrors.ValidationError(fmt.Sprintf("file type %q is not allowed", ext))
+	}
+	if containsFold(u.storageConfig.DeniedExtensions, ext) {
+		return apperrors.ValidationError(fmt.Sprintf("file type %q is not allowed", ext))
+	}
+
+	if len(content) > 0 && len(u.storageConfig.DeniedMIMETypes) > 0 {
+		mimeType := http.DetectContentType(content)
+		if i := strings.Index(mimeType, ";"); i >= 0 {
+			mimeType = mimeType[:i]
 		}
+		if containsFold(u.storageConfig.DeniedMIMETypes, mimeType) {
+			return apperrors.ValidationError(fmt.Sprintf("file type %q is not allowed", mimeType))
+		}
+	}
+
+	return nil
+}
+
+// sanitizeName applies the configured storage name policy to a new object
+// name, so unsafe names never reach the filesystem
+func (u *objectUseCase) sanitizeName(name string) (string, error) {
+	policy := storage.NamePolicyReject
+	if u.storageConfig != nil {
+		policy = u.storageConfig.GetNamePolicy()
+	}
+	sanitized, err := storage.SanitizeName(name, policy)
+	if err != nil {
+		return "", apperrors.InvalidArgumentError(err.Error(), "name")
+	}
+	return sanitized, nil
+}
+
+// caseInsensitiveNames reports whether names within a directory must be unique
+// ignoring case, following the configured policy or the storage filesystem
+func (u *objectUseCase) caseInsensitiveNames() bool {
+	if u.storageConfig == nil {
+		return false
+	}
+	switch u.storageConfig.GetNameCase() {
+	case "insensitive":
+		return true
+	case "sensitive":
+		return false
+	default:
+		return u.storage.CaseInsensitive()
 	}
-	return item
+}
+
+// pathTaken reports whether an object occupies path. Under case-insensitive
+// names any object other than selfID whose path differs only in case counts,
+// so two names cannot collide on disk.
+func (u *objectUseCase) pathTaken(ctx context.Context, path string, selfID int64) (bool, error) {
+	if u.caseInsensitiveNames() {
+		return u.objectRepo.ExistsByPathFold(ctx, path, selfID)
+	}
+	return u.objectRepo.ExistsByPath(ctx, path)
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// createStaged inserts the row of a new file or copied directory and then
+// moves its staged upload into place. The file at obj.Path is only written once the row
+// insert has claimed the path, so losing a race for it leaves the winner's
+// content alone. On failure the upload is left for the caller.
+func (u *objectUseCase) createStaged(ctx context.Context, obj *entity.Object, uploadID string) error {
+	if err := u.objectRepo.Create(ctx, obj); err != nil {
+		// A unique violation means a concurrent request created the same path
+		if apperrors.IsAlreadyExists(err) {
+			return err
+		}
+		return apperrors.InternalError("failed to create object", err)
+	}
+
+	if err := u.storage.CommitUpload(ctx, uploadID, obj.Path); err != nil {
+		if delErr := u.objectRepo.HardDelete(ctx, obj.ID); delErr != nil {
+			log.Error().Err(delErr).Int64("object_id", obj.ID).Msg("Failed to remove object whose content could not be stored")
+		}
+		return apperrors.InternalError("failed to write file to storage", err)
+	}
+	return nil
+}
+
+// scanUpload streams the temporary file of an upload through the upload
+// scanner, before it is committed to the workspace
+func (u *objectUseCase) scanUpload(ctx context.Context, uploadID, name string) error {
+	f, err := u.storage.OpenUpload(ctx, uploadID)
+	if err != nil {
+		return apperrors.InternalError("failed to open file for scanning", err)
+	}
+	defer f.Close()
+
+	if err := u.scanner.Scan(ctx, name, f); err != nil {
+		return apperrors.ValidationError(fmt.Sprintf("file rejected: %v", err))
+	}
+	return nil
+}
+
+// rebasePath rewrites a descendant path from oldPrefix to newPrefix.
+// Only paths strictly under oldPrefix + "/" are rewritten, so a sibling such as
+// "/u/data2" is left untouched when "/u/data" is renamed or moved.
+func rebasePath(path, oldPrefix, newPrefix string) (string, bool) {
+	prefix := oldPrefix + "/"
+	if !strings.HasPrefix(path, prefix) {
+		return path, false
+	}
+	return newPrefix + "/" + strings.TrimPrefix(path, prefix), true
 }
 
 func (u *objectUseCase) Update(ctx context.Context, id int64, input *UpdateInput) (*entity.ObjectResponse, error) {
@@ -666,8 +1364,32 @@ func (u *objectUseCase) Update(ctx context.Context, id int64, input *UpdateInput
 		return nil, apperrors.InternalError("failed to get object", err)
 	}
 
+	// Reject edits based on a stale copy before touching storage
+	expectedUpdatedAt := obj.UpdatedAt
+	if input.UpdatedAt != nil {
+		if !input.UpdatedAt.Equal(obj.UpdatedAt) {
+			return nil, apperrors.ConflictError("object")
+		}
+		expectedUpdatedAt = *input.UpdatedAt
+	}
+
 	// Update name (rename)
+	original := *obj
+	oldName := obj.Name
+	oldPath := obj.Path
 	if input.Name != nil && *input.Name != obj.Name {
+		name, err := u.sanitizeName(*input.Name)
+		if err != nil {
+			return nil, err
+		}
+		input.Name = &name
+
+		if !obj.IsDirectory() {
+			if err := u.checkFileType(*input.Name, nil); err != nil {
+				return nil, err
+			}
+		}
+
 		// Build new path
 		newPath := filepath.Dir(obj.Path) + "/" + *input.Name
 		if newPath == "/"+*input.Name {
@@ -675,7 +1397,7 @@ func (u *objectUseCase) Update(ctx context.Context, id int64, input *UpdateInput
 		}
 
 		// Check if new name exists
-		exists, err := u.objectRepo.ExistsByPath(ctx, newPath)
+		exists, err := u.pathTaken(ctx, newPath, obj.ID)
 		if err != nil {
 			return nil, apperrors.InternalError("failed to check path", err)
 		}
@@ -683,43 +1405,145 @@ func (u *objectUseCase) Update(ctx context.Context, id int64, input *UpdateInput
 			return nil, apperrors.AlreadyExistsError("object with this name")
 		}
 
-		// Rename in storage
-		if err := u.storage.Move(ctx, obj.Path, newPath); err != nil {
-			return nil, apperrors.InternalError("failed to rename in storage", err)
-		}
-
-		// Update path in DB
-		oldPath := obj.Path
 		obj.Name = *input.Name
 		obj.Path = newPath
-
-		// Update descendants paths if directory
-		if obj.IsDirectory() {
-			descendants, err := u.objectRepo.GetDescendants(ctx, oldPath)
-			if err != nil {
-				return nil, apperrors.InternalError("failed to get descendants", err)
-			}
-			for _, desc := range descendants {
-				descNewPath := strings.Replace(desc.Path, oldPath, newPath, 1)
-				if err := u.objectRepo.UpdatePath(ctx, desc.ID, descNewPath); err != nil {
-					return nil, apperrors.InternalError("failed to update descendant path", err)
-				}
-			}
-		}
 	}
 
 	if input.Description != nil {
 		obj.Description = *input.Description
 	}
 
-	if err := u.objectRepo.Update(ctx, obj); err != nil {
+	if obj.Name != oldName && !obj.IsDirectory() {
+		u.setLanguage(obj, nil)
+	}
+
+	if input.UserID != uuid.Nil {
+		obj.MarkModifiedBy(input.UserID)
+	}
+
+	if err := u.objectRepo.UpdateIfUnmodified(ctx, obj, expectedUpdatedAt); err != nil {
+		if apperrors.IsConflict(err) {
+			return nil, apperrors.ConflictError("object")
+		}
+		if apperrors.IsAlreadyExists(err) {
+			return nil, err
+		}
 		return nil, apperrors.InternalError("failed to update object", err)
 	}
 
+	// The row is renamed first, so an edit that lost the race never touches
+	// storage or the descendants
+	if obj.Path != oldPath {
+		if err := u.renameContent(ctx, obj, &original); err != nil {
+			return nil, err
+		}
+	}
+
+	if obj.Name != oldName {
+		u.recordRename(ctx, obj.ID, oldName, obj.Name, input.UserID)
+		u.publish(ctx, event.ObjectMoved, obj, oldPath, input.UserID)
+	}
+
 	return obj.ToResponse(), nil
 }
 
-func (u *objectUseCase) Delete(ctx context.Context, id int64) error {
+// renameContent moves a renamed object's content in storage and rewrites the
+// paths of its descendants. When storage cannot be renamed, the object's row
+// is put back as original.
+func (u *objectUseCase) renameContent(ctx context.Context, obj, original *entity.Object) error {
+	if err := u.storage.Move(ctx, original.Path, obj.Path); err != nil {
+		if restoreErr := u.objectRepo.Update(ctx, original); restoreErr != nil {
+			log.Error().Err(restoreErr).Int64("object_id", obj.ID).Str("path", original.Path).Msg("Failed to restore object after storage rename failed")
+		}
+		return apperrors.InternalError("failed to rename in storage", err)
+	}
+
+	if !obj.IsDirectory() {
+		return nil
+	}
+	descendants, err := u.objectRepo.GetDescendants(ctx, original.Path)
+	if err != nil {
+		return apperrors.InternalError("failed to get descendants", err)
+	}
+	for _, desc := range descendants {
+		descNewPath, ok := rebasePath(desc.Path, original.Path, obj.Path)
+		if !ok {
+			continue
+		}
+		if err := u.objectRepo.UpdatePath(ctx, desc.ID, descNewPath); err != nil {
+			return apperrors.InternalError("failed to update descendant path", err)
+		}
+	}
+	return nil
+}
+
+// recordRename adds a rename to the object's name history. The rename has
+// already been applied, so a failure here is logged rather than returned.
+func (u *objectUseCase) recordRename(ctx context.Context, objectID int64, oldName, newName string, userID uuid.UUID) {
+	history := &entity.ObjectNameHistory{
+		ObjectID: objectID,
+		OldName:  oldName,
+		NewName:  newName,
+	}
+	if userID != uuid.Nil {
+		history.RenamedBy = &userID
+	}
+	if err := u.nameHistoryRepo.Create(ctx, history); err != nil {
+		log.Warn().Err(err).Int64("object_id", objectID).Msg("Failed to record object rename")
+	}
+}
+
+// inheritParentPermissions gives a new object inherited copies of its parent's
+// grants, as granting on a directory does for the objects already in it. The
+// object has already been created, so a failure here is logged rather than
+// returned; access still falls back to the parent's grants.
+func (u *objectUseCase) inheritParentPermissions(ctx context.Context, obj *entity.Object) {
+	if u.permissionConfig == nil || !u.permissionConfig.InheritOnCreate || obj.ParentID == nil {
+		return
+	}
+
+	grants, err := u.permissionRepo.ListByObject(ctx, *obj.ParentID)
+	if err != nil {
+		log.Warn().Err(err).Int64("object_id", obj.ID).Msg("Failed to list parent permissions to inherit")
+		return
+	}
+
+	maxRole := entity.Role(u.permissionConfig.GetInheritedRole())
+	for _, grant := range grants {
+		role := grant.Role
+		if role.Priority() > maxRole.Priority() {
+			role = maxRole
+		}
+		perm := &entity.Permission{
+			ObjectID:    obj.ID,
+			UserID:      grant.UserID,
+			Role:        role,
+			IsInherited: true,
+			GrantedBy:   grant.GrantedBy,
+		}
+		if err := u.permissionRepo.Create(ctx, perm); err != nil {
+			log.Warn().Err(err).Int64("object_id", obj.ID).Str("user_id", grant.UserID.String()).Msg("Failed to inherit parent permission")
+		}
+	}
+}
+
+// GetNameHistory returns the former names of an object, newest first
+func (u *objectUseCase) GetNameHistory(ctx context.Context, objectID int64) ([]entity.ObjectNameHistory, error) {
+	if _, err := u.objectRepo.GetByID(ctx, objectID); err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+
+	history, err := u.nameHistoryRepo.ListByObject(ctx, objectID)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to get name history", err)
+	}
+	return history, nil
+}
+
+func (u *objectUseCase) Delete(ctx context.Context, id int64, userID uuid.UUID) error {
 	obj, err := u.objectRepo.GetByID(ctx, id)
 	if err != nil {
 		if apperrors.IsNotFound(err) {
@@ -728,16 +1552,38 @@ func (u *objectUseCase) Delete(ctx context.Context, id int64) error {
 		return apperrors.InternalError("failed to get object", err)
 	}
 
-	// Delete from storage
-	if err := u.storage.Delete(ctx, obj.Path); err != nil {
+	// Record the delete operation so the trash can list and restore it as one entry
+	itemCount := 1
+	if obj.IsDirectory() {
+		descendants, err := u.objectRepo.GetDescendants(ctx, obj.Path)
+		if err != nil {
+			return apperrors.InternalError("failed to get descendants", err)
+		}
+		itemCount += len(descendants)
+	}
+	batch := &entity.DeleteBatch{
+		RootObjectID: obj.ID,
+		RootName:     obj.Name,
+		RootPath:     obj.Path,
+		ItemCount:    itemCount,
+		DeletedBy:    &userID,
+	}
+	if err := u.deleteBatchRepo.Create(ctx, batch); err != nil {
+		return apperrors.InternalError("failed to record delete", err)
+	}
+
+	// Keep the content in the trash so RestoreTree can bring it back
+	if err := u.storage.MoveToTrash(ctx, obj.Path, batch.ID.String()); err != nil {
+		_ = u.deleteBatchRepo.Delete(ctx, batch.ID)
 		return apperrors.InternalError("failed to delete from storage", err)
 	}
 
-	// Soft delete in DB
-	if err := u.objectRepo.Delete(ctx, id); err != nil {
+	// Soft delete the object and its descendants in DB as one batch
+	if err := u.objectRepo.DeleteTree(ctx, obj, batch.ID); err != nil {
 		return apperrors.InternalError("failed to delete object", err)
 	}
 
+	u.publish(ctx, event.ObjectDeleted, obj, "", userID)
 	return nil
 }
 
@@ -750,38 +1596,9 @@ func (u *objectUseCase) Move(ctx context.Context, id int64, input *MoveInput) (*
 		return nil, apperrors.InternalError("failed to get object", err)
 	}
 
-	newName := obj.Name
-	if input.NewName != nil {
-		newName = *input.NewName
-	}
-
-	// Build new path
-	var newPath string
-	if input.TargetParentID != nil {
-		parent, err := u.objectRepo.GetByID(ctx, *input.TargetParentID)
-		if err != nil {
-			if apperrors.IsNotFound(err) {
-				return nil, apperrors.NotFoundError("target directory")
-			}
-			return nil, apperrors.InternalError("failed to get target", err)
-		}
-		if !parent.IsDirectory() {
-			return nil, apperrors.ValidationError("target must be a directory")
-		}
-		newPath = parent.Path + "/" + newName
-	} else {
-		// When moving to root, use user's directory: /{appID}/{email}
-		userDir := "/" + input.AppID + "/" + input.Email
-		newPath = userDir + "/" + newName
-	}
-
-	// Check if target exists
-	exists, err := u.objectRepo.ExistsByPath(ctx, newPath)
+	newName, newPath, err := u.resolveMoveTarget(ctx, obj, input)
 	if err != nil {
-		return nil, apperrors.InternalError("failed to check path", err)
-	}
-	if exists && newPath != obj.Path {
-		return nil, apperrors.AlreadyExistsError("object at target path")
+		return nil, err
 	}
 
 	// Move in storage
@@ -799,7 +1616,10 @@ func (u *objectUseCase) Move(ctx context.Context, id int64, input *MoveInput) (*
 			return nil, apperrors.InternalError("failed to get descendants", err)
 		}
 		for _, desc := range descendants {
-			descNewPath := strings.Replace(desc.Path, oldPath, newPath, 1)
+			descNewPath, ok := rebasePath(desc.Path, oldPath, newPath)
+			if !ok {
+				continue
+			}
 			if err := u.objectRepo.UpdatePath(ctx, desc.ID, descNewPath); err != nil {
 				return nil, apperrors.InternalError("failed to update descendant path", err)
 			}
@@ -810,76 +1630,105 @@ func (u *objectUseCase) Move(ctx context.Context, id int64, input *MoveInput) (*
 	obj.Name = newName
 	obj.Path = newPath
 	obj.ParentID = input.TargetParentID
+	if !obj.IsDirectory() {
+		u.setLanguage(obj, nil)
+	}
 
 	if err := u.objectRepo.Update(ctx, obj); err != nil {
 		return nil, apperrors.InternalError("failed to update object", err)
 	}
 
+	if newPath != oldPath {
+		u.publish(ctx, event.ObjectMoved, obj, oldPath, input.UserID)
+	}
 	return obj.ToResponse(), nil
 }
 
-func (u *objectUseCase) Copy(ctx context.Context, id int64, creatorID uuid.UUID, appID, email string, input *CopyInput) (*entity.ObjectResponse, error) {
-	obj, err := u.objectRepo.GetByID(ctx, id)
-	if err != nil {
-		if apperrors.IsNotFound(err) {
-			return nil, apperrors.NotFoundError("object")
-		}
-		return nil, apperrors.InternalError("failed to get object", err)
-	}
-
+// resolveMoveTarget validates a move and returns the object's new name and path
+func (u *objectUseCase) resolveMoveTarget(ctx context.Context, obj *entity.Object, input *MoveInput) (string, string, error) {
+	var err error
 	newName := obj.Name
 	if input.NewName != nil {
-		newName = *input.NewName
-	} else if !obj.IsDirectory() {
-		// Generate copy name for files
-		ext := filepath.Ext(obj.Name)
-		base := strings.TrimSuffix(obj.Name, ext)
-		newName = fmt.Sprintf("%s_copy%s", base, ext)
-	} else {
-		// Generate copy name for directories
-		newName = fmt.Sprintf("%s_copy", obj.Name)
+		newName, err = u.sanitizeName(*input.NewName)
+		if err != nil {
+			return "", "", err
+		}
+		if !obj.IsDirectory() {
+			if err := u.checkFileType(newName, nil); err != nil {
+				return "", "", err
+			}
+		}
 	}
 
 	// Build new path
 	var newPath string
-	var parentID *int64
 	if input.TargetParentID != nil {
 		parent, err := u.objectRepo.GetByID(ctx, *input.TargetParentID)
 		if err != nil {
 			if apperrors.IsNotFound(err) {
-				return nil, apperrors.NotFoundError("target directory")
+				return "", "", apperrors.NotFoundError("target directory")
 			}
-			return nil, apperrors.InternalError("failed to get target", err)
+			return "", "", apperrors.InternalError("failed to get target", err)
 		}
 		if !parent.IsDirectory() {
-			return nil, apperrors.ValidationError("target must be a directory")
+			return "", "", apperrors.ValidationError("target must be a directory")
 		}
 		newPath = parent.Path + "/" + newName
-		parentID = input.TargetParentID
 	} else {
-		// When copying to root, use user's directory: /{appID}/{email}
-		userDir := "/" + appID + "/" + email
+		// When moving to root, use user's directory: /{appID}/{email}
+		userDir := "/" + input.AppID + "/" + input.Email
 		newPath = userDir + "/" + newName
-		parentID = nil
 	}
 
 	// Check if target exists
-	exists, err := u.objectRepo.ExistsByPath(ctx, newPath)
+	exists, err := u.pathTaken(ctx, newPath, obj.ID)
 	if err != nil {
-		return nil, apperrors.InternalError("failed to check path", err)
+		return "", "", apperrors.InternalError("failed to check path", err)
 	}
-	if exists {
-		return nil, apperrors.AlreadyExistsError("object at target path")
+	if exists && newPath != obj.Path {
+		return "", "", apperrors.AlreadyExistsError("object at target path")
 	}
 
-	// Copy in storage (supports both files and directories)
-	if err := u.storage.Copy(ctx, obj.Path, newPath); err != nil {
+	return newName, newPath, nil
+}
+
+func (u *objectUseCase) Copy(ctx context.Context, id int64, creatorID uuid.UUID, appID, email string, input *CopyInput) (*entity.ObjectResponse, error) {
+	obj, err := u.objectRepo.GetByID(ctx, id)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+
+	dir, err := u.copyTargetDir(ctx, appID, email, input)
+	if err != nil {
+		return nil, err
+	}
+	return u.copyInto(ctx, obj, dir, creatorID, input)
+}
+
+// copyInto copies obj into dir, the path of the directory input.TargetParentID
+// (or of a workspace root when it is nil)
+func (u *objectUseCase) copyInto(ctx context.Context, obj *entity.Object, dir string, creatorID uuid.UUID, input *CopyInput) (*entity.ObjectResponse, error) {
+	newName, newPath, err := u.resolveCopyTarget(ctx, obj, dir, input)
+	if err != nil {
+		return nil, err
+	}
+	parentID := input.TargetParentID
+
+	// Copy in storage (files and directories) to a staging location; the copy
+	// is only moved to newPath once its row has claimed the path
+	uploadID := uuid.NewString()
+	if err := u.storage.CopyToUpload(ctx, obj.Path, uploadID); err != nil {
+		_ = u.storage.DeleteUpload(ctx, uploadID)
 		return nil, apperrors.InternalError("failed to copy in storage", err)
 	}
 
-	// Get new inode
-	inode, err := u.storage.GetInode(ctx, newPath)
+	// The inode survives the rename into the workspace
+	inode, err := u.storage.UploadInode(ctx, uploadID)
 	if err != nil {
+		_ = u.storage.DeleteUpload(ctx, uploadID)
 		return nil, apperrors.InternalError("failed to get inode", err)
 	}
 
@@ -893,13 +1742,22 @@ func (u *objectUseCase) Copy(ctx context.Context, id int64, creatorID uuid.UUID,
 		CreatorID:      creatorID,
 		Size:           obj.Size,
 		ContentHash:    obj.ContentHash,
+		MimeType:       obj.MimeType,
+		Language:       obj.Language,
 		Description:    obj.Description,
 		CurrentVersion: 1,
 	}
 
-	if err := u.objectRepo.Create(ctx, newObj); err != nil {
-		_ = u.storage.Delete(ctx, newPath)
-		return nil, apperrors.InternalError("failed to create object", err)
+	if input.ClearOutputs {
+		if err := u.clearStagedOutputs(ctx, newObj, uploadID); err != nil {
+			_ = u.storage.DeleteUpload(ctx, uploadID)
+			return nil, apperrors.InternalError("failed to clear notebook outputs", err)
+		}
+	}
+
+	if err := u.createStaged(ctx, newObj, uploadID); err != nil {
+		_ = u.storage.DeleteUpload(ctx, uploadID)
+		return nil, err
 	}
 
 	// Create owner permission
@@ -915,7 +1773,7 @@ func (u *objectUseCase) Copy(ctx context.Context, id int64, creatorID uuid.UUID,
 
 	// If directory, recursively create child objects in database
 	if obj.IsDirectory() {
-		if err := u.copyDirectoryChildren(ctx, obj, newObj, creatorID); err != nil {
+		if err := u.copyDirectoryChildren(ctx, obj, newObj, creatorID, input.ClearOutputs); err != nil {
 			// Cleanup on failure
 			_ = u.storage.Delete(ctx, newPath)
 			_ = u.objectRepo.Delete(ctx, newObj.ID)
@@ -929,13 +1787,119 @@ func (u *objectUseCase) Copy(ctx context.Context, id int64, creatorID uuid.UUID,
 		return nil, apperrors.InternalError("failed to get created object", err)
 	}
 
+	// A copy into someone else's directory is addressed to its owner
+	e := objectEvent(event.ObjectCreated, created, "", creatorID)
+	if parentID != nil {
+		if parent, err := u.objectRepo.GetByID(ctx, *parentID); err == nil && parent.CreatorID != creatorID {
+			e.RecipientID = &parent.CreatorID
+		}
+	}
+	u.publishEvent(ctx, e)
 	return created.ToResponse(), nil
 }
 
+// copyTargetDir returns the path of the directory a copy goes to: the target
+// parent, or the caller's workspace root /{appID}/{email}
+func (u *objectUseCase) copyTargetDir(ctx context.Context, appID, email string, input *CopyInput) (string, error) {
+	if input.TargetParentID == nil {
+		return "/" + appID + "/" + email, nil
+	}
+	parent, err := u.objectRepo.GetByID(ctx, *input.TargetParentID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return "", apperrors.NotFoundError("target directory")
+		}
+		return "", apperrors.InternalError("failed to get target", err)
+	}
+	if !parent.IsDirectory() {
+		return "", apperrors.ValidationError("target must be a directory")
+	}
+	return parent.Path, nil
+}
+
+// resolveCopyTarget validates a copy into dir and returns the copy's name and
+// path
+func (u *objectUseCase) resolveCopyTarget(ctx context.Context, obj *entity.Object, dir string, input *CopyInput) (string, string, error) {
+	var err error
+	newName := copyName(obj, 1)
+	if input.NewName != nil {
+		newName, err = u.sanitizeName(*input.NewName)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	if !obj.IsDirectory() {
+		if err := u.checkFileType(newName, nil); err != nil {
+			return "", "", err
+		}
+	}
+	newPath := dir + "/" + newName
+
+	// Check if target exists
+	exists, err := u.pathTaken(ctx, newPath, 0)
+	if err != nil {
+		return "", "", apperrors.InternalError("failed to check path", err)
+	}
+	if exists {
+		return "", "", apperrors.AlreadyExistsError("object at target path")
+	}
+
+	return newName, newPath, nil
+}
+
+// Duplicate copies an object into its own parent directory, picking the first
+// free "_copy" name (name_copy, name_copy_2, ...). A top-level object is
+// copied into the workspace root it sits in, which may not be the caller's.
+func (u *objectUseCase) Duplicate(ctx context.Context, id int64, creatorID uuid.UUID, appID, email string) (*entity.ObjectResponse, error) {
+	obj, err := u.objectRepo.GetByID(ctx, id)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+
+	// Probe and copy into the same directory, the one holding the source
+	parentDir := filepath.Dir(obj.Path)
+	for n := 1; n <= maxDuplicateAttempts; n++ {
+		name := copyName(obj, n)
+		exists, err := u.pathTaken(ctx, parentDir+"/"+name, 0)
+		if err != nil {
+			return nil, apperrors.InternalError("failed to check path", err)
+		}
+		if exists {
+			continue
+		}
+		return u.copyInto(ctx, obj, parentDir, creatorID, &CopyInput{
+			TargetParentID: obj.ParentID,
+			NewName:        &name,
+		})
+	}
+
+	return nil, apperrors.AlreadyExistsError("object copy")
+}
+
+// maxDuplicateAttempts bounds the search for a free duplicate name
+const maxDuplicateAttempts = 1000
+
+// copyName generates the n-th copy name for an object, keeping file extensions
+func copyName(obj *entity.Object, n int) string {
+	suffix := "_copy"
+	if n > 1 {
+		suffix = fmt.Sprintf("_copy_%d", n)
+	}
+	if obj.IsDirectory() {
+		return obj.Name + suffix
+	}
+	ext := filepath.Ext(obj.Name)
+	base := strings.TrimSuffix(obj.Name, ext)
+	return base + suffix + ext
+}
+
 // copyDirectoryChildren recursively copies child objects in the database
-func (u *objectUseCase) copyDirectoryChildren(ctx context.Context, srcDir, dstDir *entity.Object, creatorID uuid.UUID) error {
+func (u *objectUseCase) copyDirectoryChildren(ctx context.Context, srcDir, dstDir *entity.Object, creatorID uuid.UUID, clearOutputs bool) error {
 	// Get children of source directory
-	children, _, err := u.objectRepo.ListChildren(ctx, &srcDir.ID, 1, 1000)
+	children, _, err := u.objectRepo.ListChildren(ctx, &srcDir.ID, nil, 1, 1000)
 	if err != nil {
 		return err
 	}
@@ -959,10 +1923,18 @@ func (u *objectUseCase) copyDirectoryChildren(ctx context.Context, srcDir, dstDi
 			CreatorID:      creatorID,
 			Size:           child.Size,
 			ContentHash:    child.ContentHash,
+			MimeType:       child.MimeType,
+			Language:       child.Language,
 			Description:    child.Description,
 			CurrentVersion: 1,
 		}
 
+		if clearOutputs {
+			if err := u.clearCopiedOutputs(ctx, newChild); err != nil {
+				return fmt.Errorf("failed to clear outputs of %s: %w", child.Name, err)
+			}
+		}
+
 		if err := u.objectRepo.Create(ctx, newChild); err != nil {
 			return fmt.Errorf("failed to create child object %s: %w", child.Name, err)
 		}
@@ -980,7 +1952,7 @@ func (u *objectUseCase) copyDirectoryChildren(ctx context.Context, srcDir, dstDi
 
 		// Recursively copy children if it's a directory
 		if child.IsDirectory() {
-			if err := u.copyDirectoryChildren(ctx, &child, newChild, creatorID); err != nil {
+			if err := u.copyDirectoryChildren(ctx, &child, newChild, creatorID, clearOutputs); err != nil {
 				return err
 			}
 		}
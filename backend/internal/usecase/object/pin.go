@@ -0,0 +1,93 @@
+package object
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// SetPinnedInput represents a pin state change
+type SetPinnedInput struct {
+	Pinned *bool `json:"pinned"` // Pinned when omitted
+}
+
+// SetPinned pins an object to the top of its directory, or unpins it. Shared
+// pins change what everyone sees and need editor access; with per-user pins,
+// read access is enough.
+func (u *objectUseCase) SetPinned(ctx context.Context, objectID int64, userID uuid.UUID, pinned bool) (*entity.ObjectResponse, error) {
+	obj, err := u.objectRepo.GetByID(ctx, objectID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+
+	pinOwner := u.pinOwner(userID)
+	minRole := entity.RoleEditor
+	if pinOwner != uuid.Nil {
+		minRole = entity.RoleViewer
+	}
+	allowed, err := u.permissionRepo.HasPermission(ctx, objectID, userID, minRole)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check permission", err)
+	}
+	if !allowed {
+		return nil, apperrors.ForbiddenError("no permission to pin this object")
+	}
+
+	if pinned {
+		err = u.pinRepo.Pin(ctx, objectID, pinOwner)
+	} else {
+		err = u.pinRepo.Unpin(ctx, objectID, pinOwner)
+	}
+	if err != nil {
+		return nil, apperrors.InternalError("failed to update pin", err)
+	}
+
+	resp := obj.ToResponse()
+	resp.Pinned = pinned
+	return resp, nil
+}
+
+// pinOwner returns the user ID the pins seen by userID are stored under,
+// uuid.Nil when pins are shared by everyone
+func (u *objectUseCase) pinOwner(userID uuid.UUID) uuid.UUID {
+	if u.storageConfig != nil && u.storageConfig.GetPinScope() == "user" {
+		return userID
+	}
+	return uuid.Nil
+}
+
+// fillPinned sets the pinned flag of the objects in responses that are pinned
+// for userID
+func (u *objectUseCase) fillPinned(ctx context.Context, userID uuid.UUID, responses []entity.ObjectResponse) error {
+	ids := make([]int64, len(responses))
+	for i := range responses {
+		ids[i] = responses[i].ID
+	}
+
+	pinned, err := u.pinRepo.ListPinned(ctx, u.pinOwner(userID), ids)
+	if err != nil {
+		return apperrors.InternalError("failed to get pins", err)
+	}
+	for i := range responses {
+		responses[i].Pinned = pinned[responses[i].ID]
+	}
+	return nil
+}
+
+// comparePinned orders pinned objects before the others
+func comparePinned(a, b *entity.ObjectResponse) int {
+	switch {
+	case a.Pinned == b.Pinned:
+		return 0
+	case a.Pinned:
+		return -1
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,116 @@
+package object
+
+import (
+	"context"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// Planned operations
+const (
+	PlanOperationMove   = "move"
+	PlanOperationCopy   = "copy"
+	PlanOperationDelete = "delete"
+)
+
+// OperationPlan lists the objects a move, copy or delete would affect,
+// computed without changing storage or the database
+type OperationPlan struct {
+	Operation string          `json:"operation"`
+	Objects   []PlannedChange `json:"objects"` // The object itself first, then its descendants
+}
+
+// PlannedChange describes what an operation would do to one object
+type PlannedChange struct {
+	ID      int64             `json:"id"` // Existing object, the source for copies
+	Name    string            `json:"name"`
+	Type    entity.ObjectType `json:"type"`
+	Path    string            `json:"path"`
+	NewPath string            `json:"new_path,omitempty"` // Path after a move, or of the created copy
+}
+
+// PlanDelete returns the objects that Delete would remove
+func (u *objectUseCase) PlanDelete(ctx context.Context, id int64) (*OperationPlan, error) {
+	obj, err := u.getPlanObject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return u.buildPlan(ctx, PlanOperationDelete, obj, "")
+}
+
+// PlanMove validates a move like Move and returns the objects it would relocate
+func (u *objectUseCase) PlanMove(ctx context.Context, id int64, input *MoveInput) (*OperationPlan, error) {
+	obj, err := u.getPlanObject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	_, newPath, err := u.resolveMoveTarget(ctx, obj, input)
+	if err != nil {
+		return nil, err
+	}
+	return u.buildPlan(ctx, PlanOperationMove, obj, newPath)
+}
+
+// PlanCopy validates a copy like Copy and returns the objects it would create
+func (u *objectUseCase) PlanCopy(ctx context.Context, id int64, appID, email string, input *CopyInput) (*OperationPlan, error) {
+	obj, err := u.getPlanObject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := u.copyTargetDir(ctx, appID, email, input)
+	if err != nil {
+		return nil, err
+	}
+	_, newPath, err := u.resolveCopyTarget(ctx, obj, dir, input)
+	if err != nil {
+		return nil, err
+	}
+	return u.buildPlan(ctx, PlanOperationCopy, obj, newPath)
+}
+
+func (u *objectUseCase) getPlanObject(ctx context.Context, id int64) (*entity.Object, error) {
+	obj, err := u.objectRepo.GetByID(ctx, id)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+	return obj, nil
+}
+
+// buildPlan lists obj and, for directories, its descendants. With a non-empty
+// newPath each entry also gets its path rebased under newPath.
+func (u *objectUseCase) buildPlan(ctx context.Context, operation string, obj *entity.Object, newPath string) (*OperationPlan, error) {
+	objects := []entity.Object{*obj}
+	if obj.IsDirectory() {
+		descendants, err := u.objectRepo.GetDescendants(ctx, obj.Path)
+		if err != nil {
+			return nil, apperrors.InternalError("failed to get descendants", err)
+		}
+		objects = append(objects, descendants...)
+	}
+
+	plan := &OperationPlan{
+		Operation: operation,
+		Objects:   make([]PlannedChange, 0, len(objects)),
+	}
+	for _, o := range objects {
+		change := PlannedChange{
+			ID:   o.ID,
+			Name: o.Name,
+			Type: o.Type,
+			Path: o.Path,
+		}
+		if newPath != "" {
+			if o.ID == obj.ID {
+				change.NewPath = newPath
+			} else if rebased, ok := rebasePath(o.Path, obj.Path, newPath); ok {
+				change.NewPath = rebased
+			}
+		}
+		plan.Objects = append(plan.Objects, change)
+	}
+	return plan, nil
+}
@@ -0,0 +1,35 @@
+package object
+
+import "testing"
+
+// TestRebasePath covers descendant path rewrites on rename, move and restore,
+// including siblings whose names share a prefix with the moved directory
+func TestRebasePath(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		oldPrefix string
+		newPrefix string
+		want      string
+		rebased   bool
+	}{
+		{name: "direct child", path: "/u/data/a.txt", oldPrefix: "/u/data", newPrefix: "/u/archive", want: "/u/archive/a.txt", rebased: true},
+		{name: "nested descendant", path: "/u/data/x/y/z.py", oldPrefix: "/u/data", newPrefix: "/u/archive", want: "/u/archive/x/y/z.py", rebased: true},
+		{name: "moved into another directory", path: "/u/data/a.txt", oldPrefix: "/u/data", newPrefix: "/u/old/data", want: "/u/old/data/a.txt", rebased: true},
+		{name: "prefix-colliding sibling", path: "/u/data2", oldPrefix: "/u/data", newPrefix: "/u/archive", want: "/u/data2", rebased: false},
+		{name: "child of prefix-colliding sibling", path: "/u/data2/a.txt", oldPrefix: "/u/data", newPrefix: "/u/archive", want: "/u/data2/a.txt", rebased: false},
+		{name: "sibling with separator suffix", path: "/u/data-old/a.txt", oldPrefix: "/u/data", newPrefix: "/u/archive", want: "/u/data-old/a.txt", rebased: false},
+		{name: "directory itself", path: "/u/data", oldPrefix: "/u/data", newPrefix: "/u/archive", want: "/u/data", rebased: false},
+		{name: "unrelated path", path: "/u/other/a.txt", oldPrefix: "/u/data", newPrefix: "/u/archive", want: "/u/other/a.txt", rebased: false},
+		{name: "prefix in the middle", path: "/x/u/data/a.txt", oldPrefix: "/u/data", newPrefix: "/u/archive", want: "/x/u/data/a.txt", rebased: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rebased := rebasePath(tt.path, tt.oldPrefix, tt.newPrefix)
+			if got != tt.want || rebased != tt.rebased {
+				t.Fatalf("rebasePath(%q, %q, %q) = (%q, %v), want (%q, %v)",
+					tt.path, tt.oldPrefix, tt.newPrefix, got, rebased, tt.want, tt.rebased)
+			}
+		})
+	}
+}
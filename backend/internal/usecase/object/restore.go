@@ -0,0 +1,130 @@
+package object
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/usecase/event"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// RestoreResult describes a restored object
+type RestoreResult struct {
+	Object   *entity.ObjectResponse `json:"object"`
+	Restored int                    `json:"restored"`       // Objects restored, including descendants
+	Note     string                 `json:"note,omitempty"` // Set when the object could not go back to its original place
+}
+
+// RestoreTree restores a deleted object together with the descendants that
+// were deleted with it, moving their content back from the trash. An object
+// whose parent directory no longer exists is restored to the root of its
+// owner's workspace instead.
+func (u *objectUseCase) RestoreTree(ctx context.Context, objectID int64) (*RestoreResult, error) {
+	obj, err := u.objectRepo.GetDeletedByID(ctx, objectID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("deleted object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+	if obj.DeleteBatchID == nil {
+		return nil, apperrors.ValidationError("object was deleted before trash support and cannot be restored")
+	}
+	batchID := *obj.DeleteBatchID
+
+	batch, err := u.objectRepo.ListDeleteBatch(ctx, batchID)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to list deleted objects", err)
+	}
+	if obj.ParentID != nil {
+		for _, o := range batch {
+			if o.ID == *obj.ParentID {
+				return nil, apperrors.ValidationError("object was deleted with its parent directory, restore the parent instead")
+			}
+		}
+	}
+
+	// Fall back to the workspace root if the parent is gone
+	targetPath := obj.Path
+	parentID := obj.ParentID
+	note := ""
+	if obj.ParentID != nil {
+		if _, err := u.objectRepo.GetByID(ctx, *obj.ParentID); err != nil {
+			if !apperrors.IsNotFound(err) {
+				return nil, apperrors.InternalError("failed to get parent", err)
+			}
+			targetPath = workspaceRoot(obj.Path) + "/" + obj.Name
+			parentID = nil
+			note = "original parent directory no longer exists, restored to the workspace root"
+		}
+	}
+
+	exists, err := u.pathTaken(ctx, targetPath, obj.ID)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check path", err)
+	}
+	if exists {
+		return nil, apperrors.AlreadyExistsError("object at restore path")
+	}
+
+	if err := u.storage.RestoreFromTrash(ctx, batchID.String(), obj.Path, targetPath); err != nil {
+		return nil, apperrors.InternalError("failed to restore from trash", err)
+	}
+
+	if targetPath != obj.Path {
+		for _, desc := range batch {
+			descNewPath, ok := rebasePath(desc.Path, obj.Path, targetPath)
+			if !ok {
+				continue
+			}
+			if err := u.objectRepo.UpdatePath(ctx, desc.ID, descNewPath); err != nil {
+				return nil, apperrors.InternalError("failed to update descendant path", err)
+			}
+		}
+	}
+
+	obj.Path = targetPath
+	obj.ParentID = parentID
+	obj.IsDeleted = false
+	obj.DeletedAt = nil
+	obj.DeleteBatchID = nil
+	if err := u.objectRepo.Update(ctx, obj); err != nil {
+		return nil, apperrors.InternalError("failed to update object", err)
+	}
+	if err := u.objectRepo.RestoreBatch(ctx, batchID); err != nil {
+		return nil, apperrors.InternalError("failed to restore objects", err)
+	}
+	if err := u.deleteBatchRepo.Delete(ctx, batchID); err != nil {
+		return nil, apperrors.InternalError("failed to remove delete record", err)
+	}
+
+	u.publish(ctx, event.ObjectCreated, obj, "", uuid.Nil)
+
+	return &RestoreResult{
+		Object:   obj.ToResponse(),
+		Restored: len(batch),
+		Note:     note,
+	}, nil
+}
+
+// ListTrash lists the user's delete operations that can still be restored,
+// newest first. Each entry stands for a deleted object and its descendants.
+func (u *objectUseCase) ListTrash(ctx context.Context, userID uuid.UUID, page, pageSize int) ([]entity.DeleteBatch, int64, error) {
+	batches, total, err := u.deleteBatchRepo.ListByUser(ctx, userID, page, pageSize)
+	if err != nil {
+		return nil, 0, apperrors.InternalError("failed to list trash", err)
+	}
+	return batches, total, nil
+}
+
+// workspaceRoot returns the /{appID}/{email} workspace directory containing path
+func workspaceRoot(path string) string {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	return "/" + parts[0] + "/" + parts[1]
+}
@@ -0,0 +1,171 @@
+package object
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// RegisterTemplateInput is the request to offer a file as a template
+type RegisterTemplateInput struct {
+	ObjectID    int64  `json:"object_id" binding:"required"`
+	Name        string `json:"name" binding:"required,max=255"` // Shown in template pickers
+	Description string `json:"description"`
+}
+
+// CreateFromTemplateInput is the request to create a file from a template
+type CreateFromTemplateInput struct {
+	TemplateID int64  `json:"template_id" binding:"required"`
+	Name       string `json:"name" binding:"required,max=255"` // Without an extension, the template file's is used
+	ParentID   *int64 `json:"parent_id"`
+}
+
+// ListTemplates returns the templates offered to a user: shared ones and the
+// user's own
+func (u *objectUseCase) ListTemplates(ctx context.Context, userID uuid.UUID) ([]entity.ObjectTemplate, error) {
+	templates, err := u.templateRepo.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to list templates", err)
+	}
+	return templates, nil
+}
+
+// RegisterTemplate offers a file the user can view as a template. Shared
+// templates are offered to every user, and the caller must check that the
+// user may share them. Later edits of the file change the template.
+func (u *objectUseCase) RegisterTemplate(ctx context.Context, userID uuid.UUID, shared bool, input *RegisterTemplateInput) (*entity.ObjectTemplate, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, apperrors.InvalidArgumentError("template name is required", "name")
+	}
+
+	obj, err := u.objectRepo.GetByID(ctx, input.ObjectID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+	if obj.IsDirectory() {
+		return nil, apperrors.ValidationError("directories cannot be templates")
+	}
+
+	allowed, err := u.permissionRepo.HasPermission(ctx, obj.ID, userID, entity.RoleViewer)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check permission", err)
+	}
+	if !allowed {
+		return nil, apperrors.ForbiddenError("no permission to view this object")
+	}
+
+	template := &entity.ObjectTemplate{
+		ObjectID:    obj.ID,
+		ObjectName:  obj.Name,
+		ObjectType:  obj.Type,
+		Name:        name,
+		Description: input.Description,
+		CreatedBy:   &userID,
+	}
+	if !shared {
+		template.OwnerID = &userID
+	}
+	if err := u.templateRepo.Create(ctx, template); err != nil {
+		return nil, apperrors.InternalError("failed to create template", err)
+	}
+	return template, nil
+}
+
+// DeleteTemplate stops offering a template; the template file is kept. Users
+// may delete their own templates, and shared ones when asAdmin is set.
+func (u *objectUseCase) DeleteTemplate(ctx context.Context, templateID int64, userID uuid.UUID, asAdmin bool) error {
+	template, err := u.getTemplate(ctx, templateID, userID)
+	if err != nil {
+		return err
+	}
+	if template.Shared && !asAdmin {
+		return apperrors.ForbiddenError("only administrators can delete shared templates")
+	}
+
+	if err := u.templateRepo.Delete(ctx, templateID); err != nil {
+		return apperrors.InternalError("failed to delete template", err)
+	}
+	return nil
+}
+
+// getTemplate returns a template offered to the user
+func (u *objectUseCase) getTemplate(ctx context.Context, templateID int64, userID uuid.UUID) (*entity.ObjectTemplate, error) {
+	template, err := u.templateRepo.GetByID(ctx, templateID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("template")
+		}
+		return nil, apperrors.InternalError("failed to get template", err)
+	}
+	if !template.Shared && *template.OwnerID != userID {
+		return nil, apperrors.NotFoundError("template")
+	}
+	return template, nil
+}
+
+// CreateFromTemplate creates a file in the user's workspace with a copy of a
+// template's content. Placeholders in text and notebook templates are
+// replaced: {{name}} with the new file's name without its extension, {{date}}
+// with today's date and {{user}} with the user's email.
+func (u *objectUseCase) CreateFromTemplate(ctx context.Context, userID uuid.UUID, appID, email string, input *CreateFromTemplateInput) (*entity.ObjectResponse, error) {
+	template, err := u.getTemplate(ctx, input.TemplateID, userID)
+	if err != nil {
+		return nil, err
+	}
+	obj, content, err := u.readContent(ctx, template.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(input.Name)
+	var objectType entity.ObjectType
+	if path.Ext(name) == "" {
+		name += path.Ext(obj.Name)
+		objectType = obj.Type
+	}
+
+	content = expandTemplate(obj.Type, content, map[string]string{
+		"name": strings.TrimSuffix(name, path.Ext(name)),
+		"date": time.Now().Format("2006-01-02"),
+		"user": email,
+	})
+
+	return u.CreateFile(ctx, userID, appID, email, &CreateFileInput{
+		Name:     name,
+		Type:     objectType,
+		ParentID: input.ParentID,
+		Content:  content,
+	})
+}
+
+// expandTemplate replaces {{key}} placeholders in text content. Notebook
+// values are JSON-escaped so the notebook stays valid; binary content is
+// returned unchanged.
+func expandTemplate(objectType entity.ObjectType, content []byte, values map[string]string) []byte {
+	if !utf8.Valid(content) || !bytes.Contains(content, []byte("{{")) {
+		return content
+	}
+
+	replacements := make([]string, 0, 2*len(values))
+	for key, value := range values {
+		if objectType == entity.ObjectTypeNotebook {
+			escaped, _ := json.Marshal(value)
+			value = string(escaped[1 : len(escaped)-1])
+		}
+		replacements = append(replacements, "{{"+key+"}}", value)
+	}
+	return []byte(strings.NewReplacer(replacements...).Replace(string(content)))
+}
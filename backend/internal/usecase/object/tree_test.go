@@ -0,0 +1,150 @@
+package object
+
+import (
+	"testing"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+)
+
+func dir(id int64, name string, parentID *int64) entity.Object {
+	return entity.Object{ID: id, Name: name, Type: entity.ObjectTypeDirectory, ParentID: parentID}
+}
+
+func ptr(id int64) *int64 {
+	return &id
+}
+
+// countNodes returns how often each object appears in the tree
+func countNodes(items []*entity.ObjectResponse, seen map[int64]int) {
+	for _, item := range items {
+		seen[item.ID]++
+		countNodes(item.Children, seen)
+	}
+}
+
+func TestBuildTreeCycles(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects []entity.Object
+		roots   []string
+	}{
+		{
+			name:    "two-object cycle",
+			objects: []entity.Object{dir(1, "a", ptr(2)), dir(2, "b", ptr(1))},
+			roots:   []string{"a"},
+		},
+		{
+			name:    "self parent",
+			objects: []entity.Object{dir(1, "a", ptr(1))},
+			roots:   []string{"a"},
+		},
+		{
+			name: "cycle beside a healthy tree",
+			objects: []entity.Object{
+				dir(1, "root", nil), dir(2, "child", ptr(1)),
+				dir(3, "x", ptr(5)), dir(4, "y", ptr(3)), dir(5, "z", ptr(4)),
+			},
+			roots: []string{"root", "x"},
+		},
+		{
+			name:    "orphan whose parent is missing",
+			objects: []entity.Object{dir(1, "orphan", ptr(99))},
+			roots:   []string{"orphan"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTree(tt.objects, 10, nil)
+
+			var roots []string
+			items := make([]*entity.ObjectResponse, len(tree))
+			for i := range tree {
+				roots = append(roots, tree[i].Name)
+				items[i] = &tree[i]
+			}
+			if len(roots) != len(tt.roots) {
+				t.Fatalf("roots = %v, want %v", roots, tt.roots)
+			}
+			for i := range roots {
+				if roots[i] != tt.roots[i] {
+					t.Fatalf("roots = %v, want %v", roots, tt.roots)
+				}
+			}
+
+			seen := make(map[int64]int)
+			countNodes(items, seen)
+			for _, obj := range tt.objects {
+				if seen[obj.ID] != 1 {
+					t.Errorf("object %d (%s) appears %d times, want once", obj.ID, obj.Name, seen[obj.ID])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildTreeDepthLimit(t *testing.T) {
+	// root -> d1 -> d2 -> d3
+	objects := []entity.Object{
+		dir(1, "root", nil), dir(2, "d1", ptr(1)), dir(3, "d2", ptr(2)), dir(4, "d3", ptr(3)),
+	}
+
+	tests := []struct {
+		name      string
+		maxDepth  int
+		depth     int // Levels present in the tree
+		truncated string
+	}{
+		{name: "root only", maxDepth: 1, depth: 1, truncated: "root"},
+		{name: "cut in the middle", maxDepth: 2, depth: 2, truncated: "d1"},
+		{name: "exact depth", maxDepth: 4, depth: 4},
+		{name: "deeper than the tree", maxDepth: 10, depth: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTree(objects, tt.maxDepth, nil)
+			if len(tree) != 1 {
+				t.Fatalf("got %d roots, want 1", len(tree))
+			}
+
+			depth := 0
+			truncated := ""
+			for node := &tree[0]; node != nil; {
+				depth++
+				if node.Truncated {
+					truncated = node.Name
+				}
+				if len(node.Children) == 0 {
+					break
+				}
+				node = node.Children[0]
+			}
+			if depth != tt.depth {
+				t.Errorf("depth = %d, want %d", depth, tt.depth)
+			}
+			if truncated != tt.truncated {
+				t.Errorf("truncated node = %q, want %q", truncated, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestBuildTreePinnedFirst(t *testing.T) {
+	objects := []entity.Object{
+		dir(1, "a", nil),
+		{ID: 2, Name: "b.py", Type: entity.ObjectTypeFile},
+		dir(3, "c", nil),
+	}
+	tree := buildTree(objects, 10, map[int64]bool{2: true})
+
+	var names []string
+	for _, item := range tree {
+		names = append(names, item.Name)
+	}
+	want := []string{"b.py", "a", "c"}
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] || names[2] != want[2] {
+		t.Fatalf("order = %v, want %v", names, want)
+	}
+	if !tree[0].Pinned {
+		t.Error("pinned object is not marked as pinned")
+	}
+}
@@ -0,0 +1,296 @@
+package object
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/adapter/storage"
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/usecase/event"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// StartUploadInput represents the file a chunked upload will create
+type StartUploadInput struct {
+	Name        string            `json:"name" binding:"required,max=255"`
+	Type        entity.ObjectType `json:"type"`
+	ParentID    *int64            `json:"parent_id"`
+	Description string            `json:"description"`
+	Size        int64             `json:"size" binding:"min=0"`                        // Total size in bytes
+	Hash        string            `json:"hash" binding:"omitempty,len=64,hexadecimal"` // Optional SHA-256 of the content, checked on completion
+}
+
+// StartUpload opens a chunked upload session. The target name and location
+// are validated up front so a client does not send the content of a file that
+// cannot be created.
+func (u *objectUseCase) StartUpload(ctx context.Context, userID uuid.UUID, appID, email string, input *StartUploadInput) (*entity.UploadSession, error) {
+	name, err := u.sanitizeName(input.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkFileType(name, nil); err != nil {
+		return nil, err
+	}
+	if u.storageConfig != nil && u.storageConfig.MaxUploadSize > 0 && input.Size > u.storageConfig.MaxUploadSize {
+		return nil, apperrors.ValidationError(fmt.Sprintf("file exceeds the %d byte upload limit", u.storageConfig.MaxUploadSize))
+	}
+
+	path, _, err := u.resolveCreatePath(ctx, appID, email, input.ParentID, name)
+	if err != nil {
+		return nil, err
+	}
+	taken, err := u.pathTaken(ctx, path, 0)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check path", err)
+	}
+	if taken {
+		return nil, apperrors.AlreadyExistsError("object with this name")
+	}
+
+	session := &entity.UploadSession{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Name:        name,
+		Type:        input.Type,
+		ParentID:    input.ParentID,
+		Description: input.Description,
+		Size:        input.Size,
+		Hash:        strings.ToLower(input.Hash),
+		ExpiresAt:   time.Now().Add(u.uploadSessionTTL()),
+	}
+
+	// Create the empty temporary file, which is all a zero-byte upload needs
+	if _, err := u.storage.AppendUpload(ctx, session.ID.String(), 0, strings.NewReader(""), 0); err != nil {
+		return nil, apperrors.InternalError("failed to create upload file", err)
+	}
+	if err := u.uploadSessionRepo.Create(ctx, session); err != nil {
+		_ = u.storage.DeleteUpload(ctx, session.ID.String())
+		return nil, apperrors.InternalError("failed to create upload session", err)
+	}
+
+	return session, nil
+}
+
+// GetUpload returns an upload session; its received size is where the next
+// chunk must start
+func (u *objectUseCase) GetUpload(ctx context.Context, id, userID uuid.UUID) (*entity.UploadSession, error) {
+	session, err := u.getUploadSession(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file is authoritative: a chunk may have been stored without the
+	// session being updated, e.g. if the server stopped in between
+	if size, err := u.storage.UploadSize(ctx, id.String()); err == nil && size != session.Received {
+		session.Received = size
+		if err := u.uploadSessionRepo.Update(ctx, session); err != nil {
+			return nil, apperrors.InternalError("failed to update upload session", err)
+		}
+	}
+	return session, nil
+}
+
+// AppendUpload stores a chunk starting at offset, which must equal the bytes
+// received so far. A chunk cut short by the client is kept up to where it
+// stopped, and the session reports that size to resume from.
+func (u *objectUseCase) AppendUpload(ctx context.Context, id, userID uuid.UUID, offset int64, r io.Reader) (*entity.UploadSession, error) {
+	session, err := u.getUploadSession(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if offset > session.Size {
+		return nil, apperrors.InvalidArgumentError("offset is past the end of the upload", "offset")
+	}
+
+	size, appendErr := u.storage.AppendUpload(ctx, id.String(), offset, r, session.Size-offset)
+	switch {
+	case errors.Is(appendErr, storage.ErrUploadOffset):
+		return nil, apperrors.NewAppError(apperrors.CodeUpdateConflict, http.StatusConflict,
+			fmt.Sprintf("offset %d does not match the %d bytes received", offset, size), apperrors.ErrConflict)
+	case errors.Is(appendErr, storage.ErrUploadOverflow):
+		return nil, apperrors.ValidationError("chunk exceeds the expected upload size")
+	}
+
+	session.Received = size
+	session.ExpiresAt = time.Now().Add(u.uploadSessionTTL())
+	if err := u.uploadSessionRepo.Update(ctx, session); err != nil {
+		return nil, apperrors.InternalError("failed to update upload session", err)
+	}
+
+	if appendErr != nil {
+		return nil, apperrors.BadRequestError(fmt.Sprintf("failed to read chunk, %d bytes received: %v", size, appendErr))
+	}
+	return session, nil
+}
+
+// CompleteUpload verifies the assembled content against the expected size and
+// hash, scans it, and moves it into the workspace as a new file object
+func (u *objectUseCase) CompleteUpload(ctx context.Context, id, userID uuid.UUID, appID, email string) (*entity.ObjectResponse, error) {
+	session, err := u.getUploadSession(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	uploadID := id.String()
+
+	size, err := u.storage.UploadSize(ctx, uploadID)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to stat upload", err)
+	}
+	if size != session.Size {
+		return nil, apperrors.ValidationError(fmt.Sprintf("upload is incomplete: %d of %d bytes received", size, session.Size))
+	}
+
+	head, contentHash, err := u.inspectUpload(ctx, uploadID)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to read upload", err)
+	}
+	if session.Hash != "" && session.Hash != contentHash {
+		return nil, apperrors.ValidationError("uploaded content does not match the expected hash")
+	}
+	if err := u.checkFileType(session.Name, head); err != nil {
+		return nil, err
+	}
+
+	objType := session.Type
+	if objType == "" {
+		objType = entity.InferTypeFromExtension(session.Name)
+	}
+
+	path, parentID, err := u.resolveCreatePath(ctx, appID, email, session.ParentID, session.Name)
+	if err != nil {
+		return nil, err
+	}
+	taken, err := u.pathTaken(ctx, path, 0)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check path", err)
+	}
+	if taken {
+		return nil, apperrors.AlreadyExistsError("object with this name")
+	}
+
+	// Scan before the content enters the workspace
+	if err := u.scanUpload(ctx, uploadID, session.Name); err != nil {
+		return nil, err
+	}
+
+	// The inode survives the rename into the workspace
+	inode, err := u.storage.UploadInode(ctx, uploadID)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to get inode", err)
+	}
+
+	obj := &entity.Object{
+		ID:             inode,
+		Name:           session.Name,
+		Type:           objType,
+		Path:           path,
+		ParentID:       parentID,
+		CreatorID:      userID,
+		Size:           size,
+		ContentHash:    contentHash,
+		Description:    session.Description,
+		CurrentVersion: 1,
+	}
+
+	// Only notebooks need their content to tell the language
+	var content []byte
+	if objType == entity.ObjectTypeNotebook {
+		if content, err = u.readUpload(ctx, uploadID); err != nil {
+			log.Warn().Err(err).Str("upload_id", uploadID).Msg("Failed to read uploaded notebook for its language")
+		}
+	}
+	u.setLanguage(obj, content)
+
+	if err := u.createStaged(ctx, obj, uploadID); err != nil {
+		return nil, err
+	}
+
+	if err := u.uploadSessionRepo.Delete(ctx, id); err != nil {
+		log.Warn().Err(err).Str("upload_id", uploadID).Msg("Failed to delete completed upload session")
+	}
+
+	u.inheritParentPermissions(ctx, obj)
+	u.publish(ctx, event.ObjectCreated, obj, "", userID)
+	return obj.ToResponse(), nil
+}
+
+// AbortUpload discards an upload session and the content received so far
+func (u *objectUseCase) AbortUpload(ctx context.Context, id, userID uuid.UUID) error {
+	if _, err := u.getUploadSession(ctx, id, userID); err != nil {
+		return err
+	}
+	if err := u.storage.DeleteUpload(ctx, id.String()); err != nil {
+		return apperrors.InternalError("failed to delete upload file", err)
+	}
+	if err := u.uploadSessionRepo.Delete(ctx, id); err != nil {
+		return apperrors.InternalError("failed to delete upload session", err)
+	}
+	return nil
+}
+
+// getUploadSession returns the user's unexpired upload session. Sessions of
+// other users are reported as not found.
+func (u *objectUseCase) getUploadSession(ctx context.Context, id, userID uuid.UUID) (*entity.UploadSession, error) {
+	session, err := u.uploadSessionRepo.GetByID(ctx, id)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("upload session")
+		}
+		return nil, apperrors.InternalError("failed to get upload session", err)
+	}
+	if session.UserID != userID || session.IsExpired() {
+		return nil, apperrors.NotFoundError("upload session")
+	}
+	return session, nil
+}
+
+// inspectUpload returns the first bytes of an assembled upload, for content
+// type detection, and the SHA-256 hex digest of all of it
+func (u *objectUseCase) inspectUpload(ctx context.Context, uploadID string) ([]byte, string, error) {
+	f, err := u.storage.OpenUpload(ctx, uploadID)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, "", err
+	}
+	head = head[:n]
+
+	hash := sha256.New()
+	hash.Write(head)
+	if _, err := io.Copy(hash, f); err != nil {
+		return nil, "", err
+	}
+	return head, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// readUpload returns the content of an upload's temporary file
+func (u *objectUseCase) readUpload(ctx context.Context, uploadID string) ([]byte, error) {
+	f, err := u.storage.OpenUpload(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func (u *objectUseCase) uploadSessionTTL() time.Duration {
+	if u.storageConfig == nil {
+		return 24 * time.Hour
+	}
+	return u.storageConfig.GetUploadSessionTTL()
+}
@@ -0,0 +1,242 @@
+package object
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/parser"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// maxValidationIssues caps the issues reported for one document
+const maxValidationIssues = 100
+
+// ValidationIssue is a problem found in content. Line and Column are 1-based
+// and zero when unknown; Path locates problems in a document's structure.
+type ValidationIssue struct {
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Path    string `json:"path,omitempty"` // e.g. cells[2].source
+}
+
+// ValidationResult reports whether content is well-formed. Format names the
+// check applied, and is empty for content nothing is checked for.
+type ValidationResult struct {
+	Valid  bool              `json:"valid"`
+	Format string            `json:"format,omitempty"`
+	Issues []ValidationIssue `json:"issues,omitempty"`
+}
+
+// contentValidator checks content in one format, returning the problems found
+type contentValidator func(content []byte) []ValidationIssue
+
+// contentValidators holds the validator of each format
+var contentValidators = map[string]contentValidator{
+	"notebook": validateNotebook,
+	"json":     validateJSON,
+	"yaml":     validateYAML,
+}
+
+// ValidateContent checks that content is well-formed for objectType without
+// saving it. An empty objectType is inferred from name, which also tells
+// config formats apart. Content of types without a validator is valid.
+func (u *objectUseCase) ValidateContent(ctx context.Context, objectType entity.ObjectType, name string, content []byte) (*ValidationResult, error) {
+	if objectType == "" {
+		if name == "" {
+			return nil, apperrors.InvalidArgumentError("type or name is required", "type")
+		}
+		objectType = entity.InferTypeFromExtension(name)
+	}
+	if !objectType.IsValid() || objectType == entity.ObjectTypeDirectory {
+		return nil, apperrors.InvalidArgumentError(fmt.Sprintf("invalid object type %q", objectType), "type")
+	}
+
+	format := validationFormat(objectType, name, content)
+	result := &ValidationResult{Valid: true, Format: format}
+	if validate, ok := contentValidators[format]; ok {
+		result.Issues = validate(content)
+		if len(result.Issues) > maxValidationIssues {
+			result.Issues = result.Issues[:maxValidationIssues]
+		}
+		result.Valid = len(result.Issues) == 0
+	}
+	return result, nil
+}
+
+// validationFormat picks the format content is checked as. Config files are
+// told apart by extension; without one, only JSON objects are recognized,
+// since INI sections would read as broken JSON or YAML.
+func validationFormat(objectType entity.ObjectType, name string, content []byte) string {
+	switch objectType {
+	case entity.ObjectTypeNotebook:
+		return "notebook"
+	case entity.ObjectTypeConfig:
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".json":
+			return "json"
+		case ".yaml", ".yml":
+			return "yaml"
+		case "":
+			if bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) {
+				return "json"
+			}
+		}
+	}
+	return ""
+}
+
+func validateJSON(content []byte) []ValidationIssue {
+	var doc any
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return []ValidationIssue{jsonIssue(content, err)}
+	}
+	return nil
+}
+
+// jsonIssue describes a JSON decoding error, located by line and column when
+// it is a syntax error
+func jsonIssue(content []byte, err error) ValidationIssue {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line, column := lineColumn(content, syntaxErr.Offset)
+		return ValidationIssue{Message: syntaxErr.Error(), Line: line, Column: column}
+	}
+	return ValidationIssue{Message: err.Error()}
+}
+
+// lineColumn converts a byte offset in content to a 1-based line and column.
+// JSON syntax error offsets point just past the offending byte.
+func lineColumn(content []byte, offset int64) (int, int) {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	if offset < 1 {
+		return 1, 1
+	}
+	before := content[:offset-1]
+	return bytes.Count(before, []byte("\n")) + 1, len(before) - bytes.LastIndexByte(before, '\n')
+}
+
+func validateYAML(content []byte) []ValidationIssue {
+	if _, err := parser.ParseBytes(content, 0); err != nil {
+		var yamlErr yaml.Error
+		if errors.As(err, &yamlErr) && yamlErr.GetToken() != nil {
+			pos := yamlErr.GetToken().Position
+			return []ValidationIssue{{Message: yamlErr.GetMessage(), Line: pos.Line, Column: pos.Column}}
+		}
+		return []ValidationIssue{{Message: err.Error()}}
+	}
+	return nil
+}
+
+// Known nbformat 4 cell and output types
+var (
+	notebookCellTypes   = []string{"code", "markdown", "raw"}
+	notebookOutputTypes = []string{"stream", "display_data", "execute_result", "error"}
+)
+
+// validateNotebook checks the nbformat 4 structure that clients and kernels
+// rely on: a cells list of known cell types with string or line list sources,
+// and outputs on code cells.
+func validateNotebook(content []byte) []ValidationIssue {
+	var doc any
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return []ValidationIssue{jsonIssue(content, err)}
+	}
+	notebook, ok := doc.(map[string]any)
+	if !ok {
+		return []ValidationIssue{{Message: "notebook must be a JSON object"}}
+	}
+
+	var issues []ValidationIssue
+	add := func(path, format string, args ...any) {
+		issues = append(issues, ValidationIssue{Message: fmt.Sprintf(format, args...), Path: path})
+	}
+
+	switch nbformat, ok := notebook["nbformat"].(float64); {
+	case !ok:
+		add("nbformat", "nbformat is required")
+	case nbformat != 4:
+		add("nbformat", "unsupported nbformat %v, expected 4", nbformat)
+	}
+	if metadata, present := notebook["metadata"]; present {
+		if _, ok := metadata.(map[string]any); !ok {
+			add("metadata", "metadata must be an object")
+		}
+	}
+
+	cells, ok := notebook["cells"].([]any)
+	if !ok {
+		add("cells", "cells must be a list")
+		return issues
+	}
+	for i, c := range cells {
+		path := fmt.Sprintf("cells[%d]", i)
+		cell, ok := c.(map[string]any)
+		if !ok {
+			add(path, "cell must be an object")
+			continue
+		}
+
+		cellType, _ := cell["cell_type"].(string)
+		if !slices.Contains(notebookCellTypes, cellType) {
+			add(path+".cell_type", "unknown cell type %q", cellType)
+		}
+		if !isNotebookText(cell["source"]) {
+			add(path+".source", "source must be a string or a list of strings")
+		}
+		if cellType != "code" {
+			continue
+		}
+
+		if count, present := cell["execution_count"]; present && count != nil {
+			if _, ok := count.(float64); !ok {
+				add(path+".execution_count", "execution_count must be a number or null")
+			}
+		}
+		outputs, ok := cell["outputs"].([]any)
+		if !ok {
+			add(path+".outputs", "code cells must have an outputs list")
+			continue
+		}
+		for j, o := range outputs {
+			outputPath := fmt.Sprintf("%s.outputs[%d]", path, j)
+			output, ok := o.(map[string]any)
+			if !ok {
+				add(outputPath, "output must be an object")
+				continue
+			}
+			if outputType, _ := output["output_type"].(string); !slices.Contains(notebookOutputTypes, outputType) {
+				add(outputPath+".output_type", "unknown output type %q", outputType)
+			}
+		}
+	}
+	return issues
+}
+
+// isNotebookText reports whether v is multiline notebook text: a string, or
+// a list of strings to be joined
+func isNotebookText(v any) bool {
+	switch text := v.(type) {
+	case string:
+		return true
+	case []any:
+		for _, line := range text {
+			if _, ok := line.(string); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
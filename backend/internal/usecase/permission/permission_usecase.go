@@ -2,11 +2,13 @@ package permission
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/leondli/workspace/internal/domain/entity"
 	"github.com/leondli/workspace/internal/domain/repository"
+	"github.com/leondli/workspace/internal/usecase/event"
 	apperrors "github.com/leondli/workspace/pkg/errors"
 )
 
@@ -28,13 +30,15 @@ type GrantInput struct {
 
 // UpdateInput represents permission update input
 type UpdateInput struct {
-	Role entity.Role `json:"role" binding:"required"`
+	Role      entity.Role `json:"role" binding:"required"`
+	UpdatedAt *time.Time  `json:"updated_at"` // Optional updated_at the client last saw; stale values are rejected
 }
 
 type permissionUseCase struct {
 	permissionRepo repository.PermissionRepository
 	objectRepo     repository.ObjectRepository
 	userRepo       repository.UserRepository
+	events         event.Publisher
 }
 
 // NewUseCase creates a new permission use case
@@ -42,14 +46,30 @@ func NewUseCase(
 	permissionRepo repository.PermissionRepository,
 	objectRepo repository.ObjectRepository,
 	userRepo repository.UserRepository,
+	events event.Publisher,
 ) UseCase {
 	return &permissionUseCase{
 		permissionRepo: permissionRepo,
 		objectRepo:     objectRepo,
 		userRepo:       userRepo,
+		events:         events,
 	}
 }
 
+// publishChange emits a permission change event for obj. A grant is
+// addressed to the grantee, who is given the granted role.
+func (u *permissionUseCase) publishChange(ctx context.Context, obj *entity.Object, actorID *uuid.UUID, grant *entity.Permission) {
+	if u.events == nil || obj == nil {
+		return
+	}
+	e := event.New(event.PermissionChanged, obj, actorID)
+	if grant != nil {
+		e.RecipientID = &grant.UserID
+		e.Role = grant.Role
+	}
+	u.events.Publish(ctx, e)
+}
+
 func (u *permissionUseCase) Grant(ctx context.Context, objectID int64, input *GrantInput, grantedBy uuid.UUID) (*entity.PermissionResponse, error) {
 	// Validate role
 	if !input.Role.IsValid() {
@@ -83,6 +103,7 @@ func (u *permissionUseCase) Grant(ctx context.Context, objectID int64, input *Gr
 			return nil, apperrors.InternalError("failed to update permission", err)
 		}
 		existing.User = user
+		u.publishChange(ctx, obj, &grantedBy, existing)
 		return existing.ToResponse(), nil
 	}
 	if !apperrors.IsNotFound(err) {
@@ -109,6 +130,7 @@ func (u *permissionUseCase) Grant(ctx context.Context, objectID int64, input *Gr
 	}
 
 	perm.User = user
+	u.publishChange(ctx, obj, &grantedBy, perm)
 	return perm.ToResponse(), nil
 }
 
@@ -126,11 +148,25 @@ func (u *permissionUseCase) Update(ctx context.Context, objectID int64, userID u
 		return nil, apperrors.InternalError("failed to get permission", err)
 	}
 
+	expectedUpdatedAt := perm.UpdatedAt
+	if input.UpdatedAt != nil {
+		if !input.UpdatedAt.Equal(perm.UpdatedAt) {
+			return nil, apperrors.ConflictError("permission")
+		}
+		expectedUpdatedAt = *input.UpdatedAt
+	}
+
 	perm.Role = input.Role
-	if err := u.permissionRepo.Update(ctx, perm); err != nil {
+	if err := u.permissionRepo.UpdateIfUnmodified(ctx, perm, expectedUpdatedAt); err != nil {
+		if apperrors.IsConflict(err) {
+			return nil, apperrors.ConflictError("permission")
+		}
 		return nil, apperrors.InternalError("failed to update permission", err)
 	}
 
+	if obj, err := u.objectRepo.GetByID(ctx, objectID); err == nil {
+		u.publishChange(ctx, obj, nil, nil)
+	}
 	return perm.ToResponse(), nil
 }
 
@@ -174,6 +210,9 @@ func (u *permissionUseCase) Revoke(ctx context.Context, objectID int64, userID u
 		}
 	}
 
+	if err == nil {
+		u.publishChange(ctx, obj, nil, nil)
+	}
 	return nil
 }
 
@@ -6,17 +6,21 @@ import (
 	"os"
 	"strings"
 
+	"github.com/google/uuid"
+
 	"github.com/leondli/workspace/internal/adapter/storage"
 	"github.com/leondli/workspace/internal/domain/entity"
 	"github.com/leondli/workspace/internal/domain/repository"
 	apperrors "github.com/leondli/workspace/pkg/errors"
 )
 
-// UseCase defines the search use case interface
+// UseCase defines the search use case interface. A non-empty pathPrefix
+// restricts name and content searches to the descendants of that path.
 type UseCase interface {
-	SearchByName(ctx context.Context, query string, types []entity.ObjectType, page, pageSize int) ([]entity.ObjectResponse, int64, error)
-	SearchByContent(ctx context.Context, query string, types []entity.ObjectType, page, pageSize int) ([]ContentSearchResult, int64, error)
+	SearchByName(ctx context.Context, query string, types []entity.ObjectType, pathPrefix string, page, pageSize int) ([]entity.ObjectResponse, int64, error)
+	SearchByContent(ctx context.Context, query string, types []entity.ObjectType, pathPrefix string, page, pageSize int) ([]ContentSearchResult, int64, error)
 	SearchByTag(ctx context.Context, tagName string, page, pageSize int) ([]entity.ObjectResponse, int64, error)
+	DirectoryScope(ctx context.Context, userID uuid.UUID, dirID int64) (string, error)
 }
 
 // ContentSearchResult represents a content search match
@@ -32,26 +36,54 @@ type ContentMatch struct {
 }
 
 type searchUseCase struct {
-	objectRepo repository.ObjectRepository
-	tagRepo    repository.TagRepository
-	storage    *storage.LocalFileStorage
+	objectRepo     repository.ObjectRepository
+	tagRepo        repository.TagRepository
+	permissionRepo repository.PermissionRepository
+	storage        *storage.LocalFileStorage
 }
 
 // NewUseCase creates a new search use case
 func NewUseCase(
 	objectRepo repository.ObjectRepository,
 	tagRepo repository.TagRepository,
+	permissionRepo repository.PermissionRepository,
 	storage *storage.LocalFileStorage,
 ) UseCase {
 	return &searchUseCase{
-		objectRepo: objectRepo,
-		tagRepo:    tagRepo,
-		storage:    storage,
+		objectRepo:     objectRepo,
+		tagRepo:        tagRepo,
+		permissionRepo: permissionRepo,
+		storage:        storage,
+	}
+}
+
+// DirectoryScope returns the path prefix for searching within a directory
+// after checking that it exists, is a directory and is visible to the user
+func (u *searchUseCase) DirectoryScope(ctx context.Context, userID uuid.UUID, dirID int64) (string, error) {
+	dir, err := u.objectRepo.GetByID(ctx, dirID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return "", apperrors.NotFoundError("object")
+		}
+		return "", apperrors.InternalError("failed to get object", err)
 	}
+
+	allowed, err := u.permissionRepo.HasPermission(ctx, dir.ID, userID, entity.RoleViewer)
+	if err != nil {
+		return "", apperrors.InternalError("failed to check permission", err)
+	}
+	if !allowed {
+		return "", apperrors.NotFoundError("object")
+	}
+	if !dir.IsDirectory() {
+		return "", apperrors.InvalidArgumentError("search scope must be a directory", "id")
+	}
+
+	return dir.Path, nil
 }
 
-func (u *searchUseCase) SearchByName(ctx context.Context, query string, types []entity.ObjectType, page, pageSize int) ([]entity.ObjectResponse, int64, error) {
-	objects, total, err := u.objectRepo.Search(ctx, query, types, page, pageSize)
+func (u *searchUseCase) SearchByName(ctx context.Context, query string, types []entity.ObjectType, pathPrefix string, page, pageSize int) ([]entity.ObjectResponse, int64, error) {
+	objects, total, err := u.objectRepo.Search(ctx, query, types, pathPrefix, page, pageSize)
 	if err != nil {
 		return nil, 0, apperrors.InternalError("failed to search objects", err)
 	}
@@ -64,12 +96,13 @@ func (u *searchUseCase) SearchByName(ctx context.Context, query string, types []
 	return responses, total, nil
 }
 
-func (u *searchUseCase) SearchByContent(ctx context.Context, query string, types []entity.ObjectType, page, pageSize int) ([]ContentSearchResult, int64, error) {
+func (u *searchUseCase) SearchByContent(ctx context.Context, query string, types []entity.ObjectType, pathPrefix string, page, pageSize int) ([]ContentSearchResult, int64, error) {
 	// Get all files (with pagination consideration)
 	filter := &entity.ObjectFilter{
-		Type:     types,
-		Page:     1,
-		PageSize: 1000, // Search in up to 1000 files
+		Type:       types,
+		PathPrefix: pathPrefix,
+		Page:       1,
+		PageSize:   1000, // Search in up to 1000 files
 	}
 
 	// Exclude directories from content search
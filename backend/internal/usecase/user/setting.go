@@ -0,0 +1,93 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/google/uuid"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+const (
+	// maxSettingValueSize caps the encoded JSON size of a single setting value
+	maxSettingValueSize = 64 * 1024
+	// maxSettingsPerUser caps the number of distinct keys a user may store
+	maxSettingsPerUser = 100
+)
+
+var settingKeyPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,100}$`)
+
+func validateSettingKey(key string) error {
+	if !settingKeyPattern.MatchString(key) {
+		return apperrors.InvalidArgumentError("setting key must be 1-100 characters of letters, digits, '.', '_' or '-'", "key")
+	}
+	return nil
+}
+
+func (u *userUseCase) GetSetting(ctx context.Context, userID uuid.UUID, key string) (*entity.UserSetting, error) {
+	if err := validateSettingKey(key); err != nil {
+		return nil, err
+	}
+
+	setting, err := u.settingRepo.Get(ctx, userID, key)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("setting")
+		}
+		return nil, apperrors.InternalError("failed to get setting", err)
+	}
+	return setting, nil
+}
+
+func (u *userUseCase) SetSetting(ctx context.Context, userID uuid.UUID, key string, value json.RawMessage) (*entity.UserSetting, error) {
+	if err := validateSettingKey(key); err != nil {
+		return nil, err
+	}
+	if len(value) > maxSettingValueSize {
+		return nil, apperrors.ValidationError(fmt.Sprintf("setting value exceeds %d bytes", maxSettingValueSize))
+	}
+	if len(value) == 0 || !json.Valid(value) {
+		return nil, apperrors.ValidationError("setting value must be valid JSON")
+	}
+
+	// Only new keys count against the per-user limit; overwriting is always allowed
+	if _, err := u.settingRepo.Get(ctx, userID, key); err != nil {
+		if !apperrors.IsNotFound(err) {
+			return nil, apperrors.InternalError("failed to get setting", err)
+		}
+		count, err := u.settingRepo.CountByUser(ctx, userID)
+		if err != nil {
+			return nil, apperrors.InternalError("failed to count settings", err)
+		}
+		if count >= maxSettingsPerUser {
+			return nil, apperrors.ResourceExhaustedError(fmt.Sprintf("at most %d settings are allowed per user", maxSettingsPerUser))
+		}
+	}
+
+	setting := &entity.UserSetting{
+		UserID: userID,
+		Key:    key,
+		Value:  value,
+	}
+	if err := u.settingRepo.Upsert(ctx, setting); err != nil {
+		return nil, apperrors.InternalError("failed to save setting", err)
+	}
+	return setting, nil
+}
+
+func (u *userUseCase) GetAllSettings(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error) {
+	settings, err := u.settingRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to list settings", err)
+	}
+
+	result := make(map[string]json.RawMessage, len(settings))
+	for _, s := range settings {
+		result[s.Key] = s.Value
+	}
+	return result, nil
+}
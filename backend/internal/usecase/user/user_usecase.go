@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/google/uuid"
 
@@ -16,6 +17,9 @@ type UseCase interface {
 	GetByEmail(ctx context.Context, email string) (*entity.UserResponse, error)
 	GetByAppID(ctx context.Context, appID string) ([]*entity.UserResponse, error)
 	Update(ctx context.Context, id uuid.UUID, input *UpdateInput) (*entity.UserResponse, error)
+	GetSetting(ctx context.Context, userID uuid.UUID, key string) (*entity.UserSetting, error)
+	SetSetting(ctx context.Context, userID uuid.UUID, key string, value json.RawMessage) (*entity.UserSetting, error)
+	GetAllSettings(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error)
 }
 
 // UpdateInput represents user update input
@@ -25,12 +29,13 @@ type UpdateInput struct {
 }
 
 type userUseCase struct {
-	userRepo repository.UserRepository
+	userRepo    repository.UserRepository
+	settingRepo repository.UserSettingRepository
 }
 
 // NewUseCase creates a new user use case
-func NewUseCase(userRepo repository.UserRepository) UseCase {
-	return &userUseCase{userRepo: userRepo}
+func NewUseCase(userRepo repository.UserRepository, settingRepo repository.UserSettingRepository) UseCase {
+	return &userUseCase{userRepo: userRepo, settingRepo: settingRepo}
 }
 
 func (u *userUseCase) GetByID(ctx context.Context, id uuid.UUID) (*entity.UserResponse, error) {
@@ -0,0 +1,80 @@
+package version
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// DeleteVersionsResult reports the outcome of a bulk version deletion
+type DeleteVersionsResult struct {
+	Removed   []int `json:"removed"`
+	Protected []int `json:"protected"`           // The current version and the most recent ones
+	NotFound  []int `json:"not_found,omitempty"` // Numbers with no version
+}
+
+// DeleteVersions removes the given versions of an object and their snapshots.
+// Only the owner may delete versions. The current version and the most recent
+// configured number of versions are never removed and are reported as
+// protected instead.
+func (u *versionUseCase) DeleteVersions(ctx context.Context, objectID int64, versionNumbers []int, userID uuid.UUID) (*DeleteVersionsResult, error) {
+	obj, err := u.objectRepo.GetByID(ctx, objectID)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+
+	isOwner, err := u.permissionRepo.HasPermission(ctx, objectID, userID, entity.RoleOwner)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check permission", err)
+	}
+	if !isOwner {
+		return nil, apperrors.ForbiddenError("only the owner can delete versions")
+	}
+
+	recent, _, err := u.versionRepo.ListByObject(ctx, objectID, 1, u.storageConfig.GetVersionKeepMin())
+	if err != nil {
+		return nil, apperrors.InternalError("failed to list versions", err)
+	}
+	protected := []int{obj.CurrentVersion}
+	for _, v := range recent {
+		protected = append(protected, v.VersionNumber)
+	}
+
+	result := &DeleteVersionsResult{Removed: []int{}, Protected: []int{}}
+	numbers := slices.Clone(versionNumbers)
+	slices.Sort(numbers)
+	for _, number := range slices.Compact(numbers) {
+		if slices.Contains(protected, number) {
+			result.Protected = append(result.Protected, number)
+			continue
+		}
+
+		version, err := u.versionRepo.GetByObjectAndNumber(ctx, objectID, number)
+		if err != nil {
+			if apperrors.IsNotFound(err) {
+				result.NotFound = append(result.NotFound, number)
+				continue
+			}
+			return nil, apperrors.InternalError("failed to get version", err)
+		}
+
+		// Drop the record first so no version points at a missing snapshot
+		if err := u.versionRepo.Delete(ctx, version.ID); err != nil {
+			return nil, apperrors.InternalError("failed to delete version", err)
+		}
+		if err := u.storage.DeleteVersion(ctx, version.StoragePath); err != nil {
+			log.Warn().Err(err).Str("path", version.StoragePath).Msg("Failed to delete version snapshot")
+		}
+		result.Removed = append(result.Removed, number)
+	}
+
+	return result, nil
+}
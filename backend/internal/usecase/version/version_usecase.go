@@ -8,6 +8,8 @@ import (
 	"github.com/leondli/workspace/internal/adapter/storage"
 	"github.com/leondli/workspace/internal/domain/entity"
 	"github.com/leondli/workspace/internal/domain/repository"
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	"github.com/leondli/workspace/internal/usecase/event"
 	apperrors "github.com/leondli/workspace/pkg/errors"
 )
 
@@ -17,24 +19,34 @@ type UseCase interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*entity.VersionResponse, error)
 	GetContent(ctx context.Context, versionID uuid.UUID) ([]byte, error)
 	Restore(ctx context.Context, versionID uuid.UUID, userID uuid.UUID) (*entity.ObjectResponse, error)
+	DeleteVersions(ctx context.Context, objectID int64, versionNumbers []int, userID uuid.UUID) (*DeleteVersionsResult, error)
 }
 
 type versionUseCase struct {
-	versionRepo repository.VersionRepository
-	objectRepo  repository.ObjectRepository
-	storage     *storage.LocalFileStorage
+	versionRepo    repository.VersionRepository
+	objectRepo     repository.ObjectRepository
+	permissionRepo repository.PermissionRepository
+	storage        *storage.LocalFileStorage
+	storageConfig  *config.StorageConfig
+	events         event.Publisher
 }
 
 // NewUseCase creates a new version use case
 func NewUseCase(
 	versionRepo repository.VersionRepository,
 	objectRepo repository.ObjectRepository,
+	permissionRepo repository.PermissionRepository,
 	storage *storage.LocalFileStorage,
+	storageConfig *config.StorageConfig,
+	events event.Publisher,
 ) UseCase {
 	return &versionUseCase{
-		versionRepo: versionRepo,
-		objectRepo:  objectRepo,
-		storage:     storage,
+		versionRepo:    versionRepo,
+		objectRepo:     objectRepo,
+		permissionRepo: permissionRepo,
+		storage:        storage,
+		storageConfig:  storageConfig,
+		events:         events,
 	}
 }
 
@@ -137,10 +149,15 @@ func (u *versionUseCase) Restore(ctx context.Context, versionID uuid.UUID, userI
 	obj.ContentHash = version.ContentHash
 	obj.Size = version.Size
 	obj.CurrentVersion = nextVersion
+	obj.Language = entity.DetectLanguage(obj.Name, obj.Type, content, u.storageConfig.GetLanguages())
+	obj.MarkModifiedBy(userID)
 
 	if err := u.objectRepo.Update(ctx, obj); err != nil {
 		return nil, apperrors.InternalError("failed to update object", err)
 	}
 
+	if u.events != nil {
+		u.events.Publish(ctx, event.New(event.ObjectSaved, obj, &userID))
+	}
 	return obj.ToResponse(), nil
 }
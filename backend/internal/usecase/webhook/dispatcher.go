@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/infrastructure/config"
+	"github.com/leondli/workspace/internal/usecase/event"
+)
+
+// Headers sent with each webhook delivery
+const (
+	HeaderEvent     = "X-Workspace-Event"
+	HeaderDelivery  = "X-Workspace-Delivery"  // Same for every attempt, so receivers can drop duplicates
+	HeaderSignature = "X-Workspace-Signature" // "sha256=" + hex HMAC-SHA256 of the body keyed with the webhook secret
+)
+
+// ErrPrivateTarget is returned for webhook targets on loopback, link-local,
+// private or otherwise reserved addresses, unless those are allowed
+var ErrPrivateTarget = errors.New("webhook target is a private or reserved address")
+
+// reservedPrefixes are the special-purpose ranges netip has no predicate for
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "This network"
+	netip.MustParsePrefix("100.64.0.0/10"),  // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // Reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b:1::/48"), // Local-use IPv4/IPv6 translation
+	netip.MustParsePrefix("2001:db8::/32"),  // Documentation
+}
+
+// delivery is one event to be sent to one webhook
+type delivery struct {
+	id        uuid.UUID
+	webhookID uuid.UUID
+	url       string
+	secret    string
+	eventType string
+	body      []byte
+	attempt   int // Attempts made so far
+}
+
+// Dispatcher sends webhook deliveries from a bounded queue in the background,
+// retrying failures with exponential backoff. A delivery waiting for its
+// retry does not hold a worker.
+type Dispatcher struct {
+	cfg      *config.WebhookConfig
+	client   *http.Client
+	queue    chan *delivery
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+	started  atomic.Bool
+}
+
+// NewDispatcher creates a new webhook dispatcher. Unless the config allows
+// private targets, connections to loopback, link-local, private and reserved
+// addresses are refused when dialing, so a host name cannot be pointed at
+// one after the webhook was registered.
+func NewDispatcher(cfg *config.WebhookConfig) *Dispatcher {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !cfg.AllowPrivateTargets {
+		dialer.Control = refusePrivate
+	}
+	return &Dispatcher{
+		cfg: cfg,
+		client: &http.Client{Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		}},
+		queue:    make(chan *delivery, cfg.GetQueueSize()),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// CheckTarget rejects a webhook URL whose host is, or resolves to, an address
+// deliveries would be refused for. Dialing checks again, since the host name
+// may resolve differently later.
+func (d *Dispatcher) CheckTarget(ctx context.Context, target *url.URL) error {
+	if d.cfg.AllowPrivateTargets {
+		return nil
+	}
+	host := target.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !publicAddr(addr) {
+			return ErrPrivateTarget
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("cannot resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return ErrPrivateTarget
+		}
+	}
+	return nil
+}
+
+// refusePrivate is a net.Dialer Control function refusing connections to
+// addresses that are not public
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(addrPort.Addr()) {
+		return ErrPrivateTarget
+	}
+	return nil
+}
+
+// publicAddr reports whether addr is a public unicast address
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Start runs the delivery workers
+func (d *Dispatcher) Start() {
+	if !d.started.CompareAndSwap(false, true) {
+		return
+	}
+	for range d.cfg.GetWorkers() {
+		d.wg.Add(1)
+		go d.work()
+	}
+}
+
+// Stop signals the workers to exit and waits for in-flight attempts. Queued
+// deliveries and pending retries are dropped.
+func (d *Dispatcher) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stopChan)
+	})
+	d.wg.Wait()
+	if n := len(d.queue); n > 0 {
+		log.Warn().Int("count", n).Msg("Dropped queued webhook deliveries on shutdown")
+	}
+}
+
+// Enqueue queues event for webhook, dropping it if the queue is full
+func (d *Dispatcher) Enqueue(webhook *entity.Webhook, e *event.Event, body []byte) {
+	d.push(&delivery{
+		id:        e.ID,
+		webhookID: webhook.ID,
+		url:       webhook.URL,
+		secret:    webhook.Secret,
+		eventType: e.Type,
+		body:      body,
+	})
+}
+
+// push queues item unless the dispatcher has stopped or the queue is full
+func (d *Dispatcher) push(item *delivery) {
+	select {
+	case <-d.stopChan:
+		return
+	default:
+	}
+	select {
+	case d.queue <- item:
+	default:
+		log.Warn().Str("webhook_id", item.webhookID.String()).Str("event", item.eventType).Int("attempts", item.attempt).Msg("Webhook queue full, dropping delivery")
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.stopChan:
+			return
+		case item := <-d.queue:
+			d.deliver(item)
+		}
+	}
+}
+
+// deliver makes one attempt to send item. A failure worth retrying is queued
+// again once its backoff delay has passed; the worker moves on meanwhile.
+func (d *Dispatcher) deliver(item *delivery) {
+	item.attempt++
+	retry, err := d.send(item)
+	if err == nil {
+		return
+	}
+	if !retry || item.attempt >= d.cfg.GetMaxAttempts() {
+		log.Warn().
+			Err(err).
+			Str("webhook_id", item.webhookID.String()).
+			Str("event", item.eventType).
+			Int("attempts", item.attempt).
+			Msg("Webhook delivery failed")
+		return
+	}
+
+	delay := d.cfg.GetRetryBaseDelay() << (item.attempt - 1)
+	if maxDelay := d.cfg.GetRetryMaxDelay(); delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+	time.AfterFunc(delay, func() { d.push(item) })
+}
+
+// send makes one delivery attempt, reporting whether a failure is worth retrying
+func (d *Dispatcher) send(item *delivery) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.cfg.GetDeliveryTimeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, item.url, bytes.NewReader(item.body))
+	if err != nil {
+		return false, err
+	}
+	mac := hmac.New(sha256.New, []byte(item.secret))
+	mac.Write(item.body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, item.eventType)
+	req.Header.Set(HeaderDelivery, item.id.String())
+	req.Header.Set(HeaderSignature, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return !errors.Is(err, ErrPrivateTarget), err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("receiver returned %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("receiver returned %d", resp.StatusCode)
+	}
+}
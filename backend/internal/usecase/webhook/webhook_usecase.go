@@ -0,0 +1,199 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"path"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/leondli/workspace/internal/domain/entity"
+	"github.com/leondli/workspace/internal/domain/repository"
+	"github.com/leondli/workspace/internal/usecase/event"
+	apperrors "github.com/leondli/workspace/pkg/errors"
+)
+
+// UseCase defines the webhook use case interface
+type UseCase interface {
+	event.Publisher
+	RegisterWebhook(ctx context.Context, userID uuid.UUID, input *RegisterInput) (*entity.Webhook, error)
+	ListWebhooks(ctx context.Context, userID uuid.UUID, page, pageSize int) ([]entity.Webhook, int64, error)
+	DeleteWebhook(ctx context.Context, id, userID uuid.UUID) error
+}
+
+// RegisterInput represents webhook registration input
+type RegisterInput struct {
+	ObjectID int64    `json:"object_id" binding:"required"`
+	URL      string   `json:"url" binding:"required,max=2000"`
+	Secret   string   `json:"secret" binding:"max=255"` // Generated when empty
+	Events   []string `json:"events"`                   // Empty subscribes to all event types
+}
+
+type webhookUseCase struct {
+	webhookRepo    repository.WebhookRepository
+	objectRepo     repository.ObjectRepository
+	permissionRepo repository.PermissionRepository
+	dispatcher     *Dispatcher
+}
+
+// NewUseCase creates a new webhook use case
+func NewUseCase(
+	webhookRepo repository.WebhookRepository,
+	objectRepo repository.ObjectRepository,
+	permissionRepo repository.PermissionRepository,
+	dispatcher *Dispatcher,
+) UseCase {
+	return &webhookUseCase{
+		webhookRepo:    webhookRepo,
+		objectRepo:     objectRepo,
+		permissionRepo: permissionRepo,
+		dispatcher:     dispatcher,
+	}
+}
+
+// RegisterWebhook subscribes a URL to events of an object the user can view
+func (u *webhookUseCase) RegisterWebhook(ctx context.Context, userID uuid.UUID, input *RegisterInput) (*entity.Webhook, error) {
+	target, err := url.Parse(input.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, apperrors.InvalidArgumentError("url must be an absolute http or https URL", "url")
+	}
+	if err := u.dispatcher.CheckTarget(ctx, target); err != nil {
+		return nil, apperrors.InvalidArgumentError("url cannot receive webhooks: "+err.Error(), "url")
+	}
+	for _, eventType := range input.Events {
+		if !slices.Contains(event.Types, eventType) {
+			return nil, apperrors.InvalidArgumentError("unknown event type: "+eventType, "events")
+		}
+	}
+
+	if _, err := u.objectRepo.GetByID(ctx, input.ObjectID); err != nil {
+		if apperrors.IsNotFound(err) {
+			return nil, apperrors.NotFoundError("object")
+		}
+		return nil, apperrors.InternalError("failed to get object", err)
+	}
+	allowed, err := u.permissionRepo.HasPermission(ctx, input.ObjectID, userID, entity.RoleViewer)
+	if err != nil {
+		return nil, apperrors.InternalError("failed to check permission", err)
+	}
+	if !allowed {
+		return nil, apperrors.ForbiddenError("no permission to view this object")
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, apperrors.InternalError("failed to generate secret", err)
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	webhook := &entity.Webhook{
+		ObjectID:  input.ObjectID,
+		CreatorID: userID,
+		URL:       input.URL,
+		Secret:    secret,
+		Events:    slices.Compact(slices.Sorted(slices.Values(input.Events))),
+	}
+	if err := u.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, apperrors.InternalError("failed to create webhook", err)
+	}
+	return webhook, nil
+}
+
+// ListWebhooks returns the webhooks registered by a user, without their secrets
+func (u *webhookUseCase) ListWebhooks(ctx context.Context, userID uuid.UUID, page, pageSize int) ([]entity.Webhook, int64, error) {
+	webhooks, total, err := u.webhookRepo.ListByCreator(ctx, userID, page, pageSize)
+	if err != nil {
+		return nil, 0, apperrors.InternalError("failed to list webhooks", err)
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, total, nil
+}
+
+// DeleteWebhook removes a webhook registered by the user
+func (u *webhookUseCase) DeleteWebhook(ctx context.Context, id, userID uuid.UUID) error {
+	webhook, err := u.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		if apperrors.IsNotFound(err) {
+			return apperrors.NotFoundError("webhook")
+		}
+		return apperrors.InternalError("failed to get webhook", err)
+	}
+	if webhook.CreatorID != userID {
+		return apperrors.ForbiddenError("only the creator can delete this webhook")
+	}
+
+	if err := u.webhookRepo.Delete(ctx, id); err != nil {
+		return apperrors.InternalError("failed to delete webhook", err)
+	}
+	return nil
+}
+
+// Publish queues the event for every webhook on the object or on a directory
+// containing it, before or after a move, whose creator can still view the
+// object it was registered on. Paths are sent relative to the workspace root.
+// Failures are logged, never returned, so a change is not undone because its
+// notification failed.
+func (u *webhookUseCase) Publish(ctx context.Context, e *event.Event) {
+	paths := ancestorPaths(e.Path)
+	if e.OldPath != "" {
+		paths = append(paths, ancestorPaths(e.OldPath)...)
+	}
+	webhooks, err := u.webhookRepo.ListMatching(ctx, e.ObjectID, paths)
+	if err != nil {
+		log.Error().Err(err).Str("event", e.Type).Int64("object_id", e.ObjectID).Msg("Failed to find webhooks for event")
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload := *e
+	payload.Path = entity.ConvertToDisplayPath(e.Path)
+	if e.OldPath != "" {
+		payload.OldPath = entity.ConvertToDisplayPath(e.OldPath)
+	}
+	body, err := json.Marshal(&payload)
+	if err != nil {
+		log.Error().Err(err).Str("event", e.Type).Msg("Failed to marshal webhook event")
+		return
+	}
+	for _, webhook := range webhooks {
+		if !webhook.Accepts(e.Type) || !u.canView(ctx, &webhook) {
+			continue
+		}
+		u.dispatcher.Enqueue(&webhook, e, body)
+	}
+}
+
+// canView reports whether a webhook's creator can still view the object it
+// was registered on, so revoking access also stops the deliveries
+func (u *webhookUseCase) canView(ctx context.Context, webhook *entity.Webhook) bool {
+	allowed, err := u.permissionRepo.HasPermission(ctx, webhook.ObjectID, webhook.CreatorID, entity.RoleViewer)
+	if err != nil {
+		log.Error().Err(err).Str("webhook_id", webhook.ID.String()).Msg("Failed to check webhook creator's permission")
+		return false
+	}
+	if !allowed {
+		log.Debug().Str("webhook_id", webhook.ID.String()).Msg("Webhook creator can no longer view the object, skipping delivery")
+	}
+	return allowed
+}
+
+// ancestorPaths returns the paths of the directories containing p
+func ancestorPaths(p string) []string {
+	var paths []string
+	for dir := path.Dir(p); dir != "/" && dir != "."; dir = path.Dir(dir) {
+		paths = append(paths, dir)
+	}
+	return paths
+}
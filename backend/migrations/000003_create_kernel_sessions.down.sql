@@ -0,0 +1,5 @@
+-- Migration: 000003_create_kernel_sessions (rollback)
+-- Description: Drop kernel_sessions table
+
+DROP TRIGGER IF EXISTS update_kernel_sessions_updated_at ON kernel_sessions;
+DROP TABLE IF EXISTS kernel_sessions;
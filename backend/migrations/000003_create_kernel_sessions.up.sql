@@ -0,0 +1,16 @@
+-- Migration: 000003_create_kernel_sessions
+-- Description: Persist kernel ownership so gateway kernels can be reattached after a restart
+
+CREATE TABLE kernel_sessions (
+    kernel_id VARCHAR(255) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    spec_name VARCHAR(255) NOT NULL,
+    is_gateway BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_kernel_sessions_user ON kernel_sessions(user_id);
+
+CREATE TRIGGER update_kernel_sessions_updated_at BEFORE UPDATE ON kernel_sessions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,6 @@
+-- Migration: 000004_add_kernel_session_activity (rollback)
+-- Description: Remove last_activity column from kernel_sessions table
+
+DROP INDEX IF EXISTS idx_kernel_sessions_last_activity;
+
+ALTER TABLE kernel_sessions DROP COLUMN IF EXISTS last_activity;
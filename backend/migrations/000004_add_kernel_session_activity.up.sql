@@ -0,0 +1,6 @@
+-- Migration: 000004_add_kernel_session_activity
+-- Description: Track last activity on kernel sessions and persist local kernels too
+
+ALTER TABLE kernel_sessions ADD COLUMN last_activity TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX idx_kernel_sessions_last_activity ON kernel_sessions(last_activity);
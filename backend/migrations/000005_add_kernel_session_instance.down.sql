@@ -0,0 +1,7 @@
+-- Migration: 000005_add_kernel_session_instance (rollback)
+-- Description: Remove instance columns from kernel_sessions table
+
+DROP INDEX IF EXISTS idx_kernel_sessions_instance;
+
+ALTER TABLE kernel_sessions DROP COLUMN IF EXISTS instance_addr;
+ALTER TABLE kernel_sessions DROP COLUMN IF EXISTS instance_id;
@@ -0,0 +1,7 @@
+-- Migration: 000005_add_kernel_session_instance
+-- Description: Record which server instance owns each kernel for multi-instance routing
+
+ALTER TABLE kernel_sessions ADD COLUMN instance_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE kernel_sessions ADD COLUMN instance_addr VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_kernel_sessions_instance ON kernel_sessions(instance_id);
@@ -0,0 +1,2 @@
+-- Migration: 000006_backfill_object_parents (rollback)
+-- Description: No-op; backfilled parent_id values are consistent with paths and are kept
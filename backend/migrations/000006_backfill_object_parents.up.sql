@@ -0,0 +1,9 @@
+-- Migration: 000006_backfill_object_parents
+-- Description: Backfill missing parent_id on objects from their path
+
+UPDATE objects AS child
+SET parent_id = parent.id
+FROM objects AS parent
+WHERE child.parent_id IS NULL
+  AND parent.type = 'directory'
+  AND parent.path = regexp_replace(child.path, '/[^/]+$', '');
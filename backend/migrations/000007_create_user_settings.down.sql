@@ -0,0 +1,5 @@
+-- Migration: 000007_create_user_settings (rollback)
+-- Description: Drop user_settings table
+
+DROP TRIGGER IF EXISTS update_user_settings_updated_at ON user_settings;
+DROP TABLE IF EXISTS user_settings;
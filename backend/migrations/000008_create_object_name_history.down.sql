@@ -0,0 +1,4 @@
+-- Migration: 000008_create_object_name_history (rollback)
+-- Description: Drop object_name_history table
+
+DROP TABLE IF EXISTS object_name_history;
@@ -0,0 +1,14 @@
+-- Migration: 000008_create_object_name_history
+-- Description: Record former object names on rename for search and audit
+
+CREATE TABLE object_name_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    object_id BIGINT NOT NULL REFERENCES objects(id) ON DELETE CASCADE,
+    old_name VARCHAR(255) NOT NULL,
+    new_name VARCHAR(255) NOT NULL,
+    renamed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    renamed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_object_name_history_object ON object_name_history(object_id);
+CREATE INDEX idx_object_name_history_old_name ON object_name_history(old_name);
@@ -0,0 +1,6 @@
+-- Migration: 000009_add_object_last_modified_by (rollback)
+-- Description: Remove last_modified_by column from objects table
+
+DROP INDEX IF EXISTS idx_objects_last_modified_by;
+
+ALTER TABLE objects DROP COLUMN IF EXISTS last_modified_by;
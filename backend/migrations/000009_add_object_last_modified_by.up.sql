@@ -0,0 +1,15 @@
+-- Migration: 000009_add_object_last_modified_by
+-- Description: Record which user last modified each object
+
+ALTER TABLE objects ADD COLUMN last_modified_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- Backfill from the author of the current version, falling back to the creator
+UPDATE objects o
+SET last_modified_by = COALESCE(
+    (SELECT v.creator_id FROM versions v
+     WHERE v.object_id = o.id AND v.version_number = o.current_version
+     LIMIT 1),
+    o.creator_id
+);
+
+CREATE INDEX idx_objects_last_modified_by ON objects(last_modified_by);
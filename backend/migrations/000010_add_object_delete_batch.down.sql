@@ -0,0 +1,6 @@
+-- Migration: 000010_add_object_delete_batch (rollback)
+-- Description: Remove delete_batch_id column from objects table
+
+DROP INDEX IF EXISTS idx_objects_delete_batch_id;
+
+ALTER TABLE objects DROP COLUMN IF EXISTS delete_batch_id;
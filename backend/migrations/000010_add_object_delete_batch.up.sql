@@ -0,0 +1,6 @@
+-- Migration: 000010_add_object_delete_batch
+-- Description: Group objects soft-deleted by one delete operation so they can be restored together
+
+ALTER TABLE objects ADD COLUMN delete_batch_id UUID;
+
+CREATE INDEX idx_objects_delete_batch_id ON objects(delete_batch_id);
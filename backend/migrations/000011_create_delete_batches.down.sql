@@ -0,0 +1,6 @@
+-- Migration: 000011_create_delete_batches (rollback)
+-- Description: Drop delete_batches table
+
+ALTER TABLE objects DROP CONSTRAINT IF EXISTS fk_objects_delete_batch;
+
+DROP TABLE IF EXISTS delete_batches;
@@ -0,0 +1,30 @@
+-- Migration: 000011_create_delete_batches
+-- Description: Record each delete operation so its objects can be listed and restored as one trash entry
+
+CREATE TABLE delete_batches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    root_object_id BIGINT NOT NULL REFERENCES objects(id) ON DELETE CASCADE,
+    root_name VARCHAR(255) NOT NULL,
+    root_path VARCHAR(1000) NOT NULL,
+    item_count INT NOT NULL DEFAULT 1,
+    deleted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    deleted_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_delete_batches_deleted_by ON delete_batches(deleted_by, deleted_at DESC);
+
+-- Backfill batches created before this table existed; the root of a batch is
+-- the object whose parent is not part of the same batch
+INSERT INTO delete_batches (id, root_object_id, root_name, root_path, item_count, deleted_at)
+SELECT o.delete_batch_id, o.id, o.name, o.path,
+       (SELECT COUNT(*) FROM objects c WHERE c.delete_batch_id = o.delete_batch_id),
+       o.deleted_at
+FROM objects o
+WHERE o.delete_batch_id IS NOT NULL
+  AND NOT EXISTS (
+      SELECT 1 FROM objects p
+      WHERE p.id = o.parent_id AND p.delete_batch_id = o.delete_batch_id
+  );
+
+ALTER TABLE objects ADD CONSTRAINT fk_objects_delete_batch
+    FOREIGN KEY (delete_batch_id) REFERENCES delete_batches(id) ON DELETE SET NULL;
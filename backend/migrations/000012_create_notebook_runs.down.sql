@@ -0,0 +1,4 @@
+-- Migration: 000012_create_notebook_runs (rollback)
+-- Description: Drop notebook_runs table
+
+DROP TABLE IF EXISTS notebook_runs;
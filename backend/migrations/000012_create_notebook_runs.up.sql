@@ -0,0 +1,16 @@
+-- Migration: 000012_create_notebook_runs
+-- Description: Record notebook executions and the version each run saved
+
+CREATE TABLE notebook_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    object_id BIGINT NOT NULL REFERENCES objects(id) ON DELETE CASCADE,
+    version_id UUID REFERENCES versions(id) ON DELETE SET NULL,
+    version_number INT NOT NULL,
+    kernel_id VARCHAR(255) NOT NULL,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    run_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ran_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_notebook_runs_object ON notebook_runs(object_id, ran_at DESC);
+CREATE INDEX idx_notebook_runs_version ON notebook_runs(version_id);
@@ -0,0 +1,4 @@
+-- Migration: 000013_add_object_path_lower_index (rollback)
+-- Description: Drop case-folded path index
+
+DROP INDEX IF EXISTS idx_objects_path_lower;
@@ -0,0 +1,4 @@
+-- Migration: 000013_add_object_path_lower_index
+-- Description: Index case-folded paths for case-insensitive name uniqueness checks
+
+CREATE INDEX idx_objects_path_lower ON objects(LOWER(path)) WHERE is_deleted = false;
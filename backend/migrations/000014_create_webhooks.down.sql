@@ -0,0 +1,4 @@
+-- Migration: 000014_create_webhooks (rollback)
+-- Description: Drop webhooks table
+
+DROP TABLE IF EXISTS webhooks;
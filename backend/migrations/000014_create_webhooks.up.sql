@@ -0,0 +1,15 @@
+-- Migration: 000014_create_webhooks
+-- Description: Webhook subscriptions notified of changes to an object or, for directories, its descendants
+
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    object_id BIGINT NOT NULL REFERENCES objects(id) ON DELETE CASCADE,
+    creator_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url VARCHAR(2000) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    events VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhooks_object ON webhooks(object_id);
+CREATE INDEX idx_webhooks_creator ON webhooks(creator_id, created_at DESC);
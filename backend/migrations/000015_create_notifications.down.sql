@@ -0,0 +1,4 @@
+-- Migration: 000015_create_notifications (rollback)
+-- Description: Drop notifications table
+
+DROP TABLE IF EXISTS notifications;
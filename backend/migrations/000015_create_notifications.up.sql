@@ -0,0 +1,17 @@
+-- Migration: 000015_create_notifications
+-- Description: In-app notifications, e.g. when an object is shared with a user
+
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    object_id BIGINT REFERENCES objects(id) ON DELETE CASCADE,
+    object_name VARCHAR(255) NOT NULL DEFAULT '',
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    role VARCHAR(20) NOT NULL DEFAULT '',
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_notifications_user ON notifications(user_id, created_at DESC);
+CREATE INDEX idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
@@ -0,0 +1,4 @@
+-- Migration: 000016_add_object_content_hash_index (rollback)
+-- Description: Drop content hash index
+
+DROP INDEX IF EXISTS idx_objects_creator_content_hash;
@@ -0,0 +1,4 @@
+-- Migration: 000016_add_object_content_hash_index
+-- Description: Index content hashes per creator for lookups by content
+
+CREATE INDEX idx_objects_creator_content_hash ON objects(creator_id, content_hash) WHERE is_deleted = false;
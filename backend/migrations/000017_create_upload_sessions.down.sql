@@ -0,0 +1,4 @@
+-- Migration: 000017_create_upload_sessions (rollback)
+-- Description: Drop upload_sessions table
+
+DROP TABLE IF EXISTS upload_sessions;
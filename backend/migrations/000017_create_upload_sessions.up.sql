@@ -0,0 +1,19 @@
+-- Migration: 000017_create_upload_sessions
+-- Description: Track resumable chunked uploads until they are committed as objects
+
+CREATE TABLE upload_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL DEFAULT '',
+    parent_id BIGINT,
+    description TEXT NOT NULL DEFAULT '',
+    size BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL DEFAULT '',
+    received BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_upload_sessions_expires ON upload_sessions(expires_at);
@@ -0,0 +1,4 @@
+-- Migration: 000018_add_object_mime_type (rollback)
+-- Description: Drop object MIME type override
+
+ALTER TABLE objects DROP COLUMN IF EXISTS mime_type;
@@ -0,0 +1,4 @@
+-- Migration: 000018_add_object_mime_type
+-- Description: Store a user-set MIME type overriding the one inferred from the name
+
+ALTER TABLE objects ADD COLUMN mime_type VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+-- Migration: 000019_add_object_language (rollback)
+-- Description: Drop object language
+
+ALTER TABLE objects DROP COLUMN IF EXISTS language;
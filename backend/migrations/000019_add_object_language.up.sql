@@ -0,0 +1,29 @@
+-- Migration: 000019_add_object_language
+-- Description: Store the syntax highlighting language of files, backfilled from extensions
+
+ALTER TABLE objects ADD COLUMN language VARCHAR(50) NOT NULL DEFAULT '';
+
+-- Notebooks get the language from their metadata on the next save; until then
+-- assume the default Python kernel
+UPDATE objects SET language = CASE
+    WHEN type = 'notebook' THEN 'python'
+    WHEN lower(name) ~ '\.pyi?$' THEN 'python'
+    WHEN lower(name) ~ '\.r$' THEN 'r'
+    WHEN lower(name) ~ '\.sql$' THEN 'sql'
+    WHEN lower(name) ~ '\.scala$' THEN 'scala'
+    WHEN lower(name) ~ '\.java$' THEN 'java'
+    WHEN lower(name) ~ '\.go$' THEN 'go'
+    WHEN lower(name) ~ '\.m?js$' THEN 'javascript'
+    WHEN lower(name) ~ '\.ts$' THEN 'typescript'
+    WHEN lower(name) ~ '\.(sh|bash)$' THEN 'shell'
+    WHEN lower(name) ~ '\.(md|markdown)$' THEN 'markdown'
+    WHEN lower(name) ~ '\.json$' THEN 'json'
+    WHEN lower(name) ~ '\.ya?ml$' THEN 'yaml'
+    WHEN lower(name) ~ '\.toml$' THEN 'toml'
+    WHEN lower(name) ~ '\.(ini|conf|cfg)$' THEN 'ini'
+    WHEN lower(name) ~ '\.xml$' THEN 'xml'
+    WHEN lower(name) ~ '\.html$' THEN 'html'
+    WHEN lower(name) ~ '\.css$' THEN 'css'
+    ELSE 'plaintext'
+END
+WHERE type <> 'directory';
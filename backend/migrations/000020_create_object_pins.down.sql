@@ -0,0 +1,4 @@
+-- Migration: 000020_create_object_pins (rollback)
+-- Description: Drop object pins
+
+DROP TABLE IF EXISTS object_pins;
@@ -0,0 +1,11 @@
+-- Migration: 000020_create_object_pins
+-- Description: Objects pinned to the top of their directory, per user or shared (nil user ID)
+
+CREATE TABLE object_pins (
+    object_id BIGINT NOT NULL REFERENCES objects(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (object_id, user_id)
+);
+
+CREATE INDEX idx_object_pins_user ON object_pins(user_id);
@@ -0,0 +1,7 @@
+-- Migration: 000021_add_object_access_stats (rollback)
+-- Description: Drop object access statistics
+
+DROP TABLE IF EXISTS object_access_log;
+
+ALTER TABLE objects DROP COLUMN IF EXISTS last_accessed_at;
+ALTER TABLE objects DROP COLUMN IF EXISTS download_count;
@@ -0,0 +1,14 @@
+-- Migration: 000021_add_object_access_stats
+-- Description: Count object downloads and record access times, with an optional per-user access log
+
+ALTER TABLE objects ADD COLUMN download_count BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE objects ADD COLUMN last_accessed_at TIMESTAMP WITH TIME ZONE;
+
+-- One row per user and object, written only while the access log is enabled
+CREATE TABLE object_access_log (
+    object_id BIGINT NOT NULL REFERENCES objects(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    access_count BIGINT NOT NULL DEFAULT 0,
+    last_accessed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (object_id, user_id)
+);
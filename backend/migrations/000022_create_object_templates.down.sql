@@ -0,0 +1,4 @@
+-- Migration: 000022_create_object_templates (rollback)
+-- Description: Drop object_templates table
+
+DROP TABLE IF EXISTS object_templates;
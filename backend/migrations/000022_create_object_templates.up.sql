@@ -0,0 +1,15 @@
+-- Migration: 000022_create_object_templates
+-- Description: Files offered as starting points for new files, per user or shared (NULL owner)
+
+CREATE TABLE object_templates (
+    id BIGSERIAL PRIMARY KEY,
+    object_id BIGINT NOT NULL REFERENCES objects(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_object_templates_owner ON object_templates(owner_id);
+CREATE INDEX idx_object_templates_object ON object_templates(object_id);
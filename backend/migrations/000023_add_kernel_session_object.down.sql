@@ -0,0 +1,6 @@
+-- Migration: 000023_add_kernel_session_object (rollback)
+-- Description: Remove object_id column from kernel_sessions table
+
+DROP INDEX IF EXISTS idx_kernel_sessions_object;
+
+ALTER TABLE kernel_sessions DROP COLUMN IF EXISTS object_id;
@@ -0,0 +1,6 @@
+-- Migration: 000023_add_kernel_session_object
+-- Description: Record the notebook each kernel was started for, so collaborators can find its live sessions
+
+ALTER TABLE kernel_sessions ADD COLUMN object_id BIGINT REFERENCES objects(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_kernel_sessions_object ON kernel_sessions(object_id);
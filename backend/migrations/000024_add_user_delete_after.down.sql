@@ -0,0 +1,6 @@
+-- Migration: 000024_add_user_delete_after (rollback)
+-- Description: Remove delete_after column from users table
+
+DROP INDEX IF EXISTS idx_users_delete_after;
+
+ALTER TABLE users DROP COLUMN IF EXISTS delete_after;
@@ -0,0 +1,6 @@
+-- Migration: 000024_add_user_delete_after
+-- Description: When an account deleted with a grace period is erased
+
+ALTER TABLE users ADD COLUMN delete_after TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_users_delete_after ON users(delete_after) WHERE delete_after IS NOT NULL;
@@ -0,0 +1,66 @@
+package charset
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Charset names reported by Detect
+const (
+	UTF8        = "utf-8"
+	UTF16LE     = "utf-16le"
+	UTF16BE     = "utf-16be"
+	GB18030     = "gb18030"
+	Windows1252 = "windows-1252"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// sniffLen is how much content is inspected for NUL bytes when deciding
+// whether content is binary
+const sniffLen = 8000
+
+// ToUTF8 detects the charset of text content and converts it to UTF-8,
+// stripping any byte order mark. It returns ok=false for binary content,
+// which is returned unchanged.
+//
+// Detection order: byte order mark, valid UTF-8, GB18030, then Windows-1252,
+// which accepts any byte sequence.
+func ToUTF8(data []byte) (converted []byte, charset string, ok bool) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return data[len(bomUTF8):], UTF8, true
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return decode(data, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), UTF16LE)
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return decode(data, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), UTF16BE)
+	}
+
+	if bytes.IndexByte(data[:min(len(data), sniffLen)], 0) >= 0 {
+		return data, "", false
+	}
+	if utf8.Valid(data) {
+		return data, UTF8, true
+	}
+	if converted, charset, ok := decode(data, simplifiedchinese.GB18030, GB18030); ok && !bytes.ContainsRune(converted, utf8.RuneError) {
+		return converted, charset, true
+	}
+	return decode(data, charmap.Windows1252, Windows1252)
+}
+
+func decode(data []byte, enc encoding.Encoding, charset string) ([]byte, string, bool) {
+	converted, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return data, "", false
+	}
+	return converted, charset, true
+}
@@ -18,16 +18,20 @@ const (
 	CodeValidationError   = "VALIDATION_ERROR"
 	CodeInvalidArgument   = "INVALID_ARGUMENT"
 	CodeResourceExhausted = "RESOURCE_EXHAUSTED"
+	CodeUpdateConflict    = "CONFLICT"
+	CodeUnavailable       = "UNAVAILABLE"
 )
 
 // Application error codes
 var (
 	ErrNotFound          = errors.New("resource not found")
 	ErrAlreadyExists     = errors.New("resource already exists")
+	ErrConflict          = errors.New("resource was modified concurrently")
 	ErrUnauthorized      = errors.New("unauthorized")
 	ErrForbidden         = errors.New("forbidden")
 	ErrInvalidInput      = errors.New("invalid input")
 	ErrInternalServer    = errors.New("internal server error")
+	ErrUnavailable       = errors.New("service unavailable")
 	ErrInvalidCredential = errors.New("invalid credentials")
 	ErrTokenExpired      = errors.New("token expired")
 	ErrTokenInvalid      = errors.New("invalid token")
@@ -98,6 +102,17 @@ func AlreadyExistsError(resource string) *AppError {
 	}
 }
 
+// ConflictError creates an error for an update that lost a race with a
+// concurrent modification; the client should refetch and retry
+func ConflictError(resource string) *AppError {
+	return &AppError{
+		Code:     CodeUpdateConflict,
+		HTTPCode: http.StatusConflict,
+		Message:  fmt.Sprintf("%s was modified by another request, please refetch and retry", resource),
+		Err:      ErrConflict,
+	}
+}
+
 // UnauthorizedError creates an unauthorized error
 func UnauthorizedError(message string) *AppError {
 	return &AppError{
@@ -184,6 +199,27 @@ func BadRequestError(message string) *AppError {
 	}
 }
 
+// ResourceExhaustedError creates an error for a request that would exceed a quota
+func ResourceExhaustedError(message string) *AppError {
+	return &AppError{
+		Code:     CodeResourceExhausted,
+		HTTPCode: http.StatusBadRequest,
+		Message:  message,
+		Err:      ErrInvalidInput,
+	}
+}
+
+// UnavailableError creates an error for a feature this server is not set up
+// to provide; message should tell operators what to configure
+func UnavailableError(message string) *AppError {
+	return &AppError{
+		Code:     CodeUnavailable,
+		HTTPCode: http.StatusServiceUnavailable,
+		Message:  message,
+		Err:      ErrUnavailable,
+	}
+}
+
 // IsNotFound checks if the error is a not found error
 func IsNotFound(err error) bool {
 	return errors.Is(err, ErrNotFound)
@@ -194,6 +230,11 @@ func IsAlreadyExists(err error) bool {
 	return errors.Is(err, ErrAlreadyExists)
 }
 
+// IsConflict checks if the error is a concurrent modification conflict
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
 // IsUnauthorized checks if the error is an unauthorized error
 func IsUnauthorized(err error) bool {
 	return errors.Is(err, ErrUnauthorized)
@@ -204,6 +245,16 @@ func IsForbidden(err error) bool {
 	return errors.Is(err, ErrForbidden)
 }
 
+// IsInvalidInput checks if the error is a validation or bad request error
+func IsInvalidInput(err error) bool {
+	return errors.Is(err, ErrInvalidInput)
+}
+
+// IsUnavailable checks if the error is an unavailable feature error
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrUnavailable)
+}
+
 // GetAppError attempts to extract AppError from error chain
 func GetAppError(err error) *AppError {
 	var appErr *AppError
@@ -0,0 +1,53 @@
+package jwt
+
+import (
+	"sync"
+	"time"
+)
+
+// Denylist tracks users whose outstanding access tokens have been revoked.
+// An entry only needs to live for one access token lifetime: after that every
+// token issued before the revocation has expired on its own. Entries are kept
+// in memory, so each instance only rejects revocations it has seen.
+type Denylist struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time // user ID -> revocation time
+	ttl     time.Duration
+}
+
+// NewDenylist creates a denylist whose entries expire after ttl
+func NewDenylist(ttl time.Duration) *Denylist {
+	return &Denylist{
+		revoked: make(map[string]time.Time),
+		ttl:     ttl,
+	}
+}
+
+// RevokeUser rejects all access tokens issued to the user up to now
+func (d *Denylist) RevokeUser(userID string) {
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Drop entries whose tokens have all expired
+	for id, revokedAt := range d.revoked {
+		if now.Sub(revokedAt) > d.ttl {
+			delete(d.revoked, id)
+		}
+	}
+	d.revoked[userID] = now
+}
+
+// IsRevoked reports whether a token issued to the user at issuedAt has been revoked
+func (d *Denylist) IsRevoked(userID string, issuedAt time.Time) bool {
+	d.mu.RLock()
+	revokedAt, ok := d.revoked[userID]
+	d.mu.RUnlock()
+
+	if !ok || time.Since(revokedAt) > d.ttl {
+		return false
+	}
+	// IssuedAt has second precision, so tokens from the same second are rejected too
+	return !issuedAt.After(revokedAt.Truncate(time.Second))
+}
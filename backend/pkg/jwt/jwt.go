@@ -13,6 +13,7 @@ import (
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrRevokedToken = errors.New("token has been revoked")
 )
 
 // Claims represents the JWT claims
@@ -37,6 +38,7 @@ type JWTManager struct {
 	accessTokenExpiry  time.Duration
 	refreshTokenExpiry time.Duration
 	issuer             string
+	denylist           *Denylist
 }
 
 // NewJWTManager creates a new JWT manager
@@ -46,6 +48,7 @@ func NewJWTManager(secret string, accessExpiry, refreshExpiry time.Duration, iss
 		accessTokenExpiry:  accessExpiry,
 		refreshTokenExpiry: refreshExpiry,
 		issuer:             issuer,
+		denylist:           NewDenylist(accessExpiry),
 	}
 }
 
@@ -113,9 +116,18 @@ func (m *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
 		return nil, ErrInvalidToken
 	}
 
+	if claims.IssuedAt != nil && m.denylist.IsRevoked(claims.UserID, claims.IssuedAt.Time) {
+		return nil, ErrRevokedToken
+	}
+
 	return claims, nil
 }
 
+// RevokeUserTokens rejects every access token issued to the user so far
+func (m *JWTManager) RevokeUserTokens(userID string) {
+	m.denylist.RevokeUser(userID)
+}
+
 // HashRefreshToken hashes a refresh token for storage
 func HashRefreshToken(token string) string {
 	hash := sha256.Sum256([]byte(token))
@@ -20,6 +20,11 @@ const (
 	CodeValidationError  = "VALIDATION_ERROR"
 	CodeInvalidArgument  = "INVALID_ARGUMENT"
 	CodeResourceExhausted = "RESOURCE_EXHAUSTED"
+	CodeMisdirected       = "MISDIRECTED_REQUEST"
+	CodeUpdateConflict    = "CONFLICT"
+	CodePayloadTooLarge   = "PAYLOAD_TOO_LARGE"
+	CodeFailedPrecondition = "FAILED_PRECONDITION"
+	CodeUnavailable        = "UNAVAILABLE"
 )
 
 // RequestIDKey is the key used to store request ID in gin context
@@ -195,6 +200,11 @@ func ConflictWithReason(c *gin.Context, message string, reason string, metadata
 	ErrorWithReason(c, http.StatusConflict, CodeConflict, message, reason, metadata)
 }
 
+// PayloadTooLarge sends a payload too large response
+func PayloadTooLarge(c *gin.Context, message string) {
+	Error(c, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, message)
+}
+
 // InternalError sends an internal server error response
 func InternalError(c *gin.Context, message string) {
 	Error(c, http.StatusInternalServerError, CodeInternalError, message)
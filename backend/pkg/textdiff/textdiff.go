@@ -0,0 +1,232 @@
+package textdiff
+
+import "strings"
+
+// Op is the kind of change a diff line represents
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+// MaxEditDistance bounds the work done by Diff. Inputs needing more line
+// edits than this are reported as a full replacement, which keeps diffing of
+// unrelated files cheap.
+const MaxEditDistance = 1000
+
+// Line is one line of a diff. OldLine and NewLine are 1-based line numbers
+// in the old and new text; the one a line does not appear in is 0.
+type Line struct {
+	Op      Op     `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// Hunk is a run of changed lines with surrounding context, as in a unified diff
+type Hunk struct {
+	OldStart int    `json:"old_start"`
+	OldLines int    `json:"old_lines"`
+	NewStart int    `json:"new_start"`
+	NewLines int    `json:"new_lines"`
+	Lines    []Line `json:"lines"`
+}
+
+// SplitLines splits text into lines without their line endings
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Diff returns the line-by-line edit script turning a into b, using Myers'
+// algorithm after trimming the common prefix and suffix
+func Diff(a, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, Line{Op: OpEqual, Text: a[i]})
+	}
+	lines = append(lines, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for i := len(a) - suffix; i < len(a); i++ {
+		lines = append(lines, Line{Op: OpEqual, Text: a[i]})
+	}
+
+	oldLine, newLine := 0, 0
+	for i := range lines {
+		switch lines[i].Op {
+		case OpEqual:
+			oldLine++
+			newLine++
+			lines[i].OldLine, lines[i].NewLine = oldLine, newLine
+		case OpDelete:
+			oldLine++
+			lines[i].OldLine = oldLine
+		case OpInsert:
+			newLine++
+			lines[i].NewLine = newLine
+		}
+	}
+	return lines
+}
+
+// myers computes a shortest edit script, falling back to deleting all of a
+// and inserting all of b when more than MaxEditDistance edits are needed
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	maxD := min(n+m, MaxEditDistance)
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// trace[d] holds v[-d..d] as it was at the start of round d
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+
+	lines := make([]Line, 0, n+m)
+	for _, text := range a {
+		lines = append(lines, Line{Op: OpDelete, Text: text})
+	}
+	for _, text := range b {
+		lines = append(lines, Line{Op: OpInsert, Text: text})
+	}
+	return lines
+}
+
+func backtrack(trace [][]int, a, b []string) []Line {
+	x, y := len(a), len(b)
+	var reversed []Line
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d] }
+
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Line{Op: OpEqual, Text: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, Line{Op: OpInsert, Text: b[y-1]})
+		} else {
+			reversed = append(reversed, Line{Op: OpDelete, Text: a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, Line{Op: OpEqual, Text: a[x-1]})
+		x--
+		y--
+	}
+
+	lines := make([]Line, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
+
+// Hunks groups the changes in lines into hunks with up to context unchanged
+// lines around them. Identical input yields no hunks.
+func Hunks(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+	for i := 0; i < len(lines); {
+		if lines[i].Op == OpEqual {
+			i++
+			continue
+		}
+
+		start := max(i-context, 0)
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != OpEqual {
+				end++
+				continue
+			}
+			// Merge with the next change if the unchanged gap is small enough
+			gap := end
+			for gap < len(lines) && lines[gap].Op == OpEqual {
+				gap++
+			}
+			if gap == len(lines) || gap-end > 2*context {
+				end = min(end+context, len(lines))
+				break
+			}
+			end = gap
+		}
+
+		hunks = append(hunks, newHunk(lines[start:end]))
+		i = end
+	}
+	return hunks
+}
+
+func newHunk(lines []Line) Hunk {
+	h := Hunk{Lines: lines}
+	for _, line := range lines {
+		if line.Op != OpInsert {
+			if h.OldStart == 0 {
+				h.OldStart = line.OldLine
+			}
+			h.OldLines++
+		}
+		if line.Op != OpDelete {
+			if h.NewStart == 0 {
+				h.NewStart = line.NewLine
+			}
+			h.NewLines++
+		}
+	}
+	return h
+}
+
+// Stats counts the inserted and deleted lines in lines
+func Stats(lines []Line) (additions, deletions int) {
+	for _, line := range lines {
+		switch line.Op {
+		case OpInsert:
+			additions++
+		case OpDelete:
+			deletions++
+		}
+	}
+	return additions, deletions
+}
@@ -1,5 +1,5 @@
 import axios, { AxiosInstance, AxiosError, AxiosResponse } from 'axios';
-import { ApiResponse, ApiErrorResponse, FileItem, SearchSuggestion, RecentItem, PaginatedResponse, UserResponse, API_CODE } from '../types';
+import { ApiResponse, ApiErrorResponse, DeleteBatch, FileItem, FileType, NotebookRun, OperationPlan, SearchSuggestion, RecentItem, PaginatedResponse, UserResponse, Webhook, UserNotification, UploadSession, ValidationResult, ObjectAccessStats, ExportFormat, ExportJob, ClientConfig, MaintenanceMode, ObjectTemplate, API_CODE } from '../types';
 import { v4 as uuidv4 } from 'uuid';
 
 // Token 管理
@@ -194,10 +194,146 @@ export const getObjectById = async (id: number): Promise<FileItem> => {
   return response.data.data!;
 };
 
+// 创建分片上传会话
+export const startUpload = async (
+  name: string,
+  size: number,
+  options: { parentId?: number; description?: string; hash?: string } = {}
+): Promise<UploadSession> => {
+  const response = await apiClient.post<ApiResponse<UploadSession>>('/api/v1/uploads', {
+    name,
+    size,
+    parent_id: options.parentId,
+    description: options.description,
+    hash: options.hash
+  });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 获取上传进度（断点续传时从 received 处继续）
+export const getUpload = async (id: string): Promise<UploadSession> => {
+  const response = await apiClient.get<ApiResponse<UploadSession>>(`/api/v1/uploads/${id}`);
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 上传一个分片，offset 必须等于已接收字节数
+export const uploadChunk = async (id: string, offset: number, chunk: Blob): Promise<UploadSession> => {
+  const response = await apiClient.put<ApiResponse<UploadSession>>(`/api/v1/uploads/${id}`, chunk, {
+    params: { offset },
+    headers: { 'Content-Type': 'application/octet-stream' }
+  });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 完成分片上传，创建文件
+export const completeUpload = async (id: string): Promise<FileItem> => {
+  const response = await apiClient.post<ApiResponse<FileItem>>(`/api/v1/uploads/${id}/complete`);
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 取消分片上传
+export const abortUpload = async (id: string): Promise<void> => {
+  const response = await apiClient.delete<ApiResponse>(`/api/v1/uploads/${id}`);
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+};
+
+// 按内容哈希（SHA-256 十六进制）查找当前用户已有的文件，上传前可用于去重
+export const findObjectsByHash = async (hash: string): Promise<FileItem[]> => {
+  const response = await apiClient.get<ApiResponse<FileItem[]>>('/api/v1/objects/by-hash', {
+    params: { hash }
+  });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 修正文件类型：type 为空时保持不变，mimeType 为空时清除覆盖
+export const setContentType = async (fileId: number, type?: FileType, mimeType?: string): Promise<FileItem> => {
+  const response = await apiClient.put<ApiResponse<FileItem>>(`/api/v1/objects/${fileId}/content-type`, {
+    type,
+    mime_type: mimeType
+  });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 置顶或取消置顶对象
+export const setPinned = async (fileId: number, pinned: boolean = true): Promise<FileItem> => {
+  const response = await apiClient.post<ApiResponse<FileItem>>(`/api/v1/objects/${fileId}/pin`, { pinned });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 保存前校验内容格式（不保存）；type 省略时按 name 推断
+export const validateContent = async (content: string, options: { type?: FileType; name?: string }): Promise<ValidationResult> => {
+  const response = await apiClient.post<ApiResponse<ValidationResult>>('/api/v1/objects/validate', { ...options, content });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 获取客户端配置，包括维护横幅和只读模式
+export const getClientConfig = async (): Promise<ClientConfig> => {
+  const response = await apiClient.get<ApiResponse<ClientConfig>>('/api/v1/config');
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 设置维护模式（管理员）
+export const setMaintenanceMode = async (mode: MaintenanceMode): Promise<MaintenanceMode> => {
+  const response = await apiClient.put<ApiResponse<MaintenanceMode>>('/api/v1/admin/maintenance/mode', mode);
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 获取文件访问统计
+export const getAccessStats = async (fileId: number): Promise<ObjectAccessStats> => {
+  const response = await apiClient.get<ApiResponse<ObjectAccessStats>>(`/api/v1/objects/${fileId}/access-stats`);
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 服务端渲染 Markdown 文件，返回已清洗的 HTML
+export const renderMarkdown = async (fileId: number): Promise<string> => {
+  const response = await apiClient.get<ApiResponse<{ html: string }>>(`/api/v1/objects/${fileId}/render`);
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!.html;
+};
+
 // 获取文件内容（二进制转字符串）
-export const getFileContent = async (fileId: number): Promise<string> => {
+// includeOutputs 为 false 时去掉 Notebook 的单元格输出
+export const getFileContent = async (fileId: number, includeOutputs: boolean = true): Promise<string> => {
   const response = await apiClient.get(`/api/v1/objects/${fileId}/content`, {
-    responseType: 'arraybuffer'
+    responseType: 'arraybuffer',
+    params: includeOutputs ? undefined : { include_outputs: false }
   });
   // 将 ArrayBuffer 转换为字符串
   const decoder = new TextDecoder('utf-8');
@@ -294,6 +430,52 @@ export const createFile = async (
   return response.data.data!;
 };
 
+// 获取可用的文件模板：共享模板和自己的模板
+export const getTemplates = async (): Promise<ObjectTemplate[]> => {
+  const response = await apiClient.get<ApiResponse<ObjectTemplate[]>>('/api/v1/templates');
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 将文件注册为模板；shared 为 true 时注册为共享模板（需管理员权限）
+export const registerTemplate = async (
+  objectId: number,
+  name: string,
+  description = '',
+  shared = false
+): Promise<ObjectTemplate> => {
+  const url = shared ? '/api/v1/admin/templates' : '/api/v1/templates';
+  const response = await apiClient.post<ApiResponse<ObjectTemplate>>(url, { object_id: objectId, name, description });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 删除模板（模板文件保留）；删除共享模板需管理员权限
+export const deleteTemplate = async (template: ObjectTemplate): Promise<void> => {
+  const url = template.shared ? `/api/v1/admin/templates/${template.id}` : `/api/v1/templates/${template.id}`;
+  const response = await apiClient.delete<ApiResponse<null>>(url);
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+};
+
+// 从模板创建文件；名称不含扩展名时使用模板文件的扩展名
+export const createFromTemplate = async (templateId: number, name: string, parentId?: number): Promise<FileItem> => {
+  const response = await apiClient.post<ApiResponse<FileItem>>('/api/v1/objects/from-template', {
+    template_id: templateId,
+    name,
+    parent_id: parentId
+  });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
 // 更新对象（重命名等）
 export const updateObject = async (id: number, input: { name?: string; description?: string }): Promise<FileItem> => {
   const response = await apiClient.put<ApiResponse<FileItem>>(`/api/v1/objects/${id}`, input);
@@ -311,6 +493,50 @@ export const deleteObject = async (id: number): Promise<void> => {
   }
 };
 
+// 获取回收站（每条为一次删除操作）
+export const listTrash = async (page: number = 1, pageSize: number = 20): Promise<PaginatedResponse<DeleteBatch>> => {
+  const response = await apiClient.get<ApiResponse<PaginatedResponse<DeleteBatch>>>('/api/v1/objects/trash', {
+    params: { page, page_size: pageSize }
+  });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 恢复已删除的对象（连同一起删除的子对象）
+export const restoreObject = async (id: number): Promise<{ object: FileItem; restored: number; note?: string }> => {
+  const response = await apiClient.post<ApiResponse<{ object: FileItem; restored: number; note?: string }>>(`/api/v1/objects/${id}/restore`);
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 记录 Notebook 执行（默认关联当前版本）
+export const recordNotebookRun = async (id: number, kernelId: string, durationMs: number, versionNumber?: number): Promise<NotebookRun> => {
+  const response = await apiClient.post<ApiResponse<NotebookRun>>(`/api/v1/objects/${id}/runs`, {
+    kernel_id: kernelId,
+    duration_ms: durationMs,
+    version_number: versionNumber
+  });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 获取 Notebook 执行记录
+export const listNotebookRuns = async (id: number, page: number = 1, pageSize: number = 20): Promise<PaginatedResponse<NotebookRun>> => {
+  const response = await apiClient.get<ApiResponse<PaginatedResponse<NotebookRun>>>(`/api/v1/objects/${id}/runs`, {
+    params: { page, page_size: pageSize }
+  });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
 // 移动对象
 export const moveObject = async (id: number, targetParentId?: number, newName?: string): Promise<FileItem> => {
   const response = await apiClient.post<ApiResponse<FileItem>>(`/api/v1/objects/${id}/move`, {
@@ -335,6 +561,26 @@ export const copyObject = async (id: number, targetParentId?: number, newName?:
   return response.data.data!;
 };
 
+// 预览移动/复制/删除将影响的对象（dry run，不做任何修改）
+export const planObjectOperation = async (
+  operation: 'move' | 'copy' | 'delete',
+  id: number,
+  targetParentId?: number,
+  newName?: string
+): Promise<OperationPlan> => {
+  const params = { dry_run: true };
+  const response = operation === 'delete'
+    ? await apiClient.delete<ApiResponse<OperationPlan>>(`/api/v1/objects/${id}`, { params })
+    : await apiClient.post<ApiResponse<OperationPlan>>(`/api/v1/objects/${id}/${operation}`, {
+        target_parent_id: targetParentId,
+        new_name: newName
+      }, { params });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
 // 下载文件
 export const downloadFile = async (id: number, fileName: string): Promise<void> => {
   const response = await apiClient.get(`/api/v1/objects/${id}/download`, {
@@ -353,6 +599,84 @@ export const downloadFile = async (id: number, fileName: string): Promise<void>
   window.URL.revokeObjectURL(url);
 };
 
+// 获取文件可用的导出格式，用于构建导出菜单
+export const getExportFormats = async (id: number): Promise<ExportFormat[]> => {
+  const response = await apiClient.get<ApiResponse<ExportFormat[]>>(`/api/v1/objects/${id}/export-formats`);
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 启动后台导出任务（PDF 等耗时格式）
+export const startExportJob = async (id: number, format: string): Promise<ExportJob> => {
+  const response = await apiClient.post<ApiResponse<ExportJob>>(`/api/v1/objects/${id}/export-jobs`, { format });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 获取可推送文件的存储后端名称
+export const getExportBackends = async (): Promise<string[]> => {
+  const response = await apiClient.get<ApiResponse<string[]>>('/api/v1/export-backends');
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 将文件或目录的副本推送到存储后端，返回导出任务
+export const exportToBackend = async (id: number, backend: string, destPrefix = ''): Promise<ExportJob> => {
+  const response = await apiClient.post<ApiResponse<ExportJob>>(`/api/v1/objects/${id}/export-to-backend`, {
+    backend,
+    dest_prefix: destPrefix
+  });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 获取导出任务状态
+export const getExportJob = async (jobId: string): Promise<ExportJob> => {
+  const response = await apiClient.get<ApiResponse<ExportJob>>(`/api/v1/export-jobs/${jobId}`);
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 按指定格式导出并下载文件；异步格式会等待导出任务完成
+export const exportFile = async (id: number, format: ExportFormat, fileName: string): Promise<void> => {
+  let url = `/api/v1/objects/${id}/export`;
+  let params: Record<string, string> | undefined = { format: format.id };
+  if (format.async) {
+    let job = await startExportJob(id, format.id);
+    while (job.status === 'pending' || job.status === 'running') {
+      await new Promise(resolve => setTimeout(resolve, 1000));
+      job = await getExportJob(job.id);
+    }
+    if (job.status === 'failed') {
+      throw new Error(job.error || 'export failed');
+    }
+    url = `/api/v1/export-jobs/${job.id}/download`;
+    params = undefined;
+  }
+
+  const response = await apiClient.get(url, { params, responseType: 'blob' });
+
+  const blob = new Blob([response.data], { type: format.mime_type });
+  const url = window.URL.createObjectURL(blob);
+  const link = document.createElement('a');
+  link.href = url;
+  link.download = fileName.replace(/\.[^./]*$/, '') + format.extension;
+  document.body.appendChild(link);
+  link.click();
+  document.body.removeChild(link);
+  window.URL.revokeObjectURL(url);
+};
+
 // 获取文件下载 URL（用于在新标签页打开）
 export const getDownloadUrl = (id: number): string => {
   const baseURL = (import.meta.env?.VITE_API_BASE_URL as string) || 'http://localhost:8080';
@@ -403,6 +727,26 @@ export const searchByContent = async (
   return response.data.data!;
 };
 
+// 在目录子树内搜索
+export const searchInDirectory = async (
+  dirId: number,
+  query: string,
+  mode: 'name' | 'content' = 'name',
+  types?: string[],
+  page: number = 1,
+  pageSize: number = 20
+): Promise<PaginatedResponse<SearchSuggestion>> => {
+  const params: any = { q: query, mode, page, page_size: pageSize };
+  if (types && types.length > 0) {
+    params.type = types;
+  }
+  const response = await apiClient.get<ApiResponse<PaginatedResponse<SearchSuggestion>>>(`/api/v1/objects/${dirId}/search`, { params });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
 // 按标签搜索
 export const searchByTag = async (tag: string, page: number = 1, pageSize: number = 20): Promise<PaginatedResponse<SearchSuggestion>> => {
   const response = await apiClient.get<ApiResponse<PaginatedResponse<SearchSuggestion>>>('/api/v1/search/tags', {
@@ -527,6 +871,21 @@ export const restoreVersion = async (versionId: string): Promise<FileItem> => {
   return response.data.data!;
 };
 
+// 批量删除版本（仅所有者；当前版本和最近的版本受保护）
+export const deleteVersions = async (
+  objectId: number,
+  versionNumbers: number[]
+): Promise<{ removed: number[]; protected: number[]; not_found?: number[] }> => {
+  const response = await apiClient.post<ApiResponse<{ removed: number[]; protected: number[]; not_found?: number[] }>>(
+    `/api/v1/versions/objects/${objectId}/delete`,
+    { version_numbers: versionNumbers }
+  );
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
 // 获取版本详情
 export const getVersionById = async (versionId: string): Promise<VersionInfo> => {
   const response = await apiClient.get<ApiResponse<VersionInfo>>(
@@ -538,4 +897,57 @@ export const getVersionById = async (versionId: string): Promise<VersionInfo> =>
   return response.data.data!;
 };
 
+// 注册 Webhook（目录会同时收到其下所有对象的事件）
+export const registerWebhook = async (objectId: number, url: string, events?: string[], secret?: string): Promise<Webhook> => {
+  const response = await apiClient.post<ApiResponse<Webhook>>('/api/v1/webhooks', {
+    object_id: objectId,
+    url,
+    events,
+    secret
+  });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 获取当前用户的 Webhook 列表
+export const listWebhooks = async (page: number = 1, pageSize: number = 20): Promise<PaginatedResponse<Webhook>> => {
+  const response = await apiClient.get<ApiResponse<PaginatedResponse<Webhook>>>('/api/v1/webhooks', {
+    params: { page, page_size: pageSize }
+  });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 删除 Webhook
+export const deleteWebhook = async (id: string): Promise<void> => {
+  const response = await apiClient.delete<ApiResponse>(`/api/v1/webhooks/${id}`);
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+};
+
+// 获取站内通知
+export const listNotifications = async (unreadOnly: boolean = false, page: number = 1, pageSize: number = 20): Promise<PaginatedResponse<UserNotification>> => {
+  const response = await apiClient.get<ApiResponse<PaginatedResponse<UserNotification>>>('/api/v1/notifications', {
+    params: { unread: unreadOnly || undefined, page, page_size: pageSize }
+  });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
+
+// 标记通知为已读（不传 ids 时全部标记）
+export const markNotificationsRead = async (ids?: string[]): Promise<number> => {
+  const response = await apiClient.post<ApiResponse<{ marked: number }>>('/api/v1/notifications/read', { ids });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!.marked;
+};
+
 export default apiClient;
@@ -1,5 +1,5 @@
 import apiClient from './api';
-import { ApiResponse, LoginInput, RegisterInput, AuthOutput, UserResponse, API_CODE } from '../types';
+import { ApiResponse, LoginInput, RegisterInput, AuthOutput, UserResponse, AccountDeletion, API_CODE } from '../types';
 
 // 认证相关 API
 
@@ -65,3 +65,14 @@ export const changePassword = async (oldPassword: string, newPassword: string):
     throw new Error(response.data.message);
   }
 };
+
+// 删除当前账号，需再次输入密码确认
+export const deleteAccount = async (password: string): Promise<AccountDeletion> => {
+  const response = await apiClient.delete<ApiResponse<AccountDeletion>>('/api/v1/users/me', {
+    data: { password }
+  });
+  if (response.data.code !== API_CODE.SUCCESS) {
+    throw new Error(response.data.message);
+  }
+  return response.data.data!;
+};
@@ -14,10 +14,25 @@ export interface KernelSpec {
 export interface KernelInfo {
   id: string;
   name: string;
-  status: 'starting' | 'idle' | 'busy' | 'dead';
+  status: 'starting' | 'idle' | 'busy' | 'dead' | 'unresponsive';
   execution_count: number;
   last_activity: string;
   user_id: number;
+  allow_concurrent_execution: boolean; // 是否并发执行单元格（仅本地内核）
+}
+
+// 为某个笔记本启动的内核，包括协作者的内核；其他实例上的内核 status 为 'remote'
+export interface ObjectKernel extends Omit<KernelInfo, 'status' | 'user_id'> {
+  status: KernelInfo['status'] | 'remote';
+  user_id: string;
+  object_id?: number;
+  owner?: {
+    id: string;
+    username: string;
+    email: string;
+    display_name?: string;
+    avatar_url?: string;
+  };
 }
 
 export interface KernelStatus {
@@ -27,6 +42,7 @@ export interface KernelStatus {
   execution_count: number;
   last_activity: string;
   connection_status: string;
+  allow_concurrent_execution: boolean;
 }
 
 export interface KernelMessage {
@@ -43,6 +59,9 @@ export interface ExecuteRequest {
   silent?: boolean;
   store_history?: boolean;
   cell_id?: string;
+  line_offset?: number; // 执行选中代码时，选区之前的行数，使错误行号与单元格一致
+  cwd?: string; // 执行时的工作目录，相对于工作区根目录（仅本地内核生效）
+  capture_outputs?: boolean; // 在空目录中执行，并将写出的文件导入结果文件夹；execute_reply 的 metadata.captured_outputs 列出对象 ID（仅本地内核生效）
 }
 
 export interface CellOutput {
@@ -99,10 +118,15 @@ export async function listKernels(): Promise<KernelInfo[]> {
   return apiCall<KernelInfo[]>('/api/v1/kernels');
 }
 
-export async function startKernel(name: string): Promise<KernelInfo> {
+export async function listObjectKernels(objectId: number): Promise<ObjectKernel[]> {
+  return apiCall<ObjectKernel[]>(`/api/v1/objects/${objectId}/kernels`);
+}
+
+// allowConcurrentExecution 为 true 时单元格提交后立即执行，不再按顺序排队
+export async function startKernel(name: string, allowConcurrentExecution = false): Promise<KernelInfo> {
   return apiCall<KernelInfo>('/api/v1/kernels', {
     method: 'POST',
-    body: JSON.stringify({ name }),
+    body: JSON.stringify({ name, allow_concurrent_execution: allowConcurrentExecution }),
   });
 }
 
@@ -124,10 +148,33 @@ export async function interruptKernel(kernelId: string): Promise<void> {
   });
 }
 
+export async function resetKernel(kernelId: string): Promise<void> {
+  await apiCall<void>(`/api/v1/kernels/${kernelId}/reset`, {
+    method: 'POST',
+  });
+}
+
 export async function getKernelStatus(kernelId: string): Promise<KernelStatus> {
   return apiCall<KernelStatus>(`/api/v1/kernels/${kernelId}`);
 }
 
+export interface NotebookRunStarted {
+  object_id: number;
+  kernel_id: string;
+  cells: number;
+}
+
+// 在服务端后台依次运行笔记本的所有代码单元格，输出会定期写入笔记本文件，结束后保存为一个版本
+export async function runNotebook(kernelId: string, objectId: number): Promise<NotebookRunStarted> {
+  return apiCall<NotebookRunStarted>(`/api/v1/kernels/${kernelId}/run-notebook`, {
+    method: 'POST',
+    body: JSON.stringify({ object_id: objectId }),
+  });
+}
+
+// Close code the server sends when a connection has been idle too long
+const WS_CLOSE_IDLE_TIMEOUT = 4408;
+
 // Kernel WebSocket connection manager
 export class KernelConnection {
   private ws: WebSocket | null = null;
@@ -162,6 +209,12 @@ export class KernelConnection {
         console.log(`Kernel WebSocket closed: ${this.kernelId}`, event.code, event.reason);
         this.connected = false;
         this.notifyStatus('disconnected');
+
+        // The server closed an idle connection; the kernel is still running,
+        // so leave reconnecting to the next connect() call
+        if (event.code === WS_CLOSE_IDLE_TIMEOUT) {
+          return;
+        }
         
         // Attempt reconnection
         if (this.reconnectAttempts < this.maxReconnectAttempts) {
@@ -21,11 +21,19 @@ export interface FileItem {
   type: FileType;
   path: string;
   full_path: string; // Databricks-style path: /Workspace/Users/{email}/...
+  display_path: string; // Path relative to the workspace root: /...
   parent_id?: number | null;
   size: number;
+  mime_type?: string; // 用户设置的 MIME 类型，覆盖按扩展名推断的类型
+  language?: string; // 服务端推断的语法高亮语言（如 python），未知为 plaintext，目录无此字段
   description?: string;
   current_version: number;
   creator?: UserResponse;
+  last_modified_by?: UserResponse;
+  has_children?: boolean; // Directories in lazily loaded trees
+  child_count?: number;
+  truncated?: boolean; // 目录树达到深度上限，子节点未返回
+  pinned?: boolean; // 置顶，在目录中排在最前
   tags?: TagResponse[];
   created_at: string;
   updated_at: string;
@@ -33,6 +41,161 @@ export interface FileItem {
   content?: string; // 前端缓存的文件内容
 }
 
+// 移动/复制/删除的 dry-run 结果（匹配后端 OperationPlan）
+export interface PlannedChange {
+  id: number; // 现有对象，复制时为源对象
+  name: string;
+  type: FileType;
+  path: string;
+  new_path?: string; // 移动后的路径或副本路径
+}
+
+export interface OperationPlan {
+  operation: 'move' | 'copy' | 'delete';
+  objects: PlannedChange[]; // 对象本身在前，随后是其后代
+}
+
+// 回收站条目：一次删除操作（匹配后端 DeleteBatch）
+export interface DeleteBatch {
+  id: string;
+  root_object_id: number;
+  root_name: string;
+  root_path: string;
+  item_count: number; // 删除的对象数，包括子对象
+  deleted_by?: string;
+  deleted_at: string;
+}
+
+// Notebook 执行记录（匹配后端 NotebookRun）
+export interface NotebookRun {
+  id: string;
+  object_id: number;
+  version_id?: string; // 版本被清理后为空
+  version_number: number; // 保存本次执行结果的版本号
+  kernel_id: string;
+  duration_ms: number;
+  run_by?: string;
+  ran_at: string;
+}
+
+// Webhook 订阅（匹配后端 Webhook）
+export interface Webhook {
+  id: string;
+  object_id: number;
+  creator_id: string;
+  url: string;
+  secret?: string; // 仅在注册时返回，用于校验 X-Workspace-Signature
+  events: string[]; // 订阅的事件类型，为空表示全部
+  created_at: string;
+}
+
+// 文件访问统计（匹配后端 ObjectAccessStats）
+export interface ObjectAccessStats {
+  object_id: number;
+  download_count: number; // 内容读取与下载次数
+  last_accessed_at?: string;
+  distinct_users?: number; // 仅在启用访问日志时返回
+}
+
+// 文件模板（匹配后端 ObjectTemplate）
+export interface ObjectTemplate {
+  id: number;
+  object_id: number;
+  object_name: string; // 模板文件名
+  object_type: string;
+  name: string; // 模板选择器中显示的名称
+  description?: string;
+  shared: boolean; // 共享模板对所有用户可见，由管理员注册
+  owner_id?: string;
+  created_by?: string;
+  created_at: string;
+}
+
+// 维护状态（匹配后端 MaintenanceMode）
+export interface MaintenanceMode {
+  enabled: boolean; // 为 true 时显示横幅
+  message?: string; // 横幅文本
+  read_only: boolean; // 为 true 时服务端拒绝写操作
+}
+
+// 客户端配置（匹配后端 ClientConfig）
+export interface ClientConfig {
+  maintenance: MaintenanceMode;
+}
+
+// 文件导出格式（匹配后端 ExportFormat）
+export interface ExportFormat {
+  id: string; // 导出时作为 format 参数传入
+  label: string; // 菜单显示名称
+  mime_type: string;
+  extension: string; // 导出文件扩展名，包含点
+  async: boolean; // 为 true 时需通过导出任务导出
+}
+
+// 导出到存储后端的单个文件结果（匹配后端 ExportedFile）
+export interface ExportedFile {
+  path: string; // 后端中的路径
+  size: number;
+  error?: string;
+}
+
+// 导出任务（匹配后端 ExportJob）
+export interface ExportJob {
+  id: string;
+  object_id: number;
+  format?: string; // 格式导出任务
+  backend?: string; // 推送到存储后端的任务
+  destination?: string;
+  status: 'pending' | 'running' | 'done' | 'failed';
+  error?: string;
+  files?: ExportedFile[]; // 推送的文件，包括失败的
+  created_at: string;
+  finished_at?: string; // 完成后一段时间过期
+}
+
+// 内容校验结果（匹配后端 ValidationResult）
+export interface ValidationIssue {
+  message: string;
+  line?: number; // 从 1 开始，未知时省略
+  column?: number;
+  path?: string; // 结构中的位置，如 cells[2].source
+}
+
+export interface ValidationResult {
+  valid: boolean;
+  format?: string; // 使用的校验格式：notebook、json、yaml；未校验时省略
+  issues?: ValidationIssue[];
+}
+
+// 分片上传会话（匹配后端 UploadSession）
+export interface UploadSession {
+  id: string;
+  user_id: string;
+  name: string;
+  type?: string;
+  parent_id?: number;
+  description?: string;
+  size: number; // 文件总大小（字节）
+  hash?: string; // 预期的 SHA-256，完成时校验
+  received: number; // 已接收字节数，即下一个分片的 offset
+  created_at: string;
+  updated_at: string;
+  expires_at: string;
+}
+
+// 站内通知（匹配后端 Notification）
+export interface UserNotification {
+  id: string;
+  user_id: string;
+  type: 'permission_granted' | 'shared_copy'; // 被授予权限 / 他人复制到我的目录
+  object_id?: number;
+  object_name: string;
+  actor_id?: string;
+  role?: string; // permission_granted 时为授予的角色
+  read_at?: string;
+  created_at: string;
+}
+
 // 用户信息（匹配后端 UserResponse）
 export interface UserResponse {
   id: string; // UUID
@@ -44,6 +207,13 @@ export interface UserResponse {
   created_at: string;
 }
 
+// 账号删除结果（匹配后端 account.Deletion）
+export interface AccountDeletion {
+  mode: 'disable' | 'erase'; // disable: 宽限期后清除；erase: 已立即清除
+  erase_after?: string; // 宽限期结束时间
+  transferred?: { object_id: number; path: string; to: string }[]; // 转交给协作者的共享对象
+}
+
 // 用户信息（前端使用）
 export interface User {
   id: string;